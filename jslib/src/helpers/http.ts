@@ -20,6 +20,29 @@
 
 class ResponseError extends Error {
   response: Response;
+
+  // fields maps the name of each invalid field to the reason, if the server
+  // rejected the payload in the validation
+  fields?: { [field: string]: string };
+}
+
+// getErrorDetail returns the message to show for the body of a failed response,
+// and the invalid fields if the body is a validation error
+function getErrorDetail(
+  response: Response,
+  body: string
+): { message: string; fields?: { [field: string]: string } } {
+  if (response.headers.get('Content-Type') !== 'application/json') {
+    return { message: body };
+  }
+
+  try {
+    const data = JSON.parse(body);
+
+    return { message: data.message || body, fields: data.fields };
+  } catch (e) {
+    return { message: body };
+  }
 }
 
 function checkStatus(response: Response): Response | Promise<Response> {
@@ -28,8 +51,11 @@ function checkStatus(response: Response): Response | Promise<Response> {
   }
 
   return response.text().then(body => {
-    const error = new ResponseError(body);
+    const { message, fields } = getErrorDetail(response, body);
+
+    const error = new ResponseError(message);
     error.response = response;
+    error.fields = fields;
 
     throw error;
   });
@@ -194,6 +194,7 @@ type SyncFragNote struct {
 	Body      string    `json:"content"`
 	Public    bool      `json:"public"`
 	Deleted   bool      `json:"deleted"`
+	Source    string    `json:"source"`
 }
 
 // SyncFragBook represents a book in a sync fragment and contains only the necessary information
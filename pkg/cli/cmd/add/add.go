@@ -19,32 +19,81 @@
 package add
 
 import (
-	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
 	"github.com/dnote/dnote/pkg/cli/ui"
 	//"github.com/dnote/dnote/pkg/cli/upgrade"
-	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/dnote/dnote/pkg/cli/validate"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var contentFlag string
+var quietFlag bool
+var dateFlag string
+var allowFutureFlag bool
+var allowEmptyFlag bool
+var inboxFlag bool
+var fromStdinFlag bool
+
+// errEmptyStdin is returned when --from-stdin reads no content
+var errEmptyStdin = errors.New("no content was given on the standard input")
+
+// errTemplateUnchanged is returned when the editor is closed without changing
+// the template it was pre-filled with
+var errTemplateUnchanged = errors.New("the template was not changed, note not saved")
+
+// fetchTimeout is the time allowed for fetching the content from a URL
+const fetchTimeout = 10 * time.Second
 
 var example = `
  * Open an editor to write content
  dnote new git
 
  * Skip the editor by providing content directly
- dnote new git -c "time is a part of the commit hash"`
+ dnote new git -c "time is a part of the commit hash"
+
+ * Print only the id of the new note
+ dnote new git -c "time is a part of the commit hash" -q
+
+ * Read the content from a file or a URL
+ dnote new git -c @./notes/commit.md
+ dnote new reading -c @https://example.com/article.txt
+
+ * Add a note to the inbox book, reading the content from the standard input
+ echo "call the bank" | dnote new --inbox
+
+ * Read the whole standard input as the content, failing if it is empty
+ cat todo.txt | dnote new chores --from-stdin
+
+ * Backdate the note
+ dnote new git -c "time is a part of the commit hash" --date 2019-06-01`
 
 func preRun(cmd *cobra.Command, args []string) error {
+	if fromStdinFlag && contentFlag != "" {
+		return errors.New("--from-stdin cannot be used with --content")
+	}
+
+	if inboxFlag {
+		if len(args) != 0 {
+			return errors.New("--inbox cannot be used with a book name")
+		}
+
+		return nil
+	}
 	if len(args) != 1 {
 		return errors.New("Incorrect number of argument")
 	}
@@ -55,7 +104,7 @@ func preRun(cmd *cobra.Command, args []string) error {
 // NewCmd returns a new add command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "new <book>",
+		Use:     "new <book?>",
 		Short:   "Add a new note",
 		Aliases: []string{"n", "add"},
 		Example: example,
@@ -64,14 +113,140 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	}
 
 	f := cmd.Flags()
-	f.StringVarP(&contentFlag, "content", "c", "", "The new content for the note")
+	f.StringVarP(&contentFlag, "content", "c", "", "The new content for the note. Prefix a path or a URL with @ to read the content from it")
+	f.BoolVarP(&quietFlag, "quiet", "q", false, "print only the id of the new note")
+	f.StringVarP(&dateFlag, "date", "", "", "the date the note was added, in YYYY-MM-DD or RFC3339")
+	f.BoolVarP(&allowFutureFlag, "allow-future", "", false, "allow --date to be in the future")
+	f.BoolVarP(&inboxFlag, "inbox", "", false, "add the note to the inbox book set by inboxBook in the config, 'inbox' by default")
+	f.BoolVarP(&allowEmptyFlag, "allow-empty", "", false, "save the note even if its content is empty")
+	f.BoolVarP(&fromStdinFlag, "from-stdin", "", false, "read the content from the standard input, and fail if it is empty")
 
 	return cmd
 }
 
-func getContent(ctx context.DnoteCtx) (string, error) {
+// parseDate parses the given date in either YYYY-MM-DD in the local time zone or
+// RFC3339, and returns it as a unix timestamp in nanoseconds. Unless allowFuture
+// is true, the date must not be later than now.
+func parseDate(s string, now time.Time, allowFuture bool) (int64, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return 0, errors.Errorf("invalid date '%s'. Use YYYY-MM-DD or RFC3339", s)
+		}
+	}
+
+	if !allowFuture && t.After(now) {
+		return 0, errors.Errorf("date '%s' is in the future. Pass --allow-future to use it anyway", s)
+	}
+
+	return t.UnixNano(), nil
+}
+
+// readContentSource returns the content referenced by the given flag value. A
+// value with a leading '@' is read from the file or the http(s) URL following
+// it. Any other value is returned as is.
+func readContentSource(s string, hc *http.Client) (string, error) {
+	if !strings.HasPrefix(s, "@") {
+		return s, nil
+	}
+
+	src := strings.TrimPrefix(s, "@")
+	if src == "" {
+		return "", errors.New("no path or URL is given after '@'")
+	}
+
+	u, err := url.Parse(src)
+	if err == nil {
+		switch u.Scheme {
+		case "http", "https":
+			return fetchContent(src, hc)
+		case "file":
+			src = u.Path
+		}
+	}
+
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading '%s'", src)
+	}
+
+	return string(b), nil
+}
+
+// fetchContent returns the body of the response to a GET request to the given URL
+func fetchContent(u string, hc *http.Client) (string, error) {
+	res, err := hc.Get(u)
+	if err != nil {
+		return "", errors.Wrapf(err, "fetching '%s'", u)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("fetching '%s': %s", u, res.Status)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading the response from '%s'", u)
+	}
+
+	return string(b), nil
+}
+
+// readStdinContent reads the whole content from the given reader with CRLF line
+// endings normalized and the trailing newlines removed. It returns errEmptyStdin
+// if no content is left.
+func readStdinContent(r io.Reader) (string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", errors.Wrap(err, "reading the standard input")
+	}
+
+	content := strings.ReplaceAll(string(b), "\r\n", "\n")
+	content = strings.TrimRight(content, "\n")
+	if core.IsEmptyBody(content) {
+		return "", errEmptyStdin
+	}
+
+	return content, nil
+}
+
+// getSeed returns the content to pre-fill the editor with, which is the
+// template of the book rendered for the note being added
+func getSeed(ctx context.DnoteCtx, bookName string, now time.Time) (string, error) {
+	tmpl, ok, err := core.GetBookTemplate(ctx, bookName)
+	if err != nil {
+		return "", errors.Wrap(err, "getting the book template")
+	}
+	if !ok {
+		return "", nil
+	}
+
+	return core.RenderBookTemplate(tmpl, core.TemplateData{Book: bookName, Now: now})
+}
+
+// getContent returns the content from the flag, the standard input, or an
+// editor, in that order. Without --from-stdin, the standard input is read only
+// for --inbox if it is piped, so that scripts running dnote still get the editor.
+func getContent(ctx context.DnoteCtx, bookName string, now time.Time) (string, error) {
 	if contentFlag != "" {
-		return contentFlag, nil
+		hc := &http.Client{Timeout: fetchTimeout}
+
+		return readContentSource(contentFlag, hc)
+	}
+	if fromStdinFlag {
+		return readStdinContent(os.Stdin)
+	}
+
+	if inboxFlag {
+		piped, ok, err := ui.ReadPipedInput()
+		if err != nil {
+			return "", errors.Wrap(err, "reading the standard input")
+		}
+		if ok {
+			return piped, nil
+		}
 	}
 
 	fpath, err := ui.GetTmpContentPath(ctx)
@@ -79,37 +254,74 @@ func getContent(ctx context.DnoteCtx) (string, error) {
 		return "", errors.Wrap(err, "getting temporarily content file path")
 	}
 
+	seed, err := getSeed(ctx, bookName, now)
+	if err != nil {
+		return "", err
+	}
+	if seed != "" {
+		if err := ioutil.WriteFile(fpath, []byte(seed), 0644); err != nil {
+			return "", errors.Wrap(err, "preparing tmp content file")
+		}
+	}
+
 	c, err := ui.GetEditorInput(ctx, fpath)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to get editor input")
 	}
+	if seed != "" && strings.TrimSpace(c) == strings.TrimSpace(seed) {
+		return "", errTemplateUnchanged
+	}
 
 	return c, nil
 }
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		bookName := args[0]
+		var bookName string
+		if inboxFlag {
+			bookName = ctx.InboxBook
+		} else {
+			bookName = args[0]
+		}
 		if err := validate.BookName(bookName); err != nil {
 			return errors.Wrap(err, "invalid book name")
 		}
 
-		content, err := getContent(ctx)
+		now := time.Now()
+		ts := now.UnixNano()
+		if dateFlag != "" {
+			t, err := parseDate(dateFlag, now, allowFutureFlag)
+			if err != nil {
+				return err
+			}
+
+			ts = t
+		}
+
+		content, err := getContent(ctx, bookName, time.Unix(0, ts))
 		if err != nil {
 			return errors.Wrap(err, "getting content")
 		}
-		if content == "" {
-			return errors.New("Empty content")
+		if ctx.TrimOnSave {
+			content = core.TrimBlankLines(content)
+		}
+		if core.IsEmptyBody(content) && !allowEmptyFlag {
+			return core.ErrEmptyNote
 		}
 
-		ts := time.Now().UnixNano()
-		noteRowID, err := writeNote(ctx, bookName, content, ts)
+		note, err := core.AddNote(ctx, bookName, content, ts)
 		if err != nil {
 			return errors.Wrap(err, "Failed to write note")
 		}
+		noteRowID := note.RowID
+
+		if quietFlag {
+			fmt.Printf("%d\n", noteRowID)
+			return nil
+		}
+
+		log.Successf("added to %s (%d)\n", bookName, noteRowID)
 
-		log.Successf("added to %s\n", bookName)
-		
 		db := ctx.DB
 		info, err := database.GetNoteInfo(db, noteRowID)
 		if err != nil {
@@ -121,59 +333,3 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 		return nil
 	}
 }
-
-func writeNote(ctx context.DnoteCtx, bookLabel string, content string, ts int64) (int, error) {
-	tx, err := ctx.DB.Begin()
-	if err != nil {
-		return 0, errors.Wrap(err, "beginning a transaction")
-	}
-
-	var bookUUID string
-	err = tx.QueryRow("SELECT uuid FROM books WHERE label = ?", bookLabel).Scan(&bookUUID)
-	if err == sql.ErrNoRows {
-		bookUUID, err = utils.GenerateUUID()
-		if err != nil {
-			return 0, errors.Wrap(err, "generating uuid")
-		}
-
-		b := database.NewBook(bookUUID, bookLabel, 0, false, true)
-		err = b.Insert(tx)
-		if err != nil {
-			tx.Rollback()
-			return 0, errors.Wrap(err, "creating the book")
-		}
-	} else if err != nil {
-		return 0, errors.Wrap(err, "finding the book")
-	}
-
-	noteUUID, err := utils.GenerateUUID()
-	if err != nil {
-		return 0, errors.Wrap(err, "generating uuid")
-	}
-
-	n := database.NewNote(noteUUID, bookUUID, content, ts, 0, 0, false, false, true)
-
-	err = n.Insert(tx)
-	if err != nil {
-		tx.Rollback()
-		return 0, errors.Wrap(err, "creating the note")
-	}
-
-	var noteRowID int
-	err = tx.QueryRow(`SELECT notes.rowid
-			FROM notes
-			WHERE notes.uuid = ?`, noteUUID).
-		Scan(&noteRowID)
-	if err != nil {
-		tx.Rollback()
-		return noteRowID, errors.Wrap(err, "getting the note rowid")
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		return noteRowID, errors.Wrap(err, "committing a transaction")
-	}
-
-	return noteRowID, nil
-}
@@ -0,0 +1,208 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package add
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2020, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		input       string
+		allowFuture bool
+		expected    int64
+		expectedErr bool
+	}{
+		{
+			input:    "2019-06-01T09:30:00Z",
+			expected: time.Date(2019, time.June, 1, 9, 30, 0, 0, time.UTC).UnixNano(),
+		},
+		{
+			input:    "2019-06-01",
+			expected: time.Date(2019, time.June, 1, 0, 0, 0, 0, time.Local).UnixNano(),
+		},
+		{
+			input:       "2019-13-01",
+			expectedErr: true,
+		},
+		{
+			input:       "yesterday",
+			expectedErr: true,
+		},
+		{
+			input:       "2021-01-01",
+			expectedErr: true,
+		},
+		{
+			input:       "2021-01-01",
+			allowFuture: true,
+			expected:    time.Date(2021, time.January, 1, 0, 0, 0, 0, time.Local).UnixNano(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseDate(tc.input, now, tc.allowFuture)
+
+			assert.Equal(t, err != nil, tc.expectedErr, "error mismatch")
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestReadContentSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnote-add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "note.md")
+	if err := ioutil.WriteFile(fpath, []byte("# title\nfile body\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article.txt":
+			fmt.Fprint(w, "remote body")
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, "too late")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	hc := &http.Client{Timeout: 50 * time.Millisecond}
+
+	testCases := []struct {
+		input       string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			input:    "plain content",
+			expected: "plain content",
+		},
+		{
+			input:    "email me at foo@example.com",
+			expected: "email me at foo@example.com",
+		},
+		{
+			input:    "@" + fpath,
+			expected: "# title\nfile body\n",
+		},
+		{
+			input:    "@file://" + fpath,
+			expected: "# title\nfile body\n",
+		},
+		{
+			input:    "@" + server.URL + "/article.txt",
+			expected: "remote body",
+		},
+		{
+			input:       "@" + server.URL + "/missing",
+			expectedErr: true,
+		},
+		{
+			input:       "@" + server.URL + "/slow",
+			expectedErr: true,
+		},
+		{
+			input:       "@" + filepath.Join(dir, "missing.md"),
+			expectedErr: true,
+		},
+		{
+			input:       "@",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := readContentSource(tc.input, hc)
+
+			assert.Equal(t, err != nil, tc.expectedErr, "error mismatch")
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestReadStdinContent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "single line",
+			input:    "call the bank\n",
+			expected: "call the bank",
+		},
+		{
+			name:     "multiple lines",
+			input:    "# groceries\n\n- milk\n- eggs\n\n\n",
+			expected: "# groceries\n\n- milk\n- eggs",
+		},
+		{
+			name:     "CRLF",
+			input:    "# groceries\r\n- milk\r\n- eggs\r\n",
+			expected: "# groceries\n- milk\n- eggs",
+		},
+		{
+			name:     "no trailing newline",
+			input:    "call the bank",
+			expected: "call the bank",
+		},
+		{
+			name:        "empty",
+			input:       "",
+			expectedErr: errEmptyStdin,
+		},
+		{
+			name:        "only whitespace",
+			input:       "\r\n  \n\n",
+			expectedErr: errEmptyStdin,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readStdinContent(strings.NewReader(tc.input))
+
+			assert.Equal(t, err, tc.expectedErr, "error mismatch")
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
@@ -19,29 +19,66 @@
 package archive
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/root"
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/dnote/dnote/pkg/cli/validate"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	
-	//"strconv"
 )
 
 var reverseFlag bool
+var toggleFlag bool
+var olderThanFlag string
+var yesFlag bool
+var quietFlag bool
 
 var example = `
  * Archive a book
  dnote archive git
 
  * Reverse archiving a book
- dnote archive git -reverse`
+ dnote archive git --reverse
+
+ * Archive a book if it is active, or de-archive it if it is archived
+ dnote archive git --toggle
+
+ * See what would be archived without archiving
+ dnote archive git --dry-run
+
+ * Archive all books without a new or edited note in the last 90 days
+ dnote archive --older-than 90d`
 
 func preRun(cmd *cobra.Command, args []string) error {
+	if olderThanFlag != "" {
+		if len(args) != 0 {
+			return errors.New("--older-than cannot be used with a book name")
+		}
+		if reverseFlag {
+			return errors.New("--older-than cannot be used with --reverse")
+		}
+		if toggleFlag {
+			return errors.New("--older-than cannot be used with --toggle")
+		}
+
+		return nil
+	}
+
 	if len(args) != 1 {
 		return errors.New("Incorrect number of argument")
 	}
+	if toggleFlag && reverseFlag {
+		return errors.New("--toggle cannot be used with --reverse")
+	}
 
 	return nil
 }
@@ -49,7 +86,7 @@ func preRun(cmd *cobra.Command, args []string) error {
 // NewCmd returns a new add command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "archive <book>",
+		Use:     "archive [<book>]",
 		Short:   "Archive a book",
 		Aliases: []string{"a"},
 		Example: example,
@@ -59,41 +96,235 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 
 	f := cmd.Flags()
 	f.BoolVarP(&reverseFlag, "reverse", "r", false, "Reverse archiving a book")
+	f.BoolVarP(&toggleFlag, "toggle", "t", false, "Archive the book if it is active, or de-archive it if it is archived")
+	f.StringVarP(&olderThanFlag, "older-than", "", "", "archive all books whose latest note is older than the given age. e.g. 90d, 2w, 36h")
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+	f.BoolVarP(&quietFlag, "quiet", "q", false, "Do not print the progress and the archived books")
 
 	return cmd
 }
 
+// parseAge parses an age such as "90d". On top of the units supported by
+// time.ParseDuration, it supports days ("d") and weeks ("w").
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+		if err != nil || n < 0 {
+			return 0, errors.Errorf("invalid age '%s'", s)
+		}
+
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.Errorf("invalid age '%s'. e.g. 90d, 2w, 36h", s)
+	}
+
+	return d, nil
+}
+
+// staleBook is a book whose latest note is older than a given age
+type staleBook struct {
+	UUID      string
+	Label     string
+	NoteCount int
+}
+
+// getStaleBooks returns the active books none of whose notes have been added
+// or edited since the given time. Books without notes are not included.
+func getStaleBooks(db *database.DB, cutoff int64) ([]staleBook, error) {
+	rows, err := db.Query(`SELECT books.uuid, books.label, count(notes.uuid) note_count
+	FROM books
+	INNER JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
+	WHERE books.deleted = false
+		AND books.archive = false
+	GROUP BY books.uuid
+	HAVING max(max(notes.added_on, notes.edited_on)) < ?
+	ORDER BY books.label ASC;`, cutoff)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	ret := []staleBook{}
+	for rows.Next() {
+		var b staleBook
+		if err := rows.Scan(&b.UUID, &b.Label, &b.NoteCount); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating books")
+	}
+
+	return ret, nil
+}
+
+func runOlderThan(ctx context.DnoteCtx, age string) error {
+	d, err := parseAge(age)
+	if err != nil {
+		return err
+	}
+
+	cutoff := ctx.Clock.Now().Add(-d).UnixNano()
+	books, err := getStaleBooks(ctx.DB, cutoff)
+	if err != nil {
+		return errors.Wrap(err, "finding books to archive")
+	}
+
+	if len(books) == 0 {
+		log.Infof("no books older than %s\n", age)
+		return nil
+	}
+
+	if root.DryRunFlag {
+		for _, b := range books {
+			log.Infof("would archive '%s' (%d notes)\n", b.Label, b.NoteCount)
+		}
+
+		return nil
+	}
+
+	if !quietFlag {
+		for _, b := range books {
+			log.Plainf("%s (%d notes)\n", b.Label, b.NoteCount)
+		}
+	}
+
+	ok, err := root.Confirm(ctx, fmt.Sprintf("archive %d books?", len(books)), false, yesFlag)
+	if err != nil {
+		return errors.Wrap(err, "getting confirmation")
+	}
+	if !ok {
+		log.Warnf("aborted by user\n")
+		return nil
+	}
+
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	p := ui.NewProgress("archiving", len(books), quietFlag)
+	for _, b := range books {
+		if _, err = tx.Exec("UPDATE books SET archive = ? WHERE uuid = ?", true, b.UUID); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "archiving '%s'", b.Label)
+		}
+
+		p.Done()
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "comitting transaction")
+	}
+
+	if quietFlag {
+		return nil
+	}
+	for _, b := range books {
+		log.Successf("archived %s\n", b.Label)
+	}
+
+	return nil
+}
+
+func runToggle(ctx context.DnoteCtx, bookName string) error {
+	archived, err := core.ToggleArchiveBook(ctx, bookName)
+	if err == core.ErrBookNotFound {
+		return errors.Errorf("book '%s' not found", bookName)
+	} else if err != nil {
+		return errors.Wrap(err, "toggling the archive state")
+	}
+
+	if archived {
+		log.Successf("archived %s\n", bookName)
+	} else {
+		log.Successf("de-archived %s\n", bookName)
+	}
+
+	return nil
+}
+
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		if olderThanFlag != "" {
+			return runOlderThan(ctx, olderThanFlag)
+		}
+
 		bookName := args[0]
 		if err := validate.BookName(bookName); err != nil {
 			return errors.Wrap(err, "invalid book name")
 		}
-		
-		tx, err := ctx.DB.Begin()
-		if err != nil {
-			return errors.Wrap(err, "beginning a transaction")
+
+		if root.DryRunFlag {
+			bookUUID, err := database.GetBookUUID(ctx.DB, bookName)
+			if err != nil {
+				return err
+			}
+
+			noteCount, err := database.CountBookNotes(ctx.DB, bookUUID)
+			if err != nil {
+				return errors.Wrap(err, "counting notes in the book")
+			}
+
+			unarchive := reverseFlag
+			if toggleFlag {
+				if err := ctx.DB.QueryRow("SELECT archive FROM books WHERE uuid = ?", bookUUID).Scan(&unarchive); err != nil {
+					return errors.Wrap(err, "getting the archive state")
+				}
+			}
+
+			if unarchive {
+				log.Infof("would de-archive '%s' (%d notes)\n", bookName, noteCount)
+			} else {
+				log.Infof("would archive '%s' (%d notes)\n", bookName, noteCount)
+			}
+
+			return nil
 		}
-		
-		var bookUUID string
-		err = tx.QueryRow("SELECT uuid FROM books WHERE label = ?", bookName).Scan(&bookUUID)
-		
-		if err != nil {
-		return errors.Wrap(err, "finding the book")
+
+		// archiving a single book is confirmed only in the confirm-destructive mode
+		if !reverseFlag {
+			message := fmt.Sprintf("archive book '%s'?", bookName)
+			if toggleFlag {
+				message = fmt.Sprintf("toggle the archive state of book '%s'?", bookName)
+			}
+
+			ok, err := root.Confirm(ctx, message, false, true)
+			if err != nil {
+				return errors.Wrap(err, "getting confirmation")
+			}
+			if !ok {
+				log.Warnf("aborted by user\n")
+				return nil
+			}
 		}
-		
-		if _, err = tx.Exec("UPDATE books SET archive = ? WHERE uuid = ?", !reverseFlag, bookUUID); err != nil {
-			tx.Rollback()
-			return errors.Wrap(err, "archiving the book")
+
+		if toggleFlag {
+			return runToggle(ctx, bookName)
 		}
 
-		err = tx.Commit()
-		if err != nil {
-			tx.Rollback()
-			return errors.Wrap(err, "comitting transaction")
+		if err := core.ArchiveBook(ctx, bookName, !reverseFlag); err == core.ErrBookNotFound {
+			return errors.Errorf("book '%s' not found", bookName)
+		} else if err != nil {
+			return errors.Wrap(err, "archiving the book")
 		}
 
-		if reverseFlag{
+		if reverseFlag {
 			log.Successf("de-archived %s\n", bookName)
 		} else {
 			log.Successf("archived %s\n", bookName)
@@ -0,0 +1,114 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package archive
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func TestParseAge(t *testing.T) {
+	testCases := []struct {
+		input       string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{input: "90d", expected: 90 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "0d", expected: 0},
+		{input: "d", expectedErr: true},
+		{input: "-3d", expectedErr: true},
+		{input: "1.5d", expectedErr: true},
+		{input: "ninety", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseAge(tc.input)
+
+			assert.Equal(t, err != nil, tc.expectedErr, "error mismatch")
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetStaleBooks(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../../tmp",
+		Cache: "../../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	day := int64(24 * time.Hour)
+	now := 1000 * day
+
+	books := []struct {
+		uuid    string
+		label   string
+		archive bool
+	}{
+		{"stale-uuid", "stale", false},
+		{"edited-uuid", "edited", false},
+		{"fresh-uuid", "fresh", false},
+		{"archived-uuid", "archived", true},
+		{"empty-uuid", "empty", false},
+	}
+	for i, b := range books {
+		database.MustExec(t, fmt.Sprintf("setting up book %d", i), db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", b.uuid, b.label, b.archive)
+	}
+
+	notes := []struct {
+		uuid     string
+		bookUUID string
+		addedOn  int64
+		editedOn int64
+		deleted  bool
+	}{
+		{"n1-uuid", "stale-uuid", now - 200*day, 0, false},
+		{"n2-uuid", "stale-uuid", now - 100*day, now - 95*day, false},
+		// a recent deleted note does not make the book fresh
+		{"n3-uuid", "stale-uuid", now - day, 0, true},
+		{"n4-uuid", "edited-uuid", now - 200*day, now - 10*day, false},
+		{"n5-uuid", "fresh-uuid", now - 200*day, 0, false},
+		{"n6-uuid", "fresh-uuid", now - 5*day, 0, false},
+		{"n7-uuid", "archived-uuid", now - 200*day, 0, false},
+	}
+	for i, n := range notes {
+		database.MustExec(t, fmt.Sprintf("setting up note %d", i), db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, deleted) VALUES (?, ?, ?, ?, ?, ?)", n.uuid, n.bookUUID, "body", n.addedOn, n.editedOn, n.deleted)
+	}
+
+	// Execute
+	got, err := getStaleBooks(db, now-90*day)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test
+	assert.DeepEqual(t, got, []staleBook{
+		{UUID: "stale-uuid", Label: "stale", NoteCount: 2},
+	}, "result mismatch")
+}
@@ -19,20 +19,48 @@
 package cat
 
 import (
+	"os"
 	"strconv"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var porcelainFlag bool
+var navFlag bool
+var nextFlag bool
+var prevFlag bool
+var renderFlag bool
+var copyFlag bool
+
+// clipboard is the clipboard that --copy writes the note to
+var clipboard = ui.NewClipboard()
+
 var example = `
+ * See the note with id 2
+ dnote cat 2
+
  * See the notes with index 2 from a book 'javascript'
  dnote cat javascript 2
+
+ * Print the note in a stable format for scripts
+ dnote cat javascript 2 --porcelain
+
+ * Render a Markdown note for the terminal
+ dnote cat javascript 2 --render
+
+ * See the note added after the note with index 2 in its book
+ dnote cat javascript 2 --next
+
+ * Copy the content of the note with id 2 to the clipboard
+ dnote cat 2 --copy
  `
 
 var deprecationWarning = `and "view" will replace it in the future version.
@@ -41,9 +69,21 @@ var deprecationWarning = `and "view" will replace it in the future version.
 `
 
 func preRun(cmd *cobra.Command, args []string) error {
-	if len(args) != 2 {
+	if len(args) != 1 && len(args) != 2 {
 		return errors.New("Incorrect number of arguments")
 	}
+	if nextFlag && prevFlag {
+		return errors.New("--next and --prev cannot be used together")
+	}
+	if porcelainFlag && (navFlag || nextFlag || prevFlag) {
+		return errors.New("--porcelain cannot be used with --nav, --next or --prev")
+	}
+	if porcelainFlag && renderFlag {
+		return errors.New("--porcelain cannot be used with --render")
+	}
+	if porcelainFlag && copyFlag {
+		return errors.New("--porcelain cannot be used with --copy")
+	}
 
 	return nil
 }
@@ -51,7 +91,7 @@ func preRun(cmd *cobra.Command, args []string) error {
 // NewCmd returns a new cat command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:        "cat <book name> <note index>",
+		Use:        "cat <book name?> <note index>",
 		Aliases:    []string{"c"},
 		Short:      "See a note",
 		Example:    example,
@@ -60,6 +100,14 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 		Deprecated: deprecationWarning,
 	}
 
+	f := cmd.Flags()
+	f.BoolVarP(&navFlag, "nav", "", false, "show the ids of the previous and next notes in the book")
+	f.BoolVarP(&nextFlag, "next", "", false, "see the note added after the given note in its book")
+	f.BoolVarP(&prevFlag, "prev", "", false, "see the note added before the given note in its book")
+	f.BoolVarP(&renderFlag, "render", "r", false, "render the Markdown content with styles for the terminal, or as plain text if the output is not a terminal")
+	f.BoolVarP(&copyFlag, "copy", "y", false, "copy the content of the note to the clipboard")
+	f.BoolVarP(&porcelainFlag, "porcelain", "", false, "print the note in a stable format for scripts: a 'note\\t<id>\\t<book>' line, the content, and a NUL byte")
+
 	return cmd
 }
 
@@ -69,7 +117,10 @@ func NewRun(ctx context.DnoteCtx, contentOnly bool) infra.RunEFunc {
 		var noteRowIDArg string
 
 		if len(args) == 2 {
-			log.Plain(log.ColorYellow.Sprintf("DEPRECATED: you no longer need to pass book name to the view command. e.g. `dnote view 123`.\n\n"))
+			// keep the porcelain output free of any notice
+			if !porcelainFlag {
+				log.Plain(log.ColorYellow.Sprintf("DEPRECATED: you no longer need to pass book name to the view command. e.g. `dnote view 123`.\n\n"))
+			}
 
 			noteRowIDArg = args[1]
 		} else {
@@ -81,18 +132,67 @@ func NewRun(ctx context.DnoteCtx, contentOnly bool) infra.RunEFunc {
 			return errors.Wrap(err, "invalid rowid")
 		}
 
+		if nextFlag || prevFlag {
+			prev, next, err := core.AdjacentNotes(ctx, noteRowID)
+			if err != nil {
+				return err
+			}
+
+			if nextFlag {
+				if next == 0 {
+					return errors.Errorf("note %d is the last note in the book", noteRowID)
+				}
+
+				noteRowID = next
+			} else {
+				if prev == 0 {
+					return errors.Errorf("note %d is the first note in the book", noteRowID)
+				}
+
+				noteRowID = prev
+			}
+		}
+
 		db := ctx.DB
 		info, err := database.GetNoteInfo(db, noteRowID)
 		if err != nil {
 			return err
 		}
 
-		if contentOnly {
+		// copy the content as it is saved, before any rendering
+		if copyFlag {
+			if err := clipboard.WriteText(info.Content); err != nil {
+				return errors.Wrap(err, "copying the note")
+			}
+		}
+
+		if renderFlag {
+			info.Content = output.Markdown(info.Content)
+		}
+
+		if porcelainFlag {
+			if err := output.NotePorcelain(os.Stdout, info); err != nil {
+				return errors.Wrap(err, "printing the note")
+			}
+		} else if contentOnly {
 			output.NoteContent(info)
 		} else {
 			output.NoteInfo(info)
 		}
 
+		if navFlag || nextFlag || prevFlag {
+			prev, next, err := core.AdjacentNotes(ctx, noteRowID)
+			if err != nil {
+				return err
+			}
+
+			output.NoteNav(prev, next)
+		}
+
+		if copyFlag {
+			log.Successf("copied note %d to the clipboard\n", noteRowID)
+		}
+
 		return nil
 	}
 }
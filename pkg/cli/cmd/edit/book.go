@@ -19,8 +19,11 @@
 package edit
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/dnote/dnote/pkg/cli/cmd/merge"
+	"github.com/dnote/dnote/pkg/cli/cmd/root"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/log"
@@ -34,13 +37,31 @@ func validateRunBookFlags() error {
 	if contentFlag != "" {
 		return errors.New("--content is invalid for editing a book")
 	}
-	if bookFlag != "" {
+	// --book names the book to rename only together with --name
+	if bookFlag != "" && nameFlag == "" {
 		return errors.New("--book is invalid for editing a book")
 	}
 
 	return nil
 }
 
+func validateRunMergeFlags(sourceName, targetName string) error {
+	if sourceName == "" {
+		return errors.New("--book is required to merge a book")
+	}
+	if contentFlag != "" {
+		return errors.New("--content is invalid for merging a book")
+	}
+	if nameFlag != "" {
+		return errors.New("--name is invalid for merging a book")
+	}
+	if sourceName == targetName {
+		return errors.New("cannot merge a book into itself")
+	}
+
+	return nil
+}
+
 func waitEditorBookName(ctx context.DnoteCtx) (string, error) {
 	fpath, err := ui.GetTmpContentPath(ctx)
 	if err != nil {
@@ -94,6 +115,16 @@ func runBook(ctx context.DnoteCtx, bookName string) error {
 		return errors.Wrap(err, "validating book name")
 	}
 
+	// renaming is confirmed only in the confirm-destructive mode
+	ok, err := root.Confirm(ctx, fmt.Sprintf("rename book '%s' to '%s'?", bookName, name), false, true)
+	if err != nil {
+		return errors.Wrap(err, "getting confirmation")
+	}
+	if !ok {
+		log.Warnf("aborted by user\n")
+		return nil
+	}
+
 	tx, err := ctx.DB.Begin()
 	if err != nil {
 		return errors.Wrap(err, "beginning a transaction")
@@ -105,12 +136,25 @@ func runBook(ctx context.DnoteCtx, bookName string) error {
 		return errors.Wrap(err, "updating the book name")
 	}
 
+	// the template of the book follows it to its new name
+	if _, err := tx.Exec("UPDATE OR REPLACE book_templates SET book_label = ? WHERE book_label = ?", name, bookName); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "updating the book template")
+	}
+
 	bookInfo, err := database.GetBookInfo(tx, uuid)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "getting book info")
 	}
 
+	if root.DryRunFlag {
+		tx.Rollback()
+		log.Infof("would rename '%s' to '%s'\n", bookName, name)
+
+		return nil
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
@@ -122,3 +166,14 @@ func runBook(ctx context.DnoteCtx, bookName string) error {
 
 	return nil
 }
+
+// runMerge moves all notes in the source book into the existing target book,
+// and removes the source book. Unlike renaming, the notes end up in a book
+// with a different uuid.
+func runMerge(ctx context.DnoteCtx, sourceName, targetName string) error {
+	if err := validateRunMergeFlags(sourceName, targetName); err != nil {
+		return errors.Wrap(err, "validating flags")
+	}
+
+	return merge.Run(ctx, sourceName, targetName, yesFlag, false)
+}
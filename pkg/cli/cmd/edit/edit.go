@@ -21,17 +21,18 @@ package edit
 import (
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/infra"
-	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	
-	"github.com/dnote/dnote/pkg/cli/cmd/ls"
 )
 
 var contentFlag string
 var bookFlag string
 var nameFlag string
+var mergeIntoFlag string
+var allowEmptyFlag bool
+var editFlag bool
+var yesFlag bool
 
 var example = `
   * Edit a note by id
@@ -40,11 +41,26 @@ var example = `
   * Edit a note without launching an editor
   dnote edit 3 -c "new content"
 
+  * Open the editor with a new content to finish it before saving
+  dnote edit 3 -c "new content" -E
+
   * Move a note to another book
   dnote edit 3 -b javascript
 
   * Rename a book
   dnote edit javascript -n js
+
+  * Rename a book, never taking the name for a note
+  dnote edit --book javascript -n js
+
+  * See what a rename would do without renaming
+  dnote edit javascript -n js --dry-run
+
+  * Move all notes in a book into another book and remove it
+  dnote edit --book javascript_2 --merge-into javascript
+
+  * Merge books without the prompt
+  dnote edit --book javascript_2 --merge-into javascript --yes
 `
 
 // NewCmd returns a new edit command
@@ -60,13 +76,32 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 
 	f := cmd.Flags()
 	f.StringVarP(&contentFlag, "content", "c", "", "a new content for the note")
-	f.StringVarP(&bookFlag, "book", "b", "", "the name of the book to move the note to")
+	f.StringVarP(&bookFlag, "book", "b", "", "the name of the book to move the note to, or with --name, the book to rename")
 	f.StringVarP(&nameFlag, "name", "n", "", "a new name for a book")
+	f.StringVarP(&mergeIntoFlag, "merge-into", "", "", "move all notes in the book given by --book into this book and remove it")
+	f.BoolVarP(&editFlag, "edit", "E", false, "open the editor pre-filled with the content given by --content")
+	f.BoolVarP(&allowEmptyFlag, "allow-empty", "", false, "save the note even if its new content is empty")
+	f.BoolVarP(&yesFlag, "yes", "y", false, "with --merge-into, assume yes to the prompt and run in non-interactive mode")
 
 	return cmd
 }
 
 func preRun(cmd *cobra.Command, args []string) error {
+	if mergeIntoFlag != "" {
+		if len(args) != 0 {
+			return errors.New("--merge-into takes the book to merge from --book, not from the arguments")
+		}
+
+		return nil
+	}
+	if nameFlag != "" && bookFlag != "" {
+		if len(args) != 0 {
+			return errors.New("--name with --book takes the book to rename from --book, not from the arguments")
+		}
+
+		return nil
+	}
+
 	if len(args) != 1 && len(args) != 2 {
 		return errors.New("Incorrect number of argument")
 	}
@@ -76,6 +111,24 @@ func preRun(cmd *cobra.Command, args []string) error {
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		if mergeIntoFlag != "" {
+			if err := runMerge(ctx, bookFlag, mergeIntoFlag); err != nil {
+				return errors.Wrap(err, "merging the book")
+			}
+
+			return nil
+		}
+
+		// --book names the book to rename explicitly, so that it is never
+		// resolved to a note
+		if nameFlag != "" && bookFlag != "" {
+			if err := runBook(ctx, bookFlag); err != nil {
+				return errors.Wrap(err, "editing book")
+			}
+
+			return nil
+		}
+
 		// DEPRECATED: Remove in 1.0.0
 		if len(args) == 2 {
 			//log.Plain(log.ColorYellow.Sprintf("DEPRECATED: you no longer need to pass book name to the view command. e.g. `dnote view 123`.\n\n"))
@@ -96,22 +149,8 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 				return errors.Wrap(err, "editing note")
 			}
 		} else {
-			n, err := ls.RetSingle(ctx, args[0])
-			if err != nil {
-				return errors.Wrap(err, "querying books/notes")
-			} else if (nameFlag != "") {
-				if err := runBook(ctx, target); err != nil {
-					return errors.Wrap(err, "editing book")
-				}
-			} else if (n == "") && (nameFlag == "") {
-				log.Plain(log.ColorYellow.Sprintf("This book has several notes, choose one:\n"))
-				ls.PrintNotes(ctx, target)
-				//return errors.Wrap(err, "editing book")
-			} else {
-				target = n
-				if err := runNote(ctx, target); err != nil {
-					return errors.Wrap(err, "editing note")
-				}
+			if err := runBook(ctx, target); err != nil {
+				return errors.Wrap(err, "editing book")
 			}
 		}
 
@@ -24,6 +24,7 @@ import (
 	"strconv"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
@@ -33,21 +34,29 @@ import (
 	"github.com/dnote/dnote/pkg/cli/utils"
 )
 
+// errEditCancelled is returned when the editor is quit without saving
+var errEditCancelled = errors.New("edit cancelled, note unchanged")
+
 func validateRunNoteFlags() error {
 	if nameFlag != "" {
 		return errors.New("--name is invalid for editing a book")
 	}
+	if editFlag && contentFlag == "" {
+		return errors.New("--edit requires the content to pre-fill the editor with, given by --content")
+	}
 
 	return nil
 }
 
-func waitEditorNoteContent(ctx context.DnoteCtx, note database.Note) (string, error) {
+// waitEditorNoteContent launches an editor pre-filled with the given seed and
+// returns the content the user saved
+func waitEditorNoteContent(ctx context.DnoteCtx, seed string) (string, error) {
 	fpath, err := ui.GetTmpContentPath(ctx)
 	if err != nil {
 		return "", errors.Wrap(err, "getting temporarily content file path")
 	}
 
-	if err := ioutil.WriteFile(fpath, []byte(note.Body), 0644); err != nil {
+	if err := ioutil.WriteFile(fpath, []byte(seed), 0644); err != nil {
 		return "", errors.Wrap(err, "preparing tmp content file")
 	}
 
@@ -60,11 +69,16 @@ func waitEditorNoteContent(ctx context.DnoteCtx, note database.Note) (string, er
 }
 
 func getContent(ctx context.DnoteCtx, note database.Note) (string, error) {
-	if contentFlag != "" {
+	if contentFlag != "" && !editFlag {
 		return contentFlag, nil
 	}
 
-	c, err := waitEditorNoteContent(ctx, note)
+	seed := note.Body
+	if editFlag {
+		seed = contentFlag
+	}
+
+	c, err := waitEditorNoteContent(ctx, seed)
 	if err != nil {
 		return "", errors.Wrap(err, "getting content from editor")
 	}
@@ -101,13 +115,13 @@ func moveBook(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookNam
 	return nil
 }
 
-func updateNote(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookName, content string) error {
+func updateNote(ctx context.DnoteCtx, tx *database.DB, note database.Note, bookName, content string, updateContent bool) error {
 	if bookName != "" {
 		if err := moveBook(ctx, tx, note, bookName); err != nil {
 			return errors.Wrap(err, "moving book")
 		}
 	}
-	if content != "" {
+	if updateContent {
 		if err := changeContent(ctx, tx, note, content); err != nil {
 			return errors.Wrap(err, "changing content")
 		}
@@ -142,10 +156,15 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 
 	content := contentFlag
 
-	// If no flag was provided, launch an editor to get the content
-	if bookFlag == "" && contentFlag == "" {
+	// If no flag was provided, or the content only seeds the editor, launch an
+	// editor to get the content
+	if (bookFlag == "" && contentFlag == "") || editFlag {
 		c, err := getContent(ctx, note)
-		if err != nil {
+		if errors.Cause(err) == ui.ErrEditorCancelled {
+			tx.Rollback()
+			return errEditCancelled
+		} else if err != nil {
+			tx.Rollback()
 			return errors.Wrap(err, "getting content from editor")
 		}
 
@@ -168,7 +187,19 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 		}
 	}
 
-	err = updateNote(ctx, tx, note, bookFlag, content)
+	// The content is left alone if only the book is being changed
+	updateContent := bookFlag == "" || contentFlag != ""
+	if updateContent {
+		if ctx.TrimOnSave {
+			content = core.TrimBlankLines(content)
+		}
+		if core.IsEmptyBody(content) && !allowEmptyFlag {
+			tx.Rollback()
+			return core.ErrEmptyNote
+		}
+	}
+
+	err = updateNote(ctx, tx, note, bookFlag, content, updateContent)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "updating note fields")
@@ -21,11 +21,15 @@ package find
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -39,16 +43,42 @@ var example = `
 
 	# find notes within a book
 	dnote find "merge sort" -b algorithm
+
+	# find the book of the note with an id
+	dnote find --id 12
+
+	# fetch at most 50 matches
+	dnote find "merge sort" --max-results 50
 	`
 
+// defaultMaxResults is the number of matches fetched at most unless
+// --max-results is given, so that a broad query does not read the whole
+// database into memory
+const defaultMaxResults = 10000
+
 var bookName string
 var all bool
+var idFlag bool
+var maxResultsFlag int
 
 func preRun(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return errors.New("Incorrect number of argument")
 	}
 
+	if maxResultsFlag < 1 {
+		return errors.New("--max-results must be a positive number")
+	}
+
+	if idFlag {
+		if len(args) != 1 || !utils.IsNumber(args[0]) {
+			return errors.New("--id takes a single note id")
+		}
+		if bookName != "" || all {
+			return errors.New("--id cannot be used with --book or --all")
+		}
+	}
+
 	return nil
 }
 
@@ -66,6 +96,8 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	f := cmd.Flags()
 	f.StringVarP(&bookName, "book", "b", "", "book name to find notes in")
 	f.BoolVarP(&all, "all", "a", false, "find keywords in all notes including the archived")
+	f.BoolVarP(&idFlag, "id", "", false, "find the note with the given id and print its book")
+	f.IntVarP(&maxResultsFlag, "max-results", "", defaultMaxResults, "the maximum number of matches to fetch")
 
 	return cmd
 }
@@ -89,7 +121,22 @@ func formatFTSSnippet(s string) (string, error) {
 
 	toks := tokenize(body)
 
+	// collapse runs of whitespace into a single space. The state is kept across
+	// the highlight boundaries so that the boundaries themselves stay in place.
+	var prevSpace bool
+
 	for _, tok := range toks {
+		if tok.Kind == tokenKindChar && isSpace(tok.Value) {
+			if prevSpace {
+				continue
+			}
+
+			tok.Value = ' '
+			prevSpace = true
+		} else if tok.Kind == tokenKindChar {
+			prevSpace = false
+		}
+
 		if tok.Kind == tokenKindHLBegin || tok.Kind == tokenKindEOL {
 			format.WriteString("%s")
 			args = append(args, buf.String())
@@ -97,7 +144,7 @@ func formatFTSSnippet(s string) (string, error) {
 			buf.Reset()
 		} else if tok.Kind == tokenKindHLEnd {
 			format.WriteString("%s")
-			str := log.ColorYellow.Sprintf("%s", buf.String())
+			str := log.ColorHighlight.Sprintf("%s", buf.String())
 			args = append(args, str)
 
 			buf.Reset()
@@ -133,10 +180,22 @@ func escapePhrase(s string) (string, error) {
 	return b.String(), nil
 }
 
-func doQuery(ctx context.DnoteCtx, query, bookName string, all bool) (*sql.Rows, error) {
-	db := ctx.DB
+// buildQuery returns the SQL statement and its arguments to find the notes
+// matching the query. The conditions are joined with AND, and at most limit
+// rows are returned.
+func buildQuery(query, bookName string, all bool, limit int) (string, []interface{}) {
+	conds := []string{"note_fts MATCH ?"}
+	args := []interface{}{query}
+
+	if bookName != "" {
+		conds = append(conds, "books.label LIKE ?")
+		args = append(args, bookName)
+	} else if !all {
+		conds = append(conds, "books.archive = ?")
+		args = append(args, false)
+	}
 
-	sql := `SELECT
+	sql := fmt.Sprintf(`SELECT
 		notes.rowid,
 		books.label AS book_label,
 		snippet(note_fts, 0, '<dnotehl>', '</dnotehl>', '<dnotehl>...</dnotehl>', 28),
@@ -144,26 +203,66 @@ func doQuery(ctx context.DnoteCtx, query, bookName string, all bool) (*sql.Rows,
 	FROM note_fts
 	INNER JOIN notes ON notes.rowid = note_fts.rowid
 	INNER JOIN books ON notes.book_uuid = books.uuid
-	WHERE note_fts MATCH ?`
-	args := []interface{}{query}
+	WHERE %s
+	LIMIT ?`, strings.Join(conds, " AND "))
+	args = append(args, limit)
 
-	if bookName != "" {
-		sql = fmt.Sprintf("%s AND books.label LIKE ?", sql)
-		args = append(args, bookName)
-	} else if !all {
-		sql = fmt.Sprintf("%s AND books.archive = false", sql)
+	return sql, args
+}
+
+func doQuery(ctx context.DnoteCtx, query, bookName string, all bool, limit int) (*sql.Rows, error) {
+	sql, args := buildQuery(query, bookName, all, limit)
+
+	return ctx.DB.Query(sql, args...)
+}
+
+// formatExcerpt returns the first line of the note body, followed by an
+// ellipsis if the body has more lines
+func formatExcerpt(body string) string {
+	trimmed := strings.TrimSpace(body)
+
+	if idx := strings.IndexAny(trimmed, "\r\n"); idx > -1 {
+		return fmt.Sprintf("%s...", strings.TrimSpace(trimmed[:idx]))
 	}
 
-	rows, err := db.Query(sql, args...)
+	return trimmed
+}
+
+// formatIDResult formats the note found by its id into a line containing its
+// book, the date it was added and an excerpt
+func formatIDResult(info database.NoteInfo) string {
+	bookLabel := log.ColorYellow.Sprintf("(%s)", info.BookLabel)
+	addedOn := log.ColorGray.Sprint(time.Unix(0, info.AddedOn).Format("Jan 2, 2006"))
 
-	return rows, err
+	return fmt.Sprintf("%s %s %s", bookLabel, addedOn, formatExcerpt(info.Content))
+}
+
+func runID(ctx context.DnoteCtx, idArg string) error {
+	rowID, err := strconv.Atoi(idArg)
+	if err != nil {
+		return errors.Wrap(err, "invalid note id")
+	}
+
+	info, err := database.GetNoteInfo(ctx.DB, rowID)
+	if err != nil {
+		return err
+	}
+
+	log.Plainf("%s\n", formatIDResult(info))
+
+	return nil
 }
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		if idFlag {
+			return runID(ctx, args[0])
+		}
+
 		phrase := strings.Join(args[:], " ")
 
-		rows, err := doQuery(ctx, phrase, bookName, all)
+		// fetch one more than the cap to tell if any match is left out
+		rows, err := doQuery(ctx, phrase, bookName, all, maxResultsFlag+1)
 		if err != nil {
 			return errors.Wrap(err, "querying notes")
 		}
@@ -188,6 +287,14 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 
 			infos = append(infos, info)
 		}
+		if err := rows.Err(); err != nil {
+			return errors.Wrap(err, "iterating notes")
+		}
+
+		capped := len(infos) > maxResultsFlag
+		if capped {
+			infos = infos[:maxResultsFlag]
+		}
 
 		for _, info := range infos {
 			var bookLabel string
@@ -201,6 +308,10 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			log.Plainf("%s %s %s\n", bookLabel, rowid, info.Body)
 		}
 
+		if capped {
+			log.Warnf("showing the first %d matches. Narrow down the query or raise --max-results to see more\n", maxResultsFlag)
+		}
+
 		return nil
 	}
 }
@@ -0,0 +1,164 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package find
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnote/color"
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+)
+
+func TestFormatFTSSnippet(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "foo bar",
+			expected: "foo bar",
+		},
+		{
+			input:    "foo\t\tbar\t<dnotehl>baz</dnotehl>",
+			expected: "foo bar baz",
+		},
+		{
+			input:    "foo    bar  \n  <dnotehl>baz</dnotehl>   quz",
+			expected: "foo bar baz quz",
+		},
+		{
+			input:    "foo <dnotehl>  bar \t baz</dnotehl> \t quz",
+			expected: "foo bar baz quz",
+		},
+		{
+			input:    "\t\tfoo\r\n\r\nbar",
+			expected: " foo bar",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			got, err := formatFTSSnippet(tc.input)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatFTSSnippetHighlight(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	got, err := formatFTSSnippet("foo  \t<dnotehl>bar\t\tbaz</dnotehl>\t  quz")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	expected := fmt.Sprintf("foo %s quz", log.ColorYellow.Sprintf("bar baz"))
+	assert.Equal(t, got, expected, "result mismatch")
+}
+
+func TestFormatExcerpt(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "foo", expected: "foo"},
+		{input: "  foo bar  \n", expected: "foo bar"},
+		{input: "foo\nbar", expected: "foo..."},
+		{input: "foo \r\nbar", expected: "foo..."},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, formatExcerpt(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatIDResult(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	info := database.NoteInfo{
+		RowID:     3,
+		BookLabel: "js",
+		Content:   "Booleans have toString()\nmore",
+		AddedOn:   time.Date(2018, time.January, 6, 12, 0, 0, 0, time.UTC).UnixNano(),
+	}
+
+	assert.Equal(t, formatIDResult(info), "(js) Jan 6, 2018 Booleans have toString()...", "result mismatch")
+}
+
+func TestBuildQuery(t *testing.T) {
+	testCases := []struct {
+		bookName      string
+		all           bool
+		expectedWhere string
+		expectedArgs  []interface{}
+	}{
+		{
+			expectedWhere: "note_fts MATCH ? AND books.archive = ?\n\tLIMIT ?",
+			expectedArgs:  []interface{}{"foo", false, 100},
+		},
+		{
+			all:           true,
+			expectedWhere: "note_fts MATCH ?\n\tLIMIT ?",
+			expectedArgs:  []interface{}{"foo", 100},
+		},
+		{
+			bookName:      "js",
+			expectedWhere: "note_fts MATCH ? AND books.label LIKE ?\n\tLIMIT ?",
+			expectedArgs:  []interface{}{"foo", "js", 100},
+		},
+		{
+			bookName:      "js",
+			all:           true,
+			expectedWhere: "note_fts MATCH ? AND books.label LIKE ?\n\tLIMIT ?",
+			expectedArgs:  []interface{}{"foo", "js", 100},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			sql, args := buildQuery("foo", tc.bookName, tc.all, 100)
+
+			parts := strings.SplitN(sql, "WHERE ", 2)
+			assert.Equal(t, len(parts), 2, "sql should have a WHERE clause")
+			assert.Equal(t, parts[1], tc.expectedWhere, "WHERE clause mismatch")
+			assert.DeepEqual(t, args, tc.expectedArgs, "args mismatch")
+			assert.Equal(t, strings.Count(sql, "?"), len(args), "placeholder count mismatch")
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * See the books a note has been moved between
+ dnote history 3`
+
+// NewCmd returns a new history command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "history <note id>",
+		Short:   "See the history of a note",
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		noteRowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+
+		info, err := database.GetNoteInfo(ctx.DB, noteRowID)
+		if err != nil {
+			return err
+		}
+
+		moves, err := database.GetNoteMoves(ctx.DB, info.UUID)
+		if err != nil {
+			return err
+		}
+
+		if len(moves) == 0 {
+			log.Infof("note %d has not been moved\n", noteRowID)
+			return nil
+		}
+
+		for _, m := range moves {
+			movedOn := log.ColorGray.Sprint(time.Unix(0, m.MovedOn).Format("Jan 2, 2006 3:04pm (MST)"))
+			log.Plainf("%s moved from %s to %s\n", movedOn, m.FromBookLabel, m.ToBookLabel)
+		}
+
+		return nil
+	}
+}
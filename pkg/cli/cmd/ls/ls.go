@@ -19,24 +19,115 @@
 package ls
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"io"
+	"os"
 	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var allFlag bool
+var deletedFlag bool
+var allNotesFlag bool
+var formatFlag string
+var multilineMarkerFlag bool
+var singleLineOnlyFlag bool
+var sortBooksFlag string
+var plainFlag bool
+var grepFlag string
+var sourceFlag bool
+var jsonFlag bool
+var treeFlag bool
+var fuzzyFlag string
+var insensitiveBookFlag bool
+var sinceIDFlag int
+var porcelainFlag bool
+var emptyFlag bool
+var reverseFlag bool
+var noPinsFlag bool
+
+// multilineMarker is prefixed to the notes that have more than one line when
+// --multiline-marker is given
+const multilineMarker = "¶"
+
+// pinMarker is prefixed to the pinned notes
+const pinMarker = "★"
+
 var example = `
  * List all books
  dnote ls
 
+ * List all books including the archived
+ dnote ls --all
+
+ * List books in the reverse alphabetical order
+ dnote ls --reverse
+
+ * List books with the most notes first
+ dnote ls --sort-books count
+
+ * List books that have no notes, including the archived
+ dnote ls --empty --all
+
+ * List books whose names fuzzily match a query, the best match first
+ dnote ls --fuzzy algo
+
  * List notes in a book
  dnote ls javascript
+
+ * List deleted notes in a book
+ dnote ls javascript --deleted
+
+ * List notes in a book in the order they were added, ignoring the pins
+ dnote ls javascript --no-pins
+
+ * List notes in a book that were added after the note with id 40
+ dnote ls javascript --since-id 40
+
+ * List notes in a book whose name matches regardless of case
+ dnote ls JavaScript --insensitive-book
+
+ * List notes in all books, the most recent first
+ dnote ls --all-notes
+
+ * List books in a custom format
+ dnote ls --format "{{.BookLabel}}: {{.NoteCount}}"
+
+ * List notes in a book in a custom format
+ dnote ls javascript --format "{{.RowID}} {{.Body}}"
+
+ * Mark the notes that have more than one line
+ dnote ls javascript --multiline-marker
+
+ * List notes in a book without any decoration, for use in scripts
+ dnote ls javascript --plain
+
+ * List notes in a book that contain a term
+ dnote ls javascript --grep promise
+
+ * Show where each note was created, such as 'cli' or 'web'
+ dnote ls javascript --source
+
+ * List books in a stable tab-separated format, for use in scripts
+ dnote ls --porcelain
+
+ * List books in JSON
+ dnote ls --json
+
+ * List all books and their notes in JSON
+ dnote ls --json --tree
  `
 
 var deprecationWarning = `and "view" will replace it in the future version.
@@ -48,6 +139,65 @@ func preRun(cmd *cobra.Command, args []string) error {
 	if len(args) > 1 {
 		return errors.New("Incorrect number of argument")
 	}
+	if allNotesFlag && len(args) != 0 {
+		return errors.New("--all-notes cannot be used with a book name")
+	}
+	if grepFlag != "" && len(args) == 0 && !allNotesFlag {
+		return errors.New("--grep can only be used when listing notes")
+	}
+	if jsonFlag && (formatFlag != "" || plainFlag) {
+		return errors.New("--json cannot be used with --format or --plain")
+	}
+	if jsonFlag && allNotesFlag {
+		return errors.New("--json cannot be used with --all-notes. Use --json --tree to get the notes in all books")
+	}
+	if treeFlag {
+		if !jsonFlag {
+			return errors.New("--tree can only be used with --json")
+		}
+		if len(args) != 0 {
+			return errors.New("--tree cannot be used with a book name")
+		}
+	}
+	if plainFlag && formatFlag != "" {
+		return errors.New("--plain cannot be used with --format")
+	}
+	if porcelainFlag && (jsonFlag || plainFlag || formatFlag != "" || allNotesFlag) {
+		return errors.New("--porcelain cannot be used with --json, --plain, --format or --all-notes")
+	}
+	if fuzzyFlag != "" && (len(args) != 0 || allNotesFlag || treeFlag || sortBooksFlag != "") {
+		return errors.New("--fuzzy cannot be used with a book name, --all-notes, --tree or --sort-books")
+	}
+	if emptyFlag && (len(args) != 0 || allNotesFlag || fuzzyFlag != "") {
+		return errors.New("--empty can only be used when listing books")
+	}
+	if reverseFlag && (len(args) != 0 || allNotesFlag || fuzzyFlag != "") {
+		return errors.New("--reverse can only be used when listing books")
+	}
+	if sinceIDFlag < 0 {
+		return errors.New("--since-id must be a non-negative integer")
+	}
+	if cmd.Flags().Changed("since-id") && len(args) == 0 {
+		return errors.New("--since-id can only be used when listing notes in a book")
+	}
+	if noPinsFlag && len(args) == 0 {
+		return errors.New("--no-pins can only be used when listing notes in a book")
+	}
+	if sortBooksFlag != "" {
+		if len(args) != 0 || allNotesFlag {
+			return errors.New("--sort-books can only be used when listing books")
+		}
+
+		valid := false
+		for _, s := range core.BookSorts {
+			if sortBooksFlag == s {
+				valid = true
+			}
+		}
+		if !valid {
+			return errors.Errorf("invalid sort '%s'. Use one of: %s", sortBooksFlag, strings.Join(core.BookSorts, ", "))
+		}
+	}
 
 	return nil
 }
@@ -59,19 +209,72 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 		Aliases:    []string{"l", "notes"},
 		Short:      "List all notes",
 		Example:    example,
-		RunE:       NewRun(ctx, false),
+		RunE:       newRun(ctx),
 		PreRunE:    preRun,
 		Deprecated: deprecationWarning,
 	}
 
+	f := cmd.Flags()
+	f.BoolVarP(&allFlag, "all", "a", false, "list all books including the archived")
+	f.BoolVarP(&deletedFlag, "deleted", "", false, "list the deleted notes in the book")
+	f.BoolVarP(&allNotesFlag, "all-notes", "", false, "list the notes in all books, the most recent first")
+	f.StringVarP(&formatFlag, "format", "", "", "format each book or note with the given Go template")
+	f.BoolVarP(&multilineMarkerFlag, "multiline-marker", "", false, fmt.Sprintf("prefix the notes that have more than one line with %s", multilineMarker))
+	f.BoolVarP(&singleLineOnlyFlag, "single-line-only", "", false, "list only the notes that have a single line")
+	f.StringVarP(&sortBooksFlag, "sort-books", "", "", "order the books by 'name', note 'count', or most 'recent' activity")
+	f.BoolVarP(&emptyFlag, "empty", "", false, "list only the books that have no notes")
+	f.BoolVarP(&reverseFlag, "reverse", "r", false, "list the books in the reverse order")
+	f.BoolVarP(&noPinsFlag, "no-pins", "", false, "list the pinned notes in a book in the usual order instead of first")
+	f.StringVarP(&fuzzyFlag, "fuzzy", "", "", "list the books whose names fuzzily match the given query, the best match first")
+	f.IntVarP(&sinceIDFlag, "since-id", "", 0, "list only the notes whose id is greater than the given id")
+	f.BoolVarP(&insensitiveBookFlag, "insensitive-book", "", false, "match the book name regardless of case. By default, the book name must match exactly")
+	f.BoolVarP(&jsonFlag, "json", "", false, "print the books or notes in JSON")
+	f.BoolVarP(&treeFlag, "tree", "", false, "with --json, print every book with its notes nested in it")
+	f.BoolVarP(&sourceFlag, "source", "", false, "show where each note was created, such as 'cli' or 'web'")
+	f.StringVarP(&grepFlag, "grep", "", "", "list only the notes that contain the given term, ignoring case")
+	f.BoolVarP(&porcelainFlag, "porcelain", "", false, "print 'label, note count, archive' for each book, or 'id, added on in unix nanoseconds, first line' for each note, separated by tabs. The format will not change across versions")
+	f.BoolVarP(&plainFlag, "plain", "", false, "print only the book labels, or the note ids and first lines separated by a tab, without colors, counts or headers")
+
 	return cmd
 }
 
-// NewRun returns a new run function for ls
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		return NewRun(ctx, allFlag)(cmd, args)
+	}
+}
+
+// NewRun returns a new run function for ls. If all is true, the archived books
+// are included in the book listings.
 func NewRun(ctx context.DnoteCtx, all bool) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		if jsonFlag {
+			return runJSON(ctx, os.Stdout, args, all)
+		}
+
+		tmpl, err := parseFormat(formatFlag)
+		if err != nil {
+			return errors.Wrap(err, "parsing the format")
+		}
+
+		if fuzzyFlag != "" {
+			if err := printFuzzyBooks(ctx, os.Stdout, fuzzyFlag, all, tmpl); err != nil {
+				return errors.Wrap(err, "viewing books")
+			}
+
+			return nil
+		}
+
+		if len(args) == 0 && allNotesFlag {
+			if err := printAllNotes(ctx, os.Stdout, all, tmpl); err != nil {
+				return errors.Wrap(err, "viewing notes")
+			}
+
+			return nil
+		}
+
 		if len(args) == 0 {
-			if err := printBooks(ctx, all); err != nil {
+			if err := printBooks(ctx, os.Stdout, all, tmpl); err != nil {
 				return errors.Wrap(err, "viewing books")
 			}
 
@@ -80,14 +283,14 @@ func NewRun(ctx context.DnoteCtx, all bool) infra.RunEFunc {
 
 		bookName := args[0]
 		if strings.Contains(bookName, "%") {
-			if err := printMatchBooks(ctx, bookName, false); err != nil {
+			if err := printMatchBooks(ctx, os.Stdout, bookName, all, false, tmpl); err != nil {
 				return errors.Wrap(err, "viewing books")
 			}
 
 			return nil
 		}
 
-		if err := PrintNotes(ctx, bookName); err != nil {
+		if err := printNotes(ctx, os.Stdout, bookName, deletedFlag, tmpl); err != nil {
 			return errors.Wrapf(err, "viewing book '%s'", bookName)
 		}
 
@@ -95,6 +298,59 @@ func NewRun(ctx context.DnoteCtx, all bool) infra.RunEFunc {
 	}
 }
 
+// runJSON writes the books, or the notes in the book given by the arguments,
+// to the writer in JSON. With --tree, every book is written with its notes.
+func runJSON(ctx context.DnoteCtx, w io.Writer, args []string, all bool) error {
+	var data interface{}
+	var err error
+
+	switch {
+	case fuzzyFlag != "":
+		data, err = core.FuzzyMatchBooks(ctx, fuzzyFlag, all)
+	case treeFlag:
+		data, err = core.ListBookTree(ctx, core.ListBooksOptions{All: all, Sort: sortBooksFlag, Empty: emptyFlag, Reverse: reverseFlag})
+	case len(args) == 0:
+		data, err = core.ListBooks(ctx, core.ListBooksOptions{All: all, Sort: sortBooksFlag, Empty: emptyFlag, Reverse: reverseFlag})
+	case strings.Contains(args[0], "%"):
+		data, err = core.MatchBooks(ctx, args[0], all)
+	default:
+		var notes []core.Note
+		notes, err = core.ListNotes(ctx, args[0], core.ListNotesOptions{Deleted: deletedFlag, InsensitiveBook: insensitiveBookFlag, SinceID: sinceIDFlag, IgnorePins: noPinsFlag})
+		data = filterNotes(notes)
+	}
+	if err != nil {
+		return errors.Wrap(err, "listing")
+	}
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		return errors.Wrap(err, "encoding JSON")
+	}
+
+	return nil
+}
+
+// parseFormat parses the given Go template for formatting each book or note.
+// It returns nil if no format is given.
+func parseFormat(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+
+	return template.New("format").Parse(format)
+}
+
+// printFormatted prints the given book or note using the given template
+func printFormatted(w io.Writer, tmpl *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return errors.Wrap(err, "executing the format")
+	}
+
+	fmt.Fprintln(w, buf.String())
+
+	return nil
+}
+
 // bookInfo is an information about the book to be printed on screen
 type bookInfo struct {
 	BookLabel string
@@ -104,25 +360,32 @@ type bookInfo struct {
 
 // noteInfo is an information about the note to be printed on screen
 type noteInfo struct {
-	RowID int
-	Body  string
+	RowID   int
+	Body    string
+	Source  string
+	AddedOn int64
+	Pinned  bool
+}
+
+// bookNoteInfo is an information about the note to be printed on screen
+// along with its book
+type bookNoteInfo struct {
+	RowID     int
+	Body      string
+	BookLabel string
+	Archive   bool
+	Source    string
 }
 
 type noteID struct {
 	RowID int
 }
 
-// getNewlineIdx returns the index of newline character in a string
+// getNewlineIdx returns the index of the first line break in a string. A line
+// break can be any of "\n", "\r\n" or "\r", and for "\r\n" the index of "\r"
+// is returned so that no carriage return is left before it.
 func getNewlineIdx(str string) int {
-	var ret int
-
-	ret = strings.Index(str, "\n")
-
-	if ret == -1 {
-		ret = strings.Index(str, "\r\n")
-	}
-
-	return ret
+	return strings.IndexAny(str, "\r\n")
 }
 
 // formatBody returns an excerpt of the given raw note content and a boolean
@@ -140,150 +403,456 @@ func formatBody(noteBody string) (string, bool) {
 	return strings.Trim(trimmed, " "), false
 }
 
-func printBookLine(info bookInfo, nameOnly bool) {
+// isHidden returns whether the note with the given body should be left out of
+// the listing
+func isHidden(noteBody string) bool {
+	if grepFlag != "" && !containsFold(noteBody, grepFlag) {
+		return true
+	}
+	if !singleLineOnlyFlag {
+		return false
+	}
+
+	_, isExcerpt := formatBody(noteBody)
+
+	return isExcerpt
+}
+
+// filterNotes returns the notes that are not hidden from the listing
+func filterNotes(notes []core.Note) []core.Note {
+	ret := []core.Note{}
+	for _, n := range notes {
+		if !isHidden(n.Body) {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
+
+// markSource prefixes the source of the note to the given excerpt if it is
+// requested by --source and the source is known
+func markSource(excerpt, source string) string {
+	if !sourceFlag || source == "" {
+		return excerpt
+	}
+
+	return fmt.Sprintf("%s %s", log.ColorGray.Sprintf("[%s]", source), excerpt)
+}
+
+// containsFold returns whether s contains the term, ignoring case
+func containsFold(s, term string) bool {
+	for i := range s {
+		if _, ok := core.MatchFoldAt(s[i:], term); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlightGrep highlights the occurrences of the term given by --grep in the
+// excerpt
+func highlightGrep(excerpt string) string {
+	if grepFlag == "" {
+		return excerpt
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(excerpt); {
+		if n, ok := core.MatchFoldAt(excerpt[i:], grepFlag); ok {
+			b.WriteString(log.ColorHighlight.Sprint(excerpt[i : i+n]))
+			i += n
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(excerpt[i:])
+		b.WriteString(excerpt[i : i+size])
+		i += size
+	}
+
+	return b.String()
+}
+
+// markPinned prefixes the excerpt of a pinned note with the pin marker
+func markPinned(excerpt string, pinned bool) string {
+	if !pinned {
+		return excerpt
+	}
+
+	return fmt.Sprintf("%s %s", log.ColorYellow.Sprint(pinMarker), excerpt)
+}
+
+// markMultiline prefixes the marker to the given excerpt if the note has more
+// than one line and the marker is requested
+func markMultiline(excerpt string, isExcerpt bool) string {
+	if !isExcerpt || !multilineMarkerFlag {
+		return excerpt
+	}
+
+	return fmt.Sprintf("%s %s", multilineMarker, excerpt)
+}
+
+// getLabelWidth returns the width of the longest label among the given books
+func getLabelWidth(infos []bookInfo) int {
+	var ret int
+
+	for _, info := range infos {
+		if w := utf8.RuneCountInString(info.BookLabel); w > ret {
+			ret = w
+		}
+	}
+
+	return ret
+}
+
+// formatBookLine returns a line to be printed for the given book. The label is
+// padded to the given width so that the note counts line up in a column.
+func formatBookLine(info bookInfo, width int, nameOnly bool) string {
 	if nameOnly {
-		fmt.Println(info.BookLabel)
-	} else if info.Archive == false{
-		log.Printf("%s %s\n", info.BookLabel, log.ColorYellow.Sprintf("(%d)", info.NoteCount))
+		return info.BookLabel
+	}
+
+	label := info.BookLabel
+	if padding := width - utf8.RuneCountInString(label); padding > 0 {
+		label = label + strings.Repeat(" ", padding)
+	}
+
+	if info.Archive {
+		label = log.ColorGray.Sprintf("%s", label)
+	}
+
+	return fmt.Sprintf("%s %s", label, log.ColorYellow.Sprintf("(%d)", info.NoteCount))
+}
+
+func printBookLine(w io.Writer, info bookInfo, width int, nameOnly bool) {
+	if nameOnly {
+		fmt.Fprintln(w, formatBookLine(info, width, true))
 	} else {
-		log.Printf("%s %s\n", log.ColorGray.Sprintf("%s",info.BookLabel), log.ColorYellow.Sprintf("(%d)", info.NoteCount))
+		log.Fprintf(w, "%s\n", formatBookLine(info, width, false))
 	}
 }
 
-func printBooks(ctx context.DnoteCtx, all bool) error {
-	db := ctx.DB
+// porcelainField makes the value safe to print as a field of --porcelain output
+// by replacing the tabs and the line breaks in it with spaces
+func porcelainField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+// formatPorcelainBook formats the book for --porcelain as its label, note
+// count and archive state separated by tabs
+func formatPorcelainBook(info bookInfo) string {
+	return fmt.Sprintf("%s\t%d\t%t", porcelainField(info.BookLabel), info.NoteCount, info.Archive)
+}
 
-	rows, err := db.Query(`SELECT books.label, books.archive, count(notes.uuid) note_count
-	FROM books
-	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
-	WHERE books.deleted = false
-		AND books.archive = false
-	GROUP BY books.uuid
-	ORDER BY books.label ASC;`)
+// formatPorcelainNote formats the note for --porcelain as its id, the time it
+// was added in unix nanoseconds and its first line separated by tabs
+func formatPorcelainNote(info noteInfo) string {
+	body, _ := formatBody(info.Body)
+
+	return fmt.Sprintf("%d\t%d\t%s", info.RowID, info.AddedOn, porcelainField(body))
+}
+
+func printBooks(ctx context.DnoteCtx, w io.Writer, all bool, tmpl *template.Template) error {
+	books, err := core.ListBooks(ctx, core.ListBooksOptions{All: all, Sort: sortBooksFlag, Empty: emptyFlag, Reverse: reverseFlag})
 	if err != nil {
-		return errors.Wrap(err, "querying books")
+		return errors.Wrap(err, "listing books")
 	}
-	defer rows.Close()
 
 	infos := []bookInfo{}
-	for rows.Next() {
-		var info bookInfo
-		err = rows.Scan(&info.BookLabel, &info.Archive, &info.NoteCount)
-		if err != nil {
-			return errors.Wrap(err, "scanning a row")
+	for _, b := range books {
+		infos = append(infos, bookInfo{BookLabel: b.Label, NoteCount: b.NoteCount, Archive: b.Archive})
+	}
+
+	if tmpl != nil {
+		for _, info := range infos {
+			if err := printFormatted(w, tmpl, info); err != nil {
+				return err
+			}
 		}
 
-		infos = append(infos, info)
+		return nil
 	}
 
-	for _, info := range infos {
-		printBookLine(info, false)
+	if porcelainFlag {
+		for _, info := range infos {
+			fmt.Fprintln(w, formatPorcelainBook(info))
+		}
+
+		return nil
 	}
 
-	if all {
-		rows, err := db.Query(`SELECT books.label, books.archive, count(notes.uuid) note_count
-		FROM books
-		LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
-		WHERE books.deleted = false
-			AND books.archive = true
-		GROUP BY books.uuid
-		ORDER BY books.label ASC;`)
-		if err != nil {
-			return errors.Wrap(err, "querying books")
+	if plainFlag {
+		for _, info := range infos {
+			printBookLine(w, info, 0, true)
 		}
-		defer rows.Close()
 
-		infos := []bookInfo{}
-		for rows.Next() {
-			var info bookInfo
-			err = rows.Scan(&info.BookLabel, &info.Archive, &info.NoteCount)
-			if err != nil {
-				return errors.Wrap(err, "scanning a row")
-			}
+		return nil
+	}
 
-			infos = append(infos, info)
+	if len(infos) == 0 {
+		if err := printNoBooks(ctx, w, all); err != nil {
+			return errors.Wrap(err, "printing the empty state")
 		}
 
-		for _, info := range infos {
-			printBookLine(info, false)
+		return nil
+	}
+
+	width := getLabelWidth(infos)
+	for _, info := range infos {
+		printBookLine(w, info, width, false)
+	}
+
+	return nil
+}
+
+// printNoBooks prints a message for when there are no books to list
+func printNoBooks(ctx context.DnoteCtx, w io.Writer, all bool) error {
+	if !all {
+		var archivedCount int
+		if err := ctx.DB.QueryRow("SELECT count(*) FROM books WHERE deleted = false AND archive = true").Scan(&archivedCount); err != nil {
+			return errors.Wrap(err, "counting archived books")
+		}
+
+		if archivedCount > 0 {
+			log.Finfof(w, "no active books. See the archived books with `dnote ls --all`\n")
+			return nil
 		}
 	}
+
+	log.Finfof(w, "no books yet. Create one with `dnote add <book>`\n")
+
 	return nil
 }
 
-func printMatchBooks(ctx context.DnoteCtx, keyw string, nameOnly bool) error {
-	db := ctx.DB
+func printMatchBooks(ctx context.DnoteCtx, w io.Writer, keyw string, all, nameOnly bool, tmpl *template.Template) error {
+	books, err := core.MatchBooks(ctx, keyw, all)
+	if err != nil {
+		return errors.Wrap(err, "listing books")
+	}
+
+	return printBookList(w, books, nameOnly, tmpl)
+}
 
-	rows, err := db.Query(`SELECT books.label, books.archive, count(notes.uuid) note_count
-	FROM books
-	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
-	WHERE books.deleted = false
-		and books.label LIKE ?
-	GROUP BY books.uuid
-	ORDER BY books.label ASC;`, keyw)
+// printFuzzyBooks prints the books whose labels fuzzily match the query, the
+// best match first
+func printFuzzyBooks(ctx context.DnoteCtx, w io.Writer, query string, all bool, tmpl *template.Template) error {
+	books, err := core.FuzzyMatchBooks(ctx, query, all)
 	if err != nil {
-		return errors.Wrap(err, "querying books")
+		return errors.Wrap(err, "matching books")
 	}
-	defer rows.Close()
 
+	if len(books) == 0 && tmpl == nil && !plainFlag && !porcelainFlag {
+		log.Finfof(w, "no books match '%s'\n", query)
+		return nil
+	}
+
+	return printBookList(w, books, false, tmpl)
+}
+
+// printBookList prints the given books in order, with their note counts
+// unless nameOnly or --plain is given
+func printBookList(w io.Writer, books []core.Book, nameOnly bool, tmpl *template.Template) error {
 	infos := []bookInfo{}
-	for rows.Next() {
-		var info bookInfo
-		err = rows.Scan(&info.BookLabel, &info.Archive, &info.NoteCount)
-		if err != nil {
-			return errors.Wrap(err, "scanning a row")
+	for _, b := range books {
+		infos = append(infos, bookInfo{BookLabel: b.Label, NoteCount: b.NoteCount, Archive: b.Archive})
+	}
+
+	if tmpl != nil {
+		for _, info := range infos {
+			if err := printFormatted(w, tmpl, info); err != nil {
+				return err
+			}
 		}
 
-		infos = append(infos, info)
+		return nil
+	}
+
+	if porcelainFlag {
+		for _, info := range infos {
+			fmt.Fprintln(w, formatPorcelainBook(info))
+		}
+
+		return nil
 	}
 
+	width := getLabelWidth(infos)
 	for _, info := range infos {
-		printBookLine(info, nameOnly)
+		printBookLine(w, info, width, nameOnly || plainFlag)
 	}
 
 	return nil
 }
 
+// PrintNotes writes the notes in the book with the given name to the writer
+func PrintNotes(ctx context.DnoteCtx, w io.Writer, bookName string) error {
+	return printNotes(ctx, w, bookName, false, nil)
+}
+
+// printNotes prints either the active or the deleted notes in the book with
+// the given name. Deleted notes are dimmed. If a template is given, each note
+// is printed with it instead.
+func printNotes(ctx context.DnoteCtx, w io.Writer, bookName string, deleted bool, tmpl *template.Template) error {
+	notes, err := core.ListNotes(ctx, bookName, core.ListNotesOptions{Deleted: deleted, InsensitiveBook: insensitiveBookFlag, SinceID: sinceIDFlag, IgnorePins: noPinsFlag})
+	if err != nil {
+		return errors.Wrap(err, "listing notes")
+	}
+
+	infos := []noteInfo{}
+	for _, n := range filterNotes(notes) {
+		infos = append(infos, noteInfo{RowID: n.RowID, Body: n.Body, Source: n.Source, AddedOn: n.AddedOn, Pinned: n.Pinned})
+	}
+
+	if tmpl != nil {
+		for _, info := range infos {
+			if err := printFormatted(w, tmpl, info); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if porcelainFlag {
+		for _, info := range infos {
+			fmt.Fprintln(w, formatPorcelainNote(info))
+		}
+
+		return nil
+	}
+
+	if plainFlag {
+		for _, info := range infos {
+			body, _ := formatBody(info.Body)
+			fmt.Fprintf(w, "%d\t%s\n", info.RowID, body)
+		}
+
+		return nil
+	}
+
+	if len(infos) == 0 {
+		if len(notes) != 0 {
+			log.Finfof(w, "no notes in '%s' match the filters\n", bookName)
+		} else if deleted {
+			log.Finfof(w, "no deleted notes in '%s'\n", bookName)
+		} else {
+			log.Finfof(w, "no notes in '%s' yet. Add one with `dnote add %s`\n", bookName, bookName)
+		}
+
+		return nil
+	}
+
+	if deleted {
+		log.Finfof(w, "deleted notes on book %s\n", bookName)
+	} else {
+		log.Finfof(w, "on book %s\n", bookName)
+	}
+
+	for _, info := range infos {
+		body, isExcerpt := formatBody(info.Body)
+		body = markMultiline(body, isExcerpt)
+
+		if deleted {
+			if isExcerpt {
+				body = fmt.Sprintf("%s [---More---]", body)
+			}
+
+			log.Fplain(w, log.ColorGray.Sprintf("(%d) %s\n", info.RowID, body))
+			continue
+		}
+
+		rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
+		body = markPinned(markSource(highlightGrep(body), info.Source), info.Pinned)
+		if isExcerpt {
+			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
+		}
+
+		log.Fplainf(w, "%s %s\n", rowid, body)
+	}
+
+	return nil
+}
 
-func PrintNotes(ctx context.DnoteCtx, bookName string) error {
+// printAllNotes prints the notes in all books, the most recently added first.
+// Notes in archived books are included only if all is true.
+func printAllNotes(ctx context.DnoteCtx, w io.Writer, all bool, tmpl *template.Template) error {
 	db := ctx.DB
 
-	var bookUUID string
-	err := db.QueryRow("SELECT uuid FROM books WHERE label = ?", bookName).Scan(&bookUUID)
-	if err == sql.ErrNoRows {
-		return errors.New("book not found")
-	} else if err != nil {
-		return errors.Wrap(err, "querying the book")
+	query := `SELECT notes.rowid, notes.body, books.label, books.archive, notes.source
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false
+		AND books.deleted = false`
+	if !all {
+		query = fmt.Sprintf("%s AND books.archive = false", query)
 	}
+	query = fmt.Sprintf("%s ORDER BY notes.added_on DESC;", query)
 
-	rows, err := db.Query(`SELECT rowid, body FROM notes WHERE book_uuid = ? AND deleted = ? ORDER BY added_on ASC;`, bookUUID, false)
+	rows, err := db.Query(query)
 	if err != nil {
 		return errors.Wrap(err, "querying notes")
 	}
 	defer rows.Close()
 
-	infos := []noteInfo{}
+	infos := []bookNoteInfo{}
 	for rows.Next() {
-		var info noteInfo
-		err = rows.Scan(&info.RowID, &info.Body)
+		var info bookNoteInfo
+		err = rows.Scan(&info.RowID, &info.Body, &info.BookLabel, &info.Archive, &info.Source)
 		if err != nil {
 			return errors.Wrap(err, "scanning a row")
 		}
+		if isHidden(info.Body) {
+			continue
+		}
 
 		infos = append(infos, info)
 	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating notes")
+	}
+
+	if tmpl != nil {
+		for _, info := range infos {
+			if err := printFormatted(w, tmpl, info); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if plainFlag {
+		for _, info := range infos {
+			body, _ := formatBody(info.Body)
+			fmt.Fprintf(w, "%d\t%s\t%s\n", info.RowID, info.BookLabel, body)
+		}
+
+		return nil
+	}
 
-	log.Infof("on book %s\n", bookName)
+	if len(infos) == 0 {
+		log.Finfof(w, "no notes yet. Add one with `dnote add <book>`\n")
+		return nil
+	}
 
 	for _, info := range infos {
 		body, isExcerpt := formatBody(info.Body)
+		body = markMultiline(body, isExcerpt)
+
+		var bookLabel string
+		if info.Archive {
+			bookLabel = log.ColorGray.Sprintf("(%s)", info.BookLabel)
+		} else {
+			bookLabel = log.ColorYellow.Sprintf("(%s)", info.BookLabel)
+		}
 
 		rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
+		body = markSource(highlightGrep(body), info.Source)
 		if isExcerpt {
 			body = fmt.Sprintf("%s %s", body, log.ColorYellow.Sprintf("[---More---]"))
 		}
 
-		log.Plainf("%s %s\n", rowid, body)
+		log.Fplainf(w, "%s %s %s\n", bookLabel, rowid, body)
 	}
 
 	return nil
@@ -318,35 +887,13 @@ func RetSingle(ctx context.DnoteCtx, bookName string) (string,error) {
 			return "", nil
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return "", errors.Wrap(err, "iterating notes")
+	}
 	return strconv.Itoa(info.RowID), nil
 }
 
-func CountBooks(ctx context.DnoteCtx, bookName string) (int,error) {
-	db := ctx.DB
-
-	rows, err := db.Query(`SELECT count(notes.uuid) note_count
-	FROM books
-	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
-	WHERE books.deleted = false
-		AND books.label = ?
-	GROUP BY books.uuid;`, bookName)
-	if err != nil {
-		return 0, errors.Wrap(err, "querying books")
-	}
-	defer rows.Close()
-
-	var count int 
-	var isMany int = 0
-	for rows.Next() {
-		err = rows.Scan(&count)
-		if err != nil {
-			return 0, errors.Wrap(err, "scanning a row")
-		}
-		isMany += 1
-		if isMany > 1 {
-			return 0, nil
-		}
-	}
-
-	return count, nil
+// CountBooks returns the number of notes in the book with the given name
+func CountBooks(ctx context.DnoteCtx, bookName string) (int, error) {
+	return core.CountNotes(ctx, bookName)
 }
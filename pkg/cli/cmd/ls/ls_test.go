@@ -0,0 +1,403 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ls
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dnote/color"
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+)
+
+func TestRowsErrPropagation(t *testing.T) {
+	bookQuery := database.FaultyQuery{
+		Match:   "SELECT uuid FROM books",
+		Columns: []string{"uuid"},
+		Rows:    [][]driver.Value{{"js-book-uuid"}},
+	}
+
+	t.Run("printBooks", func(t *testing.T) {
+		db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+			{
+				Match:   "SELECT books.label",
+				Columns: []string{"label", "archive", "note_count"},
+				Rows:    [][]driver.Value{{"js", false, int64(1)}},
+				Fail:    true,
+			},
+		})
+		defer db.Close()
+
+		err := printBooks(context.DnoteCtx{DB: db}, ioutil.Discard, false, nil)
+		assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+	})
+
+	t.Run("printMatchBooks", func(t *testing.T) {
+		db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+			{
+				Match:   "SELECT books.label",
+				Columns: []string{"label", "archive", "note_count"},
+				Rows:    [][]driver.Value{{"js", false, int64(1)}},
+				Fail:    true,
+			},
+		})
+		defer db.Close()
+
+		err := printMatchBooks(context.DnoteCtx{DB: db}, ioutil.Discard, "j%", false, false, nil)
+		assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+	})
+
+	t.Run("PrintNotes", func(t *testing.T) {
+		db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+			bookQuery,
+			{
+				Match:   "SELECT rowid, body, uuid, added_on, edited_on, source, pinned FROM notes",
+				Columns: []string{"rowid", "body", "uuid", "added_on", "edited_on", "source", "pinned"},
+				Rows:    [][]driver.Value{{int64(1), "n1 body", "n1-uuid", int64(1515199951), int64(0), "cli", false}},
+				Fail:    true,
+			},
+		})
+		defer db.Close()
+
+		err := PrintNotes(context.DnoteCtx{DB: db}, ioutil.Discard, "js")
+		assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+	})
+
+	t.Run("RetSingle", func(t *testing.T) {
+		db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+			bookQuery,
+			{
+				Match:   "SELECT rowid FROM notes",
+				Columns: []string{"rowid"},
+				Rows:    [][]driver.Value{{int64(1)}},
+				Fail:    true,
+			},
+		})
+		defer db.Close()
+
+		_, err := RetSingle(context.DnoteCtx{DB: db}, "js")
+		assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+	})
+
+	t.Run("CountBooks", func(t *testing.T) {
+		db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+			{
+				Match:   "SELECT books.label",
+				Columns: []string{"label", "archive", "note_count"},
+				Rows:    [][]driver.Value{{"js", false, int64(3)}},
+				Fail:    true,
+			},
+		})
+		defer db.Close()
+
+		_, err := CountBooks(context.DnoteCtx{DB: db}, "js")
+		assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+	})
+}
+
+func setupPrint(t *testing.T, db *database.DB) {
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "algorithms")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1515199951)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 1515199943)
+}
+
+func TestPrintBooks(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../../tmp",
+		Cache: "../../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	setupPrint(t, ctx.DB)
+
+	// Execute
+	var buf bytes.Buffer
+	if err := printBooks(ctx, &buf, false, nil); err != nil {
+		t.Fatal(errors.Wrap(err, "printing books"))
+	}
+
+	// Test
+	assert.Equal(t, buf.String(), "  • algorithms (0)\n  • js         (2)\n", "output mismatch")
+}
+
+func TestPrintNotes(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../../tmp",
+		Cache: "../../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	setupPrint(t, ctx.DB)
+
+	// Execute
+	var buf bytes.Buffer
+	if err := PrintNotes(ctx, &buf, "js"); err != nil {
+		t.Fatal(errors.Wrap(err, "printing notes"))
+	}
+
+	// Test
+	assert.Equal(t, buf.String(), "  • on book js\n  (2) n2 body\n  (1) n1 body\n", "output mismatch")
+}
+
+func TestPrintNotesPinned(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../../tmp",
+		Cache: "../../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	setupPrint(t, ctx.DB)
+	database.MustExec(t, "pinning n1", ctx.DB, "UPDATE notes SET pinned = ? WHERE uuid = ?", true, "n1-uuid")
+
+	// Execute
+	var buf bytes.Buffer
+	if err := PrintNotes(ctx, &buf, "js"); err != nil {
+		t.Fatal(errors.Wrap(err, "printing notes"))
+	}
+
+	// Test
+	assert.Equal(t, buf.String(), "  • on book js\n  (1) ★ n1 body\n  (2) n2 body\n", "output mismatch")
+}
+
+func TestFormatBookLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	infos := []bookInfo{
+		{BookLabel: "js", NoteCount: 3},
+		{BookLabel: "algorithms", NoteCount: 12},
+		{BookLabel: "한국어", NoteCount: 1},
+		{BookLabel: "go", NoteCount: 100, Archive: true},
+	}
+
+	width := getLabelWidth(infos)
+	assert.Equal(t, width, 10, "width mismatch")
+
+	t.Run("aligned", func(t *testing.T) {
+		for _, info := range infos {
+			line := formatBookLine(info, width, false)
+			col := utf8.RuneCountInString(line[:strings.Index(line, "(")])
+
+			assert.Equal(t, col, width+1, fmt.Sprintf("count column mismatch for %s", info.BookLabel))
+		}
+	})
+
+	t.Run("name only", func(t *testing.T) {
+		for _, info := range infos {
+			assert.Equal(t, formatBookLine(info, width, true), info.BookLabel, "line mismatch")
+		}
+	})
+}
+
+func TestFormatPorcelain(t *testing.T) {
+	t.Run("book", func(t *testing.T) {
+		testCases := []struct {
+			info     bookInfo
+			expected string
+		}{
+			{info: bookInfo{BookLabel: "js", NoteCount: 3}, expected: "js\t3\tfalse"},
+			{info: bookInfo{BookLabel: "go", NoteCount: 0, Archive: true}, expected: "go\t0\ttrue"},
+			{info: bookInfo{BookLabel: "한국어", NoteCount: 12}, expected: "한국어\t12\tfalse"},
+		}
+
+		for _, tc := range testCases {
+			assert.Equal(t, formatPorcelainBook(tc.info), tc.expected, "line mismatch")
+		}
+	})
+
+	t.Run("note", func(t *testing.T) {
+		testCases := []struct {
+			info     noteInfo
+			expected string
+		}{
+			{info: noteInfo{RowID: 1, AddedOn: 1515199943, Body: "foo"}, expected: "1\t1515199943\tfoo"},
+			{info: noteInfo{RowID: 12, AddedOn: 1515199951, Body: "  foo bar\nbaz\n"}, expected: "12\t1515199951\tfoo bar"},
+			{info: noteInfo{RowID: 3, AddedOn: 1515199961, Body: "a\tb\r\nc"}, expected: "3\t1515199961\ta b"},
+			{info: noteInfo{RowID: 4, AddedOn: 1515199971, Body: ""}, expected: "4\t1515199971\t"},
+		}
+
+		for _, tc := range testCases {
+			got := formatPorcelainNote(tc.info)
+
+			assert.Equal(t, got, tc.expected, "line mismatch")
+			assert.Equal(t, len(strings.Split(got, "\t")), 3, "field count mismatch")
+		}
+	})
+}
+
+func TestGetNewlineIdx(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int
+	}{
+		{
+			input:    "foo",
+			expected: -1,
+		},
+		{
+			input:    "foo\nbar",
+			expected: 3,
+		},
+		{
+			input:    "foo\r\nbar",
+			expected: 3,
+		},
+		{
+			input:    "foo\rbar",
+			expected: 3,
+		},
+		{
+			input:    "\r\nfoo",
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q", tc.input), func(t *testing.T) {
+			assert.Equal(t, getNewlineIdx(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedBody    string
+		expectedExcerpt bool
+	}{
+		{
+			input:           "foo bar",
+			expectedBody:    "foo bar",
+			expectedExcerpt: false,
+		},
+		{
+			input:           "foo bar\n",
+			expectedBody:    "foo bar",
+			expectedExcerpt: false,
+		},
+		{
+			input:           "foo bar\r\n",
+			expectedBody:    "foo bar",
+			expectedExcerpt: false,
+		},
+		{
+			input:           "foo bar\nbaz",
+			expectedBody:    "foo bar",
+			expectedExcerpt: true,
+		},
+		{
+			input:           "foo bar\r\nbaz\r\n",
+			expectedBody:    "foo bar",
+			expectedExcerpt: true,
+		},
+		{
+			input:           "foo bar\rbaz",
+			expectedBody:    "foo bar",
+			expectedExcerpt: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q", tc.input), func(t *testing.T) {
+			body, excerpt := formatBody(tc.input)
+
+			assert.Equal(t, body, tc.expectedBody, "body mismatch")
+			assert.Equal(t, excerpt, tc.expectedExcerpt, "excerpt mismatch")
+		})
+	}
+}
+
+func TestIsHiddenGrep(t *testing.T) {
+	grep := grepFlag
+	grepFlag = "promise"
+	defer func() { grepFlag = grep }()
+
+	testCases := []struct {
+		body     string
+		expected bool
+	}{
+		{body: "a Promise resolves once", expected: false},
+		{body: "first line\nthen promise", expected: false},
+		{body: "async functions", expected: true},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, isHidden(tc.body), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestHighlightGrep(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	grep := grepFlag
+	defer func() { grepFlag = grep }()
+
+	grepFlag = ""
+	assert.Equal(t, highlightGrep("a Promise"), "a Promise", "no term should be left alone")
+
+	grepFlag = "promise"
+	expected := fmt.Sprintf("a %s and a %s", log.ColorHighlight.Sprint("Promise"), log.ColorHighlight.Sprint("promise"))
+	assert.Equal(t, highlightGrep("a Promise and a promise"), expected, "result mismatch")
+}
+
+func TestMarkSource(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	source := sourceFlag
+	defer func() { sourceFlag = source }()
+
+	sourceFlag = false
+	assert.Equal(t, markSource("foo", "cli"), "foo", "source should be shown only if requested")
+
+	sourceFlag = true
+	assert.Equal(t, markSource("foo", "cli"), "[cli] foo", "result mismatch")
+	assert.Equal(t, markSource("foo", ""), "foo", "unknown source should not be shown")
+}
@@ -0,0 +1,128 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package merge
+
+import (
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/root"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var yesFlag bool
+var quietFlag bool
+
+var example = `
+ * Merge the notes in javascript_2 into javascript
+ dnote merge javascript_2 javascript`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Incorrect number of argument")
+	}
+	if args[0] == args[1] {
+		return errors.New("cannot merge a book into itself")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new merge command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "merge <book> <target book>",
+		Short:   "Move all notes in a book into another book and remove it",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&yesFlag, "yes", "y", false, "Assume yes to the prompts and run in non-interactive mode")
+	f.BoolVarP(&quietFlag, "quiet", "q", false, "Do not print the progress")
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		return Run(ctx, args[0], args[1], yesFlag, quietFlag)
+	}
+}
+
+// Run moves all notes in the source book into the target book and removes the
+// source book. It asks for a confirmation unless yes is true, and only reports
+// what would be merged in a dry run. The progress is not printed if quiet is
+// true. Both merge and edit --merge-into run it.
+func Run(ctx context.DnoteCtx, sourceLabel, targetLabel string, yes, quiet bool) error {
+	sourceUUID, err := database.GetBookUUID(ctx.DB, sourceLabel)
+	if err != nil {
+		return err
+	}
+	targetUUID, err := database.GetBookUUID(ctx.DB, targetLabel)
+	if err != nil {
+		return err
+	}
+
+	noteCount, err := database.CountBookNotes(ctx.DB, sourceUUID)
+	if err != nil {
+		return errors.Wrap(err, "counting notes in the book")
+	}
+
+	if root.DryRunFlag {
+		log.Infof("would merge '%s' into '%s' (%d notes)\n", sourceLabel, targetLabel, noteCount)
+		return nil
+	}
+
+	ok, err := root.Confirm(ctx, fmt.Sprintf("move %d notes from '%s' into '%s' and remove '%s'?", noteCount, sourceLabel, targetLabel, sourceLabel), false, yes)
+	if err != nil {
+		return errors.Wrap(err, "getting confirmation")
+	}
+	if !ok {
+		log.Warnf("aborted by user\n")
+		return nil
+	}
+
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	p := ui.NewProgress("merging", noteCount, quiet)
+	if err := core.MergeBook(ctx, tx, sourceUUID, targetUUID, p.Done); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "merging the book")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing transaction")
+	}
+
+	log.Successf("merged %s into %s (%d notes)\n", sourceLabel, targetLabel, noteCount)
+
+	return nil
+}
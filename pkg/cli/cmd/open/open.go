@@ -0,0 +1,207 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package open
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var openerFlag string
+
+var example = `
+ * Open all notes in a book
+ dnote open javascript
+
+ * Open a note
+ dnote open 3
+
+ * Open a book with a specific application
+ dnote open javascript --opener "typora"`
+
+// noteSeparator separates the notes of a book in the file to open
+const noteSeparator = "\n\n---\n\n"
+
+// staleFileAge is the age after which the files written for the opener are
+// removed. The opener may return before the application reads the file, so
+// the files are removed by a later run rather than right after opening.
+const staleFileAge = 24 * time.Hour
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new open command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "open <book name|note id>",
+		Short:   "Open a book or a note in a GUI application",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&openerFlag, "opener", "", "", "the command to open the file with. Overrides opener in the config, which defaults to the application of the operating system")
+
+	return cmd
+}
+
+// formatBook returns the Markdown content of a book, which is its label as a
+// heading followed by its notes in the order they were added
+func formatBook(label string, notes []core.Note) string {
+	bodies := []string{}
+	for _, n := range notes {
+		bodies = append(bodies, strings.TrimSpace(n.Body))
+	}
+
+	ret := fmt.Sprintf("# %s\n\n", label)
+	if len(bodies) > 0 {
+		ret += strings.Join(bodies, noteSeparator) + "\n"
+	}
+
+	return ret
+}
+
+// getOpenDir returns the directory to write the files to open in
+func getOpenDir(ctx context.DnoteCtx) string {
+	return filepath.Join(ctx.Paths.Cache, consts.DnoteDirName, "open")
+}
+
+// removeStaleFiles removes the files in the directory that were last modified
+// before the given time
+func removeStaleFiles(dir string, before time.Time) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "reading the directory")
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !f.ModTime().Before(before) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			return errors.Wrapf(err, "removing %s", f.Name())
+		}
+	}
+
+	return nil
+}
+
+// sanitizeFileName replaces the path separators in s, which a book label may
+// contain, so that s can be a part of a file name
+func sanitizeFileName(s string) string {
+	return strings.NewReplacer("/", "-", "\\", "-").Replace(s)
+}
+
+// writeTmpFile writes the content to a new Markdown file in the given
+// directory and returns its path. The name of the file starts with the given
+// prefix.
+func writeTmpFile(dir, prefix, content string) (string, error) {
+	f, err := ioutil.TempFile(dir, fmt.Sprintf("dnote-%s-*.md", sanitizeFileName(prefix)))
+	if err != nil {
+		return "", errors.Wrap(err, "creating a temporary file")
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", errors.Wrap(err, "writing the temporary file")
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.Wrap(err, "closing the temporary file")
+	}
+
+	return f.Name(), nil
+}
+
+// materialize writes the book or the note given by the argument to a
+// temporary file in the given directory and returns its path
+func materialize(ctx context.DnoteCtx, dir, target string) (string, error) {
+	if utils.IsNumber(target) {
+		noteRowID, err := strconv.Atoi(target)
+		if err != nil {
+			return "", errors.Wrap(err, "invalid rowid")
+		}
+
+		info, err := database.GetNoteInfo(ctx.DB, noteRowID)
+		if err != nil {
+			return "", err
+		}
+
+		return writeTmpFile(dir, fmt.Sprintf("%s-%d", info.BookLabel, info.RowID), info.Content)
+	}
+
+	notes, err := core.ListNotes(ctx, target, core.ListNotesOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "listing the notes in '%s'", target)
+	}
+
+	return writeTmpFile(dir, target, formatBook(target, notes))
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		dir := getOpenDir(ctx)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return errors.Wrap(err, "creating the directory for the files to open")
+		}
+		if err := removeStaleFiles(dir, ctx.Clock.Now().Add(-staleFileAge)); err != nil {
+			return errors.Wrap(err, "removing the files opened before")
+		}
+
+		fpath, err := materialize(ctx, dir, args[0])
+		if err != nil {
+			return err
+		}
+
+		opener := ctx.Opener
+		if openerFlag != "" {
+			opener = openerFlag
+		}
+
+		if err := ui.OpenFile(opener, fpath); err != nil {
+			return errors.Wrap(err, "opening the file")
+		}
+
+		log.Successf("opened %s\n", fpath)
+
+		return nil
+	}
+}
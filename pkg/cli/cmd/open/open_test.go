@@ -0,0 +1,186 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package open
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func TestFormatBook(t *testing.T) {
+	testCases := []struct {
+		label    string
+		notes    []core.Note
+		expected string
+	}{
+		{
+			label: "js",
+			notes: []core.Note{
+				{Body: "n1 body"},
+				{Body: "\nn2 line 1\nn2 line 2\n\n"},
+			},
+			expected: "# js\n\nn1 body\n\n---\n\nn2 line 1\nn2 line 2\n",
+		},
+		{
+			label:    "empty",
+			notes:    []core.Note{},
+			expected: "# empty\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			assert.Equal(t, formatBook(tc.label, tc.notes), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../../tmp",
+		Cache: "../../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	dir, err := ioutil.TempDir("", "dnote-open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b1-uuid", "n3 body", 1542058877, true)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "go/concurrency")
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b2-uuid", "n4 body", 1542058878)
+
+	t.Run("book", func(t *testing.T) {
+		fpath, err := materialize(ctx, dir, "js")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, filepath.Dir(fpath), dir, "directory mismatch")
+		assert.Equal(t, strings.HasPrefix(filepath.Base(fpath), "dnote-js-"), true, "file name should start with the book label")
+		assert.Equal(t, filepath.Ext(fpath), ".md", "extension mismatch")
+		assert.Equal(t, string(b), "# js\n\nn1 body\n\n---\n\nn2 body\n", "content mismatch")
+	})
+
+	t.Run("note", func(t *testing.T) {
+		fpath, err := materialize(ctx, dir, "2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, strings.HasPrefix(filepath.Base(fpath), "dnote-js-2-"), true, "file name should have the book label and the note id")
+		assert.Equal(t, string(b), "n2 body", "content mismatch")
+	})
+
+	t.Run("book with a slash", func(t *testing.T) {
+		fpath, err := materialize(ctx, dir, "go/concurrency")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, filepath.Dir(fpath), dir, "directory mismatch")
+		assert.Equal(t, strings.HasPrefix(filepath.Base(fpath), "dnote-go-concurrency-"), true, "file name should have the label without the slash")
+		assert.Equal(t, string(b), "# go/concurrency\n\nn4 body\n", "content mismatch")
+	})
+
+	t.Run("note in a book with a slash", func(t *testing.T) {
+		fpath, err := materialize(ctx, dir, "4")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, filepath.Dir(fpath), dir, "directory mismatch")
+		assert.Equal(t, strings.HasPrefix(filepath.Base(fpath), "dnote-go-concurrency-4-"), true, "file name should have the label without the slash")
+	})
+
+	t.Run("missing book", func(t *testing.T) {
+		_, err := materialize(ctx, dir, "css")
+
+		assert.NotEqual(t, err, nil, "error should be returned")
+	})
+
+	t.Run("missing note", func(t *testing.T) {
+		_, err := materialize(ctx, dir, "9")
+
+		assert.NotEqual(t, err, nil, "error should be returned")
+	})
+}
+
+func TestRemoveStaleFiles(t *testing.T) {
+	// Setup
+	dir, err := ioutil.TempDir("", "dnote-open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	stalePath := filepath.Join(dir, "dnote-js-1.md")
+	freshPath := filepath.Join(dir, "dnote-js-2.md")
+	for _, fpath := range []string{stalePath, freshPath} {
+		if err := ioutil.WriteFile(fpath, []byte("content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(stalePath, now.Add(-2*staleFileAge), now.Add(-2*staleFileAge)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute
+	if err := removeStaleFiles(dir, now.Add(-staleFileAge)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Test
+	_, err = os.Stat(stalePath)
+	assert.Equal(t, os.IsNotExist(err), true, "stale file should be removed")
+	_, err = os.Stat(freshPath)
+	assert.Equal(t, err, nil, "fresh file should be kept")
+}
@@ -0,0 +1,93 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pin
+
+import (
+	"strconv"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * Pin a note so that it is listed first in its book
+ dnote pin 3`
+
+var unpinExample = `
+ * Unpin a note
+ dnote unpin 3`
+
+// NewCmd returns a new pin command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "pin <note id>",
+		Short:   "Pin a note to list it first in its book",
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE:    newRun(ctx, true),
+	}
+
+	return cmd
+}
+
+// NewUnpinCmd returns a new unpin command
+func NewUnpinCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "unpin <note id>",
+		Short:   "Unpin a note",
+		Example: unpinExample,
+		Args:    cobra.ExactArgs(1),
+		RunE:    newRun(ctx, false),
+	}
+
+	return cmd
+}
+
+func newRun(ctx context.DnoteCtx, pinned bool) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		rowID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid rowid")
+		}
+
+		// make sure that the note exists and is not deleted
+		noteInfo, err := database.GetNoteInfo(ctx.DB, rowID)
+		if err != nil {
+			return err
+		}
+
+		if err := database.UpdateNotePinned(ctx.DB, rowID, pinned); err != nil {
+			return err
+		}
+
+		if pinned {
+			log.Success("pinned the note\n")
+		} else {
+			log.Success("unpinned the note\n")
+		}
+		output.NoteHead(noteInfo)
+
+		return nil
+	}
+}
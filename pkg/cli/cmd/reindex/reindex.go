@@ -0,0 +1,91 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package reindex
+
+import (
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var example = `
+ * Rebuild the search index
+ dnote reindex`
+
+// NewCmd returns a new reindex command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "reindex",
+		Short:   "Rebuild the full-text search index from the notes",
+		Example: example,
+		Args:    cobra.NoArgs,
+		RunE:    newRun(ctx),
+	}
+
+	return cmd
+}
+
+// rebuild repopulates the full-text search index with the bodies of the notes
+// that are not deleted and returns the number of the indexed notes
+func rebuild(db *database.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, errors.Wrap(err, "beginning a transaction")
+	}
+
+	if _, err := tx.Exec("INSERT INTO note_fts(note_fts) VALUES ('delete-all')"); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "clearing note_fts")
+	}
+
+	res, err := tx.Exec("INSERT INTO note_fts(rowid, body) SELECT rowid, body FROM notes WHERE deleted = false")
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "populating note_fts")
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "counting the indexed notes")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "committing a transaction")
+	}
+
+	return int(count), nil
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		count, err := rebuild(ctx.DB)
+		if err != nil {
+			return err
+		}
+
+		log.Successf("indexed %d notes\n", count)
+
+		return nil
+	}
+}
@@ -22,12 +22,12 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/dnote/dnote/pkg/cli/cmd/root"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/output"
-	"github.com/dnote/dnote/pkg/cli/ui"
 	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -42,6 +42,9 @@ var example = `
 
   * Delete a book by name
   dnote delete js
+
+  * See what would be deleted without deleting
+  dnote delete js --dry-run
 `
 
 // NewCmd returns a new remove command
@@ -72,14 +75,6 @@ func preRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func maybeConfirm(message string, defaultValue bool) (bool, error) {
-	if yesFlag {
-		return true, nil
-	}
-
-	return ui.Confirm(message, defaultValue)
-}
-
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
 		// DEPRECATED: Remove in 1.0.0
@@ -134,7 +129,7 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 
 	output.NoteInfo(noteInfo)
 
-	ok, err := maybeConfirm("remove this note?", false)
+	ok, err := root.Confirm(ctx, "remove this note?", false, yesFlag)
 	if err != nil {
 		return errors.Wrap(err, "getting confirmation")
 	}
@@ -148,11 +143,19 @@ func runNote(ctx context.DnoteCtx, rowIDArg string) error {
 		return errors.Wrap(err, "beginning a transaction")
 	}
 
-	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE uuid = ?", true, true, "", noteInfo.UUID); err != nil {
+	// the body is kept so that the note can be restored until it is synced
+	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ? WHERE uuid = ?", true, true, noteInfo.UUID); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "removing the note")
 	}
 
+	if root.DryRunFlag {
+		tx.Rollback()
+		log.Infof("would remove note %d from '%s'\n", noteInfo.RowID, noteInfo.BookLabel)
+
+		return nil
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
@@ -172,7 +175,12 @@ func runBook(ctx context.DnoteCtx, bookLabel string) error {
 		return errors.Wrap(err, "finding book uuid")
 	}
 
-	ok, err := maybeConfirm(fmt.Sprintf("delete book '%s' and all its notes?", bookLabel), false)
+	noteCount, err := database.CountBookNotes(db, bookUUID)
+	if err != nil {
+		return errors.Wrap(err, "counting notes in the book")
+	}
+
+	ok, err := root.Confirm(ctx, fmt.Sprintf("delete book '%s' and all its notes?", bookLabel), false, yesFlag)
 	if err != nil {
 		return errors.Wrap(err, "getting confirmation")
 	}
@@ -186,22 +194,30 @@ func runBook(ctx context.DnoteCtx, bookLabel string) error {
 		return errors.Wrap(err, "beginning a transaction")
 	}
 
-	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ?, body = ? WHERE book_uuid = ?", true, true, "", bookUUID); err != nil {
+	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ? WHERE book_uuid = ?", true, true, bookUUID); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "removing notes in the book")
 	}
 
-	// override the label with a random string
+	// override the label with a random string so that a new book can take
+	// it, and keep the original label to restore the book with
 	uniqLabel, err := utils.GenerateUUID()
 	if err != nil {
 		return errors.Wrap(err, "generating uuid to override with")
 	}
 
-	if _, err = tx.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ?", true, true, uniqLabel, bookUUID); err != nil {
+	if _, err = tx.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ?, original_label = ? WHERE uuid = ?", true, true, uniqLabel, bookLabel, bookUUID); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "removing the book")
 	}
 
+	if root.DryRunFlag {
+		tx.Rollback()
+		log.Infof("would remove '%s' (%d notes)\n", bookLabel, noteCount)
+
+		return nil
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
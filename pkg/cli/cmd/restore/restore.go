@@ -0,0 +1,262 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package restore
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/output"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var bookFlag string
+
+var example = `
+  * Restore a deleted note by id
+  dnote restore 2
+
+  * Restore a deleted book and its notes
+  dnote restore --book js
+`
+
+// NewCmd returns a new restore command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "restore <note id>",
+		Short:   "Restore a deleted note or book",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&bookFlag, "book", "b", "", "the name or uuid of the deleted book to restore")
+
+	return cmd
+}
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if bookFlag != "" {
+		if len(args) != 0 {
+			return errors.New("Incorrect number of argument")
+		}
+
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if bookFlag != "" {
+			if err := runBook(ctx, bookFlag); err != nil {
+				return errors.Wrap(err, "restoring the book")
+			}
+
+			return nil
+		}
+
+		if err := runNote(ctx, args[0]); err != nil {
+			return errors.Wrap(err, "restoring the note")
+		}
+
+		return nil
+	}
+}
+
+func runNote(ctx context.DnoteCtx, rowIDArg string) error {
+	rowID, err := strconv.Atoi(rowIDArg)
+	if err != nil {
+		return errors.Wrap(err, "invalid rowid")
+	}
+
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	var noteUUID, bookUUID string
+	var noteDeleted, bookDeleted bool
+	err = tx.QueryRow(`SELECT notes.uuid, notes.deleted, books.uuid, books.deleted
+		FROM notes
+		INNER JOIN books ON books.uuid = notes.book_uuid
+		WHERE notes.rowid = ?`, rowID).Scan(&noteUUID, &noteDeleted, &bookUUID, &bookDeleted)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return errors.Errorf("note %d not found", rowID)
+	} else if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "finding the note")
+	}
+
+	if !noteDeleted {
+		tx.Rollback()
+		return errors.Errorf("note %d is not deleted", rowID)
+	}
+
+	// a note cannot be active in a deleted book
+	if bookDeleted {
+		if _, err = restoreBook(tx, bookUUID); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "restoring the book")
+		}
+	}
+
+	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ? WHERE uuid = ?", false, true, noteUUID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "restoring the note")
+	}
+
+	noteInfo, err := database.GetNoteInfo(tx, rowID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "getting note info")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	if bookDeleted {
+		log.Successf("restored the note and its book %s\n", noteInfo.BookLabel)
+	} else {
+		log.Successf("restored the note to %s\n", noteInfo.BookLabel)
+	}
+	output.NoteHead(noteInfo)
+
+	return nil
+}
+
+// restoreBook marks the deleted book with the given uuid as active, giving it
+// back the label it had before it was deleted. It returns the label.
+func restoreBook(tx *database.DB, bookUUID string) (string, error) {
+	var label string
+	var originalLabel sql.NullString
+	if err := tx.QueryRow("SELECT label, original_label FROM books WHERE uuid = ?", bookUUID).Scan(&label, &originalLabel); err != nil {
+		return "", errors.Wrap(err, "finding the book")
+	}
+
+	if originalLabel.Valid {
+		label = originalLabel.String
+
+		var count int
+		if err := tx.QueryRow("SELECT count(*) FROM books WHERE label = ?", label).Scan(&count); err != nil {
+			return "", errors.Wrap(err, "checking the label")
+		}
+		if count > 0 {
+			return "", errors.Errorf("a book named '%s' already exists", label)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ?, original_label = NULL WHERE uuid = ?", false, true, label, bookUUID); err != nil {
+		return "", errors.Wrap(err, "updating the book")
+	}
+
+	return label, nil
+}
+
+// findDeletedBook returns the uuid of the deleted book with the given uuid or
+// the given label before it was deleted
+func findDeletedBook(tx *database.DB, bookArg string) (string, error) {
+	rows, err := tx.Query("SELECT uuid FROM books WHERE deleted = ? AND (uuid = ? OR original_label = ?)", true, bookArg, bookArg)
+	if err != nil {
+		return "", errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	uuids := []string{}
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return "", errors.Wrap(err, "scanning a row")
+		}
+
+		uuids = append(uuids, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return "", errors.Wrap(err, "iterating books")
+	}
+
+	if len(uuids) > 1 {
+		return "", errors.Errorf("more than one deleted book is named '%s'. Pass the uuid of the book instead: %s", bookArg, strings.Join(uuids, ", "))
+	}
+	if len(uuids) == 1 {
+		return uuids[0], nil
+	}
+
+	var activeCount int
+	if err := tx.QueryRow("SELECT count(*) FROM books WHERE deleted = ? AND (uuid = ? OR label = ?)", false, bookArg, bookArg).Scan(&activeCount); err != nil {
+		return "", errors.Wrap(err, "finding the book")
+	}
+	if activeCount > 0 {
+		return "", errors.Errorf("book '%s' is not deleted", bookArg)
+	}
+
+	return "", errors.Errorf("book '%s' not found", bookArg)
+}
+
+func runBook(ctx context.DnoteCtx, bookArg string) error {
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	// deleted books have their labels overridden with a random string, so
+	// they are looked up by the label they had before or by uuid
+	bookUUID, err := findDeletedBook(tx, bookArg)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	bookLabel, err := restoreBook(tx, bookUUID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "restoring the book")
+	}
+
+	if _, err = tx.Exec("UPDATE notes SET deleted = ?, dirty = ? WHERE book_uuid = ? AND deleted = ?", false, true, bookUUID, true); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "restoring notes in the book")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing a transaction")
+	}
+
+	log.Successf("restored book %s\n", bookLabel)
+
+	return nil
+}
@@ -0,0 +1,37 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package root
+
+import (
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/ui"
+)
+
+// Confirm prompts for the confirmation of a destructive operation. It assumes
+// yes without prompting if yes is true, unless ctx.ConfirmDestructive is set.
+// Nothing is written in a dry run, so a dry run is never confirmed.
+func Confirm(ctx context.DnoteCtx, message string, defaultValue, yes bool) (bool, error) {
+	if DryRunFlag {
+		return true, nil
+	}
+	if yes && !ctx.ConfirmDestructive {
+		return true, nil
+	}
+
+	return ui.Confirm(message, defaultValue)
+}
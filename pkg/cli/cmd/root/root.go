@@ -19,9 +19,18 @@
 package root
 
 import (
+	"os"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// DryRunFlag is the value of the global --dry-run flag. Destructive commands
+// print what would change without writing to the database if it is set.
+var DryRunFlag bool
+
 var Root = &cobra.Command{
 	Use:           "dnote",
 	Short:         "Dnote - a simple command line notebook",
@@ -29,6 +38,46 @@ var Root = &cobra.Command{
 	SilenceUsage:  true,
 }
 
+func init() {
+	f := Root.PersistentFlags()
+	f.BoolVar(&DryRunFlag, "dry-run", false, "print what would change without making any changes")
+	f.StringVar(&ui.EditorFlag, "editor", "", "the editor command to write notes with. e.g. \"code --wait\"")
+	f.StringArrayVar(&ui.EditorArgsFlag, "editor-args", []string{}, "an argument to pass to the editor. Repeat to pass several arguments")
+
+	Root.SetFlagErrorFunc(flagError)
+}
+
+// findSingleDashFlag returns the name of the first argument that is a long flag
+// of the command written with a single dash, e.g. "-reverse" instead of
+// "--reverse". Such an argument is parsed as a cluster of shorthands.
+func findSingleDashFlag(cmd *cobra.Command, args []string) string {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+			continue
+		}
+
+		name := strings.SplitN(arg[1:], "=", 2)[0]
+		if cmd.Flags().Lookup(name) != nil {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// flagError points out a long flag written with a single dash, which otherwise
+// surfaces as an unknown shorthand in the middle of the flag name
+func flagError(cmd *cobra.Command, err error) error {
+	if name := findSingleDashFlag(cmd, os.Args[1:]); name != "" {
+		return errors.Errorf("unknown flag '-%s'. Did you mean '--%s'?", name, name)
+	}
+
+	return err
+}
+
 // Register adds a new command
 func Register(cmd *cobra.Command) {
 	Root.AddCommand(cmd)
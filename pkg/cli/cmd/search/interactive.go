@@ -0,0 +1,423 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dnote/dnote/pkg/cli/cmd/cat"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// debounceInterval is how long the interactive search waits after a keystroke
+// before running the query, so that typing a word runs a single search
+const debounceInterval = 150 * time.Millisecond
+
+// escapeTimeout is how long the interactive search waits for the rest of an
+// escape sequence before taking the escape as a key press of its own
+const escapeTimeout = 50 * time.Millisecond
+
+// maxInteractiveResults is the number of results listed in the interactive search
+const maxInteractiveResults = 10
+
+// interactivePrompt is printed before the query in the interactive search
+const interactivePrompt = "search: "
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyBackspace
+	keyEnter
+	keyUp
+	keyDown
+	keyQuit
+)
+
+// key is a key press read from the terminal
+type key struct {
+	Kind keyKind
+	Rune rune
+}
+
+// parseKeys decodes the bytes read from a terminal in raw mode into key presses.
+// Unrecognized control sequences are dropped. An escape sequence cut off at the
+// end is returned to be completed by the next read, because a terminal can
+// split a sequence across reads.
+func parseKeys(b []byte) ([]key, []byte) {
+	ret := []key{}
+
+	for len(b) > 0 {
+		switch {
+		case isPartialEscape(b):
+			return ret, b
+		case b[0] == 0x1b && len(b) >= 3 && b[1] == '[':
+			switch b[2] {
+			case 'A':
+				ret = append(ret, key{Kind: keyUp})
+			case 'B':
+				ret = append(ret, key{Kind: keyDown})
+			}
+			b = b[3:]
+		case b[0] == 0x1b:
+			// a lone escape
+			ret = append(ret, key{Kind: keyQuit})
+			b = b[1:]
+		case b[0] == 3 || b[0] == 4:
+			// ctrl-c and ctrl-d
+			ret = append(ret, key{Kind: keyQuit})
+			b = b[1:]
+		case b[0] == 127 || b[0] == 8:
+			ret = append(ret, key{Kind: keyBackspace})
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			ret = append(ret, key{Kind: keyEnter})
+			b = b[1:]
+		case b[0] == 16:
+			// ctrl-p
+			ret = append(ret, key{Kind: keyUp})
+			b = b[1:]
+		case b[0] == 14:
+			// ctrl-n
+			ret = append(ret, key{Kind: keyDown})
+			b = b[1:]
+		case b[0] < 0x20:
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			ret = append(ret, key{Kind: keyRune, Rune: r})
+			b = b[size:]
+		}
+	}
+
+	return ret, nil
+}
+
+// isPartialEscape returns whether the bytes are the start of an escape
+// sequence and nothing else
+func isPartialEscape(b []byte) bool {
+	switch len(b) {
+	case 1:
+		return b[0] == 0x1b
+	case 2:
+		return b[0] == 0x1b && b[1] == '['
+	}
+
+	return false
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	// actionSearch means the query has changed
+	actionSearch
+	// actionMove means the selection has changed
+	actionMove
+	actionOpen
+	actionQuit
+)
+
+// session is the state of an interactive search
+type session struct {
+	input    []rune
+	results  []core.Result
+	selected int
+}
+
+// handleKey updates the session with the key press and returns what the
+// interactive search should do next
+func (s *session) handleKey(k key) action {
+	switch k.Kind {
+	case keyRune:
+		s.input = append(s.input, k.Rune)
+		return actionSearch
+	case keyBackspace:
+		if len(s.input) == 0 {
+			return actionNone
+		}
+
+		s.input = s.input[:len(s.input)-1]
+		return actionSearch
+	case keyUp:
+		if s.selected == 0 {
+			return actionNone
+		}
+
+		s.selected--
+		return actionMove
+	case keyDown:
+		if s.selected >= len(s.results)-1 {
+			return actionNone
+		}
+
+		s.selected++
+		return actionMove
+	case keyEnter:
+		if len(s.results) == 0 {
+			return actionNone
+		}
+
+		return actionOpen
+	case keyQuit:
+		return actionQuit
+	}
+
+	return actionNone
+}
+
+// setResults replaces the listed results, keeping at most maxInteractiveResults,
+// and selects the first one
+func (s *session) setResults(results []core.Result) {
+	if len(results) > maxInteractiveResults {
+		results = results[:maxInteractiveResults]
+	}
+
+	s.results = results
+	s.selected = 0
+}
+
+// selectedResult returns the selected result, if any
+func (s *session) selectedResult() (core.Result, bool) {
+	if s.selected >= len(s.results) {
+		return core.Result{}, false
+	}
+
+	return s.results[s.selected], true
+}
+
+// buildInteractiveQuery returns the query for the given input, with the other
+// options taken from the base query. It returns false if there is nothing to
+// search for.
+func buildInteractiveQuery(input string, base core.Query) (core.Query, bool) {
+	keywords := strings.Fields(input)
+	if len(keywords) == 0 {
+		return core.Query{}, false
+	}
+
+	q := base
+	q.Keywords = keywords
+
+	return q, true
+}
+
+// isTerminal returns true if both the input and the output are terminals
+func isTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// formatResultLine formats a result as a single line. If word is true, only
+// the whole-word occurrences of the keyword are marked.
+func formatResultLine(r core.Result, input string, word bool) (string, error) {
+	excerpt := r.Body
+	if idx := strings.Index(excerpt, "\n"); idx > -1 {
+		excerpt = excerpt[:idx]
+	}
+
+	// mark only the first keyword, as the keywords need not be adjacent
+	if keywords := strings.Fields(input); len(keywords) > 0 {
+		excerpt = markMatches(excerpt, keywords[0], word)
+	}
+
+	excerpt, err := formatFTSSnippet(excerpt, markFormatANSI)
+	if err != nil {
+		return "", errors.Wrap(err, "formatting the excerpt")
+	}
+
+	bookLabel := log.ColorYellow.Sprintf("(%s)", r.BookLabel)
+	rowID := log.ColorYellow.Sprintf("(%d)", r.RowID)
+	if r.Deleted {
+		rowID = fmt.Sprintf("%s %s", rowID, log.ColorRed.Sprint("[deleted]"))
+	}
+
+	return fmt.Sprintf("%s %s %s", bookLabel, rowID, excerpt), nil
+}
+
+// render draws the session on the terminal. Lines end with "\r\n" because
+// the terminal is in raw mode.
+func render(w io.Writer, s *session, word bool) error {
+	var b strings.Builder
+
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(interactivePrompt + string(s.input) + "\r\n")
+
+	input := string(s.input)
+	for idx, r := range s.results {
+		line, err := formatResultLine(r, input, word)
+		if err != nil {
+			return err
+		}
+
+		if idx == s.selected {
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(line + "\r\n")
+	}
+	if len(s.results) == 0 && len(strings.TrimSpace(input)) > 0 {
+		b.WriteString(log.ColorGray.Sprint("no matching notes") + "\r\n")
+	}
+
+	// move the cursor back to the end of the query
+	fmt.Fprintf(&b, "\x1b[1;%dH", utf8.RuneCountInString(interactivePrompt)+len(s.input)+1)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errors.Wrap(err, "writing to the terminal")
+	}
+
+	return nil
+}
+
+// runInteractive runs a search that is updated as the user types the query,
+// and prints the note the user selects
+func runInteractive(ctx context.DnoteCtx, cmd *cobra.Command, initial string, base core.Query) error {
+	fd := int(os.Stdin.Fd())
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return errors.Wrap(err, "setting the terminal to raw mode")
+	}
+	restore := func() {
+		terminal.Restore(fd, oldState)
+	}
+	defer restore()
+
+	input := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			buf := make([]byte, 64)
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			input <- buf[:n]
+		}
+	}()
+
+	s := &session{input: []rune(initial)}
+
+	search := func() error {
+		q, ok := buildInteractiveQuery(string(s.input), base)
+		if !ok {
+			s.setResults(nil)
+			return nil
+		}
+
+		results, _, err := core.Search(ctx, q)
+		if err != nil {
+			return errors.Wrap(err, "searching notes")
+		}
+
+		s.setResults(results)
+		return nil
+	}
+
+	if err := search(); err != nil {
+		return err
+	}
+	if err := render(os.Stdout, s, base.Word); err != nil {
+		return err
+	}
+
+	var pending []byte
+	var debounce, escape <-chan time.Time
+	for {
+		var keys []key
+		changed := false
+
+		select {
+		case b := <-input:
+			keys, pending = parseKeys(append(pending, b...))
+
+			escape = nil
+			if len(pending) > 0 {
+				escape = time.After(escapeTimeout)
+			}
+		case <-escape:
+			// nothing followed the escape, so the escape key itself was pressed
+			escape = nil
+			pending = nil
+			keys = []key{{Kind: keyQuit}}
+		case <-debounce:
+			debounce = nil
+
+			if err := search(); err != nil {
+				return err
+			}
+			changed = true
+		case err := <-errc:
+			return errors.Wrap(err, "reading the input")
+		}
+
+		for _, k := range keys {
+			// run the pending search so that the note listed for the query
+			// as typed is opened, not the one listed for an older query
+			if k.Kind == keyEnter && debounce != nil {
+				debounce = nil
+
+				if err := search(); err != nil {
+					return err
+				}
+				changed = true
+			}
+
+			switch s.handleKey(k) {
+			case actionSearch:
+				debounce = time.After(debounceInterval)
+				changed = true
+			case actionMove:
+				changed = true
+			case actionOpen:
+				result, ok := s.selectedResult()
+				if !ok {
+					continue
+				}
+
+				fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+				restore()
+
+				return cat.NewRun(ctx, false)(cmd, []string{strconv.Itoa(result.RowID)})
+			case actionQuit:
+				fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+				return nil
+			}
+		}
+
+		if !changed {
+			continue
+		}
+		if err := render(os.Stdout, s, base.Word); err != nil {
+			return err
+		}
+	}
+}
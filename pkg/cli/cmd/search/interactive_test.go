@@ -0,0 +1,166 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/core"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		input        string
+		expected     []key
+		expectedRest string
+	}{
+		{
+			input:    "ab",
+			expected: []key{{Kind: keyRune, Rune: 'a'}, {Kind: keyRune, Rune: 'b'}},
+		},
+		{
+			input:    "é",
+			expected: []key{{Kind: keyRune, Rune: 'é'}},
+		},
+		{
+			input:    "\x1b[A\x1b[B",
+			expected: []key{{Kind: keyUp}, {Kind: keyDown}},
+		},
+		{
+			input:    "\x7f\r",
+			expected: []key{{Kind: keyBackspace}, {Kind: keyEnter}},
+		},
+		{
+			input:    "\x1bq",
+			expected: []key{{Kind: keyQuit}, {Kind: keyRune, Rune: 'q'}},
+		},
+		{
+			input:    "\x03",
+			expected: []key{{Kind: keyQuit}},
+		},
+		{
+			input:    "a\x1b[Cb",
+			expected: []key{{Kind: keyRune, Rune: 'a'}, {Kind: keyRune, Rune: 'b'}},
+		},
+		{
+			input:        "\x1b",
+			expected:     []key{},
+			expectedRest: "\x1b",
+		},
+		{
+			input:        "a\x1b[",
+			expected:     []key{{Kind: keyRune, Rune: 'a'}},
+			expectedRest: "\x1b[",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			keys, rest := parseKeys([]byte(tc.input))
+			assert.DeepEqual(t, keys, tc.expected, "keys mismatch")
+			assert.Equal(t, string(rest), tc.expectedRest, "rest mismatch")
+		})
+	}
+
+	t.Run("split sequence", func(t *testing.T) {
+		keys, rest := parseKeys([]byte("\x1b"))
+		assert.DeepEqual(t, keys, []key{}, "keys mismatch on the first read")
+
+		keys, rest = parseKeys(append(rest, "[B"...))
+		assert.DeepEqual(t, keys, []key{{Kind: keyDown}}, "keys mismatch on the second read")
+		assert.Equal(t, len(rest), 0, "rest mismatch")
+	})
+}
+
+func TestSessionHandleKey(t *testing.T) {
+	results := []core.Result{{RowID: 1}, {RowID: 2}, {RowID: 3}}
+
+	t.Run("typing", func(t *testing.T) {
+		s := &session{}
+
+		assert.Equal(t, s.handleKey(key{Kind: keyRune, Rune: 'h'}), actionSearch, "action mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyRune, Rune: 'i'}), actionSearch, "action mismatch")
+		assert.Equal(t, string(s.input), "hi", "input mismatch")
+
+		assert.Equal(t, s.handleKey(key{Kind: keyBackspace}), actionSearch, "action mismatch")
+		assert.Equal(t, string(s.input), "h", "input mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyBackspace}), actionSearch, "action mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyBackspace}), actionNone, "backspace on an empty input should do nothing")
+	})
+
+	t.Run("selection", func(t *testing.T) {
+		s := &session{}
+		s.setResults(results)
+
+		assert.Equal(t, s.handleKey(key{Kind: keyUp}), actionNone, "moving above the first result should do nothing")
+		assert.Equal(t, s.handleKey(key{Kind: keyDown}), actionMove, "action mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyDown}), actionMove, "action mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyDown}), actionNone, "moving below the last result should do nothing")
+		assert.Equal(t, s.selected, 2, "selected mismatch")
+		assert.Equal(t, s.handleKey(key{Kind: keyUp}), actionMove, "action mismatch")
+
+		assert.Equal(t, s.handleKey(key{Kind: keyEnter}), actionOpen, "action mismatch")
+		r, ok := s.selectedResult()
+		assert.Equal(t, ok, true, "a result should be selected")
+		assert.Equal(t, r.RowID, 2, "selected result mismatch")
+
+		// new results reset the selection
+		s.setResults(results[:1])
+		assert.Equal(t, s.selected, 0, "selected mismatch")
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		s := &session{}
+
+		assert.Equal(t, s.handleKey(key{Kind: keyEnter}), actionNone, "opening without results should do nothing")
+		_, ok := s.selectedResult()
+		assert.Equal(t, ok, false, "no result should be selected")
+	})
+
+	t.Run("quit", func(t *testing.T) {
+		s := &session{}
+
+		assert.Equal(t, s.handleKey(key{Kind: keyQuit}), actionQuit, "action mismatch")
+	})
+}
+
+func TestSessionSetResultsLimit(t *testing.T) {
+	results := []core.Result{}
+	for i := 0; i < maxInteractiveResults+5; i++ {
+		results = append(results, core.Result{RowID: i})
+	}
+
+	s := &session{}
+	s.setResults(results)
+
+	assert.Equal(t, len(s.results), maxInteractiveResults, "result count mismatch")
+}
+
+func TestBuildInteractiveQuery(t *testing.T) {
+	base := core.Query{BookNames: []string{"js"}, Phrase: true}
+
+	q, ok := buildInteractiveQuery("  merge   sort ", base)
+	assert.Equal(t, ok, true, "ok mismatch")
+	assert.DeepEqual(t, q, core.Query{Keywords: []string{"merge", "sort"}, BookNames: []string{"js"}, Phrase: true}, "query mismatch")
+
+	_, ok = buildInteractiveQuery("   ", base)
+	assert.Equal(t, ok, false, "a blank input should not be searched")
+}
@@ -0,0 +1,94 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+// errNoLastSearch is an error for repeating a search when none has been made
+var errNoLastSearch = errors.New("no previous search found")
+
+// searchQuery is a search query along with its filters. It is stored so that
+// the most recent search can be repeated.
+type searchQuery struct {
+	Args             []string `json:"args"`
+	BookNames        []string `json:"book_names"`
+	ExcludeBookNames []string `json:"exclude_book_names"`
+	All              bool     `json:"all"`
+	ArchivedOnly     bool     `json:"archived_only"`
+	Sort             string   `json:"sort"`
+	Phrase           bool     `json:"phrase"`
+	Word             bool     `json:"word"`
+	Source           string   `json:"source"`
+	IncludeDeleted   bool     `json:"include_deleted"`
+}
+
+// legacySearchQuery holds the fields of the searches stored by the older
+// versions, which allowed only one book
+type legacySearchQuery struct {
+	BookName string `json:"book_name"`
+}
+
+// saveLastSearch stores the given query as the most recent search
+func saveLastSearch(ctx context.DnoteCtx, q searchQuery) error {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return errors.Wrap(err, "marshalling the query")
+	}
+
+	if err := database.UpsertSystem(ctx.DB, consts.SystemLastSearch, string(b)); err != nil {
+		return errors.Wrap(err, "saving the query")
+	}
+
+	return nil
+}
+
+// getLastSearch returns the most recent search
+func getLastSearch(ctx context.DnoteCtx) (searchQuery, error) {
+	var ret searchQuery
+
+	var val string
+	err := database.GetSystem(ctx.DB, consts.SystemLastSearch, &val)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return ret, errNoLastSearch
+	} else if err != nil {
+		return ret, errors.Wrap(err, "finding the query")
+	}
+
+	if err := json.Unmarshal([]byte(val), &ret); err != nil {
+		return ret, errors.Wrap(err, "unmarshalling the query")
+	}
+
+	var legacy legacySearchQuery
+	if err := json.Unmarshal([]byte(val), &legacy); err != nil {
+		return ret, errors.Wrap(err, "unmarshalling the legacy query")
+	}
+	if legacy.BookName != "" && len(ret.BookNames) == 0 {
+		ret.BookNames = []string{legacy.BookName}
+	}
+
+	return ret, nil
+}
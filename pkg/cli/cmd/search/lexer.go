@@ -40,6 +40,16 @@ type token struct {
 	Kind  int
 }
 
+// isSpace checks if the given byte is an ASCII whitespace character
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+
+	return false
+}
+
 // getNextIdx validates that the given index is within the range of the given string.
 // If so, it returns the given index. Otherwise it returns -1.
 func getNextIdx(candidate int, s string) int {
@@ -19,11 +19,17 @@
 package search
 
 import (
-	"database/sql"
+	"encoding/json"
 	"fmt"
+	"html"
+	"io"
+	"os"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
 	"github.com/dnote/dnote/pkg/cli/infra"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/pkg/errors"
@@ -37,17 +43,139 @@ var example = `
 	# search notes for an expression with multiple words
 	dnote search "building a heap"
 
+	# search notes interactively, narrowing the results as you type
+	dnote search -i
+
+	# search notes for words that appear next to one another
+	dnote search merge sort --phrase
+
+	# search notes for whole words only, so that 'cat' does not match 'category'
+	dnote search cat --word
+
 	# search notes within a book
 	dnote search "merge sort" -b algorithm
+
+	# search notes within several books
+	dnote search heap -b algorithms -b datastructures
+
+	# search notes in all books except some
+	dnote search TODO --exclude-book scratch --exclude-book journal
+
+	# search notes in archived books only
+	dnote search "merge sort" --archived-only
+
+	# repeat the most recent search
+	dnote search --repeat
+
+	# print the matching notes in JSON
+	dnote search "merge sort" --json
+
+	# show the most recently added matches first
+	dnote search "merge sort" --sort date
+
+	# group the matches by book
+	dnote search "merge sort" --sort book
+
+	# print each book once, with its matches indented beneath it
+	dnote search "merge sort" --group
+
+	# search only the notes added with the web application
+	dnote search "merge sort" --source web
+
+	# search the deleted notes too, to find one to restore
+	dnote search "merge sort" --include-deleted
+
+	# wrap the matches in <mark> elements, e.g. to put the results in a web page
+	dnote search "merge sort" --mark-format html
+
+	# list only the ids of the matching notes, like 'grep -l'
+	dnote search heap --ids
+
+	# list only the books that have a matching note
+	dnote search heap --books
 	`
 
-var bookName string
+var bookNames []string
+var excludeBookNames []string
 var all bool
+var archivedOnly bool
+var repeat bool
+var jsonFlag bool
+var sortFlag string
+var phraseFlag bool
+var wordFlag bool
+var sourceFlag string
+var interactiveFlag bool
+var groupFlag bool
+var includeDeletedFlag bool
+var markFormatFlag string
+var idsFlag bool
+var booksFlag bool
+var maxResultsFlag int
+
+// defaultMaxResults is the number of matches shown at most unless --max-results
+// is given, so that a broad query does not read the whole database into memory
+const defaultMaxResults = 10000
 
 func preRun(cmd *cobra.Command, args []string) error {
-	if len(args) == 0 {
+	if repeat {
+		if len(args) != 0 || len(bookNames) != 0 || len(excludeBookNames) != 0 || all || archivedOnly || sortFlag != "" || phraseFlag || wordFlag || sourceFlag != "" || interactiveFlag || includeDeletedFlag {
+			return errors.New("--repeat cannot be used with a query or other flags")
+		}
+
+		return nil
+	}
+
+	if interactiveFlag && jsonFlag {
+		return errors.New("--interactive cannot be used with --json")
+	}
+	if groupFlag && (jsonFlag || interactiveFlag) {
+		return errors.New("--group cannot be used with --json or --interactive")
+	}
+	if idsFlag && booksFlag {
+		return errors.New("--ids and --books cannot be used together")
+	}
+	if (idsFlag || booksFlag) && (jsonFlag || interactiveFlag || groupFlag) {
+		return errors.New("--ids and --books cannot be used with --json, --interactive or --group")
+	}
+	if markFormatFlag != markFormatANSI {
+		if jsonFlag || interactiveFlag {
+			return errors.New("--mark-format cannot be used with --json or --interactive")
+		}
+
+		valid := false
+		for _, f := range markFormats {
+			if markFormatFlag == f {
+				valid = true
+			}
+		}
+		if !valid {
+			return errors.Errorf("invalid mark format '%s'. Use one of: %s", markFormatFlag, strings.Join(markFormats, ", "))
+		}
+	}
+	if len(args) == 0 && !(interactiveFlag && isTerminal()) {
 		return errors.New("Incorrect number of argument")
 	}
+	if len(args) != 0 && len(keywordArgs(args)) == 0 {
+		return errors.New("the search query is empty")
+	}
+	if all && archivedOnly {
+		return errors.New("--all and --archived-only cannot be used together")
+	}
+	if maxResultsFlag < 1 {
+		return errors.New("--max-results must be a positive number")
+	}
+	if sortFlag != "" {
+		valid := false
+		for _, s := range core.SearchSorts {
+			if sortFlag == s {
+				valid = true
+			}
+		}
+		if !valid {
+			return errors.Errorf("invalid sort '%s'. Use one of: %s", sortFlag, strings.Join(core.SearchSorts, ", "))
+		}
+	}
 
 	return nil
 }
@@ -64,23 +192,83 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	}
 
 	f := cmd.Flags()
-	f.StringVarP(&bookName, "book", "b", "", "book name to find notes in")
+	f.StringArrayVarP(&bookNames, "book", "b", []string{}, "book name to find notes in. Repeat to search in several books")
+	f.StringArrayVar(&excludeBookNames, "exclude-book", []string{}, "book name to leave out of the search. Repeat to leave out several books")
 	f.BoolVarP(&all, "all", "a", false, "search all notes including the archived")
+	f.BoolVar(&archivedOnly, "archived-only", false, "search only the notes in archived books")
+	f.BoolVar(&repeat, "repeat", false, "repeat the most recent search")
+	f.BoolVar(&jsonFlag, "json", false, "print the matching notes with their full content in JSON")
+	f.BoolVarP(&phraseFlag, "phrase", "p", false, "match the words as a contiguous phrase rather than anywhere in the note")
+	f.BoolVarP(&wordFlag, "word", "w", false, "match only whole words rather than the start of longer words")
+	f.StringVarP(&sourceFlag, "source", "", "", "search only the notes created from the source, such as 'cli' or 'web'")
+	f.BoolVarP(&interactiveFlag, "interactive", "i", false, "update the results as you type and open the selected note. Falls back to a regular search if not in a terminal")
+	f.BoolVarP(&includeDeletedFlag, "include-deleted", "", false, "search the deleted notes too, marking them as deleted")
+	f.StringVar(&markFormatFlag, "mark-format", markFormatANSI, "how to mark the matches: 'ansi' colors, 'html' <mark> elements, or 'none'")
+	f.BoolVarP(&groupFlag, "group", "g", false, "print each book once with its matching notes beneath it")
+	f.BoolVarP(&idsFlag, "ids", "", false, "print only the book and the id of each matching note")
+	f.BoolVarP(&booksFlag, "books", "", false, "print only the labels of the books with a matching note")
+	f.StringVar(&sortFlag, "sort", "", "order the matching notes by relevance ('rank', the default), content ('alpha'), book label ('book'), or the most recently added first ('date')")
+	f.IntVar(&maxResultsFlag, "max-results", defaultMaxResults, "the maximum number of matches to show")
 	
 	return cmd
 }
 
 // noteInfo is an information about the note to be printed on screen
 type noteInfo struct {
-	RowID     int
-	BookLabel string
-	Body      string
-	Archive   bool
+	RowID     int    `json:"rowid"`
+	BookLabel string `json:"book_label"`
+	Body      string `json:"body"`
+	Archive   bool   `json:"archive"`
+	AddedOn   int64  `json:"added_on"`
+	Source    string `json:"source"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+// printJSON writes the given notes to the writer as a JSON array
+func printJSON(w io.Writer, infos []noteInfo) error {
+	if err := json.NewEncoder(w).Encode(infos); err != nil {
+		return errors.Wrap(err, "encoding notes")
+	}
+
+	return nil
+}
+
+const (
+	// markFormatANSI highlights the matches with the terminal color
+	markFormatANSI = "ansi"
+	// markFormatHTML wraps the matches in <mark> elements and escapes the text
+	markFormatHTML = "html"
+	// markFormatNone leaves the matches unmarked
+	markFormatNone = "none"
+)
+
+// markFormats are the supported values of --mark-format
+var markFormats = []string{markFormatANSI, markFormatHTML, markFormatNone}
+
+// renderText returns the plain text of a snippet in the given mark format
+func renderText(s, markFormat string) string {
+	if markFormat == markFormatHTML {
+		return html.EscapeString(s)
+	}
+
+	return s
 }
 
-// formatFTSSnippet turns the matched snippet from a full text search
-// into a format suitable for CLI output
-func formatFTSSnippet(s string) (string, error) {
+// renderMatch returns the highlighted text of a snippet in the given mark format
+func renderMatch(s, markFormat string) string {
+	switch markFormat {
+	case markFormatHTML:
+		return fmt.Sprintf("<mark>%s</mark>", html.EscapeString(s))
+	case markFormatNone:
+		return s
+	default:
+		return log.ColorHighlight.Sprintf("%s", s)
+	}
+}
+
+// formatFTSSnippet turns the matched snippet from a full text search into a
+// format suitable for CLI output, marking the highlights in the given format
+func formatFTSSnippet(s, markFormat string) (string, error) {
 	// first, strip all new lines
 	body := newLineReg.ReplaceAllString(s, " ")
 
@@ -89,16 +277,30 @@ func formatFTSSnippet(s string) (string, error) {
 
 	toks := tokenize(body)
 
+	// collapse runs of whitespace into a single space. The state is kept across
+	// the highlight boundaries so that the boundaries themselves stay in place.
+	var prevSpace bool
+
 	for _, tok := range toks {
+		if tok.Kind == tokenKindChar && isSpace(tok.Value) {
+			if prevSpace {
+				continue
+			}
+
+			tok.Value = ' '
+			prevSpace = true
+		} else if tok.Kind == tokenKindChar {
+			prevSpace = false
+		}
+
 		if tok.Kind == tokenKindHLBegin || tok.Kind == tokenKindEOL {
 			format.WriteString("%s")
-			args = append(args, buf.String())
+			args = append(args, renderText(buf.String(), markFormat))
 
 			buf.Reset()
 		} else if tok.Kind == tokenKindHLEnd {
 			format.WriteString("%s")
-			str := log.ColorYellow.Sprintf("%s", buf.String())
-			args = append(args, str)
+			args = append(args, renderMatch(buf.String(), markFormat))
 
 			buf.Reset()
 		} else {
@@ -133,88 +335,294 @@ func escapePhrase(s string) (string, error) {
 	return b.String(), nil
 }
 
-func doQuery(ctx context.DnoteCtx, query, bookName string, all bool) (*sql.Rows, error) {
-	db := ctx.DB
+// snippetContext is the number of bytes of text kept around each match
+const snippetContext = 60
+
+// ellipsis marks the text elided from a snippet. It is highlighted so that it
+// stands out from the note content.
+const ellipsis = "<dnotehl>...</dnotehl>"
 
-	sql := `SELECT
-		notes.rowid,
-		books.label AS book_label,
-		note_fts.body,
-		books.archive as archive
-	FROM note_fts
-	INNER JOIN notes ON notes.rowid = note_fts.rowid
-	INNER JOIN books ON notes.book_uuid = books.uuid
-	WHERE note_fts.body LIKE ?`
-	args := []interface{}{query}
+// keywordArgs returns the arguments that are not blank, with the surrounding
+// whitespace trimmed. A blank keyword would match anywhere in any note.
+func keywordArgs(args []string) []string {
+	ret := []string{}
+	for _, arg := range args {
+		if trimmed := strings.TrimSpace(arg); trimmed != "" {
+			ret = append(ret, trimmed)
+		}
+	}
+
+	return ret
+}
 
-	if bookName != "" {
-		sql = fmt.Sprintf("%s AND books.label LIKE ?", sql)
-		args = append(args, bookName)
-	} else if !all {
-		sql = fmt.Sprintf("%s AND books.archive = false", sql)
+// findMatches returns the start and end byte offsets of the non-overlapping
+// occurrences of the phrase in s, ignoring case. The offsets are in s itself,
+// as the length of a text can change when its case is changed. If word is
+// true, only the occurrences that are whole words are returned.
+func findMatches(s, phrase string, word bool) [][2]int {
+	ret := [][2]int{}
+	if strings.TrimSpace(phrase) == "" {
+		return ret
 	}
 
-	rows, err := db.Query(sql, args...)
+	for i := 0; i < len(s); {
+		if n, ok := core.MatchFoldAt(s[i:], phrase); ok && (!word || core.IsWholeWord(s, i, i+n)) {
+			ret = append(ret, [2]int{i, i + n})
+			i += n
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
 
-	return rows, err
+	return ret
 }
 
-func indexAt(s, key string, n int) int {
-	idx := strings.Index(s[n:], key)
-	if idx > -1 {
-		idx += n
+// runeStart moves the offset back to the start of the rune it falls in
+func runeStart(s string, i int) int {
+	if i <= 0 {
+		return 0
 	}
-	return idx
+	if i >= len(s) {
+		return len(s)
+	}
+
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+
+	return i
+}
+
+// markMatches wraps the occurrences of the phrase in the body with highlight
+// boundaries understood by formatFTSSnippet, eliding the text far from them.
+// The elided text always lies between the context windows of the matches, so
+// an ellipsis never cuts through a match. If word is true, only the whole-word
+// occurrences are marked.
+func markMatches(body, phrase string, word bool) string {
+	matches := findMatches(body, phrase, word)
+	if len(matches) == 0 {
+		return body
+	}
+
+	var b strings.Builder
+
+	// pos is the offset up to which the body has been written, and ctxEnd is
+	// the end of the context window of the previous match
+	pos, ctxEnd := 0, 0
+	for _, m := range matches {
+		ctxStart := runeStart(body, m[0]-snippetContext)
+		if ctxStart > ctxEnd {
+			b.WriteString(body[pos:ctxEnd])
+			b.WriteString(ellipsis)
+			pos = ctxStart
+		}
+
+		b.WriteString(body[pos:m[0]])
+		b.WriteString("<dnotehl>" + body[m[0]:m[1]] + "</dnotehl>")
+		pos = m[1]
+
+		ctxEnd = runeStart(body, m[1]+snippetContext)
+	}
+
+	if ctxEnd < len(body) {
+		b.WriteString(body[pos:ctxEnd])
+		b.WriteString(ellipsis)
+	} else {
+		b.WriteString(body[pos:])
+	}
+
+	return b.String()
+}
+
+// formatBookLabel returns the label of the book of the note, dimmed if the
+// book is archived
+func formatBookLabel(info noteInfo) string {
+	if info.Archive {
+		return log.ColorGray.Sprintf("(%s)", info.BookLabel)
+	}
+
+	return log.ColorYellow.Sprintf("(%s)", info.BookLabel)
+}
+
+// formatResult returns the date, the id and the snippet of the note. The id
+// of a deleted note is followed by a marker.
+func formatResult(info noteInfo) string {
+	addedOn := log.ColorGray.Sprint(time.Unix(0, info.AddedOn).Format("Jan 2, 2006"))
+	rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
+	if info.Deleted {
+		rowid = fmt.Sprintf("%s %s", rowid, log.ColorRed.Sprint("[deleted]"))
+	}
+
+	return fmt.Sprintf("%s %s %s", addedOn, rowid, info.Body)
+}
+
+// groupByBook groups the notes by their books. The books are in the order of
+// their first note, and the notes in a book keep their order.
+func groupByBook(infos []noteInfo) [][]noteInfo {
+	ret := [][]noteInfo{}
+	idx := map[string]int{}
+
+	for _, info := range infos {
+		i, ok := idx[info.BookLabel]
+		if !ok {
+			i = len(ret)
+			idx[info.BookLabel] = i
+			ret = append(ret, []noteInfo{})
+		}
+
+		ret[i] = append(ret[i], info)
+	}
+
+	return ret
+}
+
+// printGroups prints the label of each book once, followed by its notes
+func printGroups(groups [][]noteInfo) {
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		log.Plainf("%s\n", formatBookLabel(group[0]))
+		for _, info := range group {
+			log.Plainf("  %s\n", formatResult(info))
+		}
+	}
+}
+
+// printIDs prints the book label and the id of each note, one note per line
+func printIDs(w io.Writer, results []core.Result) error {
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "(%s) %d\n", r.BookLabel, r.RowID); err != nil {
+			return errors.Wrap(err, "writing a note")
+		}
+	}
+
+	return nil
+}
+
+// printBooks prints the label of each book of the notes once, in the order
+// of their first note
+func printBooks(w io.Writer, results []core.Result) error {
+	seen := map[string]bool{}
+
+	for _, r := range results {
+		if seen[r.BookLabel] {
+			continue
+		}
+		seen[r.BookLabel] = true
+
+		if _, err := fmt.Fprintln(w, r.BookLabel); err != nil {
+			return errors.Wrap(err, "writing a book")
+		}
+	}
+
+	return nil
 }
 
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		phrase := "%" + strings.Join(args[:], "%") + "%"
+		if interactiveFlag && isTerminal() {
+			base := core.Query{
+				BookNames:        bookNames,
+				ExcludeBookNames: excludeBookNames,
+				All:              all,
+				ArchivedOnly:     archivedOnly,
+				Sort:             sortFlag,
+				Phrase:           phraseFlag,
+				Word:             wordFlag,
+				Source:           sourceFlag,
+				IncludeDeleted:   includeDeletedFlag,
+				Limit:            maxResultsFlag,
+			}
+
+			return runInteractive(ctx, cmd, strings.Join(args, " "), base)
+		}
+
+		var q searchQuery
+		if repeat {
+			var err error
+			q, err = getLastSearch(ctx)
+			if err != nil {
+				return errors.Wrap(err, "getting the last search")
+			}
+		} else {
+			q = searchQuery{
+				Args:             keywordArgs(args),
+				BookNames:        bookNames,
+				ExcludeBookNames: excludeBookNames,
+				All:              all,
+				ArchivedOnly:     archivedOnly,
+				Sort:             sortFlag,
+				Phrase:           phraseFlag,
+				Word:             wordFlag,
+				Source:           sourceFlag,
+				IncludeDeleted:   includeDeletedFlag,
+			}
 
-		rows, err := doQuery(ctx, phrase, bookName, all)
+			if err := saveLastSearch(ctx, q); err != nil {
+				return errors.Wrap(err, "saving the search")
+			}
+		}
+
+		args = q.Args
+
+		results, truncated, err := core.Search(ctx, core.Query{
+			Keywords:         args,
+			BookNames:        q.BookNames,
+			ExcludeBookNames: q.ExcludeBookNames,
+			All:              q.All,
+			ArchivedOnly:     q.ArchivedOnly,
+			Sort:             q.Sort,
+			Phrase:           q.Phrase,
+			Word:             q.Word,
+			Source:           q.Source,
+			IncludeDeleted:   q.IncludeDeleted,
+			Limit:            maxResultsFlag,
+		})
 		if err != nil {
-			return errors.Wrap(err, "querying notes")
+			return errors.Wrap(err, "searching notes")
+		}
+
+		// the warning goes to stderr to keep the output intact for scripts
+		if truncated {
+			log.Fwarnf(os.Stderr, "showing the first %d matches. Narrow down the query or raise --max-results to see more\n", maxResultsFlag)
+		}
+
+		if idsFlag {
+			return printIDs(os.Stdout, results)
+		}
+		if booksFlag {
+			return printBooks(os.Stdout, results)
 		}
-		defer rows.Close()
 
 		infos := []noteInfo{}
-		for rows.Next() {
-			var info noteInfo
-
-			var body string
-			err = rows.Scan(&info.RowID, &info.BookLabel, &body, &info.Archive)
-			
-			c := 60
-			var phrase_lwr = strings.ToLower(args[0])
-			var s = 0
-			var e = 0
-			var idx = strings.Index(strings.ToLower(body), phrase_lwr)
-			for idx > -1 {
-				s = idx - c
-				if s > e {
-					body = body[:e] + "<dnotehl>...</dnotehl>" + body[s:idx] + 
-						"<dnotehl>" + body[idx:idx+len(phrase_lwr)] + "</dnotehl>" +
-						body[idx+len(phrase_lwr):]
-					idx = idx - (s-e) + 22 + 9
-					
-				} else {
-					body = body[:idx] + 
-						"<dnotehl>" + body[idx:idx+len(phrase_lwr)] + "</dnotehl>" + 
-						body[idx+len(phrase_lwr):]
-					idx = idx + 9
-				}
-				e = idx + len(phrase_lwr) + 10 + c
-				idx = indexAt(strings.ToLower(body), phrase_lwr, idx+len(phrase_lwr)+10)
+		for _, r := range results {
+			info := noteInfo{
+				RowID:     r.RowID,
+				BookLabel: r.BookLabel,
+				Archive:   r.Archive,
+				AddedOn:   r.AddedOn,
+				Source:    r.Source,
+				Deleted:   r.Deleted,
 			}
-			if (e != 0) && (e < len(body)) {
-				body = body[:e] + "<dnotehl>...</dnotehl>"
+			body := r.Body
+
+			if jsonFlag {
+				info.Body = body
+				infos = append(infos, info)
+				continue
 			}
-			
-			if err != nil {
-				return errors.Wrap(err, "scanning a row")
+
+			phrase := args[0]
+			if q.Phrase {
+				phrase = strings.Join(args, " ")
 			}
+			body = markMatches(body, phrase, q.Word)
 
-			body, err := formatFTSSnippet(body)
+			body, err := formatFTSSnippet(body, markFormatFlag)
 			if err != nil {
 				return errors.Wrap(err, "formatting a body")
 			}
@@ -224,17 +632,17 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 			infos = append(infos, info)
 		}
 
-		for _, info := range infos {
-			var bookLabel string
-			if info.Archive {
-				bookLabel = log.ColorGray.Sprintf("(%s)", info.BookLabel)
-			} else {
-				bookLabel = log.ColorYellow.Sprintf("(%s)", info.BookLabel)
-			}
-			
-			rowid := log.ColorYellow.Sprintf("(%d)", info.RowID)
+		if jsonFlag {
+			return printJSON(os.Stdout, infos)
+		}
+
+		if groupFlag {
+			printGroups(groupByBook(infos))
+			return nil
+		}
 
-			log.Plainf("%s %s %s\n", bookLabel, rowid, info.Body)
+		for _, info := range infos {
+			log.Plainf("%s %s\n", formatBookLabel(info), formatResult(info))
 		}
 		
 		return nil
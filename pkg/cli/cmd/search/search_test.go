@@ -0,0 +1,450 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dnote/color"
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func TestRowsErrPropagation(t *testing.T) {
+	db := database.OpenFaultyTestDB(t, []database.FaultyQuery{
+		{
+			Match:   "FROM note_fts",
+			Columns: []string{"note_id", "book_label", "body", "archive", "added_on", "edited_on", "source", "deleted", "score"},
+			Rows:    [][]driver.Value{{int64(1), "js", "foo bar", false, int64(1515199951), int64(0), "cli", false, float64(-1)}},
+			Fail:    true,
+		},
+		{
+			Match:   "system",
+			Columns: []string{"count"},
+			Rows:    [][]driver.Value{{int64(0)}},
+		},
+	})
+	defer db.Close()
+
+	run := newRun(context.DnoteCtx{DB: db})
+	err := run(&cobra.Command{}, []string{"foo"})
+	assert.Equal(t, errors.Cause(err), database.ErrFaultyDriver, "error mismatch")
+}
+
+func TestFormatFTSSnippet(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "foo bar",
+			expected: "foo bar",
+		},
+		{
+			input:    "foo\t\tbar\t<dnotehl>baz</dnotehl>",
+			expected: "foo bar baz",
+		},
+		{
+			input:    "foo    bar  \n  <dnotehl>baz</dnotehl>   quz",
+			expected: "foo bar baz quz",
+		},
+		{
+			input:    "foo <dnotehl>  bar \t baz</dnotehl> \t quz",
+			expected: "foo bar baz quz",
+		},
+		{
+			input:    "\t\tfoo\r\n\r\nbar",
+			expected: " foo bar",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			got, err := formatFTSSnippet(tc.input, markFormatANSI)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatFTSSnippetHighlight(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	testCases := []struct {
+		markFormat string
+		expected   string
+	}{
+		{
+			markFormat: markFormatANSI,
+			expected:   fmt.Sprintf("foo %s quz", log.ColorYellow.Sprintf("bar baz")),
+		},
+		{
+			markFormat: markFormatHTML,
+			expected:   "foo <mark>bar baz</mark> quz",
+		},
+		{
+			markFormat: markFormatNone,
+			expected:   "foo bar baz quz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.markFormat, func(t *testing.T) {
+			got, err := formatFTSSnippet("foo  \t<dnotehl>bar\t\tbaz</dnotehl>\t  quz", tc.markFormat)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestFormatFTSSnippetHTMLEscape(t *testing.T) {
+	got, err := formatFTSSnippet("a <b> & <dnotehl><c></dnotehl>", markFormatHTML)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	assert.Equal(t, got, "a &lt;b&gt; &amp; <mark>&lt;c&gt;</mark>", "result mismatch")
+}
+
+func TestLastSearch(t *testing.T) {
+	t.Run("no previous search", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		_, err := getLastSearch(ctx)
+		assert.Equal(t, err, errNoLastSearch, "error mismatch")
+	})
+
+	t.Run("save and get", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		q1 := searchQuery{
+			Args:      []string{"foo", "bar"},
+			BookNames: []string{"js"},
+			All:       true,
+		}
+		if err := saveLastSearch(ctx, q1); err != nil {
+			t.Fatal(errors.Wrap(err, "saving q1"))
+		}
+		q2 := searchQuery{
+			Args:         []string{"baz"},
+			ArchivedOnly: true,
+		}
+		if err := saveLastSearch(ctx, q2); err != nil {
+			t.Fatal(errors.Wrap(err, "saving q2"))
+		}
+
+		got, err := getLastSearch(ctx)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, q2, "query mismatch")
+	})
+
+	t.Run("legacy book name", func(t *testing.T) {
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		database.MustExec(t, "inserting the last search", ctx.DB, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastSearch, `{"args":["foo"],"book_name":"js"}`)
+
+		got, err := getLastSearch(ctx)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.DeepEqual(t, got, searchQuery{Args: []string{"foo"}, BookNames: []string{"js"}}, "query mismatch")
+	})
+}
+
+func TestPrintJSON(t *testing.T) {
+	infos := []noteInfo{
+		{
+			RowID:     1,
+			BookLabel: "js",
+			Body:      "foo <bar>\n\tbaz",
+			Archive:   false,
+		},
+		{
+			RowID:     3,
+			BookLabel: "css",
+			Body:      "quz",
+			Archive:   true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := printJSON(&buf, infos); err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	var got []noteInfo
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(errors.Wrap(err, "unmarshalling"))
+	}
+
+	assert.DeepEqual(t, got, infos, "notes mismatch")
+
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(errors.Wrap(err, "unmarshalling into map"))
+	}
+
+	assert.DeepEqual(t, raw[0], map[string]interface{}{
+		"rowid":      float64(1),
+		"book_label": "js",
+		"body":       "foo <bar>\n\tbaz",
+		"archive":    false,
+		"added_on":   float64(0),
+		"source":     "",
+	}, "keys mismatch")
+}
+
+func TestMarkMatches(t *testing.T) {
+	pad := func(s string, n int) string {
+		return strings.Repeat(s, n)
+	}
+
+	testCases := []struct {
+		name     string
+		body     string
+		phrase   string
+		word     bool
+		expected string
+	}{
+		{
+			name:     "no match",
+			body:     "foo bar",
+			phrase:   "baz",
+			expected: "foo bar",
+		},
+		{
+			name:     "empty phrase",
+			body:     "foo bar",
+			phrase:   "",
+			expected: "foo bar",
+		},
+		{
+			name:     "whitespace only phrase",
+			body:     "foo bar",
+			phrase:   "  ",
+			expected: "foo bar",
+		},
+		{
+			name:     "case insensitive",
+			body:     "Merge sort",
+			phrase:   "merge",
+			expected: "<dnotehl>Merge</dnotehl> sort",
+		},
+		{
+			name:     "elided before and after",
+			body:     pad("a", 61) + "merge" + pad("b", 61),
+			phrase:   "merge",
+			expected: "<dnotehl>...</dnotehl>" + pad("a", 60) + "<dnotehl>merge</dnotehl>" + pad("b", 60) + "<dnotehl>...</dnotehl>",
+		},
+		{
+			// the context windows of the two matches meet exactly
+			name:     "match at the edge of the context window",
+			body:     "merge" + pad("a", 120) + "merge",
+			phrase:   "merge",
+			expected: "<dnotehl>merge</dnotehl>" + pad("a", 120) + "<dnotehl>merge</dnotehl>",
+		},
+		{
+			name:     "match right past the edge of the context window",
+			body:     "merge" + pad("a", 121) + "merge",
+			phrase:   "merge",
+			expected: "<dnotehl>merge</dnotehl>" + pad("a", 60) + "<dnotehl>...</dnotehl>" + pad("a", 60) + "<dnotehl>merge</dnotehl>",
+		},
+		{
+			// the match straddles the end of the context window of the first match
+			name:     "match across the edge of the context window",
+			body:     "merge" + pad("a", 58) + "merge",
+			phrase:   "merge",
+			expected: "<dnotehl>merge</dnotehl>" + pad("a", 58) + "<dnotehl>merge</dnotehl>",
+		},
+		{
+			// lowercasing 'İ' makes it longer, which used to shift the match
+			name:     "text that changes length with case",
+			body:     "İİ merge",
+			phrase:   "merge",
+			expected: "İİ <dnotehl>merge</dnotehl>",
+		},
+		{
+			name:     "multibyte characters at the edge of the context window",
+			body:     "a" + pad("é", 40) + "merge",
+			phrase:   "merge",
+			expected: "<dnotehl>...</dnotehl>" + pad("é", 30) + "<dnotehl>merge</dnotehl>",
+		},
+		{
+			name:     "part of a word",
+			body:     "a cat in a category",
+			phrase:   "cat",
+			expected: "a <dnotehl>cat</dnotehl> in a <dnotehl>cat</dnotehl>egory",
+		},
+		{
+			name:     "whole word",
+			body:     "a cat in a category",
+			phrase:   "cat",
+			word:     true,
+			expected: "a <dnotehl>cat</dnotehl> in a category",
+		},
+		{
+			name:     "whole word at the edges",
+			body:     "Cat, concatenate cat",
+			phrase:   "cat",
+			word:     true,
+			expected: "<dnotehl>Cat</dnotehl>, concatenate <dnotehl>cat</dnotehl>",
+		},
+		{
+			name:     "no whole word",
+			body:     "concatenate",
+			phrase:   "cat",
+			word:     true,
+			expected: "concatenate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, markMatches(tc.body, tc.phrase, tc.word), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestKeywordArgs(t *testing.T) {
+	testCases := []struct {
+		input    []string
+		expected []string
+	}{
+		{input: []string{"foo"}, expected: []string{"foo"}},
+		{input: []string{" foo ", "bar"}, expected: []string{"foo", "bar"}},
+		{input: []string{"", "foo", " \t"}, expected: []string{"foo"}},
+		{input: []string{"", "  "}, expected: []string{}},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, keywordArgs(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGroupByBook(t *testing.T) {
+	n1 := noteInfo{RowID: 1, BookLabel: "linux"}
+	n2 := noteInfo{RowID: 2, BookLabel: "git"}
+	n3 := noteInfo{RowID: 3, BookLabel: "linux"}
+	n4 := noteInfo{RowID: 4, BookLabel: "git"}
+	n5 := noteInfo{RowID: 5, BookLabel: "js"}
+
+	testCases := []struct {
+		input    []noteInfo
+		expected [][]noteInfo
+	}{
+		{
+			input:    []noteInfo{},
+			expected: [][]noteInfo{},
+		},
+		{
+			input:    []noteInfo{n1},
+			expected: [][]noteInfo{{n1}},
+		},
+		{
+			input:    []noteInfo{n1, n2, n3, n4, n5},
+			expected: [][]noteInfo{{n1, n3}, {n2, n4}, {n5}},
+		},
+		{
+			input:    []noteInfo{n4, n3, n2, n1},
+			expected: [][]noteInfo{{n4, n2}, {n3, n1}},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.DeepEqual(t, groupByBook(tc.input), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestPrintIDsAndBooks(t *testing.T) {
+	results := []core.Result{
+		{RowID: 3, BookLabel: "linux"},
+		{RowID: 2, BookLabel: "git"},
+		{RowID: 1, BookLabel: "linux"},
+	}
+
+	t.Run("ids", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := printIDs(&buf, results); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, buf.String(), "(linux) 3\n(git) 2\n(linux) 1\n", "output mismatch")
+	})
+
+	t.Run("books", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := printBooks(&buf, results); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, buf.String(), "linux\ngit\n", "output mismatch")
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := printBooks(&buf, []core.Result{}); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		assert.Equal(t, buf.String(), "", "output mismatch")
+	})
+}
@@ -0,0 +1,272 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var jsonFlag bool
+
+var example = `
+ * Show statistics for all books
+ dnote stats
+
+ * Show statistics for a book
+ dnote stats javascript
+
+ * Show statistics in JSON
+ dnote stats --json`
+
+func preRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("Incorrect number of argument")
+	}
+
+	return nil
+}
+
+// NewCmd returns a new stats command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "stats [book]",
+		Short:   "Show statistics about books and notes",
+		Example: example,
+		PreRunE: preRun,
+		RunE:    newRun(ctx),
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&jsonFlag, "json", "", false, "print the statistics as JSON")
+
+	return cmd
+}
+
+// bookStats is the aggregate metrics of the notes in a book
+type bookStats struct {
+	BookLabel    string  `json:"book_label"`
+	Archive      bool    `json:"archive"`
+	NoteCount    int     `json:"note_count"`
+	WordCount    int     `json:"word_count"`
+	AverageWords float64 `json:"average_words"`
+	OldestNote   int64   `json:"oldest_note,omitempty"`
+	NewestNote   int64   `json:"newest_note,omitempty"`
+}
+
+// summary is the aggregate metrics across books
+type summary struct {
+	Books             []bookStats `json:"books"`
+	NoteCount         int         `json:"note_count"`
+	WordCount         int         `json:"word_count"`
+	AverageWords      float64     `json:"average_words"`
+	OldestNote        int64       `json:"oldest_note,omitempty"`
+	NewestNote        int64       `json:"newest_note,omitempty"`
+	ActiveBookCount   int         `json:"active_book_count"`
+	ArchivedBookCount int         `json:"archived_book_count"`
+	ActiveNoteCount   int         `json:"active_note_count"`
+	ArchivedNoteCount int         `json:"archived_note_count"`
+}
+
+// countWords returns the number of whitespace separated words in the given string
+func countWords(s string) int {
+	return len(strings.Fields(s))
+}
+
+func average(total, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+
+	return float64(total) / float64(count)
+}
+
+// addNote includes a note with the given body and added_on in the metrics
+func (s *bookStats) addNote(body string, addedOn int64) {
+	s.NoteCount++
+	s.WordCount += countWords(body)
+
+	if s.OldestNote == 0 || addedOn < s.OldestNote {
+		s.OldestNote = addedOn
+	}
+	if addedOn > s.NewestNote {
+		s.NewestNote = addedOn
+	}
+}
+
+// getStats computes the metrics of the books that are not deleted. If a book
+// name is given, only that book is considered.
+func getStats(db *database.DB, bookName string) (summary, error) {
+	ret := summary{Books: []bookStats{}}
+
+	query := `SELECT books.uuid, books.label, books.archive, notes.body, notes.added_on
+	FROM books
+	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
+	WHERE books.deleted = false`
+	queryArgs := []interface{}{}
+
+	if bookName != "" {
+		if _, err := database.GetBookUUID(db, bookName); err != nil {
+			return ret, err
+		}
+
+		query += " AND books.label = ?"
+		queryArgs = append(queryArgs, bookName)
+	}
+	// order by uuid as well so that the notes of a book are in consecutive rows
+	// even if another book has the same label
+	query += " ORDER BY books.label ASC, books.uuid ASC"
+
+	rows, err := db.Query(query, queryArgs...)
+	if err != nil {
+		return ret, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	var lastUUID string
+	for rows.Next() {
+		var uuid, label string
+		var archive bool
+		var body sql.NullString
+		var addedOn sql.NullInt64
+
+		if err := rows.Scan(&uuid, &label, &archive, &body, &addedOn); err != nil {
+			return ret, errors.Wrap(err, "scanning a row")
+		}
+
+		n := len(ret.Books)
+		if n == 0 || uuid != lastUUID {
+			ret.Books = append(ret.Books, bookStats{BookLabel: label, Archive: archive})
+			lastUUID = uuid
+			n++
+		}
+
+		// books without notes have a single row with null note columns
+		if body.Valid {
+			ret.Books[n-1].addNote(body.String, addedOn.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ret, errors.Wrap(err, "iterating notes")
+	}
+
+	for i := range ret.Books {
+		b := &ret.Books[i]
+		b.AverageWords = average(b.WordCount, b.NoteCount)
+
+		ret.NoteCount += b.NoteCount
+		ret.WordCount += b.WordCount
+
+		if b.NoteCount > 0 {
+			if ret.OldestNote == 0 || b.OldestNote < ret.OldestNote {
+				ret.OldestNote = b.OldestNote
+			}
+			if b.NewestNote > ret.NewestNote {
+				ret.NewestNote = b.NewestNote
+			}
+		}
+
+		if b.Archive {
+			ret.ArchivedBookCount++
+			ret.ArchivedNoteCount += b.NoteCount
+		} else {
+			ret.ActiveBookCount++
+			ret.ActiveNoteCount += b.NoteCount
+		}
+	}
+	ret.AverageWords = average(ret.WordCount, ret.NoteCount)
+
+	return ret, nil
+}
+
+func formatTime(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+
+	return time.Unix(0, ts).Format("Jan 2, 2006 3:04pm (MST)")
+}
+
+func printJSON(w io.Writer, s summary) error {
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return errors.Wrap(err, "encoding statistics")
+	}
+
+	return nil
+}
+
+func printBookStats(s bookStats) {
+	label := log.ColorYellow.Sprint(s.BookLabel)
+	if s.Archive {
+		label = fmt.Sprintf("%s %s", log.ColorGray.Sprint(s.BookLabel), log.ColorGray.Sprint("(archived)"))
+	}
+
+	log.Printf("%s\n", label)
+	log.Plainf("  notes: %d\n", s.NoteCount)
+	log.Plainf("  words: %d (%.1f per note)\n", s.WordCount, s.AverageWords)
+	log.Plainf("  oldest: %s\n", formatTime(s.OldestNote))
+	log.Plainf("  newest: %s\n", formatTime(s.NewestNote))
+}
+
+func printSummary(s summary) {
+	for _, b := range s.Books {
+		printBookStats(b)
+	}
+
+	log.Printf("%s\n", log.ColorYellow.Sprint("total"))
+	log.Plainf("  books: %d active, %d archived\n", s.ActiveBookCount, s.ArchivedBookCount)
+	log.Plainf("  notes: %d active, %d archived\n", s.ActiveNoteCount, s.ArchivedNoteCount)
+	log.Plainf("  words: %d (%.1f per note)\n", s.WordCount, s.AverageWords)
+	log.Plainf("  oldest: %s\n", formatTime(s.OldestNote))
+	log.Plainf("  newest: %s\n", formatTime(s.NewestNote))
+}
+
+func newRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		var bookName string
+		if len(args) == 1 {
+			bookName = args[0]
+		}
+
+		s, err := getStats(ctx.DB, bookName)
+		if err != nil {
+			return errors.Wrap(err, "getting statistics")
+		}
+
+		if jsonFlag {
+			return printJSON(os.Stdout, s)
+		}
+
+		printSummary(s)
+
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func setupStats(t *testing.T, db *database.DB) {
+	database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+	database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "linux-book-uuid", "linux", true)
+	database.MustExec(t, "setting up book 3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "css-book-uuid", "css")
+	database.MustExec(t, "setting up book 4", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "go-book-uuid", "go", true)
+
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "one two three", 1515199943)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js-book-uuid", "four\nfive  six seven", 1515199951)
+	database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "js-book-uuid", "deleted note", 1515199900, true)
+	database.MustExec(t, "setting up note 4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "linux-book-uuid", "ls -la", 1515199961)
+	database.MustExec(t, "setting up note 5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "go-book-uuid", "deleted book note", 1515199800)
+}
+
+func TestGetStats(t *testing.T) {
+	t.Run("all books", func(t *testing.T) {
+		// Setup
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		setupStats(t, ctx.DB)
+
+		// Execute
+		got, err := getStats(ctx.DB, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		assert.DeepEqual(t, got, summary{
+			Books: []bookStats{
+				{
+					BookLabel: "css",
+				},
+				{
+					BookLabel:    "js",
+					NoteCount:    2,
+					WordCount:    7,
+					AverageWords: 3.5,
+					OldestNote:   1515199943,
+					NewestNote:   1515199951,
+				},
+				{
+					BookLabel:    "linux",
+					Archive:      true,
+					NoteCount:    1,
+					WordCount:    2,
+					AverageWords: 2,
+					OldestNote:   1515199961,
+					NewestNote:   1515199961,
+				},
+			},
+			NoteCount:         3,
+			WordCount:         9,
+			AverageWords:      3,
+			OldestNote:        1515199943,
+			NewestNote:        1515199961,
+			ActiveBookCount:   2,
+			ArchivedBookCount: 1,
+			ActiveNoteCount:   2,
+			ArchivedNoteCount: 1,
+		}, "stats mismatch")
+	})
+
+	t.Run("single book", func(t *testing.T) {
+		// Setup
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		setupStats(t, ctx.DB)
+
+		// Execute
+		got, err := getStats(ctx.DB, "linux")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		assert.Equal(t, len(got.Books), 1, "book count mismatch")
+		assert.Equal(t, got.Books[0].BookLabel, "linux", "book label mismatch")
+		assert.Equal(t, got.NoteCount, 1, "note count mismatch")
+		assert.Equal(t, got.WordCount, 2, "word count mismatch")
+		assert.Equal(t, got.ActiveBookCount, 0, "active book count mismatch")
+		assert.Equal(t, got.ArchivedBookCount, 1, "archived book count mismatch")
+	})
+
+	t.Run("books with the same label", func(t *testing.T) {
+		// Setup
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		// the label is unique only in the schema for tests, not in the databases
+		// migrated from older versions
+		database.MustExec(t, "dropping the label index", ctx.DB, "DROP INDEX idx_books_label")
+		database.MustExec(t, "setting up book 1", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+		database.MustExec(t, "setting up book 2", ctx.DB, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b2-uuid", "js", true)
+		database.MustExec(t, "setting up note 1", ctx.DB, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "one", 1515199943)
+		database.MustExec(t, "setting up note 2", ctx.DB, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "two words", 1515199951)
+
+		// Execute
+		got, err := getStats(ctx.DB, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		assert.Equal(t, len(got.Books), 2, "book count mismatch")
+		assert.Equal(t, got.Books[0].WordCount, 1, "word count mismatch for book 1")
+		assert.Equal(t, got.Books[1].WordCount, 2, "word count mismatch for book 2")
+		assert.Equal(t, got.ActiveBookCount, 1, "active book count mismatch")
+		assert.Equal(t, got.ArchivedBookCount, 1, "archived book count mismatch")
+	})
+
+	t.Run("nonexistent book", func(t *testing.T) {
+		// Setup
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../../tmp",
+			Cache: "../../tmp",
+		}, nil)
+		defer context.TeardownTestCtx(t, ctx)
+
+		// Execute
+		_, err := getStats(ctx.DB, "foo")
+
+		// Test
+		assert.Equal(t, err.Error(), "book 'foo' not found", "error mismatch")
+	})
+}
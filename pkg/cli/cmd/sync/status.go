@@ -0,0 +1,107 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/pkg/errors"
+)
+
+// changeCounts is the number of resources changed locally since the last sync
+type changeCounts struct {
+	Added   int
+	Edited  int
+	Deleted int
+}
+
+// syncStatus is the summary of the local changes that are not yet synced
+type syncStatus struct {
+	Notes      changeCounts
+	Books      changeCounts
+	LastSyncAt int
+}
+
+// countChanges counts the dirty rows in the given table. A row that has never
+// been synced has a usn of 0, which tells an addition apart from an edit. A row
+// that was added and deleted before being synced is not counted because the
+// server has never seen it.
+func countChanges(db *database.DB, table string) (changeCounts, error) {
+	var ret changeCounts
+
+	query := fmt.Sprintf(`SELECT
+		COALESCE(SUM(CASE WHEN NOT deleted AND usn = 0 THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN NOT deleted AND usn > 0 THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN deleted AND usn > 0 THEN 1 ELSE 0 END), 0)
+	FROM %s WHERE dirty`, table)
+	if err := db.QueryRow(query).Scan(&ret.Added, &ret.Edited, &ret.Deleted); err != nil {
+		return ret, errors.Wrapf(err, "counting changes in %s", table)
+	}
+
+	return ret, nil
+}
+
+func getSyncStatus(db *database.DB) (syncStatus, error) {
+	var ret syncStatus
+
+	notes, err := countChanges(db, "notes")
+	if err != nil {
+		return ret, err
+	}
+	books, err := countChanges(db, "books")
+	if err != nil {
+		return ret, err
+	}
+	lastSyncAt, err := getLastSyncAt(db)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Notes = notes
+	ret.Books = books
+	ret.LastSyncAt = lastSyncAt
+
+	return ret, nil
+}
+
+func formatChangeCounts(c changeCounts) string {
+	return fmt.Sprintf("%d added, %d edited, %d deleted", c.Added, c.Edited, c.Deleted)
+}
+
+func printStatus(ctx context.DnoteCtx) error {
+	s, err := getSyncStatus(ctx.DB)
+	if err != nil {
+		return errors.Wrap(err, "getting the sync status")
+	}
+
+	lastSync := "never"
+	if s.LastSyncAt > 0 {
+		lastSync = time.Unix(int64(s.LastSyncAt), 0).Format("Jan 2, 2006 15:04")
+	}
+
+	log.Plainf("last synced: %s\n", lastSync)
+	log.Plainf("notes: %s\n", formatChangeCounts(s.Notes))
+	log.Plainf("books: %s\n", formatChangeCounts(s.Books))
+
+	return nil
+}
@@ -0,0 +1,77 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestGetSyncStatus(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, "../../tmp/.dnote", nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "setting up last_sync_at", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastSyncAt, 1541108743)
+
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1", 0, true, false)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?)", "b2-uuid", "b2", 5, true, false)
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?)", "b3-uuid", "b3", 6, false, false)
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?)", "b4-uuid", "b4", 7, true, true)
+
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1", 1541108743, 0, true, false)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2", 1541108743, 0, true, false)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3", 1541108743, 8, true, false)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n4-uuid", "b2-uuid", "n4", 1541108743, 9, false, false)
+	database.MustExec(t, "inserting n5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n5-uuid", "b2-uuid", "", 1541108743, 10, true, true)
+	database.MustExec(t, "inserting n6", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?, ?, ?)", "n6-uuid", "b2-uuid", "", 1541108743, 0, true, true)
+
+	// exec
+	got, err := getSyncStatus(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the sync status"))
+	}
+
+	// test
+	assert.DeepEqual(t, got.Notes, changeCounts{Added: 2, Edited: 1, Deleted: 1}, "notes mismatch")
+	assert.DeepEqual(t, got.Books, changeCounts{Added: 1, Edited: 1, Deleted: 1}, "books mismatch")
+	assert.Equal(t, got.LastSyncAt, 1541108743, "last_sync_at mismatch")
+}
+
+func TestGetSyncStatusClean(t *testing.T) {
+	// set up
+	db := database.InitTestDB(t, "../../tmp/.dnote", nil)
+	defer database.TeardownTestDB(t, db)
+
+	database.MustExec(t, "setting up last_sync_at", db, "INSERT INTO system (key, value) VALUES (?, ?)", consts.SystemLastSyncAt, 0)
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, dirty, deleted) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1", 3, false, false)
+
+	// exec
+	got, err := getSyncStatus(db)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the sync status"))
+	}
+
+	// test
+	assert.DeepEqual(t, got, syncStatus{}, "status mismatch")
+}
@@ -45,9 +45,13 @@ const (
 
 var example = `
   dnote sync
-  * sync is performed by executing the user defined script ~/.local/share/dnote/sync.py`
+  * sync is performed by executing the user defined script ~/.local/share/dnote/sync.py
+
+  * show the local changes that are not yet synced
+  dnote sync --status`
 
 var isFullSync bool
+var statusFlag bool
 
 // NewCmd returns a new sync command
 func NewCmd(ctx context.DnoteCtx) *cobra.Command {
@@ -58,11 +62,18 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 		RunE:    runPy(ctx),
 	}
 
+	f := cmd.Flags()
+	f.BoolVarP(&statusFlag, "status", "", false, "show the counts of the local changes since the last sync without syncing")
+
 	return cmd
 }
 
 func runPy(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
+		if statusFlag {
+			return printStatus(ctx)
+		}
+
 		syncPy := filepath.Join(ctx.Paths.Data, "dnote/sync.py")
 		log.Info("executing "+ syncPy + "\n")
 		if _, err := os.Stat(syncPy); err == nil {
@@ -330,6 +341,7 @@ func stepSyncNote(tx *database.DB, n client.SyncFragNote) error {
 	// if note exists in the server and does not exist in the client, insert the note.
 	if err == sql.ErrNoRows {
 		note := database.NewNote(n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, false)
+		note.Source = n.Source
 
 		if err := note.Insert(tx); err != nil {
 			return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
@@ -354,6 +366,7 @@ func fullSyncNote(tx *database.DB, n client.SyncFragNote) error {
 	// if note exists in the server and does not exist in the client, insert the note.
 	if err == sql.ErrNoRows {
 		note := database.NewNote(n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, false)
+		note.Source = n.Source
 
 		if err := note.Insert(tx); err != nil {
 			return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
@@ -524,6 +537,9 @@ func cleanLocalNotes(tx *database.DB, fullList *syncList) error {
 			}
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating local notes")
+	}
 
 	return nil
 }
@@ -550,6 +566,9 @@ func cleanLocalBooks(tx *database.DB, fullList *syncList) error {
 			}
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating local books")
+	}
 
 	return nil
 }
@@ -749,6 +768,9 @@ func sendBooks(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 			isBehind = true
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return isBehind, errors.Wrap(err, "iterating syncable books")
+	}
 
 	return isBehind, nil
 }
@@ -849,6 +871,9 @@ func sendNotes(ctx context.DnoteCtx, tx *database.DB) (bool, error) {
 			isBehind = true
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return isBehind, errors.Wrap(err, "iterating syncable notes")
+	}
 
 	return isBehind, nil
 }
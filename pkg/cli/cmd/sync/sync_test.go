@@ -610,6 +610,7 @@ func TestFullSyncNote(t *testing.T) {
 			EditedOn: 1541219321,
 			Body:     "n1-body",
 			Deleted:  false,
+			Source:   "web",
 		}
 
 		if err := fullSyncNote(tx, n); err != nil {
@@ -629,8 +630,8 @@ func TestFullSyncNote(t *testing.T) {
 
 		var n1 database.Note
 		database.MustScan(t, "getting n1",
-			db.QueryRow("SELECT uuid, book_uuid, usn, added_on, edited_on, body,  deleted, dirty FROM notes WHERE uuid = ?", n.UUID),
-			&n1.UUID, &n1.BookUUID, &n1.USN, &n1.AddedOn, &n1.EditedOn, &n1.Body, &n1.Deleted, &n1.Dirty)
+			db.QueryRow("SELECT uuid, book_uuid, usn, added_on, edited_on, body,  deleted, dirty, source FROM notes WHERE uuid = ?", n.UUID),
+			&n1.UUID, &n1.BookUUID, &n1.USN, &n1.AddedOn, &n1.EditedOn, &n1.Body, &n1.Deleted, &n1.Dirty, &n1.Source)
 
 		assert.Equal(t, n1.UUID, n.UUID, "n1 UUID mismatch")
 		assert.Equal(t, n1.BookUUID, n.BookUUID, "n1 BookUUID mismatch")
@@ -640,6 +641,7 @@ func TestFullSyncNote(t *testing.T) {
 		assert.Equal(t, n1.Body, n.Body, "n1 Body mismatch")
 		assert.Equal(t, n1.Deleted, n.Deleted, "n1 Deleted mismatch")
 		assert.Equal(t, n1.Dirty, false, "n1 Dirty mismatch")
+		assert.Equal(t, n1.Source, n.Source, "n1 Source mismatch")
 	})
 
 	t.Run("exists on server and client", func(t *testing.T) {
@@ -1096,6 +1098,7 @@ func TestStepSyncNote(t *testing.T) {
 			EditedOn: 1541219321,
 			Body:     "n1-body",
 			Deleted:  false,
+			Source:   "web",
 		}
 
 		if err := stepSyncNote(tx, n); err != nil {
@@ -1115,8 +1118,8 @@ func TestStepSyncNote(t *testing.T) {
 
 		var n1 database.Note
 		database.MustScan(t, "getting n1",
-			db.QueryRow("SELECT uuid, book_uuid, usn, added_on, edited_on, body, deleted, dirty FROM notes WHERE uuid = ?", n.UUID),
-			&n1.UUID, &n1.BookUUID, &n1.USN, &n1.AddedOn, &n1.EditedOn, &n1.Body, &n1.Deleted, &n1.Dirty)
+			db.QueryRow("SELECT uuid, book_uuid, usn, added_on, edited_on, body, deleted, dirty, source FROM notes WHERE uuid = ?", n.UUID),
+			&n1.UUID, &n1.BookUUID, &n1.USN, &n1.AddedOn, &n1.EditedOn, &n1.Body, &n1.Deleted, &n1.Dirty, &n1.Source)
 
 		assert.Equal(t, n1.UUID, n.UUID, "n1 UUID mismatch")
 		assert.Equal(t, n1.BookUUID, n.BookUUID, "n1 BookUUID mismatch")
@@ -1126,6 +1129,7 @@ func TestStepSyncNote(t *testing.T) {
 		assert.Equal(t, n1.Body, n.Body, "n1 Body mismatch")
 		assert.Equal(t, n1.Deleted, n.Deleted, "n1 Deleted mismatch")
 		assert.Equal(t, n1.Dirty, false, "n1 Dirty mismatch")
+		assert.Equal(t, n1.Source, n.Source, "n1 Source mismatch")
 	})
 
 	t.Run("exists on server and client", func(t *testing.T) {
@@ -0,0 +1,164 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/core"
+	"github.com/dnote/dnote/pkg/cli/infra"
+	"github.com/dnote/dnote/pkg/cli/log"
+	"github.com/dnote/dnote/pkg/cli/ui"
+	"github.com/dnote/dnote/pkg/cli/validate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var contentFlag string
+
+var example = `
+ * Set the template of a book in an editor
+ dnote template set journal
+
+ * Set the template of a book directly. It is a Go template that can use the
+ * book name as {{.Book}} and the time the note is added on as {{.Now}}
+ dnote template set journal -c '# {{.Now.Format "2006-01-02"}}'
+
+ * Print the template of a book
+ dnote template get journal
+
+ * Remove the template of a book
+ dnote template set journal -c ""`
+
+// NewCmd returns a new template command
+func NewCmd(ctx context.DnoteCtx) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "template",
+		Short:   "Manage the templates that pre-fill the editor when adding a note to a book",
+		Example: example,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <book name>",
+		Short: "Set the template of a book",
+		Args:  cobra.ExactArgs(1),
+		RunE:  newSetRun(ctx),
+	}
+	setCmd.Flags().StringVarP(&contentFlag, "content", "c", "", "The template. A blank template removes the template of the book")
+
+	getCmd := &cobra.Command{
+		Use:   "get <book name>",
+		Short: "Print the template of a book",
+		Args:  cobra.ExactArgs(1),
+		RunE:  newGetRun(ctx),
+	}
+
+	cmd.AddCommand(setCmd)
+	cmd.AddCommand(getCmd)
+
+	return cmd
+}
+
+// getTemplate returns the template given by --content or the standard input,
+// or written in an editor pre-filled with the current template
+func getTemplate(ctx context.DnoteCtx, cmd *cobra.Command, current string) (string, error) {
+	if cmd.Flags().Changed("content") {
+		return contentFlag, nil
+	}
+
+	piped, ok, err := ui.ReadPipedInput()
+	if err != nil {
+		return "", errors.Wrap(err, "reading the standard input")
+	}
+	if ok {
+		return piped, nil
+	}
+
+	fpath, err := ui.GetTmpContentPath(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "getting temporarily content file path")
+	}
+	if err := ioutil.WriteFile(fpath, []byte(current), 0644); err != nil {
+		return "", errors.Wrap(err, "preparing tmp content file")
+	}
+
+	c, err := ui.GetEditorInput(ctx, fpath)
+	if err != nil {
+		return "", errors.Wrap(err, "getting editor input")
+	}
+
+	return c, nil
+}
+
+func newSetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		bookName := args[0]
+		if err := validate.BookName(bookName); err != nil {
+			return errors.Wrap(err, "invalid book name")
+		}
+
+		current, _, err := core.GetBookTemplate(ctx, bookName)
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := getTemplate(ctx, cmd, current)
+		if err != nil {
+			return errors.Wrap(err, "getting the template")
+		}
+
+		// catch the syntax errors now rather than when adding a note
+		if _, err := core.RenderBookTemplate(tmpl, core.TemplateData{Book: bookName}); err != nil {
+			return errors.Wrap(err, "invalid template")
+		}
+
+		if err := core.SetBookTemplate(ctx, bookName, tmpl); err != nil {
+			return err
+		}
+
+		if core.IsEmptyBody(tmpl) {
+			log.Successf("removed the template of %s\n", bookName)
+		} else {
+			log.Successf("set the template of %s\n", bookName)
+		}
+
+		return nil
+	}
+}
+
+func newGetRun(ctx context.DnoteCtx) infra.RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		bookName := args[0]
+
+		tmpl, ok, err := core.GetBookTemplate(ctx, bookName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Infof("no template for %s\n", bookName)
+			return nil
+		}
+
+		fmt.Print(tmpl)
+
+		return nil
+	}
+}
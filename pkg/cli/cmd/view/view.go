@@ -74,6 +74,9 @@ func NewCmd(ctx context.DnoteCtx) *cobra.Command {
 	return cmd
 }
 
+// errAllWithNote is an error for using --all flag when viewing a note
+var errAllWithNote = errors.New("--all flag is only valid when viewing books")
+
 func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
 		var run infra.RunEFunc
@@ -81,26 +84,34 @@ func newRun(ctx context.DnoteCtx) infra.RunEFunc {
 		if len(args) == 0 {
 			run = ls.NewRun(ctx, all)
 		} else if len(args) == 1 {
-			if all {
-				return errors.New("--all flag is only valid when viewing books")
-			}
-
-			if strings.Contains(args[0], "%"){
-				run = ls.NewRun(ctx, false)
+			if strings.Contains(args[0], "%") {
+				run = ls.NewRun(ctx, all)
 			} else if utils.IsNumber(args[0]) {
+				if all {
+					return errAllWithNote
+				}
+
 				run = cat.NewRun(ctx, contentOnly)
 			} else {
 				n, err := ls.RetSingle(ctx, args[0])
 				if err != nil {
 					return errors.Wrap(err, "querying books/notes")
 				} else if n == "" {
-					run = ls.NewRun(ctx, false)
+					run = ls.NewRun(ctx, all)
 				} else {
+					if all {
+						return errAllWithNote
+					}
+
 					args[0] = n
 					run = cat.NewRun(ctx, contentOnly)
 				}
 			}
 		} else if len(args) == 2 {
+			if all {
+				return errAllWithNote
+			}
+
 			// DEPRECATED: passing book name to view command is deprecated
 			run = cat.NewRun(ctx, false)
 		} else {
@@ -21,11 +21,13 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/dnote/dnote/pkg/cli/validate"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
@@ -34,6 +36,112 @@ import (
 type Config struct {
 	Editor      string `yaml:"editor"`
 	APIEndpoint string `yaml:"apiEndpoint"`
+	// DBPath is the path to the database file. It overrides the default
+	// location in the data directory if set. A leading "~/" is expanded to
+	// the home directory.
+	DBPath string `yaml:"dbPath,omitempty"`
+	// HighlightColor is the color of the matches in search results
+	HighlightColor string `yaml:"highlightColor,omitempty"`
+	// WAL enables the write-ahead log journal mode of the database. It is
+	// enabled unless set to false, which some network filesystems need.
+	WAL *bool `yaml:"wal,omitempty"`
+	// WALAutocheckpoint is the number of pages after which the write-ahead
+	// log is checkpointed. SQLite's default is used if it is not set.
+	WALAutocheckpoint int `yaml:"walAutocheckpoint,omitempty"`
+	// TrimOnSave removes the blank lines at the start and the end of a note
+	// when it is added or edited. It is enabled unless set to false.
+	TrimOnSave *bool `yaml:"trimOnSave,omitempty"`
+	// InboxBook is the label of the book that `dnote add --inbox` adds to
+	InboxBook string `yaml:"inboxBook,omitempty"`
+	// Opener is the command with which `dnote open` opens the books and the
+	// notes. The default application of the operating system is used if unset.
+	Opener string `yaml:"opener,omitempty"`
+	// ConfirmDestructive, if set to "always", makes the destructive commands
+	// prompt for confirmation even if --yes is given
+	ConfirmDestructive string `yaml:"confirmDestructive,omitempty"`
+}
+
+// DefaultInboxBook is the label of the inbox book if none is configured
+const DefaultInboxBook = "inbox"
+
+// ConfirmDestructiveAlways is the value of confirmDestructive, or of the
+// DNOTE_CONFIRM_DESTRUCTIVE environment variable, that forces the destructive
+// commands to prompt for confirmation
+const ConfirmDestructiveAlways = "always"
+
+// WALEnabled returns whether the write-ahead log journal mode is enabled
+func (c Config) WALEnabled() bool {
+	return c.WAL == nil || *c.WAL
+}
+
+// TrimOnSaveEnabled returns whether the notes are trimmed when saved
+func (c Config) TrimOnSaveEnabled() bool {
+	return c.TrimOnSave == nil || *c.TrimOnSave
+}
+
+// InboxBookLabel returns the label of the inbox book
+func (c Config) InboxBookLabel() string {
+	if c.InboxBook == "" {
+		return DefaultInboxBook
+	}
+
+	return c.InboxBook
+}
+
+// knownKeys is the set of keys allowed in the config file
+var knownKeys = map[string]bool{
+	"editor":             true,
+	"apiEndpoint":        true,
+	"dbPath":             true,
+	"highlightColor":     true,
+	"wal":                true,
+	"walAutocheckpoint":  true,
+	"trimOnSave":         true,
+	"inboxBook":          true,
+	"opener":             true,
+	"confirmDestructive": true,
+	// retired keys that old config files may still have
+	"apikey": true,
+	"book":   true,
+}
+
+// checkKeys returns an error if the given YAML document has a key that is not
+// known to the config
+func checkKeys(b []byte) error {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return errors.Wrap(err, "unmarshalling config")
+	}
+
+	for key := range raw {
+		if !knownKeys[key] {
+			return errors.Errorf("unknown key '%s'", key)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the values in the config are valid
+func (c Config) validate() error {
+	if c.HighlightColor != "" {
+		if _, ok := log.ColorByName(c.HighlightColor); !ok {
+			return errors.Errorf("invalid highlightColor '%s'. Use one of %s", c.HighlightColor, strings.Join(log.ColorNames(), ", "))
+		}
+	}
+	if c.WALAutocheckpoint < 0 {
+		return errors.Errorf("invalid walAutocheckpoint %d. It must not be negative", c.WALAutocheckpoint)
+	}
+	if c.ConfirmDestructive != "" && c.ConfirmDestructive != ConfirmDestructiveAlways {
+		return errors.Errorf("invalid confirmDestructive '%s'. Use '%s' or leave it unset", c.ConfirmDestructive, ConfirmDestructiveAlways)
+	}
+	if c.InboxBook != "" {
+		if err := validate.BookName(c.InboxBook); err != nil {
+			return errors.Wrapf(err, "invalid inboxBook '%s'", c.InboxBook)
+		}
+	}
+
+	return nil
 }
 
 func checkLegacyPath(ctx context.DnoteCtx) (string, bool) {
@@ -70,11 +178,19 @@ func Read(ctx context.DnoteCtx) (Config, error) {
 		return ret, errors.Wrap(err, "reading config file")
 	}
 
+	if err := checkKeys(b); err != nil {
+		return ret, errors.Wrapf(err, "invalid config file at %s", configPath)
+	}
+
 	err = yaml.Unmarshal(b, &ret)
 	if err != nil {
 		return ret, errors.Wrap(err, "unmarshalling config")
 	}
 
+	if err := ret.validate(); err != nil {
+		return ret, errors.Wrapf(err, "invalid config file at %s", configPath)
+	}
+
 	return ret, nil
 }
 
@@ -0,0 +1,164 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+func writeConfig(t *testing.T, ctx context.DnoteCtx, content string) {
+	dir := filepath.Join(ctx.Paths.Config, consts.DnoteDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the config directory"))
+	}
+
+	if err := ioutil.WriteFile(GetPath(ctx), []byte(content), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the config file"))
+	}
+}
+
+func TestRead(t *testing.T) {
+	testCases := []struct {
+		content     string
+		expected    Config
+		expectedErr string
+	}{
+		{
+			content: "editor: vim\napiEndpoint: https://api.getdnote.com\n",
+			expected: Config{
+				Editor:      "vim",
+				APIEndpoint: "https://api.getdnote.com",
+			},
+		},
+		{
+			content: "editor: vim\ndbPath: ~/notes/dnote.db\nhighlightColor: cyan\n",
+			expected: Config{
+				Editor:         "vim",
+				DBPath:         "~/notes/dnote.db",
+				HighlightColor: "cyan",
+			},
+		},
+		{
+			content: "editor: vim\napikey: foo\n",
+			expected: Config{
+				Editor: "vim",
+			},
+		},
+		{
+			content:     "walAutocheckpoint: -1\n",
+			expectedErr: "invalid walAutocheckpoint -1",
+		},
+		{
+			content:     "editor: vim\neditr: nano\n",
+			expectedErr: "unknown key 'editr'",
+		},
+		{
+			content:     "highlightColor: pink\n",
+			expectedErr: "invalid highlightColor 'pink'",
+		},
+		{
+			content: "inboxBook: capture\n",
+			expected: Config{
+				InboxBook: "capture",
+			},
+		},
+		{
+			content:     "inboxBook: my inbox\n",
+			expectedErr: "invalid inboxBook 'my inbox'",
+		},
+		{
+			content: "opener: open -a Typora\n",
+			expected: Config{
+				Opener: "open -a Typora",
+			},
+		},
+		{
+			content: "confirmDestructive: always\n",
+			expected: Config{
+				ConfirmDestructive: "always",
+			},
+		},
+		{
+			content:     "confirmDestructive: sometimes\n",
+			expectedErr: "invalid confirmDestructive 'sometimes'",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			// Setup
+			ctx := context.DnoteCtx{
+				Paths: context.Paths{
+					Config: "../tmp/config",
+				},
+			}
+			defer os.RemoveAll(ctx.Paths.Config)
+
+			writeConfig(t, ctx, tc.content)
+
+			// Execute
+			got, err := Read(ctx)
+
+			// Test
+			if tc.expectedErr != "" {
+				assert.NotEqual(t, err, nil, "error should be returned")
+				assert.Equal(t, strings.Contains(err.Error(), tc.expectedErr), true, fmt.Sprintf("error '%s' should contain '%s'", err, tc.expectedErr))
+				return
+			}
+
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+			assert.Equal(t, got, tc.expected, "config mismatch")
+		})
+	}
+}
+
+func TestWALEnabled(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	assert.Equal(t, Config{}.WALEnabled(), true, "unset should be enabled")
+	assert.Equal(t, Config{WAL: &enabled}.WALEnabled(), true, "true should be enabled")
+	assert.Equal(t, Config{WAL: &disabled}.WALEnabled(), false, "false should be disabled")
+}
+
+func TestTrimOnSaveEnabled(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	assert.Equal(t, Config{}.TrimOnSaveEnabled(), true, "unset should be enabled")
+	assert.Equal(t, Config{TrimOnSave: &enabled}.TrimOnSaveEnabled(), true, "true should be enabled")
+	assert.Equal(t, Config{TrimOnSave: &disabled}.TrimOnSaveEnabled(), false, "false should be disabled")
+}
+
+func TestInboxBookLabel(t *testing.T) {
+	assert.Equal(t, Config{}.InboxBookLabel(), DefaultInboxBook, "unset should use the default")
+	assert.Equal(t, Config{InboxBook: "capture"}.InboxBookLabel(), "capture", "label mismatch")
+}
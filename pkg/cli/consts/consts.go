@@ -47,4 +47,6 @@ var (
 	SystemSessionKey = "session_token"
 	// SystemSessionKeyExpiry is the timestamp at which the session key will expire
 	SystemSessionKeyExpiry = "session_token_expiry"
+	// SystemLastSearch is the most recent search query
+	SystemLastSearch = "last_search"
 )
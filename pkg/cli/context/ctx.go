@@ -42,7 +42,18 @@ type DnoteCtx struct {
 	SessionKey       string
 	SessionKeyExpiry int64
 	Editor           string
-	Clock            clock.Clock
+	HighlightColor   string
+	// TrimOnSave removes the blank lines around the content of the notes
+	// that are added or edited
+	TrimOnSave bool
+	// InboxBook is the label of the book that `dnote add --inbox` adds to
+	InboxBook string
+	// Opener is the command with which `dnote open` opens the books and the notes
+	Opener string
+	// ConfirmDestructive makes the destructive commands prompt for
+	// confirmation even if --yes is given
+	ConfirmDestructive bool
+	Clock              clock.Clock
 }
 
 // Redact replaces private information from the context with a set of
@@ -0,0 +1,357 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BookSortName orders the books by label
+	BookSortName = "name"
+	// BookSortCount orders the books by the number of notes, the largest first
+	BookSortCount = "count"
+	// BookSortRecent orders the books by the time any of their notes was last
+	// added or edited, the most recent first
+	BookSortRecent = "recent"
+)
+
+// BookSorts is the list of the supported book orders
+var BookSorts = []string{BookSortName, BookSortCount, BookSortRecent}
+
+// bookOrders maps the book sorts to their ORDER BY clauses. Only these clauses
+// are ever interpolated into the query.
+var bookOrders = map[string]string{
+	BookSortName:   "books.label ASC",
+	BookSortCount:  "note_count DESC, books.label ASC",
+	BookSortRecent: "max(max(notes.added_on, notes.edited_on)) DESC, books.label ASC",
+}
+
+// reverseBookOrders maps the book sorts to the ORDER BY clauses that reverse
+// the ones in bookOrders
+var reverseBookOrders = map[string]string{
+	BookSortName:   "books.label DESC",
+	BookSortCount:  "note_count ASC, books.label DESC",
+	BookSortRecent: "max(max(notes.added_on, notes.edited_on)) ASC, books.label DESC",
+}
+
+// ListBooksOptions is the options for listing books
+type ListBooksOptions struct {
+	// All includes the archived books after the active ones
+	All bool
+	// Sort is the order of the books. If empty, the books are ordered by label.
+	Sort string
+	// Empty lists only the books that have no notes
+	Empty bool
+	// Reverse reverses the order. The archived books still follow the active ones.
+	Reverse bool
+}
+
+// bookCountQuery selects the books that are not deleted along with the number
+// of their notes. It is the single definition of the note count of a book, so
+// that every listing reports the same count: a note is counted if it is not
+// deleted, whether or not its book is archived.
+const bookCountQuery = `SELECT books.label, books.archive, count(notes.uuid) note_count
+	FROM books
+	LEFT JOIN notes ON notes.book_uuid = books.uuid AND notes.deleted = false
+	WHERE books.deleted = false`
+
+// queryBookCounts returns the books matching the given condition with their
+// note counts, in the given order. If having is not empty, only the groups
+// matching it are returned.
+func queryBookCounts(db *database.DB, cond, having, order string, args ...interface{}) ([]Book, error) {
+	if having != "" {
+		having = fmt.Sprintf("HAVING %s", having)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`%s
+		AND %s
+	GROUP BY books.uuid
+	%s
+	ORDER BY %s;`, bookCountQuery, cond, having, order), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	ret := []Book{}
+	for rows.Next() {
+		var b Book
+		if err := rows.Scan(&b.Label, &b.Archive, &b.NoteCount); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating books")
+	}
+
+	return ret, nil
+}
+
+func queryBooks(db *database.DB, archive, empty bool, order string) ([]Book, error) {
+	var having string
+	if empty {
+		having = "note_count = 0"
+	}
+
+	return queryBookCounts(db, "books.archive = ?", having, order, archive)
+}
+
+// MatchBooks returns the books whose labels match the given LIKE pattern,
+// ordered by label. The archived books are included only if all is true.
+func MatchBooks(ctx context.DnoteCtx, pattern string, all bool) ([]Book, error) {
+	cond := "books.label LIKE ?"
+	if !all {
+		cond = fmt.Sprintf("%s AND books.archive = false", cond)
+	}
+
+	return queryBookCounts(ctx.DB, cond, "", bookOrders[BookSortName], pattern)
+}
+
+// CountNotes returns the number of notes in the book with the given label, as
+// reported by the book listings. It returns 0 if there is no such book.
+func CountNotes(ctx context.DnoteCtx, label string) (int, error) {
+	books, err := queryBookCounts(ctx.DB, "books.label = ?", "", bookOrders[BookSortName], label)
+	if err != nil {
+		return 0, err
+	}
+	if len(books) != 1 {
+		return 0, nil
+	}
+
+	return books[0].NoteCount, nil
+}
+
+// ListBooks returns the books that are not deleted, in the given order
+func ListBooks(ctx context.DnoteCtx, opts ListBooksOptions) ([]Book, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = BookSortName
+	}
+
+	orders := bookOrders
+	if opts.Reverse {
+		orders = reverseBookOrders
+	}
+
+	order, ok := orders[sort]
+	if !ok {
+		return nil, errors.Errorf("unknown sort '%s'", opts.Sort)
+	}
+
+	ret, err := queryBooks(ctx.DB, false, opts.Empty, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.All {
+		archived, err := queryBooks(ctx.DB, true, opts.Empty, order)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting archived books")
+		}
+
+		ret = append(ret, archived...)
+	}
+
+	return ret, nil
+}
+
+// BookTree is a book along with its active notes
+type BookTree struct {
+	Book
+	Notes []Note `json:"notes"`
+}
+
+// ListBookTree returns the books in the given order, each with its notes that
+// are not deleted, ordered by the time they were added. The archived books are
+// included only if opts.All is true.
+func ListBookTree(ctx context.DnoteCtx, opts ListBooksOptions) ([]BookTree, error) {
+	books, err := ListBooks(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := ctx.DB.Query(`SELECT notes.rowid, notes.uuid, books.label, notes.body, notes.added_on, notes.edited_on, notes.source
+	FROM notes
+	INNER JOIN books ON books.uuid = notes.book_uuid
+	WHERE notes.deleted = false
+		AND books.deleted = false
+	ORDER BY notes.added_on ASC;`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	notes := map[string][]Note{}
+	for rows.Next() {
+		var n Note
+		if err := rows.Scan(&n.RowID, &n.UUID, &n.BookLabel, &n.Body, &n.AddedOn, &n.EditedOn, &n.Source); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		notes[n.BookLabel] = append(notes[n.BookLabel], n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating notes")
+	}
+
+	ret := []BookTree{}
+	for _, b := range books {
+		bookNotes, ok := notes[b.Label]
+		if !ok {
+			bookNotes = []Note{}
+		}
+
+		ret = append(ret, BookTree{Book: b, Notes: bookNotes})
+	}
+
+	return ret, nil
+}
+
+// ErrBookNotFound is an error for when a book with the given label does not exist
+var ErrBookNotFound = errors.New("book not found")
+
+// ArchiveBook archives the book with the given label. If archive is false, the
+// book is de-archived instead.
+func ArchiveBook(ctx context.DnoteCtx, label string, archive bool) error {
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning a transaction")
+	}
+
+	var bookUUID string
+	err = tx.QueryRow("SELECT uuid FROM books WHERE label = ? AND deleted = ?", label, false).Scan(&bookUUID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return ErrBookNotFound
+	} else if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "finding the book")
+	}
+
+	if _, err := tx.Exec("UPDATE books SET archive = ? WHERE uuid = ?", archive, bookUUID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "updating the book")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "committing the transaction")
+	}
+
+	return nil
+}
+
+// ToggleArchiveBook flips the archive state of the book with the given label
+// and returns the resulting state
+func ToggleArchiveBook(ctx context.DnoteCtx, label string) (bool, error) {
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return false, errors.Wrap(err, "beginning a transaction")
+	}
+
+	var bookUUID string
+	var archive bool
+	err = tx.QueryRow("SELECT uuid, archive FROM books WHERE label = ? AND deleted = ?", label, false).Scan(&bookUUID, &archive)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return false, ErrBookNotFound
+	} else if err != nil {
+		tx.Rollback()
+		return false, errors.Wrap(err, "finding the book")
+	}
+
+	if _, err := tx.Exec("UPDATE books SET archive = ? WHERE uuid = ?", !archive, bookUUID); err != nil {
+		tx.Rollback()
+		return false, errors.Wrap(err, "updating the book")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return false, errors.Wrap(err, "committing the transaction")
+	}
+
+	return !archive, nil
+}
+
+// MergeBook moves the notes in the source book into the target book and
+// deletes the source book. The moves are recorded in the history of the notes.
+// If done is not nil, it is called after each note that is not deleted is moved
+// so that the caller can report the progress.
+func MergeBook(ctx context.DnoteCtx, tx *database.DB, sourceUUID, targetUUID string, done func()) error {
+	ts := ctx.Clock.Now().UnixNano()
+
+	type noteInfo struct {
+		uuid    string
+		deleted bool
+	}
+
+	rows, err := tx.Query("SELECT uuid, deleted FROM notes WHERE book_uuid = ?", sourceUUID)
+	if err != nil {
+		return errors.Wrap(err, "querying notes")
+	}
+
+	var notes []noteInfo
+	for rows.Next() {
+		var n noteInfo
+		if err := rows.Scan(&n.uuid, &n.deleted); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "scanning a row")
+		}
+
+		notes = append(notes, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterating notes")
+	}
+
+	for _, n := range notes {
+		if _, err := tx.Exec("INSERT INTO note_moves (note_uuid, from_book_uuid, to_book_uuid, moved_on) VALUES (?, ?, ?, ?)", n.uuid, sourceUUID, targetUUID, ts); err != nil {
+			return errors.Wrapf(err, "recording the move of %s", n.uuid)
+		}
+		if _, err := tx.Exec("UPDATE notes SET book_uuid = ?, edited_on = ?, dirty = ? WHERE uuid = ?", targetUUID, ts, true, n.uuid); err != nil {
+			return errors.Wrapf(err, "moving %s", n.uuid)
+		}
+
+		if done != nil && !n.deleted {
+			done()
+		}
+	}
+
+	// override the label with a random string, as is done when removing a book
+	uniqLabel, err := utils.GenerateUUID()
+	if err != nil {
+		return errors.Wrap(err, "generating uuid to override with")
+	}
+
+	if _, err := tx.Exec("UPDATE books SET deleted = ?, dirty = ?, label = ? WHERE uuid = ?", true, true, uniqLabel, sourceUUID); err != nil {
+		return errors.Wrap(err, "removing the book")
+	}
+
+	return nil
+}
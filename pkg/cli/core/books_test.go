@@ -0,0 +1,436 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func setupBooks(t *testing.T, db *database.DB) {
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b2-uuid", "css", true)
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "algorithms")
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b4-uuid", "go", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b1-uuid", "n3 body", 1542058877, true)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b2-uuid", "n4 body", 1542058878)
+}
+
+func TestListBooks(t *testing.T) {
+	testCases := []struct {
+		all      bool
+		empty    bool
+		expected []Book
+	}{
+		{
+			all: false,
+			expected: []Book{
+				{Label: "algorithms", NoteCount: 0},
+				{Label: "js", NoteCount: 2},
+			},
+		},
+		{
+			all: true,
+			expected: []Book{
+				{Label: "algorithms", NoteCount: 0},
+				{Label: "js", NoteCount: 2},
+				{Label: "css", NoteCount: 1, Archive: true},
+			},
+		},
+		{
+			all:   false,
+			empty: true,
+			expected: []Book{
+				{Label: "algorithms", NoteCount: 0},
+			},
+		},
+		{
+			all:   true,
+			empty: true,
+			expected: []Book{
+				{Label: "algorithms", NoteCount: 0},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// Setup
+		ctx := context.InitTestCtx(t, context.Paths{
+			Data:  "../tmp",
+			Cache: "../tmp",
+		}, nil)
+		setupBooks(t, ctx.DB)
+
+		// Execute
+		got, err := ListBooks(ctx, ListBooksOptions{All: tc.all, Empty: tc.empty})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		assert.DeepEqual(t, got, tc.expected, "books mismatch")
+
+		context.TeardownTestCtx(t, ctx)
+	}
+}
+
+func TestListBooksSort(t *testing.T) {
+	testCases := []struct {
+		sort     string
+		reverse  bool
+		expected []string
+	}{
+		{
+			sort:     "",
+			expected: []string{"algorithms", "css", "js"},
+		},
+		{
+			sort:     BookSortName,
+			expected: []string{"algorithms", "css", "js"},
+		},
+		{
+			sort:     BookSortCount,
+			expected: []string{"css", "js", "algorithms"},
+		},
+		{
+			sort:     BookSortRecent,
+			expected: []string{"css", "js", "algorithms"},
+		},
+		{
+			sort:     "",
+			reverse:  true,
+			expected: []string{"js", "css", "algorithms"},
+		},
+		{
+			sort:     BookSortCount,
+			reverse:  true,
+			expected: []string{"algorithms", "js", "css"},
+		},
+		{
+			sort:     BookSortRecent,
+			reverse:  true,
+			expected: []string{"algorithms", "js", "css"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s reverse %t", tc.sort, tc.reverse), func(t *testing.T) {
+			// Setup
+			ctx := context.InitTestCtx(t, context.Paths{
+				Data:  "../tmp",
+				Cache: "../tmp",
+			}, nil)
+			defer context.TeardownTestCtx(t, ctx)
+
+			db := ctx.DB
+			database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+			database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+			database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "algorithms")
+			database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 200)
+			database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 100)
+			database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3 body", 10, 300)
+			database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b2-uuid", "n4 body", 20)
+			database.MustExec(t, "inserting n5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "b2-uuid", "n5 body", 30)
+			database.MustExec(t, "inserting n6", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n6-uuid", "b3-uuid", "n6 body", 400, true)
+
+			// Execute
+			books, err := ListBooks(ctx, ListBooksOptions{Sort: tc.sort, Reverse: tc.reverse})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Test
+			got := []string{}
+			for _, b := range books {
+				got = append(got, b.Label)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "order mismatch")
+		})
+	}
+}
+
+func TestListBooksUnknownSort(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	_, err := ListBooks(ctx, ListBooksOptions{Sort: "size"})
+
+	assert.Equal(t, err.Error(), "unknown sort 'size'", "error mismatch")
+}
+
+func TestArchiveBook(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	// Execute and test
+	if err := ArchiveBook(ctx, "js", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ArchiveBook(ctx, "css", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsArchive, cssArchive bool
+	database.MustScan(t, "getting js", ctx.DB.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &jsArchive)
+	database.MustScan(t, "getting css", ctx.DB.QueryRow("SELECT archive FROM books WHERE label = ?", "css"), &cssArchive)
+	assert.Equal(t, jsArchive, true, "js should be archived")
+	assert.Equal(t, cssArchive, false, "css should be de-archived")
+
+}
+
+func TestArchiveBookNotFound(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	// Execute
+	err := ArchiveBook(ctx, "foo", true)
+
+	// Test
+	assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+
+	conn, ok := ctx.DB.Conn.(*sql.DB)
+	if !ok {
+		t.Fatal("unexpected connection type")
+	}
+	assert.Equal(t, conn.Stats().InUse, 0, "transaction should not be left open")
+
+	// the database should still be writable
+	if err := ArchiveBook(ctx, "js", true); err != nil {
+		t.Fatal(errors.Wrap(err, "archiving after the failure"))
+	}
+}
+
+func TestToggleArchiveBook(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	// Execute and test
+	got, err := ToggleArchiveBook(ctx, "js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got, true, "js should be archived")
+
+	got, err = ToggleArchiveBook(ctx, "js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got, false, "js should be de-archived")
+
+	var archive bool
+	database.MustScan(t, "getting js", ctx.DB.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &archive)
+	assert.Equal(t, archive, false, "archive mismatch")
+
+	_, err = ToggleArchiveBook(ctx, "foo")
+	assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+}
+
+func TestNoteCountsAgree(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	listed, err := ListBooks(ctx, ListBooksOptions{All: true})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "listing books"))
+	}
+
+	for _, b := range listed {
+		t.Run(b.Label, func(t *testing.T) {
+			// Execute
+			matched, err := MatchBooks(ctx, b.Label, true)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "matching books"))
+			}
+			count, err := CountNotes(ctx, b.Label)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "counting notes"))
+			}
+
+			// Test
+			assert.Equal(t, len(matched), 1, "matched book count mismatch")
+			assert.Equal(t, matched[0].NoteCount, b.NoteCount, "MatchBooks count mismatch")
+			assert.Equal(t, count, b.NoteCount, "CountNotes count mismatch")
+		})
+	}
+
+	// deleted notes are not counted, and notes in archived books are
+	count, err := CountNotes(ctx, "js")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "counting js"))
+	}
+	assert.Equal(t, count, 2, "js count mismatch")
+
+	count, err = CountNotes(ctx, "css")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "counting css"))
+	}
+	assert.Equal(t, count, 1, "css count mismatch")
+
+	count, err = CountNotes(ctx, "nonexistent")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "counting a nonexistent book"))
+	}
+	assert.Equal(t, count, 0, "nonexistent count mismatch")
+}
+
+func TestMatchBooks(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	// Execute
+	active, err := MatchBooks(ctx, "%s%", false)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "matching active books"))
+	}
+	all, err := MatchBooks(ctx, "%s%", true)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "matching all books"))
+	}
+
+	// Test
+	assert.DeepEqual(t, active, []Book{
+		{Label: "algorithms", NoteCount: 0},
+		{Label: "js", NoteCount: 2},
+	}, "active books mismatch")
+	assert.DeepEqual(t, all, []Book{
+		{Label: "algorithms", NoteCount: 0},
+		{Label: "css", Archive: true, NoteCount: 1},
+		{Label: "js", NoteCount: 2},
+	}, "all books mismatch")
+}
+
+func TestListBookTree(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	t.Run("active", func(t *testing.T) {
+		got, err := ListBookTree(ctx, ListBooksOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []BookTree{
+			{Book: Book{Label: "algorithms", NoteCount: 0}, Notes: []Note{}},
+			{Book: Book{Label: "js", NoteCount: 2}, Notes: []Note{
+				{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "n1 body", AddedOn: 1542058875},
+				{RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body", AddedOn: 1542058876},
+			}},
+		}, "tree mismatch")
+	})
+
+	t.Run("all", func(t *testing.T) {
+		got, err := ListBookTree(ctx, ListBooksOptions{All: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, len(got), 3, "book count mismatch")
+		assert.Equal(t, got[2].Label, "css", "archived book should be last")
+		assert.DeepEqual(t, got[2].Notes, []Note{
+			{RowID: 4, UUID: "n4-uuid", BookLabel: "css", Body: "n4 body", AddedOn: 1542058878},
+		}, "archived book notes mismatch")
+	})
+}
+
+func TestMergeBook(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	// Execute
+	var doneCount int
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+	if err := MergeBook(ctx, tx, "b1-uuid", "b3-uuid", func() { doneCount++ }); err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "merging"))
+	}
+	tx.Commit()
+
+	// Test
+	var noteCount, b1Count, moveCount int
+	database.MustScan(t, "counting notes in b3", ctx.DB.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "b3-uuid"), &noteCount)
+	database.MustScan(t, "counting notes in b1", ctx.DB.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "b1-uuid"), &b1Count)
+	database.MustScan(t, "counting note_moves", ctx.DB.QueryRow("SELECT count(*) FROM note_moves"), &moveCount)
+	assert.Equal(t, noteCount, 3, "target book note count mismatch")
+	assert.Equal(t, b1Count, 0, "source book note count mismatch")
+	assert.Equal(t, moveCount, 3, "move count mismatch")
+	assert.Equal(t, doneCount, 2, "the deleted note should not be counted as done")
+
+	for _, noteUUID := range []string{"n1-uuid", "n2-uuid", "n3-uuid"} {
+		var fromBookUUID, toBookUUID string
+		var movedOn int64
+		database.MustScan(t, fmt.Sprintf("getting the move of %s", noteUUID), ctx.DB.QueryRow("SELECT from_book_uuid, to_book_uuid, moved_on FROM note_moves WHERE note_uuid = ?", noteUUID), &fromBookUUID, &toBookUUID, &movedOn)
+		assert.Equal(t, fromBookUUID, "b1-uuid", fmt.Sprintf("from_book_uuid mismatch for %s", noteUUID))
+		assert.Equal(t, toBookUUID, "b3-uuid", fmt.Sprintf("to_book_uuid mismatch for %s", noteUUID))
+		assert.Equal(t, movedOn, ctx.Clock.Now().UnixNano(), fmt.Sprintf("moved_on mismatch for %s", noteUUID))
+	}
+
+	var b1Deleted bool
+	database.MustScan(t, "getting b1", ctx.DB.QueryRow("SELECT deleted FROM books WHERE uuid = ?", "b1-uuid"), &b1Deleted)
+	assert.Equal(t, b1Deleted, true, "source book should be deleted")
+}
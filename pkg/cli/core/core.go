@@ -0,0 +1,41 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package core provides the operations on the books and notes stored by dnote,
+// independent of the command line interface. It can be used to embed dnote in
+// other programs.
+package core
+
+// Book is a book along with the number of its active notes
+type Book struct {
+	Label     string `json:"label"`
+	Archive   bool   `json:"archive"`
+	NoteCount int    `json:"note_count"`
+}
+
+// Note is a note along with the label of its book
+type Note struct {
+	RowID     int    `json:"rowid"`
+	UUID      string `json:"uuid"`
+	BookLabel string `json:"book_label"`
+	Body      string `json:"body"`
+	AddedOn   int64  `json:"added_on"`
+	EditedOn  int64  `json:"edited_on"`
+	Source    string `json:"source"`
+	Pinned    bool   `json:"pinned,omitempty"`
+}
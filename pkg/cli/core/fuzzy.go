@@ -0,0 +1,156 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+)
+
+const (
+	// fuzzyStartBonus is added for matching the first rune of a label
+	fuzzyStartBonus = 8
+	// fuzzyBoundaryBonus is added for matching the first rune of a word
+	fuzzyBoundaryBonus = 6
+	// fuzzyConsecutiveBonus is added for matching the rune right after the
+	// previous match
+	fuzzyConsecutiveBonus = 5
+)
+
+// isWordBoundary returns true if a word starts after the given rune
+func isWordBoundary(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// fuzzyRuneScore returns the score for matching the rune at the given index
+func fuzzyRuneScore(label []rune, idx int) int {
+	if idx == 0 {
+		return 1 + fuzzyStartBonus
+	}
+	if isWordBoundary(label[idx-1]) {
+		return 1 + fuzzyBoundaryBonus
+	}
+
+	return 1
+}
+
+// FuzzyScore scores how well the query fuzzily matches the label, ignoring
+// case. The runes of the query must appear in the label in order, though not
+// necessarily next to each other. Matches at the start of the label or of a
+// word and runs of consecutive matches score higher, and the unmatched runes
+// between matches score lower. The second return value is false if the label
+// does not match.
+func FuzzyScore(query, label string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	l := []rune(strings.ToLower(label))
+	if len(q) == 0 || len(q) > len(l) {
+		return 0, false
+	}
+
+	const none = math.MinInt32
+
+	// best[j] is the highest score for the query matched so far, with its last
+	// rune matched at l[j]
+	best := make([]int, len(l))
+	for j := range l {
+		best[j] = none
+		if l[j] == q[0] {
+			best[j] = fuzzyRuneScore(l, j) - j
+		}
+	}
+
+	for i := 1; i < len(q); i++ {
+		cur := make([]int, len(l))
+		for j := range l {
+			cur[j] = none
+			if l[j] != q[i] {
+				continue
+			}
+
+			for k := 0; k < j; k++ {
+				if best[k] == none {
+					continue
+				}
+
+				s := best[k] + fuzzyRuneScore(l, j)
+				if k == j-1 {
+					s += fuzzyConsecutiveBonus
+				} else {
+					s -= j - k - 1
+				}
+
+				if s > cur[j] {
+					cur[j] = s
+				}
+			}
+		}
+
+		best = cur
+	}
+
+	ret := none
+	for _, s := range best {
+		if s > ret {
+			ret = s
+		}
+	}
+	if ret == none {
+		return 0, false
+	}
+
+	return ret, true
+}
+
+// FuzzyMatchBooks returns the books whose labels fuzzily match the query, the
+// best match first. Among the equally good matches, shorter labels come first.
+// The archived books are included only if all is true.
+func FuzzyMatchBooks(ctx context.DnoteCtx, query string, all bool) ([]Book, error) {
+	books, err := ListBooks(ctx, ListBooksOptions{All: all})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[string]int{}
+	ret := []Book{}
+	for _, b := range books {
+		score, ok := FuzzyScore(query, b.Label)
+		if !ok {
+			continue
+		}
+
+		scores[b.Label] = score
+		ret = append(ret, b)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		si, sj := scores[ret[i].Label], scores[ret[j].Label]
+		if si != sj {
+			return si > sj
+		}
+
+		return utf8.RuneCountInString(ret[i].Label) < utf8.RuneCountInString(ret[j].Label)
+	})
+
+	return ret, nil
+}
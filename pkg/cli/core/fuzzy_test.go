@@ -0,0 +1,139 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	t.Run("matching", func(t *testing.T) {
+		testCases := []struct {
+			query string
+			label string
+			match bool
+		}{
+			{query: "algo", label: "algorithms", match: true},
+			{query: "ALGO", label: "Algorithms", match: true},
+			{query: "agm", label: "algorithms", match: true},
+			{query: "algo", label: "golang", match: false},
+			{query: "algorithms", label: "algo", match: false},
+			{query: "", label: "algo", match: false},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.query+" "+tc.label, func(t *testing.T) {
+				_, ok := FuzzyScore(tc.query, tc.label)
+				assert.Equal(t, ok, tc.match, "match mismatch")
+			})
+		}
+	})
+
+	t.Run("ranking", func(t *testing.T) {
+		// each label is expected to score no lower than the labels after it.
+		// FuzzyMatchBooks breaks the ties by the length of the labels.
+		testCases := []struct {
+			query  string
+			labels []string
+		}{
+			{
+				query:  "algo",
+				labels: []string{"algo", "algorithms", "data-algo", "a-long-group", "xalxgxo"},
+			},
+			{
+				query:  "js",
+				labels: []string{"js", "js-tips", "json-schema", "projects"},
+			},
+			{
+				query:  "ml",
+				labels: []string{"machine-learning", "html"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.query, func(t *testing.T) {
+				prev, ok := FuzzyScore(tc.query, tc.labels[0])
+				if !ok {
+					t.Fatalf("'%s' should match '%s'", tc.labels[0], tc.query)
+				}
+
+				for _, label := range tc.labels[1:] {
+					score, ok := FuzzyScore(tc.query, label)
+					if !ok {
+						t.Fatalf("'%s' should match '%s'", label, tc.query)
+					}
+					if score > prev {
+						t.Errorf("'%s' (%d) should not score higher than the previous label (%d)", label, score, prev)
+					}
+
+					prev = score
+				}
+			})
+		}
+	})
+}
+
+func TestFuzzyMatchBooks(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "data-algo")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "algorithms")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "golang")
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b4-uuid", "algebra-old", true)
+	database.MustExec(t, "inserting b5", db, "INSERT INTO books (uuid, label, deleted) VALUES (?, ?, ?)", "b5-uuid", "algol", true)
+	database.MustExec(t, "inserting b6", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b6-uuid", "algo")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b2-uuid", "n1 body", 1542058875)
+
+	t.Run("active", func(t *testing.T) {
+		got, err := FuzzyMatchBooks(ctx, "algo", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Book{
+			{Label: "algo", NoteCount: 0},
+			{Label: "algorithms", NoteCount: 1},
+			{Label: "data-algo", NoteCount: 0},
+		}, "result mismatch")
+	})
+
+	t.Run("all", func(t *testing.T) {
+		got, err := FuzzyMatchBooks(ctx, "alg", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Book{
+			{Label: "algo", NoteCount: 0},
+			{Label: "algorithms", NoteCount: 1},
+			{Label: "algebra-old", NoteCount: 0, Archive: true},
+			{Label: "data-algo", NoteCount: 0},
+		}, "result mismatch")
+	})
+}
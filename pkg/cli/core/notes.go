@@ -0,0 +1,344 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/dnote/dnote/pkg/cli/utils"
+	"github.com/pkg/errors"
+)
+
+// ListNotesOptions is the options for listing notes
+type ListNotesOptions struct {
+	// Deleted lists the deleted notes instead of the active ones
+	Deleted bool
+	// InsensitiveBook matches the book label regardless of case. A book whose
+	// label matches exactly is preferred over the others.
+	InsensitiveBook bool
+	// SinceID lists only the notes whose rowid is greater than it
+	SinceID int
+	// IgnorePins orders the pinned notes along with the others instead of
+	// listing them first
+	IgnorePins bool
+}
+
+// findBook returns the uuid and the label of the book with the given label.
+// If insensitive is true, the label is matched regardless of case and an error
+// is returned if more than one book matches and none of them matches exactly.
+func findBook(db *database.DB, label string, insensitive bool) (string, string, error) {
+	if !insensitive {
+		var uuid string
+		err := db.QueryRow("SELECT uuid FROM books WHERE label = ?", label).Scan(&uuid)
+		if err == sql.ErrNoRows {
+			return "", "", ErrBookNotFound
+		} else if err != nil {
+			return "", "", errors.Wrap(err, "querying the book")
+		}
+
+		return uuid, label, nil
+	}
+
+	rows, err := db.Query("SELECT uuid, label FROM books WHERE label = ? COLLATE NOCASE AND deleted = ?", label, false)
+	if err != nil {
+		return "", "", errors.Wrap(err, "querying books")
+	}
+	defer rows.Close()
+
+	var uuids, labels []string
+	for rows.Next() {
+		var uuid, l string
+		if err := rows.Scan(&uuid, &l); err != nil {
+			return "", "", errors.Wrap(err, "scanning a row")
+		}
+		if l == label {
+			return uuid, l, nil
+		}
+
+		uuids = append(uuids, uuid)
+		labels = append(labels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", errors.Wrap(err, "iterating books")
+	}
+
+	if len(uuids) == 0 {
+		return "", "", ErrBookNotFound
+	}
+	if len(uuids) > 1 {
+		return "", "", errors.Errorf("'%s' matches more than one book: %s", label, strings.Join(labels, ", "))
+	}
+
+	return uuids[0], labels[0], nil
+}
+
+// findDeletedBooks returns the uuids of the deleted books that had the given
+// label before they were deleted
+func findDeletedBooks(db *database.DB, label string, insensitive bool) ([]string, error) {
+	query := "SELECT uuid FROM books WHERE deleted = ? AND original_label = ?"
+	if insensitive {
+		query += " COLLATE NOCASE"
+	}
+
+	rows, err := db.Query(query, true, label)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying deleted books")
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating deleted books")
+	}
+
+	return ret, nil
+}
+
+// ListNotes returns the notes in the book with the given label, ordered by
+// the time they were added. The pinned notes come first unless opts.IgnorePins
+// is set. The label is matched exactly unless opts.InsensitiveBook is set. The
+// deleted notes also include the ones in the deleted books that had the label.
+func ListNotes(ctx context.DnoteCtx, bookLabel string, opts ListNotesOptions) ([]Note, error) {
+	db := ctx.DB
+
+	bookUUIDs := []string{}
+	bookUUID, foundLabel, err := findBook(db, bookLabel, opts.InsensitiveBook)
+	if err == nil {
+		bookUUIDs = append(bookUUIDs, bookUUID)
+		bookLabel = foundLabel
+	} else if err != ErrBookNotFound || !opts.Deleted {
+		return nil, err
+	}
+
+	if opts.Deleted {
+		deletedUUIDs, err := findDeletedBooks(db, bookLabel, opts.InsensitiveBook)
+		if err != nil {
+			return nil, err
+		}
+
+		bookUUIDs = append(bookUUIDs, deletedUUIDs...)
+		if len(bookUUIDs) == 0 {
+			return nil, ErrBookNotFound
+		}
+	}
+
+	placeholders := []string{}
+	args := []interface{}{}
+	for _, uuid := range bookUUIDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, uuid)
+	}
+	args = append(args, opts.Deleted)
+
+	query := fmt.Sprintf("SELECT rowid, body, uuid, added_on, edited_on, source, pinned FROM notes WHERE book_uuid IN (%s) AND deleted = ?", strings.Join(placeholders, ", "))
+	if opts.SinceID > 0 {
+		query += " AND rowid > ?"
+		args = append(args, opts.SinceID)
+	}
+
+	order := "added_on ASC"
+	if !opts.IgnorePins {
+		order = "pinned DESC, added_on ASC"
+	}
+
+	rows, err := db.Query(fmt.Sprintf("%s ORDER BY %s;", query, order), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []Note{}
+	for rows.Next() {
+		n := Note{BookLabel: bookLabel}
+		if err := rows.Scan(&n.RowID, &n.Body, &n.UUID, &n.AddedOn, &n.EditedOn, &n.Source, &n.Pinned); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating notes")
+	}
+
+	return ret, nil
+}
+
+// AddNote adds a note with the given content to the book with the given label,
+// creating the book if it does not exist. addedOn is in unix nanoseconds.
+func AddNote(ctx context.DnoteCtx, bookLabel, content string, addedOn int64) (Note, error) {
+	ret := Note{BookLabel: bookLabel, Body: content, AddedOn: addedOn, Source: database.NoteSourceCLI}
+
+	tx, err := ctx.DB.Begin()
+	if err != nil {
+		return ret, errors.Wrap(err, "beginning a transaction")
+	}
+
+	var bookUUID string
+	err = tx.QueryRow("SELECT uuid FROM books WHERE label = ?", bookLabel).Scan(&bookUUID)
+	if err == sql.ErrNoRows {
+		bookUUID, err = utils.GenerateUUID()
+		if err != nil {
+			tx.Rollback()
+			return ret, errors.Wrap(err, "generating uuid")
+		}
+
+		b := database.NewBook(bookUUID, bookLabel, 0, false, true)
+		if err = b.Insert(tx); err != nil {
+			tx.Rollback()
+			return ret, errors.Wrap(err, "creating the book")
+		}
+	} else if err != nil {
+		tx.Rollback()
+		return ret, errors.Wrap(err, "finding the book")
+	}
+
+	ret.UUID, err = utils.GenerateUUID()
+	if err != nil {
+		tx.Rollback()
+		return ret, errors.Wrap(err, "generating uuid")
+	}
+
+	n := database.NewNote(ret.UUID, bookUUID, content, addedOn, 0, 0, false, false, true)
+	n.Source = ret.Source
+	if err = n.Insert(tx); err != nil {
+		tx.Rollback()
+		return ret, errors.Wrap(err, "creating the note")
+	}
+
+	if err = tx.QueryRow("SELECT rowid FROM notes WHERE uuid = ?", ret.UUID).Scan(&ret.RowID); err != nil {
+		tx.Rollback()
+		return ret, errors.Wrap(err, "getting the note rowid")
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return ret, errors.Wrap(err, "committing a transaction")
+	}
+
+	return ret, nil
+}
+
+// EditNote replaces the content of the active note with the given rowid
+func EditNote(ctx context.DnoteCtx, rowID int, content string) error {
+	note, err := database.GetActiveNote(ctx.DB, rowID)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("note %d not found", rowID)
+	} else if err != nil {
+		return errors.Wrap(err, "finding the note")
+	}
+
+	if note.Body == content {
+		return errors.New("Nothing changed")
+	}
+
+	if err := database.UpdateNoteContent(ctx.DB, ctx.Clock, rowID, content); err != nil {
+		return errors.Wrap(err, "updating the note")
+	}
+
+	return nil
+}
+
+// AdjacentNotes returns the rowids of the notes added right before and right
+// after the active note with the given rowid in the same book. A rowid is 0 if
+// there is no such note.
+func AdjacentNotes(ctx context.DnoteCtx, rowID int) (int, int, error) {
+	var bookUUID string
+	var addedOn int64
+	err := ctx.DB.QueryRow("SELECT book_uuid, added_on FROM notes WHERE rowid = ? AND deleted = false", rowID).
+		Scan(&bookUUID, &addedOn)
+	if err == sql.ErrNoRows {
+		return 0, 0, errors.Errorf("note %d not found", rowID)
+	} else if err != nil {
+		return 0, 0, errors.Wrap(err, "finding the note")
+	}
+
+	// notes added at the same time are ordered by rowid
+	var prev, next int
+	err = ctx.DB.QueryRow(`SELECT rowid FROM notes
+	WHERE book_uuid = ? AND deleted = false
+		AND (added_on < ? OR (added_on = ? AND rowid < ?))
+	ORDER BY added_on DESC, rowid DESC
+	LIMIT 1`, bookUUID, addedOn, addedOn, rowID).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, 0, errors.Wrap(err, "finding the previous note")
+	}
+
+	err = ctx.DB.QueryRow(`SELECT rowid FROM notes
+	WHERE book_uuid = ? AND deleted = false
+		AND (added_on > ? OR (added_on = ? AND rowid > ?))
+	ORDER BY added_on ASC, rowid ASC
+	LIMIT 1`, bookUUID, addedOn, addedOn, rowID).Scan(&next)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, 0, errors.Wrap(err, "finding the next note")
+	}
+
+	return prev, next, nil
+}
+
+// ErrEmptyNote is returned when a note body that has only whitespace is about
+// to be saved
+var ErrEmptyNote = errors.New("empty note not saved. Pass --allow-empty to save it anyway")
+
+// IsEmptyBody returns true if the note body has only whitespace
+func IsEmptyBody(content string) bool {
+	return strings.TrimSpace(content) == ""
+}
+
+// TrimBlankLines removes the lines that have only whitespace at the start and
+// at the end of the content. The blank lines in between are kept, and so is
+// the line break that ends the last line. It returns an empty string if the
+// content has only whitespace.
+func TrimBlankLines(content string) string {
+	lines := strings.Split(content, "\n")
+
+	start, end := -1, -1
+	for idx, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if start == -1 {
+			start = idx
+		}
+		end = idx
+	}
+
+	if start == -1 {
+		return ""
+	}
+
+	ret := strings.Join(lines[start:end+1], "\n")
+	if end < len(lines)-1 {
+		ret += "\n"
+	}
+
+	return ret
+}
@@ -0,0 +1,379 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func TestListNotes(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	t.Run("active", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "n1 body", AddedOn: 1542058875},
+			{RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body", AddedOn: 1542058876},
+		}, "notes mismatch")
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{Deleted: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 3, UUID: "n3-uuid", BookLabel: "js", Body: "n3 body", AddedOn: 1542058877},
+		}, "notes mismatch")
+	})
+
+	t.Run("since id", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{SinceID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body", AddedOn: 1542058876},
+		}, "notes mismatch")
+	})
+
+	t.Run("since the latest id", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{SinceID: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{}, "notes mismatch")
+	})
+
+	t.Run("nonexistent book", func(t *testing.T) {
+		_, err := ListNotes(ctx, "foo", ListNotesOptions{})
+		assert.Equal(t, err.Error(), "book not found", "error mismatch")
+	})
+}
+
+func TestListNotesDeletedBook(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	database.MustExec(t, "inserting a deleted book", ctx.DB, "INSERT INTO books (uuid, label, original_label, deleted) VALUES (?, ?, ?, ?)", "b1-uuid", "b1-random-label", "css", true)
+	database.MustExec(t, "inserting a deleted note", ctx.DB, "INSERT INTO notes (rowid, uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?, ?)", 1, "n1-uuid", "b1-uuid", "n1 body", 1542058875, true)
+
+	t.Run("deleted", func(t *testing.T) {
+		got, err := ListNotes(ctx, "css", ListNotesOptions{Deleted: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 1, UUID: "n1-uuid", BookLabel: "css", Body: "n1 body", AddedOn: 1542058875},
+		}, "notes mismatch")
+	})
+
+	t.Run("deleted insensitive", func(t *testing.T) {
+		got, err := ListNotes(ctx, "CSS", ListNotesOptions{Deleted: true, InsensitiveBook: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, len(got), 1, "note count mismatch")
+	})
+
+	t.Run("active", func(t *testing.T) {
+		_, err := ListNotes(ctx, "css", ListNotesOptions{})
+		assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+	})
+}
+
+func TestListNotesPinned(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+	database.MustExec(t, "pinning n2", ctx.DB, "UPDATE notes SET pinned = ? WHERE uuid = ?", true, "n2-uuid")
+
+	t.Run("pinned first", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body", AddedOn: 1542058876, Pinned: true},
+			{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "n1 body", AddedOn: 1542058875},
+		}, "notes mismatch")
+	})
+
+	t.Run("ignore pins", func(t *testing.T) {
+		got, err := ListNotes(ctx, "js", ListNotesOptions{IgnorePins: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 1, UUID: "n1-uuid", BookLabel: "js", Body: "n1 body", AddedOn: 1542058875},
+			{RowID: 2, UUID: "n2-uuid", BookLabel: "js", Body: "n2 body", AddedOn: 1542058876, Pinned: true},
+		}, "notes mismatch")
+	})
+}
+
+func TestListNotesInsensitiveBook(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+	database.MustExec(t, "inserting JS", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b5-uuid", "JS")
+	database.MustExec(t, "inserting CSS", ctx.DB, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b6-uuid", "CSS")
+	database.MustExec(t, "inserting n5", ctx.DB, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "b5-uuid", "n5 body", 1542058879)
+	database.MustExec(t, "inserting n6", ctx.DB, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n6-uuid", "b3-uuid", "n6 body", 1542058880)
+
+	t.Run("different case", func(t *testing.T) {
+		got, err := ListNotes(ctx, "ALGORITHMS", ListNotesOptions{InsensitiveBook: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 6, UUID: "n6-uuid", BookLabel: "algorithms", Body: "n6 body", AddedOn: 1542058880},
+		}, "notes mismatch")
+	})
+
+	t.Run("exact match preferred", func(t *testing.T) {
+		got, err := ListNotes(ctx, "JS", ListNotesOptions{InsensitiveBook: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.DeepEqual(t, got, []Note{
+			{RowID: 5, UUID: "n5-uuid", BookLabel: "JS", Body: "n5 body", AddedOn: 1542058879},
+		}, "notes mismatch")
+	})
+
+	t.Run("case sensitive by default", func(t *testing.T) {
+		_, err := ListNotes(ctx, "ALGORITHMS", ListNotesOptions{})
+		assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := ListNotes(ctx, "Css", ListNotesOptions{InsensitiveBook: true})
+		assert.NotEqual(t, err, nil, "should fail")
+		assert.Equal(t, err.Error(), "'Css' matches more than one book: css, CSS", "error mismatch")
+	})
+}
+
+func TestAddNote(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	t.Run("existing book", func(t *testing.T) {
+		n, err := AddNote(ctx, "js", "foo", 1542058880)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var bookUUID, body, source string
+		var addedOn int64
+		var dirty bool
+		database.MustScan(t, "getting the note", ctx.DB.QueryRow("SELECT book_uuid, body, added_on, dirty, source FROM notes WHERE rowid = ?", n.RowID), &bookUUID, &body, &addedOn, &dirty, &source)
+
+		assert.Equal(t, n.BookLabel, "js", "book label mismatch")
+		assert.Equal(t, bookUUID, "b1-uuid", "book uuid mismatch")
+		assert.Equal(t, body, "foo", "body mismatch")
+		assert.Equal(t, addedOn, int64(1542058880), "added_on mismatch")
+		assert.Equal(t, dirty, true, "dirty mismatch")
+		assert.Equal(t, source, database.NoteSourceCLI, "source mismatch")
+		assert.Equal(t, n.Source, database.NoteSourceCLI, "returned source mismatch")
+	})
+
+	t.Run("new book", func(t *testing.T) {
+		n, err := AddNote(ctx, "linux", "bar", 1542058881)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var label string
+		var dirty bool
+		database.MustScan(t, "getting the book", ctx.DB.QueryRow("SELECT books.label, books.dirty FROM notes INNER JOIN books ON books.uuid = notes.book_uuid WHERE notes.uuid = ?", n.UUID), &label, &dirty)
+
+		assert.Equal(t, label, "linux", "label mismatch")
+		assert.Equal(t, dirty, true, "dirty mismatch")
+	})
+}
+
+func TestEditNote(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+	setupBooks(t, ctx.DB)
+
+	t.Run("active note", func(t *testing.T) {
+		if err := EditNote(ctx, 1, "updated body"); err != nil {
+			t.Fatal(err)
+		}
+
+		var body string
+		var editedOn int64
+		database.MustScan(t, "getting the note", ctx.DB.QueryRow("SELECT body, edited_on FROM notes WHERE rowid = ?", 1), &body, &editedOn)
+
+		assert.Equal(t, body, "updated body", "body mismatch")
+		assert.Equal(t, editedOn, ctx.Clock.Now().UnixNano(), "edited_on mismatch")
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		err := EditNote(ctx, 2, "n2 body")
+		assert.Equal(t, err.Error(), "Nothing changed", "error mismatch")
+	})
+
+	t.Run("deleted note", func(t *testing.T) {
+		err := EditNote(ctx, 3, "foo")
+		assert.Equal(t, err.Error(), "note 3 not found", "error mismatch")
+	})
+}
+
+func TestAdjacentNotes(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "css")
+	// rowid 1..6
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 300)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 100)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b2-uuid", "n3 body", 200)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n4-uuid", "b1-uuid", "", 200, true)
+	database.MustExec(t, "inserting n5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "b1-uuid", "n5 body", 300)
+	database.MustExec(t, "inserting n6", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n6-uuid", "b2-uuid", "n6 body", 100)
+
+	testCases := []struct {
+		rowID        int
+		expectedPrev int
+		expectedNext int
+	}{
+		// the first note in the book
+		{rowID: 2, expectedPrev: 0, expectedNext: 1},
+		// notes added at the same time are ordered by rowid
+		{rowID: 1, expectedPrev: 2, expectedNext: 5},
+		// the last note in the book
+		{rowID: 5, expectedPrev: 1, expectedNext: 0},
+		{rowID: 6, expectedPrev: 0, expectedNext: 3},
+		{rowID: 3, expectedPrev: 6, expectedNext: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("note %d", tc.rowID), func(t *testing.T) {
+			prev, next, err := AdjacentNotes(ctx, tc.rowID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, prev, tc.expectedPrev, "prev mismatch")
+			assert.Equal(t, next, tc.expectedNext, "next mismatch")
+		})
+	}
+
+	t.Run("deleted note", func(t *testing.T) {
+		_, _, err := AdjacentNotes(ctx, 4)
+
+		assert.Equal(t, err.Error(), "note 4 not found", "error mismatch")
+	})
+}
+
+func TestTrimBlankLines(t *testing.T) {
+	testCases := []struct {
+		content  string
+		expected string
+	}{
+		{
+			content:  "foo",
+			expected: "foo",
+		},
+		{
+			content:  "foo\n",
+			expected: "foo\n",
+		},
+		{
+			content:  "\n\n  \nfoo\nbar\n\n\t\n",
+			expected: "foo\nbar\n",
+		},
+		{
+			content:  "\n\nfoo\n\n\nbar",
+			expected: "foo\n\n\nbar",
+		},
+		{
+			content:  "\n  indented\n",
+			expected: "  indented\n",
+		},
+		{
+			content:  "\r\n\r\nfoo\r\n\r\n",
+			expected: "foo\r\n",
+		},
+		{
+			content:  " \n\t\n",
+			expected: "",
+		},
+		{
+			content:  "",
+			expected: "",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, TrimBlankLines(tc.content), tc.expected, "result mismatch")
+		})
+	}
+}
@@ -0,0 +1,374 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SortRank orders the search results by relevance, the most relevant first
+	SortRank = "rank"
+	// SortAlpha orders the search results alphabetically by the note content
+	SortAlpha = "alpha"
+	// SortBook groups the search results by book label, and orders the notes
+	// in each book by relevance
+	SortBook = "book"
+	// SortDate orders the search results by the time the notes were added, the
+	// most recent first
+	SortDate = "date"
+)
+
+// SearchSorts are the supported orders of the search results
+var SearchSorts = []string{SortRank, SortAlpha, SortBook, SortDate}
+
+// Query is a search for notes
+type Query struct {
+	// Keywords are the words that must all appear in the note. A keyword
+	// matches the words starting with it.
+	Keywords []string
+	// Phrase requires the keywords to appear next to one another, in order,
+	// rather than anywhere in the note
+	Phrase bool
+	// Word requires the keywords to appear as whole words rather than as the
+	// start of longer words
+	Word bool
+	// BookNames restricts the search to the books whose label matches any of them
+	BookNames []string
+	// ExcludeBookNames leaves out the notes in the books with these labels
+	ExcludeBookNames []string
+	// All includes the notes in archived books
+	All bool
+	// ArchivedOnly searches only the notes in archived books
+	ArchivedOnly bool
+	// Source restricts the search to the notes created from the source, such
+	// as "cli" or "web"
+	Source string
+	// Sort is the order of the results, one of SearchSorts. If empty, the
+	// most relevant notes come first.
+	Sort string
+	// IncludeDeleted also searches the deleted notes
+	IncludeDeleted bool
+	// Limit is the maximum number of notes returned, the first ones in the
+	// order of the results. Zero means no limit.
+	Limit int
+}
+
+// maxInt is the largest value of int
+const maxInt = int(^uint(0) >> 1)
+
+// fetchLimit returns the number of matching notes to read to tell if more
+// notes match than the limit
+func fetchLimit(limit int) int {
+	if limit == maxInt {
+		return limit
+	}
+
+	return limit + 1
+}
+
+// Result is a note matching a search
+type Result struct {
+	RowID     int    `json:"rowid"`
+	BookLabel string `json:"book_label"`
+	Body      string `json:"body"`
+	Archive   bool   `json:"archive"`
+	AddedOn   int64  `json:"added_on"`
+	EditedOn  int64  `json:"edited_on"`
+	Source    string `json:"source"`
+	Deleted   bool   `json:"deleted"`
+}
+
+// searchTerms returns the terms to look for, which are the keywords, or the
+// keywords joined by spaces if they must appear as a phrase
+func searchTerms(q Query) []string {
+	if q.Phrase {
+		return []string{strings.Join(q.Keywords, " ")}
+	}
+
+	return q.Keywords
+}
+
+// buildMatchQuery returns the FTS5 query for the keywords. Each term is quoted
+// as an FTS5 string so that it is matched as a sequence of words rather than
+// parsed as a query, and matches the words starting with it unless whole words
+// are asked for.
+func buildMatchQuery(q Query) string {
+	parts := []string{}
+	for _, term := range searchTerms(q) {
+		part := fmt.Sprintf("\"%s\"", strings.ReplaceAll(term, "\"", "\"\""))
+		if !q.Word {
+			part = part + "*"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " AND ")
+}
+
+// buildSearchQuery returns the SQL statement and its arguments to find the
+// notes matching the query, the most relevant first unless another sort is
+// asked for. The conditions are joined with AND.
+func buildSearchQuery(q Query) (string, []interface{}, error) {
+	// the conditions on the books and the notes that apply to both the notes
+	// in the index and the deleted ones. Removed books have random labels, and
+	// are matched by the original ones.
+	filters := []string{}
+	filterArgs := []interface{}{}
+
+	if len(q.BookNames) > 0 {
+		bookConds := []string{}
+		for _, name := range q.BookNames {
+			bookConds = append(bookConds, "COALESCE(books.original_label, books.label) LIKE ?")
+			filterArgs = append(filterArgs, name)
+		}
+
+		filters = append(filters, fmt.Sprintf("(%s)", strings.Join(bookConds, " OR ")))
+	}
+
+	if len(q.ExcludeBookNames) > 0 {
+		placeholders := []string{}
+		for _, name := range q.ExcludeBookNames {
+			placeholders = append(placeholders, "?")
+			filterArgs = append(filterArgs, name)
+		}
+
+		filters = append(filters, fmt.Sprintf("COALESCE(books.original_label, books.label) NOT IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if q.Source != "" {
+		filters = append(filters, "notes.source = ?")
+		filterArgs = append(filterArgs, q.Source)
+	}
+
+	if q.ArchivedOnly {
+		filters = append(filters, "books.archive = ?")
+		filterArgs = append(filterArgs, true)
+	} else if len(q.BookNames) == 0 && !q.All {
+		filters = append(filters, "books.archive = ?")
+		filterArgs = append(filterArgs, false)
+	}
+
+	var order string
+	switch q.Sort {
+	case "", SortRank:
+		// bm25 is lower for the more relevant notes
+		order = "score ASC, note_id ASC"
+	case SortBook:
+		order = "book_label ASC, score ASC, note_id ASC"
+	case SortAlpha:
+		order = "body COLLATE NOCASE ASC, note_id ASC"
+	case SortDate:
+		order = "added_on DESC"
+	default:
+		return "", nil, errors.Errorf("unknown sort '%s'", q.Sort)
+	}
+
+	conds := append([]string{"note_fts MATCH ?", "notes.deleted = ?"}, filters...)
+	args := append([]interface{}{buildMatchQuery(q), false}, filterArgs...)
+
+	sql := fmt.Sprintf(`SELECT
+		notes.rowid AS note_id,
+		COALESCE(books.original_label, books.label) AS book_label,
+		notes.body AS body,
+		books.archive AS archive,
+		notes.added_on AS added_on,
+		notes.edited_on,
+		notes.source,
+		notes.deleted,
+		bm25(note_fts) AS score
+	FROM note_fts
+	INNER JOIN notes ON notes.rowid = note_fts.rowid
+	INNER JOIN books ON notes.book_uuid = books.uuid
+	WHERE %s`, strings.Join(conds, " AND "))
+
+	// the index has only the notes that are not deleted, so the deleted ones
+	// are matched with LIKE and come after the ranked ones
+	if q.IncludeDeleted {
+		deletedConds := []string{"notes.deleted = ?"}
+		args = append(args, true)
+		for _, term := range searchTerms(q) {
+			deletedConds = append(deletedConds, "notes.body LIKE ?")
+			args = append(args, "%"+term+"%")
+		}
+		deletedConds = append(deletedConds, filters...)
+		args = append(args, filterArgs...)
+
+		sql = fmt.Sprintf(`%s
+	UNION ALL
+	SELECT
+		notes.rowid AS note_id,
+		COALESCE(books.original_label, books.label) AS book_label,
+		notes.body AS body,
+		books.archive AS archive,
+		notes.added_on AS added_on,
+		notes.edited_on,
+		notes.source,
+		notes.deleted,
+		0 AS score
+	FROM notes
+	INNER JOIN books ON notes.book_uuid = books.uuid
+	WHERE %s`, sql, strings.Join(deletedConds, " AND "))
+	}
+
+	sql = fmt.Sprintf("%s\n\tORDER BY %s", sql, order)
+
+	// the notes are narrowed down to the whole words after they are read, so
+	// the limit cannot be applied to the rows with --word
+	if q.Limit > 0 && !q.Word {
+		sql = fmt.Sprintf("%s\n\tLIMIT ?", sql)
+		args = append(args, fetchLimit(q.Limit))
+	}
+
+	return sql, args, nil
+}
+
+// Search returns the notes matching the given query. Notes in archived books
+// are excluded unless the query is restricted to a book or asks for them.
+// If the query has a limit, at most that many notes are returned, and the
+// returned bool tells if more notes matched.
+func Search(ctx context.DnoteCtx, q Query) ([]Result, bool, error) {
+	for _, excluded := range q.ExcludeBookNames {
+		for _, name := range q.BookNames {
+			if name == excluded {
+				return nil, false, errors.Errorf("book '%s' cannot be both searched and excluded", name)
+			}
+		}
+	}
+
+	sql, args, err := buildSearchQuery(q)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rows, err := ctx.DB.Query(sql, args...)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "querying notes")
+	}
+	defer rows.Close()
+
+	ret := []Result{}
+	for rows.Next() {
+		var r Result
+		var score float64
+		if err := rows.Scan(&r.RowID, &r.BookLabel, &r.Body, &r.Archive, &r.AddedOn, &r.EditedOn, &r.Source, &r.Deleted, &score); err != nil {
+			return nil, false, errors.Wrap(err, "scanning a row")
+		}
+
+		// the index matches the stems of the words, and LIKE any part of the
+		// words, so the notes are narrowed down to the whole words here
+		if q.Word && !matchWords(r.Body, q.Keywords, q.Phrase) {
+			continue
+		}
+
+		ret = append(ret, r)
+
+		// the rows are in the order of the results, so the rest can be left
+		// unread once the limit is exceeded
+		if q.Limit > 0 && len(ret) > q.Limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, errors.Wrap(err, "iterating notes")
+	}
+
+	if q.Limit > 0 && len(ret) > q.Limit {
+		return ret[:q.Limit], true, nil
+	}
+
+	return ret, false, nil
+}
+
+// MatchFoldAt returns the length in bytes of the text at the start of s that
+// matches the phrase, ignoring case, and false if there is no such text
+func MatchFoldAt(s, phrase string) (int, bool) {
+	n := 0
+	for _, pr := range phrase {
+		if n >= len(s) {
+			return 0, false
+		}
+
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !strings.EqualFold(string(r), string(pr)) {
+			return 0, false
+		}
+
+		n += size
+	}
+
+	return n, true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// IsWholeWord returns true if the text between the start and end offsets of s
+// is neither preceded nor followed by a letter, a digit or an underscore
+func IsWholeWord(s string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(s[:start]); isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[end:]); isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchWords returns true if all keywords appear in the body as whole words,
+// ignoring case. If phrase is true, the keywords must appear next to one
+// another, separated by a space.
+func matchWords(body string, keywords []string, phrase bool) bool {
+	words := keywords
+	if phrase {
+		words = []string{strings.Join(keywords, " ")}
+	}
+
+	for _, word := range words {
+		found := false
+		for i := 0; i < len(body); {
+			if n, ok := MatchFoldAt(body[i:], word); ok && IsWholeWord(body, i, i+n) {
+				found = true
+				break
+			}
+
+			_, size := utf8.DecodeRuneInString(body[i:])
+			i += size
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,735 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/database"
+)
+
+func TestBuildMatchQuery(t *testing.T) {
+	testCases := []struct {
+		query    Query
+		expected string
+	}{
+		{
+			query:    Query{Keywords: []string{"foo"}},
+			expected: `"foo"*`,
+		},
+		{
+			query:    Query{Keywords: []string{"foo", "bar"}},
+			expected: `"foo"* AND "bar"*`,
+		},
+		{
+			query:    Query{Keywords: []string{"foo", "bar"}, Phrase: true},
+			expected: `"foo bar"*`,
+		},
+		{
+			query:    Query{Keywords: []string{"foo", "bar"}, Word: true},
+			expected: `"foo" AND "bar"`,
+		},
+		{
+			query:    Query{Keywords: []string{`say "hi"`, "OR"}},
+			expected: `"say ""hi"""* AND "OR"*`,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, buildMatchQuery(tc.query), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	testCases := []struct {
+		query          Query
+		expectedWheres []string
+		expectedOrder  string
+		expectedArgs   []interface{}
+	}{
+		{
+			query:          Query{Keywords: []string{"foo", "bar"}},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND books.archive = ?"},
+			expectedOrder:  "score ASC, note_id ASC",
+			expectedArgs:   []interface{}{`"foo"* AND "bar"*`, false, false},
+		},
+		{
+			query: Query{Keywords: []string{"foo", "bar"}, Phrase: true, All: true, IncludeDeleted: true},
+			expectedWheres: []string{
+				"note_fts MATCH ? AND notes.deleted = ?",
+				"notes.deleted = ? AND notes.body LIKE ?",
+			},
+			expectedOrder: "score ASC, note_id ASC",
+			expectedArgs:  []interface{}{`"foo bar"*`, false, true, "%foo bar%"},
+		},
+		{
+			query: Query{Keywords: []string{"foo", "bar"}, Source: "web", IncludeDeleted: true, Sort: SortBook},
+			expectedWheres: []string{
+				"note_fts MATCH ? AND notes.deleted = ? AND notes.source = ? AND books.archive = ?",
+				"notes.deleted = ? AND notes.body LIKE ? AND notes.body LIKE ? AND notes.source = ? AND books.archive = ?",
+			},
+			expectedOrder: "book_label ASC, score ASC, note_id ASC",
+			expectedArgs:  []interface{}{`"foo"* AND "bar"*`, false, "web", false, true, "%foo%", "%bar%", "web", false},
+		},
+		{
+			query:          Query{Keywords: []string{"foo"}, BookNames: []string{"js", "css"}, Sort: SortDate},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND (COALESCE(books.original_label, books.label) LIKE ? OR COALESCE(books.original_label, books.label) LIKE ?)"},
+			expectedOrder:  "added_on DESC",
+			expectedArgs:   []interface{}{`"foo"*`, false, "js", "css"},
+		},
+		{
+			query:          Query{Keywords: []string{"foo"}, ExcludeBookNames: []string{"js", "css"}, Source: "web", Sort: SortAlpha},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND COALESCE(books.original_label, books.label) NOT IN (?, ?) AND notes.source = ? AND books.archive = ?"},
+			expectedOrder:  "body COLLATE NOCASE ASC, note_id ASC",
+			expectedArgs:   []interface{}{`"foo"*`, false, "js", "css", "web", false},
+		},
+		{
+			query:          Query{Keywords: []string{"foo"}, Limit: 100},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND books.archive = ?"},
+			expectedOrder:  "score ASC, note_id ASC",
+			expectedArgs:   []interface{}{`"foo"*`, false, false, 101},
+		},
+		{
+			query:          Query{Keywords: []string{"foo"}, Word: true, Limit: 100},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND books.archive = ?"},
+			expectedOrder:  "score ASC, note_id ASC",
+			expectedArgs:   []interface{}{`"foo"`, false, false},
+		},
+		{
+			query:          Query{Keywords: []string{"foo"}, ArchivedOnly: true, Word: true},
+			expectedWheres: []string{"note_fts MATCH ? AND notes.deleted = ? AND books.archive = ?"},
+			expectedOrder:  "score ASC, note_id ASC",
+			expectedArgs:   []interface{}{`"foo"`, false, true},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			sql, args, err := buildSearchQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clauses := strings.SplitN(strings.TrimSuffix(sql, "\n\tLIMIT ?"), "\n\tORDER BY ", 2)
+			assert.Equal(t, len(clauses), 2, "sql should have an ORDER BY clause")
+			assert.Equal(t, clauses[1], tc.expectedOrder, "ORDER BY clause mismatch")
+
+			wheres := []string{}
+			for _, part := range strings.Split(clauses[0], "WHERE ")[1:] {
+				wheres = append(wheres, strings.SplitN(part, "\n", 2)[0])
+			}
+			assert.DeepEqual(t, wheres, tc.expectedWheres, "WHERE clauses mismatch")
+			assert.DeepEqual(t, args, tc.expectedArgs, "args mismatch")
+			assert.Equal(t, strings.Count(sql, "?"), len(args), "placeholder count mismatch")
+		})
+	}
+
+	t.Run("unknown sort", func(t *testing.T) {
+		_, _, err := buildSearchQuery(Query{Keywords: []string{"foo"}, Sort: "foo"})
+		assert.Equal(t, err.Error(), "unknown sort 'foo'", "error mismatch")
+	})
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b1-uuid", "js", false)
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b2-uuid", "css", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "foo in js", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "foo in css", 1542058876)
+
+	testCases := []struct {
+		query         Query
+		expectedBooks []string
+	}{
+		{
+			query:         Query{Keywords: []string{"foo"}},
+			expectedBooks: []string{"js"},
+		},
+		{
+			query:         Query{Keywords: []string{"foo"}, All: true},
+			expectedBooks: []string{"js", "css"},
+		},
+		{
+			query:         Query{Keywords: []string{"foo"}, ArchivedOnly: true},
+			expectedBooks: []string{"css"},
+		},
+		{
+			query:         Query{Keywords: []string{"foo"}, BookNames: []string{"js"}, ArchivedOnly: true},
+			expectedBooks: []string{},
+		},
+		{
+			query:         Query{Keywords: []string{"foo"}, BookNames: []string{"css"}, ArchivedOnly: true},
+			expectedBooks: []string{"css"},
+		},
+		{
+			query:         Query{Keywords: []string{"foo", "css"}, All: true},
+			expectedBooks: []string{"css"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			results, _, err := Search(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.BookLabel)
+			}
+
+			assert.DeepEqual(t, got, tc.expectedBooks, "books mismatch")
+		})
+	}
+}
+
+func TestSearchSortDate(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "foo 1", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "foo 2", 1542058877)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "foo 3", 1542058876)
+
+	results, _, err := Search(ctx, Query{Keywords: []string{"foo"}, Sort: SortDate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []int64{}
+	for _, r := range results {
+		got = append(got, r.AddedOn)
+	}
+	assert.DeepEqual(t, got, []int64{1542058877, 1542058876, 1542058875}, "order mismatch")
+
+	_, _, err = Search(ctx, Query{Keywords: []string{"foo"}, Sort: "foo"})
+	assert.Equal(t, err.Error(), "unknown sort 'foo'", "error mismatch")
+}
+
+func TestSearchPhrase(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "merge sort is stable", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "merge two lists and sort them", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "sort before you merge", 1542058877)
+
+	testCases := []struct {
+		query    Query
+		expected []int
+	}{
+		{
+			// the keywords need not be in order, and the shorter notes are more relevant
+			query:    Query{Keywords: []string{"merge", "sort"}},
+			expected: []int{1, 3, 2},
+		},
+		{
+			query:    Query{Keywords: []string{"merge", "sort"}, Phrase: true},
+			expected: []int{1},
+		},
+		{
+			query:    Query{Keywords: []string{"merge sort"}, Phrase: true},
+			expected: []int{1},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			results, _, err := Search(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []int{}
+			for _, r := range results {
+				got = append(got, r.RowID)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "rowids mismatch")
+		})
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "datastructures")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "os")
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b4-uuid", "linux")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "heap sort", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "binary heap", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b3-uuid", "heap memory", 1542058877)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b4-uuid", "heap in the kernel", 1542058878)
+	database.MustExec(t, "inserting n5", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n5-uuid", "b1-uuid", "quick sort", 1542058879)
+
+	testCases := []struct {
+		bookNames []string
+		expected  []string
+	}{
+		{
+			bookNames: []string{},
+			expected:  []string{"algorithms", "datastructures", "os", "linux"},
+		},
+		{
+			bookNames: []string{"algorithms", "datastructures"},
+			expected:  []string{"algorithms", "datastructures"},
+		},
+		{
+			bookNames: []string{"algorithms", "datastructures", "linux"},
+			expected:  []string{"algorithms", "datastructures", "linux"},
+		},
+		{
+			bookNames: []string{"os", "foo"},
+			expected:  []string{"os"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			results, _, err := Search(ctx, Query{Keywords: []string{"heap"}, BookNames: tc.bookNames})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.BookLabel)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "book labels mismatch")
+		})
+	}
+}
+
+func TestSearchExcludeBooks(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "datastructures")
+	database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "os")
+	database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b4-uuid", "linux", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "heap sort", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "binary heap", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b3-uuid", "heap memory", 1542058877)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b4-uuid", "heap in the kernel", 1542058878)
+
+	testCases := []struct {
+		query    Query
+		expected []string
+	}{
+		{
+			query:    Query{ExcludeBookNames: []string{"os"}},
+			expected: []string{"algorithms", "datastructures"},
+		},
+		{
+			query:    Query{ExcludeBookNames: []string{"os", "algorithms"}},
+			expected: []string{"datastructures"},
+		},
+		{
+			query:    Query{ExcludeBookNames: []string{"os"}, All: true},
+			expected: []string{"algorithms", "datastructures", "linux"},
+		},
+		{
+			query:    Query{ExcludeBookNames: []string{"algorithms"}, BookNames: []string{"%s"}},
+			expected: []string{"datastructures", "os"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			q := tc.query
+			q.Keywords = []string{"heap"}
+
+			results, _, err := Search(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.BookLabel)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "book labels mismatch")
+		})
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		_, _, err := Search(ctx, Query{Keywords: []string{"heap"}, BookNames: []string{"os"}, ExcludeBookNames: []string{"os"}})
+		if err == nil {
+			t.Fatal("should fail")
+		}
+
+		assert.Equal(t, err.Error(), "book 'os' cannot be both searched and excluded", "error mismatch")
+	})
+}
+
+func TestSearchSource(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, source) VALUES (?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "heap sort", 1542058875, "cli")
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, source) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "binary heap", 1542058876, "web")
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "heap memory", 1542058877)
+
+	testCases := []struct {
+		source   string
+		expected []string
+	}{
+		{source: "", expected: []string{"heap sort", "binary heap", "heap memory"}},
+		{source: "cli", expected: []string{"heap sort"}},
+		{source: "web", expected: []string{"binary heap"}},
+		{source: "import", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.source, func(t *testing.T) {
+			results, _, err := Search(ctx, Query{Keywords: []string{"heap"}, Source: tc.source})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.Body)
+				if tc.source != "" {
+					assert.Equal(t, r.Source, tc.source, "source mismatch")
+				}
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "bodies mismatch")
+		})
+	}
+}
+
+func TestSearchIncludeDeleted(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "b2-uuid", "archived", true)
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "heap sort", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "binary heap", 1542058876, true)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n3-uuid", "b2-uuid", "heap memory", 1542058877, true)
+
+	testCases := []struct {
+		name            string
+		query           Query
+		expected        []string
+		expectedDeleted []bool
+	}{
+		{
+			name:            "without deleted",
+			query:           Query{Keywords: []string{"heap"}},
+			expected:        []string{"heap sort"},
+			expectedDeleted: []bool{false},
+		},
+		{
+			name:            "with deleted",
+			query:           Query{Keywords: []string{"heap"}, IncludeDeleted: true, Sort: SortAlpha},
+			expected:        []string{"binary heap", "heap sort"},
+			expectedDeleted: []bool{true, false},
+		},
+		{
+			name:            "with deleted in archived books",
+			query:           Query{Keywords: []string{"heap"}, IncludeDeleted: true, All: true, Sort: SortAlpha},
+			expected:        []string{"binary heap", "heap memory", "heap sort"},
+			expectedDeleted: []bool{true, true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, _, err := Search(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			gotDeleted := []bool{}
+			for _, r := range results {
+				got = append(got, r.Body)
+				gotDeleted = append(gotDeleted, r.Deleted)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "bodies mismatch")
+			assert.DeepEqual(t, gotDeleted, tc.expectedDeleted, "deleted mismatch")
+		})
+	}
+}
+
+func TestSearchWord(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "animals")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "the cat sat", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "a category of things", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "concatenate, then Cat.", 1542058877)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b1-uuid", "cats sat", 1542058878)
+
+	testCases := []struct {
+		query    Query
+		expected []string
+	}{
+		{
+			query:    Query{Keywords: []string{"cat"}, Sort: SortAlpha},
+			expected: []string{"n2-uuid", "n4-uuid", "n3-uuid", "n1-uuid"},
+		},
+		{
+			query:    Query{Keywords: []string{"cat"}, Word: true},
+			expected: []string{"n1-uuid", "n3-uuid"},
+		},
+		{
+			query:    Query{Keywords: []string{"cat", "sat"}, Word: true},
+			expected: []string{"n1-uuid"},
+		},
+		{
+			query:    Query{Keywords: []string{"cat", "sat"}, Word: true, Phrase: true},
+			expected: []string{"n1-uuid"},
+		},
+		{
+			query:    Query{Keywords: []string{"ca"}, Word: true},
+			expected: []string{},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			results, _, err := Search(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				var uuid string
+				database.MustScan(t, "getting the uuid", db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", r.RowID), &uuid)
+				got = append(got, uuid)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "linux")
+	database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "git")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "a long note that mentions merge only once among many other unrelated words", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "merge, merge and merge again", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "Merge twice: merge", 1542058877)
+	database.MustExec(t, "inserting n4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "b2-uuid", "git merge", 1542058878)
+
+	testCases := []struct {
+		sort     string
+		expected []string
+	}{
+		{
+			sort:     "",
+			expected: []string{"n2-uuid", "n3-uuid", "n4-uuid", "n1-uuid"},
+		},
+		{
+			sort:     SortRank,
+			expected: []string{"n2-uuid", "n3-uuid", "n4-uuid", "n1-uuid"},
+		},
+		{
+			sort:     SortAlpha,
+			expected: []string{"n1-uuid", "n4-uuid", "n3-uuid", "n2-uuid"},
+		},
+		{
+			sort:     SortBook,
+			expected: []string{"n2-uuid", "n4-uuid", "n3-uuid", "n1-uuid"},
+		},
+		{
+			sort:     SortDate,
+			expected: []string{"n4-uuid", "n3-uuid", "n2-uuid", "n1-uuid"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("sort '%s'", tc.sort), func(t *testing.T) {
+			results, _, err := Search(ctx, Query{Keywords: []string{"merge"}, Sort: tc.sort})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				var uuid string
+				database.MustScan(t, "getting the uuid", db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", r.RowID), &uuid)
+				got = append(got, uuid)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, _, err := Search(ctx, Query{Keywords: []string{"merge"}, Sort: "size"})
+		assert.Equal(t, err.Error(), "unknown sort 'size'", "error mismatch")
+	})
+}
+
+func TestSearchLimit(t *testing.T) {
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "git")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "a long note that mentions merge only once among many other unrelated words", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "merged, merged and merged", 1542058876)
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "git merge", 1542058877)
+
+	testCases := []struct {
+		name              string
+		query             Query
+		expected          []string
+		expectedTruncated bool
+	}{
+		{
+			name:              "most relevant first",
+			query:             Query{Keywords: []string{"merge"}, Limit: 1},
+			expected:          []string{"n2-uuid"},
+			expectedTruncated: true,
+		},
+		{
+			name:              "under the limit",
+			query:             Query{Keywords: []string{"merge"}, Limit: 3},
+			expected:          []string{"n2-uuid", "n3-uuid", "n1-uuid"},
+			expectedTruncated: false,
+		},
+		{
+			name:              "whole words",
+			query:             Query{Keywords: []string{"merge"}, Word: true, Limit: 1},
+			expected:          []string{"n3-uuid"},
+			expectedTruncated: true,
+		},
+		{
+			name:              "whole words under the limit",
+			query:             Query{Keywords: []string{"merge"}, Word: true, Limit: 2},
+			expected:          []string{"n3-uuid", "n1-uuid"},
+			expectedTruncated: false,
+		},
+		{
+			name:              "largest limit",
+			query:             Query{Keywords: []string{"merge"}, Limit: maxInt},
+			expected:          []string{"n2-uuid", "n3-uuid", "n1-uuid"},
+			expectedTruncated: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, truncated, err := Search(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, r := range results {
+				var uuid string
+				database.MustScan(t, "getting the uuid", db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", r.RowID), &uuid)
+				got = append(got, uuid)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+			assert.Equal(t, truncated, tc.expectedTruncated, "truncated mismatch")
+		})
+	}
+}
+
+func TestIsWholeWord(t *testing.T) {
+	testCases := []struct {
+		s        string
+		start    int
+		end      int
+		expected bool
+	}{
+		{s: "cat", start: 0, end: 3, expected: true},
+		{s: "a cat.", start: 2, end: 5, expected: true},
+		{s: "category", start: 0, end: 3, expected: false},
+		{s: "concat", start: 3, end: 6, expected: false},
+		{s: "my_cat", start: 3, end: 6, expected: false},
+		{s: "écat", start: 2, end: 5, expected: false},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, IsWholeWord(tc.s, tc.start, tc.end), tc.expected, "result mismatch")
+		})
+	}
+}
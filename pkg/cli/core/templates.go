@@ -0,0 +1,85 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"database/sql"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/pkg/errors"
+)
+
+// TemplateData is the data available to a book template
+type TemplateData struct {
+	// Book is the label of the book the note is added to
+	Book string
+	// Now is the time the note is added on, which is given by --date if backdated
+	Now time.Time
+}
+
+// GetBookTemplate returns the template of the book with the given label, and
+// whether the book has one
+func GetBookTemplate(ctx context.DnoteCtx, bookLabel string) (string, bool, error) {
+	var body string
+	err := ctx.DB.QueryRow("SELECT body FROM book_templates WHERE book_label = ?", bookLabel).Scan(&body)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, errors.Wrap(err, "querying the template")
+	}
+
+	return body, true, nil
+}
+
+// SetBookTemplate sets the template of the book with the given label. The book
+// does not need to exist yet. A blank template removes the template.
+func SetBookTemplate(ctx context.DnoteCtx, bookLabel, body string) error {
+	if IsEmptyBody(body) {
+		if _, err := ctx.DB.Exec("DELETE FROM book_templates WHERE book_label = ?", bookLabel); err != nil {
+			return errors.Wrap(err, "removing the template")
+		}
+
+		return nil
+	}
+
+	if _, err := ctx.DB.Exec("INSERT OR REPLACE INTO book_templates (book_label, body) VALUES (?, ?)", bookLabel, body); err != nil {
+		return errors.Wrap(err, "saving the template")
+	}
+
+	return nil
+}
+
+// RenderBookTemplate executes the book template as a Go template with the
+// given data. e.g. '# {{.Now.Format "2006-01-02"}}'
+func RenderBookTemplate(body string, data TemplateData) (string, error) {
+	tmpl, err := template.New("book").Parse(body)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing the template")
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrap(err, "executing the template")
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,95 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/context"
+)
+
+func TestBookTemplate(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:  "../tmp",
+		Cache: "../tmp",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	check := func(t *testing.T, label, expected string, expectedOK bool) {
+		got, ok, err := GetBookTemplate(ctx, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, ok, expectedOK, "ok mismatch")
+		assert.Equal(t, got, expected, "template mismatch")
+	}
+
+	check(t, "journal", "", false)
+
+	if err := SetBookTemplate(ctx, "journal", "# {{.Book}}"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "journal", "# {{.Book}}", true)
+	check(t, "snippets", "", false)
+
+	if err := SetBookTemplate(ctx, "journal", "# today"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "journal", "# today", true)
+
+	if err := SetBookTemplate(ctx, "journal", " \n"); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "journal", "", false)
+}
+
+func TestRenderBookTemplate(t *testing.T) {
+	now := time.Date(2019, time.June, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		body     string
+		expected string
+	}{
+		{body: "plain", expected: "plain"},
+		{body: "# {{.Book}}", expected: "# journal"},
+		{body: "# {{.Now.Format \"2006-01-02\"}}\n\n", expected: "# 2019-06-01\n\n"},
+		{body: "```\n\n```", expected: "```\n\n```"},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			got, err := RenderBookTemplate(tc.body, TemplateData{Book: "journal", Now: now})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := RenderBookTemplate("{{.Book", TemplateData{})
+		assert.NotEqual(t, err, nil, "should fail")
+	})
+}
@@ -32,6 +32,17 @@ type Book struct {
 	Dirty   bool   `json:"dirty"`
 }
 
+const (
+	// NoteSourceCLI is the source of the notes added with the command line interface
+	NoteSourceCLI = "cli"
+	// NoteSourceWeb is the source of the notes added with the web application
+	NoteSourceWeb = "web"
+	// NoteSourceAPI is the source of the notes added with the API by other programs
+	NoteSourceAPI = "api"
+	// NoteSourceImport is the source of the notes added by importing an export
+	NoteSourceImport = "import"
+)
+
 // Note represents a note
 type Note struct {
 	RowID    int    `json:"rowid"`
@@ -44,6 +55,9 @@ type Note struct {
 	Public   bool   `json:"public"`
 	Deleted  bool   `json:"deleted"`
 	Dirty    bool   `json:"dirty"`
+	// Source is where the note was created, such as NoteSourceCLI. It is empty
+	// for the notes created before the source was recorded.
+	Source string `json:"source"`
 }
 
 // NewNote constructs a note with the given data
@@ -63,8 +77,8 @@ func NewNote(uuid, bookUUID, body string, addedOn, editedOn int64, usn int, publ
 
 // Insert inserts a new note
 func (n Note) Insert(db *DB) error {
-	_, err := db.Exec("INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, n.Dirty)
+	_, err := db.Exec("INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty, source) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		n.UUID, n.BookUUID, n.Body, n.AddedOn, n.EditedOn, n.USN, n.Public, n.Deleted, n.Dirty, n.Source)
 
 	if err != nil {
 		return errors.Wrapf(err, "inserting note with uuid %s", n.UUID)
@@ -109,6 +109,44 @@ func GetNoteInfo(db *DB, noteRowID int) (NoteInfo, error) {
 	return ret, nil
 }
 
+// NoteMove is a record of a note having been moved between books
+type NoteMove struct {
+	FromBookLabel string
+	ToBookLabel   string
+	MovedOn       int64
+}
+
+// GetNoteMoves returns the moves of the note with the given uuid, oldest first.
+// A book that no longer exists is shown by its uuid.
+func GetNoteMoves(db *DB, noteUUID string) ([]NoteMove, error) {
+	rows, err := db.Query(`SELECT COALESCE(from_books.label, note_moves.from_book_uuid),
+			COALESCE(to_books.label, note_moves.to_book_uuid), note_moves.moved_on
+			FROM note_moves
+			LEFT JOIN books AS from_books ON from_books.uuid = note_moves.from_book_uuid
+			LEFT JOIN books AS to_books ON to_books.uuid = note_moves.to_book_uuid
+			WHERE note_moves.note_uuid = ?
+			ORDER BY note_moves.moved_on ASC`, noteUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying note moves")
+	}
+	defer rows.Close()
+
+	ret := []NoteMove{}
+	for rows.Next() {
+		var m NoteMove
+		if err := rows.Scan(&m.FromBookLabel, &m.ToBookLabel, &m.MovedOn); err != nil {
+			return nil, errors.Wrap(err, "scanning a row")
+		}
+
+		ret = append(ret, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating note moves")
+	}
+
+	return ret, nil
+}
+
 // BookInfo is a basic information about a book
 type BookInfo struct {
 	RowID int
@@ -146,6 +184,18 @@ func GetBookUUID(db *DB, label string) (string, error) {
 	return ret, nil
 }
 
+// CountBookNotes returns the number of notes in the book with the given uuid
+// that are not deleted
+func CountBookNotes(db *DB, bookUUID string) (int, error) {
+	var ret int
+	err := db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND deleted = false", bookUUID).Scan(&ret)
+	if err != nil {
+		return ret, errors.Wrap(err, "counting notes")
+	}
+
+	return ret, nil
+}
+
 // UpdateBookName updates a book name
 func UpdateBookName(db *DB, uuid string, name string) error {
 	_, err := db.Exec(`UPDATE books
@@ -209,10 +259,27 @@ func UpdateNoteContent(db *DB, c clock.Clock, rowID int, content string) error {
 	return nil
 }
 
-// UpdateNoteBook moves the note to a different book and marks the note as dirty
+// UpdateNotePinned pins or unpins the note. Pins are local and are not synced,
+// so the note is not marked as dirty.
+func UpdateNotePinned(db *DB, rowID int, pinned bool) error {
+	_, err := db.Exec("UPDATE notes SET pinned = ? WHERE rowid = ?", pinned, rowID)
+	if err != nil {
+		return errors.Wrap(err, "updating the note")
+	}
+
+	return nil
+}
+
+// UpdateNoteBook moves the note to a different book and marks the note as dirty.
+// The move is recorded in the history of the note.
 func UpdateNoteBook(db *DB, c clock.Clock, rowID int, bookUUID string) error {
 	ts := c.Now().UnixNano()
 
+	var noteUUID, prevBookUUID string
+	if err := db.QueryRow("SELECT uuid, book_uuid FROM notes WHERE rowid = ?", rowID).Scan(&noteUUID, &prevBookUUID); err != nil {
+		return errors.Wrap(err, "finding the note")
+	}
+
 	_, err := db.Exec(`UPDATE notes
 			SET book_uuid = ?, edited_on = ?, dirty = ?
 			WHERE rowid = ?`, bookUUID, ts, true, rowID)
@@ -220,5 +287,11 @@ func UpdateNoteBook(db *DB, c clock.Clock, rowID int, bookUUID string) error {
 		return errors.Wrap(err, "updating the note")
 	}
 
+	if prevBookUUID != bookUUID {
+		if _, err := db.Exec("INSERT INTO note_moves (note_uuid, from_book_uuid, to_book_uuid, moved_on) VALUES (?, ?, ?, ?)", noteUUID, prevBookUUID, bookUUID, ts); err != nil {
+			return errors.Wrap(err, "recording the move")
+		}
+	}
+
 	return nil
 }
@@ -357,6 +357,46 @@ func TestUpdateNoteBook(t *testing.T) {
 	assert.Equal(t, bookUUID, b2UUID, "content mismatch")
 	assert.Equal(t, int64(editedOn), now.UnixNano(), "editedOn mismatch")
 	assert.Equal(t, dirty, true, "dirty mismatch")
+
+	var moveCount int
+	var fromBookUUID, toBookUUID string
+	var movedOn int64
+	MustScan(t, "counting note_moves", db.QueryRow("SELECT count(*) FROM note_moves"), &moveCount)
+	MustScan(t, "getting the move", db.QueryRow("SELECT from_book_uuid, to_book_uuid, moved_on FROM note_moves WHERE note_uuid = ?", uuid), &fromBookUUID, &toBookUUID, &movedOn)
+
+	assert.Equal(t, moveCount, 1, "move count mismatch")
+	assert.Equal(t, fromBookUUID, b1UUID, "fromBookUUID mismatch")
+	assert.Equal(t, toBookUUID, b2UUID, "toBookUUID mismatch")
+	assert.Equal(t, movedOn, now.UnixNano(), "movedOn mismatch")
+}
+
+func TestUpdateNotePinned(t *testing.T) {
+	// set up
+	db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+	defer TeardownTestDB(t, db)
+
+	MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label, usn, deleted, dirty) VALUES (?, ?, ?, ?, ?)", "b1-uuid", "b1-label", 8, false, false)
+	MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, edited_on, usn, public, deleted, dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 content", 1542058875, 0, 1, false, false, false)
+
+	var rowid int
+	MustScan(t, "getting rowid", db.QueryRow("SELECT rowid FROM notes WHERE uuid = ?", "n1-uuid"), &rowid)
+
+	// execute
+	if err := UpdateNotePinned(db, rowid, true); err != nil {
+		t.Fatal(errors.Wrap(err, "pinning"))
+	}
+
+	var pinned, dirty bool
+	MustScan(t, "getting the note record", db.QueryRow("SELECT pinned, dirty FROM notes WHERE rowid = ?", rowid), &pinned, &dirty)
+	assert.Equal(t, pinned, true, "pinned mismatch")
+	assert.Equal(t, dirty, false, "pinning should not mark the note as dirty")
+
+	if err := UpdateNotePinned(db, rowid, false); err != nil {
+		t.Fatal(errors.Wrap(err, "unpinning"))
+	}
+
+	MustScan(t, "getting the note record", db.QueryRow("SELECT pinned FROM notes WHERE rowid = ?", rowid), &pinned)
+	assert.Equal(t, pinned, false, "pinned mismatch")
 }
 
 func TestUpdateBookName(t *testing.T) {
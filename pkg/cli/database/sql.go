@@ -19,11 +19,14 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
-	// use sqlite
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // SQLCommon is the minimal interface required by a db connection
@@ -49,6 +52,73 @@ type sqlTx interface {
 type DB struct {
 	Conn     SQLCommon
 	Filepath string
+	// connector opens the connections of Conn if it was opened with Open
+	connector *connector
+}
+
+// connector opens the connections to a SQLite database. Most pragmas apply
+// only to the connection that runs them, so the pragmas added with addPragma
+// are run on every new connection.
+type connector struct {
+	dsn     string
+	driver  *sqlite3.SQLiteDriver
+	mu      sync.Mutex
+	pragmas []string
+}
+
+// Connect opens a new connection and runs the pragmas on it
+func (c *connector) Connect(_ context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pragma := range c.pragmas {
+		if _, err := conn.(*sqlite3.SQLiteConn).Exec(pragma, nil); err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "running '%s'", pragma)
+		}
+	}
+
+	return conn, nil
+}
+
+// Driver returns the driver of the connector
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *connector) addPragma(pragma string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pragmas = append(c.pragmas, pragma)
+}
+
+// defaultMaxIdleConns is the default number of idle connections kept by
+// database/sql
+const defaultMaxIdleConns = 2
+
+// setPragma runs the pragma on every connection to the database, including
+// the ones opened later. It must be called before the connections are in use.
+func (d *DB) setPragma(pragma string) error {
+	if d.connector == nil {
+		return errors.New("the database was not opened with Open")
+	}
+
+	d.connector.addPragma(pragma)
+
+	// close the idle connections so that the pool opens new ones, which run
+	// the pragma
+	if conn, ok := d.Conn.(*sql.DB); ok {
+		conn.SetMaxIdleConns(0)
+		conn.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+
+	return nil
 }
 
 // Begin begins a transaction
@@ -120,15 +190,52 @@ func (d *DB) Close() error {
 
 // Open initializes a new connection to the sqlite database
 func Open(dbPath string) (*DB, error) {
-	dbConn, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, errors.Wrap(err, "opening db connection")
+	c := &connector{
+		dsn:    dbPath,
+		driver: &sqlite3.SQLiteDriver{},
 	}
 
 	db := &DB{
-		Conn:     dbConn,
-		Filepath: dbPath,
+		Conn:      sql.OpenDB(c),
+		Filepath:  dbPath,
+		connector: c,
 	}
 
 	return db, nil
 }
+
+// ErrWALUnavailable is an error for when the write-ahead log cannot be enabled,
+// e.g. on some network filesystems
+var ErrWALUnavailable = errors.New("write-ahead log is not available")
+
+// SetJournalMode sets the journal mode of the database to the write-ahead log
+// if wal is true, and to the default rollback journal otherwise. With the
+// write-ahead log, autocheckpoint is the number of pages after which the log
+// is checkpointed. SQLite's default is kept if it is zero.
+func SetJournalMode(db *DB, wal bool, autocheckpoint int) error {
+	mode := "DELETE"
+	if wal {
+		mode = "WAL"
+	}
+
+	var got string
+	if err := db.QueryRow(fmt.Sprintf("PRAGMA journal_mode=%s", mode)).Scan(&got); err != nil {
+		return errors.Wrap(err, "setting the journal mode")
+	}
+
+	if !wal {
+		return nil
+	}
+	if got != "wal" {
+		return ErrWALUnavailable
+	}
+
+	// unlike the journal mode, the autocheckpoint is set on each connection
+	if autocheckpoint > 0 {
+		if err := db.setPragma(fmt.Sprintf("PRAGMA wal_autocheckpoint=%d", autocheckpoint)); err != nil {
+			return errors.Wrap(err, "setting the autocheckpoint")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestSetJournalMode(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		// Setup
+		db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+		defer TeardownTestDB(t, db)
+
+		// Execute
+		if err := SetJournalMode(db, true, 500); err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		var mode string
+		var autocheckpoint int
+		MustScan(t, "getting the journal mode", db.QueryRow("PRAGMA journal_mode"), &mode)
+		MustScan(t, "getting the autocheckpoint", db.QueryRow("PRAGMA wal_autocheckpoint"), &autocheckpoint)
+
+		assert.Equal(t, mode, "wal", "journal mode mismatch")
+		assert.Equal(t, autocheckpoint, 500, "autocheckpoint mismatch")
+
+		// the connections held at the same time are distinct, and each has
+		// the autocheckpoint
+		conns := []*sql.Conn{}
+		for i := 0; i < 3; i++ {
+			conn, err := db.Conn.(*sql.DB).Conn(context.Background())
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "getting a connection"))
+			}
+			defer conn.Close()
+
+			conns = append(conns, conn)
+		}
+		for i, conn := range conns {
+			var got int
+			MustScan(t, "getting the autocheckpoint", conn.QueryRowContext(context.Background(), "PRAGMA wal_autocheckpoint"), &got)
+			assert.Equal(t, got, 500, fmt.Sprintf("autocheckpoint mismatch for connection %d", i))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		// Setup
+		db := InitTestDB(t, "../tmp/dnote-test.db", nil)
+		defer TeardownTestDB(t, db)
+
+		// Execute
+		if err := SetJournalMode(db, false, 500); err != nil {
+			t.Fatal(err)
+		}
+
+		// Test
+		var mode string
+		MustScan(t, "getting the journal mode", db.QueryRow("PRAGMA journal_mode"), &mode)
+
+		assert.Equal(t, mode, "delete", "journal mode mismatch")
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		// Setup
+		// in-memory databases do not support the write-ahead log
+		db, err := Open(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		// Execute
+		err = SetJournalMode(db, true, 0)
+
+		// Test
+		assert.Equal(t, err, ErrWALUnavailable, "error mismatch")
+	})
+}
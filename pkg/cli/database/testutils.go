@@ -20,9 +20,13 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/cli/consts"
@@ -34,7 +38,7 @@ var defaultSchemaSQL = `CREATE TABLE books
 		(
 			uuid text PRIMARY KEY,
 			label text NOT NULL
-		, dirty bool DEFAULT false, usn int DEFAULT 0 NOT NULL, deleted bool DEFAULT false);
+		, dirty bool DEFAULT false, usn int DEFAULT 0 NOT NULL, deleted bool DEFAULT false, archive bool DEFAULT false, original_label text);
 CREATE TABLE system
 		(
 			key string NOT NULL,
@@ -53,22 +57,22 @@ CREATE TABLE IF NOT EXISTS "notes"
 			dirty bool DEFAULT false,
 			usn int DEFAULT 0 NOT NULL,
 			deleted bool DEFAULT false
-		);
+		, source text DEFAULT '', pinned bool DEFAULT false);
 CREATE VIRTUAL TABLE note_fts USING fts5(content=notes, body, tokenize="porter unicode61 categories 'L* N* Co Ps Pe'")
 /* note_fts(body) */;
 CREATE TABLE IF NOT EXISTS 'note_fts_data'(id INTEGER PRIMARY KEY, block BLOB);
 CREATE TABLE IF NOT EXISTS 'note_fts_idx'(segid, term, pgno, PRIMARY KEY(segid, term)) WITHOUT ROWID;
 CREATE TABLE IF NOT EXISTS 'note_fts_docsize'(id INTEGER PRIMARY KEY, sz BLOB);
 CREATE TABLE IF NOT EXISTS 'note_fts_config'(k PRIMARY KEY, v) WITHOUT ROWID;
-CREATE TRIGGER notes_after_insert AFTER INSERT ON notes BEGIN
+CREATE TRIGGER notes_after_insert AFTER INSERT ON notes WHEN new.deleted = false BEGIN
 				INSERT INTO note_fts(rowid, body) VALUES (new.rowid, new.body);
 			END;
-CREATE TRIGGER notes_after_delete AFTER DELETE ON notes BEGIN
+CREATE TRIGGER notes_after_delete AFTER DELETE ON notes WHEN old.deleted = false BEGIN
 				INSERT INTO note_fts(note_fts, rowid, body) VALUES ('delete', old.rowid, old.body);
 			END;
 CREATE TRIGGER notes_after_update AFTER UPDATE ON notes BEGIN
-				INSERT INTO note_fts(note_fts, rowid, body) VALUES ('delete', old.rowid, old.body);
-				INSERT INTO note_fts(rowid, body) VALUES (new.rowid, new.body);
+				INSERT INTO note_fts(note_fts, rowid, body) SELECT 'delete', old.rowid, old.body WHERE old.deleted = false;
+				INSERT INTO note_fts(rowid, body) SELECT new.rowid, new.body WHERE new.deleted = false;
 			END;
 CREATE TABLE actions
 		(
@@ -79,7 +83,20 @@ CREATE TABLE actions
 			timestamp integer NOT NULL
 		);
 CREATE UNIQUE INDEX idx_notes_uuid ON notes(uuid);
-CREATE INDEX idx_notes_book_uuid ON notes(book_uuid);`
+CREATE INDEX idx_notes_book_uuid ON notes(book_uuid);
+CREATE TABLE book_templates
+		(
+			book_label text PRIMARY KEY,
+			body text NOT NULL
+		);
+CREATE TABLE note_moves
+		(
+			note_uuid text NOT NULL,
+			from_book_uuid text NOT NULL,
+			to_book_uuid text NOT NULL,
+			moved_on integer NOT NULL
+		);
+CREATE INDEX idx_note_moves_note_uuid ON note_moves(note_uuid);`
 
 // MustScan scans the given row and fails a test in case of any errors
 func MustScan(t *testing.T, message string, row *sql.Row, args ...interface{}) {
@@ -162,10 +179,128 @@ func OpenTestDB(t *testing.T, dnoteDir string) *DB {
 
 // MarkMigrationComplete marks all migrations as complete in the database
 func MarkMigrationComplete(t *testing.T, db *DB) {
-	if _, err := db.Exec("INSERT INTO system (key, value) VALUES (? , ?);", consts.SystemSchema, 12); err != nil {
+	if _, err := db.Exec("INSERT INTO system (key, value) VALUES (? , ?);", consts.SystemSchema, 18); err != nil {
 		t.Fatal(errors.Wrap(err, "inserting schema"))
 	}
 	if _, err := db.Exec("INSERT INTO system (key, value) VALUES (? , ?);", consts.SystemRemoteSchema, 1); err != nil {
 		t.Fatal(errors.Wrap(err, "inserting remote schema"))
 	}
 }
+
+// ErrFaultyDriver is the error returned by a faulty test database while
+// iterating rows
+var ErrFaultyDriver = errors.New("faulty driver error")
+
+// FaultyQuery is a canned response of a faulty test database to the queries
+// that contain Match. If Fail is set, the iteration fails with ErrFaultyDriver
+// after the rows are exhausted.
+type FaultyQuery struct {
+	Match   string
+	Columns []string
+	Rows    [][]driver.Value
+	Fail    bool
+}
+
+var (
+	faultyQueries      = map[string][]FaultyQuery{}
+	faultyQueriesMu    sync.Mutex
+	registerFaultyOnce sync.Once
+)
+
+type faultyDriver struct{}
+
+func (faultyDriver) Open(name string) (driver.Conn, error) {
+	faultyQueriesMu.Lock()
+	defer faultyQueriesMu.Unlock()
+
+	return &faultyConn{queries: faultyQueries[name]}, nil
+}
+
+type faultyConn struct {
+	queries []FaultyQuery
+}
+
+func (c *faultyConn) Prepare(query string) (driver.Stmt, error) {
+	for _, q := range c.queries {
+		if strings.Contains(query, q.Match) {
+			return &faultyStmt{query: q}, nil
+		}
+	}
+
+	return nil, errors.Errorf("unexpected query %s", query)
+}
+
+func (c *faultyConn) Close() error {
+	return nil
+}
+
+func (c *faultyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type faultyStmt struct {
+	query FaultyQuery
+}
+
+func (s *faultyStmt) Close() error {
+	return nil
+}
+
+func (s *faultyStmt) NumInput() int {
+	return -1
+}
+
+func (s *faultyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (s *faultyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &faultyRows{query: s.query}, nil
+}
+
+type faultyRows struct {
+	query FaultyQuery
+	idx   int
+}
+
+func (r *faultyRows) Columns() []string {
+	return r.query.Columns
+}
+
+func (r *faultyRows) Close() error {
+	return nil
+}
+
+func (r *faultyRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.query.Rows) {
+		if r.query.Fail {
+			return ErrFaultyDriver
+		}
+
+		return io.EOF
+	}
+
+	copy(dest, r.query.Rows[r.idx])
+	r.idx++
+
+	return nil
+}
+
+// OpenFaultyTestDB opens a connection to a test database that responds to
+// queries with the given canned responses and discards all writes
+func OpenFaultyTestDB(t *testing.T, queries []FaultyQuery) *DB {
+	registerFaultyOnce.Do(func() {
+		sql.Register("dnote-faulty", faultyDriver{})
+	})
+
+	faultyQueriesMu.Lock()
+	faultyQueries[t.Name()] = queries
+	faultyQueriesMu.Unlock()
+
+	conn, err := sql.Open("dnote-faulty", t.Name())
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "opening a faulty database connection"))
+	}
+
+	return &DB{Conn: conn}
+}
@@ -1,3 +1,6 @@
+//go:build linux || darwin
+// +build linux darwin
+
 /* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
  *
  * This file is part of Dnote.
@@ -15,7 +18,6 @@
  * You should have received a copy of the GNU General Public License
  * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
  */
-// +build linux darwin
 
 package dirs
 
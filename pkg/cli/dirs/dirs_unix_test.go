@@ -1,3 +1,6 @@
+//go:build linux || darwin
+// +build linux darwin
+
 /* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
  *
  * This file is part of Dnote.
@@ -15,7 +18,6 @@
  * You should have received a copy of the GNU General Public License
  * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
  */
-// +build linux darwin
 
 package dirs
 
@@ -81,3 +83,30 @@ func TestCustomDirs(t *testing.T) {
 
 	testCustomDirs(t, testCases)
 }
+
+func TestEmptyCustomDirs(t *testing.T) {
+	home := Home
+
+	testCases := []envTestCase{
+		{
+			envKey:   "XDG_CONFIG_HOME",
+			envVal:   "",
+			got:      &ConfigHome,
+			expected: filepath.Join(home, ".config"),
+		},
+		{
+			envKey:   "XDG_DATA_HOME",
+			envVal:   "",
+			got:      &DataHome,
+			expected: filepath.Join(home, ".local", "share"),
+		},
+		{
+			envKey:   "XDG_CACHE_HOME",
+			envVal:   "",
+			got:      &CacheHome,
+			expected: filepath.Join(home, ".cache"),
+		},
+	}
+
+	testCustomDirs(t, testCases)
+}
@@ -1,3 +1,6 @@
+//go:build windows
+// +build windows
+
 /* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
  *
  * This file is part of Dnote.
@@ -16,8 +19,6 @@
  * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
  */
 
-// +build windows
-
 package dirs
 
 import (
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dnote/dnote/pkg/cli/config"
@@ -44,29 +45,159 @@ import (
 // RunEFunc is a function type of dnote commands
 type RunEFunc func(*cobra.Command, []string) error
 
-func checkLegacyDBPath() (string, bool) {
-	legacyDnoteDir := getLegacyDnotePath(dirs.Home)
-	ok, err := utils.FileExists(legacyDnoteDir)
+func checkLegacyDBPath(paths context.Paths) (string, bool) {
+	legacyPath := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.DnoteDBFileName)
+	ok, err := utils.FileExists(legacyPath)
 	if ok {
-		return legacyDnoteDir, true
+		return legacyPath, true
 	}
 
 	if err != nil {
-		log.Errorf(errors.Wrapf(err, "checking legacy dnote directory at %s", legacyDnoteDir).Error())
+		log.Errorf(errors.Wrapf(err, "checking legacy dnote database at %s", legacyPath).Error())
 	}
 
 	return "", false
 }
 
 func getDBPath(paths context.Paths) string {
-	legacyDnoteDir, ok := checkLegacyDBPath()
+	legacyPath, ok := checkLegacyDBPath(paths)
 	if ok {
-		return fmt.Sprintf("%s/%s", legacyDnoteDir, consts.DnoteDBFileName)
+		return legacyPath
 	}
 
 	return fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName)
 }
 
+// rename renames a file. It is a variable so that tests can fail it.
+var rename = os.Rename
+
+// moveFile moves the file at src to dest. A file cannot be renamed to another
+// filesystem, so it is copied and then removed in that case.
+func moveFile(src, dest string) error {
+	err := rename(src, dest)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*os.LinkError); !ok {
+		return err
+	}
+
+	log.Debug("copying %s to %s because it cannot be renamed: %s\n", src, dest, err.Error())
+
+	if err := utils.CopyFile(src, dest); err != nil {
+		// a partial copy would be taken for the moved file on the next run
+		os.Remove(dest)
+		return errors.Wrap(err, "copying")
+	}
+	if err := os.Remove(src); err != nil {
+		return errors.Wrap(err, "removing the copied file")
+	}
+
+	return nil
+}
+
+// moveLegacyFile moves the file at src to dest and reports whether it was
+// moved. Nothing is moved if src does not exist or dest already exists.
+func moveLegacyFile(src, dest string) (bool, error) {
+	ok, err := utils.FileExists(src)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking if %s exists", src)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	ok, err = utils.FileExists(dest)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking if %s exists", dest)
+	}
+	if ok {
+		log.Debug("not moving %s because %s already exists\n", src, dest)
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return false, errors.Wrapf(err, "creating the directory for %s", dest)
+	}
+	if err := moveFile(src, dest); err != nil {
+		return false, errors.Wrapf(err, "moving %s to %s", src, dest)
+	}
+
+	log.Debug("moved %s to %s\n", src, dest)
+
+	return true, nil
+}
+
+// migrateLegacyDir moves the database and the config file from the legacy
+// dnote directory into the XDG base directories. The files are left in place
+// while the legacy migration is pending, and the ones whose new path is taken
+// keep being used from the legacy directory.
+func migrateLegacyDir(paths context.Paths) error {
+	if paths.LegacyDnote == "" {
+		return nil
+	}
+
+	ok, err := utils.FileExists(fmt.Sprintf("%s/schema", paths.LegacyDnote))
+	if err != nil {
+		return errors.Wrap(err, "checking the legacy schema")
+	}
+	if ok {
+		return nil
+	}
+
+	dbSrc := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.DnoteDBFileName)
+	dbDest := fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName)
+	moved, err := moveLegacyFile(dbSrc, dbDest)
+	if err != nil {
+		return errors.Wrap(err, "moving the database")
+	}
+	// the write-ahead log and its index belong to the database file
+	if moved {
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if _, err := moveLegacyFile(dbSrc+suffix, dbDest+suffix); err != nil {
+				return errors.Wrap(err, "moving the database")
+			}
+		}
+	}
+
+	configSrc := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.ConfigFilename)
+	configDest := fmt.Sprintf("%s/%s/%s", paths.Config, consts.DnoteDirName, consts.ConfigFilename)
+	if _, err := moveLegacyFile(configSrc, configDest); err != nil {
+		return errors.Wrap(err, "moving the config")
+	}
+
+	return nil
+}
+
+// resolveDBPath returns the path to the database file. The path set in the
+// config file takes precedence over the default path.
+func resolveDBPath(paths context.Paths) (string, error) {
+	ctx := context.DnoteCtx{Paths: paths}
+
+	// the config file does not exist yet on the first run
+	ok, err := utils.FileExists(config.GetPath(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "checking if config exists")
+	}
+	if !ok {
+		return getDBPath(paths), nil
+	}
+
+	cf, err := config.Read(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "reading config")
+	}
+	if cf.DBPath == "" {
+		return getDBPath(paths), nil
+	}
+
+	if strings.HasPrefix(cf.DBPath, "~/") {
+		return filepath.Join(paths.Home, cf.DBPath[2:]), nil
+	}
+
+	return cf.DBPath, nil
+}
+
 func newCtx(versionTag string) (context.DnoteCtx, error) {
 	dnoteDir := getLegacyDnotePath(dirs.Home)
 	paths := context.Paths{
@@ -77,7 +208,14 @@ func newCtx(versionTag string) (context.DnoteCtx, error) {
 		LegacyDnote: dnoteDir,
 	}
 
-	dbPath := getDBPath(paths)
+	if err := migrateLegacyDir(paths); err != nil {
+		return context.DnoteCtx{}, errors.Wrap(err, "migrating the legacy dnote directory")
+	}
+
+	dbPath, err := resolveDBPath(paths)
+	if err != nil {
+		return context.DnoteCtx{}, errors.Wrap(err, "resolving the database path")
+	}
 
 	db, err := database.Open(dbPath)
 	if err != nil {
@@ -107,6 +245,9 @@ func Init(apiEndpoint, versionTag string) (*context.DnoteCtx, error) {
 	if err := InitDB(ctx); err != nil {
 		return nil, errors.Wrap(err, "initializing database")
 	}
+	if err := initJournalMode(ctx); err != nil {
+		return nil, errors.Wrap(err, "setting the journal mode")
+	}
 	if err := InitSystem(ctx); err != nil {
 		return nil, errors.Wrap(err, "initializing system data")
 	}
@@ -123,6 +264,10 @@ func Init(apiEndpoint, versionTag string) (*context.DnoteCtx, error) {
 		return nil, errors.Wrap(err, "setting up the context")
 	}
 
+	if err := log.SetHighlightColor(ctx.HighlightColor); err != nil {
+		return nil, errors.Wrap(err, "setting the highlight color")
+	}
+
 	log.Debug("Running with Dnote context: %+v\n", context.Redact(ctx))
 
 	return &ctx, nil
@@ -150,19 +295,52 @@ func SetupCtx(ctx context.DnoteCtx) (context.DnoteCtx, error) {
 	}
 
 	ret := context.DnoteCtx{
-		Paths:            ctx.Paths,
-		Version:          ctx.Version,
-		DB:               ctx.DB,
-		SessionKey:       sessionKey,
-		SessionKeyExpiry: sessionKeyExpiry,
-		APIEndpoint:      cf.APIEndpoint,
-		Editor:           cf.Editor,
-		Clock:            clock.New(),
+		Paths:              ctx.Paths,
+		Version:            ctx.Version,
+		DB:                 ctx.DB,
+		SessionKey:         sessionKey,
+		SessionKeyExpiry:   sessionKeyExpiry,
+		APIEndpoint:        cf.APIEndpoint,
+		Editor:             cf.Editor,
+		HighlightColor:     cf.HighlightColor,
+		TrimOnSave:         cf.TrimOnSaveEnabled(),
+		InboxBook:          cf.InboxBookLabel(),
+		Opener:             cf.Opener,
+		ConfirmDestructive: confirmDestructive(cf),
+		Clock:              clock.New(),
 	}
 
 	return ret, nil
 }
 
+// confirmDestructive returns whether the destructive commands must prompt for
+// confirmation even if --yes is given. The environment variable enables it
+// regardless of the config.
+func confirmDestructive(cf config.Config) bool {
+	if os.Getenv("DNOTE_CONFIRM_DESTRUCTIVE") == config.ConfirmDestructiveAlways {
+		return true
+	}
+
+	return cf.ConfirmDestructive == config.ConfirmDestructiveAlways
+}
+
+// initJournalMode sets the journal mode of the database as configured. If the
+// write-ahead log cannot be enabled, it warns and keeps the default journal.
+func initJournalMode(ctx context.DnoteCtx) error {
+	cf, err := config.Read(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reading config")
+	}
+
+	err = database.SetJournalMode(ctx.DB, cf.WALEnabled(), cf.WALAutocheckpoint)
+	if err != nil && cf.WALEnabled() {
+		log.Warnf("could not enable the write-ahead log. Set 'wal: false' in %s to silence this warning. %s\n", config.GetPath(ctx), err.Error())
+		return nil
+	}
+
+	return err
+}
+
 // getLegacyDnotePath returns a legacy dnote directory path placed under
 // the user's home directory
 func getLegacyDnotePath(homeDir string) string {
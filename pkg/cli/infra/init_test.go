@@ -19,9 +19,17 @@
 package infra
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/config"
+	"github.com/dnote/dnote/pkg/cli/consts"
+	"github.com/dnote/dnote/pkg/cli/context"
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/pkg/errors"
 )
@@ -91,3 +99,257 @@ func TestInitSystemKV_existing(t *testing.T) {
 		db.QueryRow("SELECT value FROM system WHERE key = ?", "testKey"), &val)
 	assert.Equal(t, val, "testVal", "system value should not have been updated")
 }
+
+func TestResolveDBPath(t *testing.T) {
+	paths := context.Paths{
+		Home:   "/home/user",
+		Config: "../tmp/config",
+		Data:   "../tmp/data",
+	}
+	defaultPath := fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName)
+
+	testCases := []struct {
+		config   *string
+		expected string
+	}{
+		{
+			config:   nil,
+			expected: defaultPath,
+		},
+		{
+			config:   strPtr("editor: vim\n"),
+			expected: defaultPath,
+		},
+		{
+			config:   strPtr("editor: vim\ndbPath: /var/dnote/dnote.db\n"),
+			expected: "/var/dnote/dnote.db",
+		},
+		{
+			config:   strPtr("dbPath: ~/notes/dnote.db\n"),
+			expected: "/home/user/notes/dnote.db",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			// Setup
+			defer os.RemoveAll(paths.Config)
+
+			if tc.config != nil {
+				if err := os.MkdirAll(filepath.Join(paths.Config, consts.DnoteDirName), 0755); err != nil {
+					t.Fatal(errors.Wrap(err, "creating the config directory"))
+				}
+				if err := ioutil.WriteFile(config.GetPath(context.DnoteCtx{Paths: paths}), []byte(*tc.config), 0644); err != nil {
+					t.Fatal(errors.Wrap(err, "writing the config file"))
+				}
+			}
+
+			// Execute
+			got, err := resolveDBPath(paths)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			// Test
+			assert.Equal(t, got, tc.expected, "path mismatch")
+		})
+	}
+}
+
+func TestResolveDBPathLegacy(t *testing.T) {
+	paths := context.Paths{
+		Home:        "/home/user",
+		Config:      "../tmp/config",
+		Data:        "../tmp/data",
+		LegacyDnote: "../tmp/.dnote",
+	}
+	defer os.RemoveAll(paths.LegacyDnote)
+
+	legacyPath := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.DnoteDBFileName)
+	if err := os.MkdirAll(paths.LegacyDnote, 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the legacy directory"))
+	}
+
+	// the legacy directory without a database is not used
+	got, err := resolveDBPath(paths)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, got, fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName), "path mismatch")
+
+	if err := ioutil.WriteFile(legacyPath, []byte("db"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the legacy database"))
+	}
+
+	got, err = resolveDBPath(paths)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	assert.Equal(t, got, legacyPath, "path mismatch")
+}
+
+func TestMigrateLegacyDir(t *testing.T) {
+	paths := context.Paths{
+		Home:        "/home/user",
+		Config:      "../tmp/config",
+		Data:        "../tmp/data",
+		LegacyDnote: "../tmp/.dnote",
+	}
+
+	legacyDBPath := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.DnoteDBFileName)
+	legacyConfigPath := fmt.Sprintf("%s/%s", paths.LegacyDnote, consts.ConfigFilename)
+	dbPath := fmt.Sprintf("%s/%s/%s", paths.Data, consts.DnoteDirName, consts.DnoteDBFileName)
+	configPath := fmt.Sprintf("%s/%s/%s", paths.Config, consts.DnoteDirName, consts.ConfigFilename)
+
+	writeFile := func(t *testing.T, path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(errors.Wrap(err, "creating the directory"))
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(errors.Wrapf(err, "writing %s", path))
+		}
+	}
+	readFile := func(t *testing.T, path string) string {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(errors.Wrapf(err, "reading %s", path))
+		}
+
+		return string(b)
+	}
+	exists := func(t *testing.T, path string) bool {
+		_, err := os.Stat(path)
+		if err == nil {
+			return true
+		}
+		if !os.IsNotExist(err) {
+			t.Fatal(errors.Wrapf(err, "checking %s", path))
+		}
+
+		return false
+	}
+	teardown := func() {
+		os.RemoveAll(paths.LegacyDnote)
+		os.RemoveAll(paths.Config)
+		os.RemoveAll(paths.Data)
+	}
+
+	t.Run("moves the legacy files", func(t *testing.T) {
+		// Setup
+		defer teardown()
+		writeFile(t, legacyDBPath, "legacy db")
+		writeFile(t, legacyDBPath+"-wal", "legacy wal")
+		writeFile(t, legacyConfigPath, "editor: vim\n")
+
+		// Execute
+		if err := migrateLegacyDir(paths); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		// Test
+		assert.Equal(t, exists(t, legacyDBPath), false, "legacy database should have been moved")
+		assert.Equal(t, exists(t, legacyDBPath+"-wal"), false, "legacy wal should have been moved")
+		assert.Equal(t, exists(t, legacyConfigPath), false, "legacy config should have been moved")
+		assert.Equal(t, readFile(t, dbPath), "legacy db", "database mismatch")
+		assert.Equal(t, readFile(t, dbPath+"-wal"), "legacy wal", "wal mismatch")
+		assert.Equal(t, readFile(t, configPath), "editor: vim\n", "config mismatch")
+
+		got, err := resolveDBPath(paths)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "resolving the database path"))
+		}
+		assert.Equal(t, got, dbPath, "path mismatch")
+	})
+
+	t.Run("moves the legacy files across filesystems", func(t *testing.T) {
+		// Setup
+		defer teardown()
+		writeFile(t, legacyDBPath, "legacy db")
+		writeFile(t, legacyConfigPath, "editor: vim\n")
+
+		rename = func(src, dest string) error {
+			return &os.LinkError{Op: "rename", Old: src, New: dest, Err: syscall.EXDEV}
+		}
+		defer func() { rename = os.Rename }()
+
+		// Execute
+		if err := migrateLegacyDir(paths); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		// Test
+		assert.Equal(t, exists(t, legacyDBPath), false, "legacy database should have been removed")
+		assert.Equal(t, exists(t, legacyConfigPath), false, "legacy config should have been removed")
+		assert.Equal(t, readFile(t, dbPath), "legacy db", "database mismatch")
+		assert.Equal(t, readFile(t, configPath), "editor: vim\n", "config mismatch")
+	})
+
+	t.Run("new paths taken", func(t *testing.T) {
+		// Setup
+		defer teardown()
+		writeFile(t, legacyDBPath, "legacy db")
+		writeFile(t, legacyConfigPath, "editor: vim\n")
+		writeFile(t, dbPath, "new db")
+		writeFile(t, configPath, "editor: nano\n")
+
+		// Execute
+		if err := migrateLegacyDir(paths); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		// Test
+		assert.Equal(t, readFile(t, legacyDBPath), "legacy db", "legacy database mismatch")
+		assert.Equal(t, readFile(t, legacyConfigPath), "editor: vim\n", "legacy config mismatch")
+		assert.Equal(t, readFile(t, dbPath), "new db", "database mismatch")
+		assert.Equal(t, readFile(t, configPath), "editor: nano\n", "config mismatch")
+	})
+
+	t.Run("legacy migration pending", func(t *testing.T) {
+		// Setup
+		defer teardown()
+		writeFile(t, legacyConfigPath, "editor: vim\n")
+		writeFile(t, fmt.Sprintf("%s/schema", paths.LegacyDnote), "current_version: 7\n")
+
+		// Execute
+		if err := migrateLegacyDir(paths); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		// Test
+		assert.Equal(t, exists(t, legacyConfigPath), true, "legacy config should not have been moved")
+		assert.Equal(t, exists(t, configPath), false, "config should not have been created")
+	})
+}
+
+func TestSetupCtxConfig(t *testing.T) {
+	// Setup
+	ctx := context.InitTestCtx(t, context.Paths{
+		Data:   "../tmp/data",
+		Config: "../tmp/config",
+		Cache:  "../tmp/cache",
+	}, nil)
+	defer context.TeardownTestCtx(t, ctx)
+
+	if err := os.MkdirAll(filepath.Join(ctx.Paths.Config, consts.DnoteDirName), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "creating the config directory"))
+	}
+	if err := config.Write(ctx, config.Config{Editor: "nano", HighlightColor: "cyan"}); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the config"))
+	}
+
+	// Execute
+	got, err := SetupCtx(ctx)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	// Test
+	assert.Equal(t, got.Editor, "nano", "editor mismatch")
+	assert.Equal(t, got.HighlightColor, "cyan", "highlight color mismatch")
+	assert.Equal(t, got.TrimOnSave, true, "trim on save should be enabled by default")
+	assert.Equal(t, got.InboxBook, "inbox", "inbox book should be the default")
+}
+
+func strPtr(s string) *string {
+	return &s
+}
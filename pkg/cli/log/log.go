@@ -20,8 +20,12 @@ package log
 
 import (
 	"fmt"
-	"github.com/dnote/color"
+	"io"
 	"os"
+	"sort"
+
+	"github.com/dnote/color"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -35,8 +39,57 @@ var (
 	ColorBlue = color.New(color.FgBlue)
 	// ColorGray is a gray foreground color
 	ColorGray = color.New(color.FgHiBlack)
+
+	// ColorHighlight is the color of the matches in search results
+	ColorHighlight = ColorYellow
 )
 
+// namedColors maps the color names that can be configured to colors
+var namedColors = map[string]*color.Color{
+	"red":     ColorRed,
+	"green":   ColorGreen,
+	"yellow":  ColorYellow,
+	"blue":    ColorBlue,
+	"magenta": color.New(color.FgMagenta),
+	"cyan":    color.New(color.FgCyan),
+	"gray":    ColorGray,
+}
+
+// ColorByName returns the color with the given name and whether it exists
+func ColorByName(name string) (*color.Color, bool) {
+	c, ok := namedColors[name]
+
+	return c, ok
+}
+
+// ColorNames returns the names of the colors that can be configured, sorted
+func ColorNames() []string {
+	ret := []string{}
+	for name := range namedColors {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
+
+// SetHighlightColor sets the color of the matches in search results to the
+// color with the given name. An empty name leaves the color unchanged.
+func SetHighlightColor(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	c, ok := ColorByName(name)
+	if !ok {
+		return errors.Errorf("unknown color '%s'", name)
+	}
+
+	ColorHighlight = c
+
+	return nil
+}
+
 var indent = "  "
 
 // Info prints information
@@ -46,7 +99,12 @@ func Info(msg string) {
 
 // Infof prints information with optional format verbs
 func Infof(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorBlue.Sprint("•"), fmt.Sprintf(msg, v...))
+	Finfof(color.Output, msg, v...)
+}
+
+// Finfof writes information with optional format verbs to the writer
+func Finfof(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s %s", indent, ColorBlue.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Success prints a success message
@@ -61,17 +119,33 @@ func Successf(msg string, v ...interface{}) {
 
 // Plain prints a plain message without any prefix symbol
 func Plain(msg string) {
-	fmt.Printf("%s%s", indent, msg)
+	Fplain(os.Stdout, msg)
+}
+
+// Fplain writes a plain message without any prefix symbol to the writer
+func Fplain(w io.Writer, msg string) {
+	fmt.Fprintf(w, "%s%s", indent, msg)
 }
 
 // Plainf prints a plain message without any prefix symbol. It takes optional format verbs.
 func Plainf(msg string, v ...interface{}) {
-	fmt.Printf("%s%s", indent, fmt.Sprintf(msg, v...))
+	Fplainf(os.Stdout, msg, v...)
+}
+
+// Fplainf writes a plain message without any prefix symbol to the writer. It
+// takes optional format verbs.
+func Fplainf(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s", indent, fmt.Sprintf(msg, v...))
 }
 
 // Warnf prints a warning message with optional format verbs
 func Warnf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorRed.Sprint("•"), fmt.Sprintf(msg, v...))
+	Fwarnf(color.Output, msg, v...)
+}
+
+// Fwarnf writes a warning message with optional format verbs to the writer
+func Fwarnf(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s %s", indent, ColorRed.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Error prints an error message
@@ -86,7 +160,12 @@ func Errorf(msg string, v ...interface{}) {
 
 // Printf prints an normal message
 func Printf(msg string, v ...interface{}) {
-	fmt.Fprintf(color.Output, "%s%s %s", indent, ColorGray.Sprint("•"), fmt.Sprintf(msg, v...))
+	Fprintf(color.Output, msg, v...)
+}
+
+// Fprintf writes a normal message to the writer
+func Fprintf(w io.Writer, msg string, v ...interface{}) {
+	fmt.Fprintf(w, "%s%s %s", indent, ColorGray.Sprint("•"), fmt.Sprintf(msg, v...))
 }
 
 // Askf prints an question with optional format verbs. The leading symbol differs in color depending
@@ -31,10 +31,18 @@ import (
 	"github.com/dnote/dnote/pkg/cli/cmd/cat"
 	"github.com/dnote/dnote/pkg/cli/cmd/edit"
 	"github.com/dnote/dnote/pkg/cli/cmd/find"
+	"github.com/dnote/dnote/pkg/cli/cmd/history"
 	"github.com/dnote/dnote/pkg/cli/cmd/ls"
+	"github.com/dnote/dnote/pkg/cli/cmd/merge"
+	"github.com/dnote/dnote/pkg/cli/cmd/open"
+	"github.com/dnote/dnote/pkg/cli/cmd/pin"
+	"github.com/dnote/dnote/pkg/cli/cmd/reindex"
 	"github.com/dnote/dnote/pkg/cli/cmd/remove"
+	"github.com/dnote/dnote/pkg/cli/cmd/restore"
 	"github.com/dnote/dnote/pkg/cli/cmd/root"
+	"github.com/dnote/dnote/pkg/cli/cmd/stats"
 	"github.com/dnote/dnote/pkg/cli/cmd/sync"
+	"github.com/dnote/dnote/pkg/cli/cmd/template"
 	"github.com/dnote/dnote/pkg/cli/cmd/version"
 	"github.com/dnote/dnote/pkg/cli/cmd/view"
 	
@@ -69,6 +77,15 @@ func main() {
 	root.Register(view.NewCmd(*ctx))
 	root.Register(find.NewCmd(*ctx))
 	root.Register(archive.NewCmd(*ctx))
+	root.Register(restore.NewCmd(*ctx))
+	root.Register(stats.NewCmd(*ctx))
+	root.Register(merge.NewCmd(*ctx))
+	root.Register(open.NewCmd(*ctx))
+	root.Register(reindex.NewCmd(*ctx))
+	root.Register(template.NewCmd(*ctx))
+	root.Register(history.NewCmd(*ctx))
+	root.Register(pin.NewCmd(*ctx))
+	root.Register(pin.NewUnpinCmd(*ctx))
 	
 	cmd, _, err := root.Root.Find(os.Args[1:])
 
@@ -19,11 +19,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dnote/dnote/pkg/assert"
 	"github.com/dnote/dnote/pkg/cli/consts"
@@ -175,6 +181,77 @@ func TestAddNote(t *testing.T) {
 	})
 }
 
+func TestAddNoteID(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup3(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "add", "js", "-c", "foo")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var rowID int
+		database.MustScan(t, "getting the note rowid", db.QueryRow("SELECT rowid FROM notes WHERE body = ?", "foo"), &rowID)
+
+		expected := fmt.Sprintf("added to js (%d)", rowID)
+		assert.Equal(t, strings.Contains(output, expected), true, fmt.Sprintf("output should contain '%s'. got: %s", expected, output))
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup3(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "add", "js", "-c", "foo", "-q")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var rowID int
+		database.MustScan(t, "getting the note rowid", db.QueryRow("SELECT rowid FROM notes WHERE body = ?", "foo"), &rowID)
+
+		assert.Equal(t, output, fmt.Sprintf("%d\n", rowID), "output mismatch")
+	})
+}
+
+func TestAddNoteDate(t *testing.T) {
+	t.Run("backdate", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "foo", "--date", "2019-06-01T09:30:00Z")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var addedOn int64
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT added_on FROM notes WHERE body = ?", "foo"), &addedOn)
+
+		assert.Equal(t, addedOn, time.Date(2019, time.June, 1, 9, 30, 0, 0, time.UTC).UnixNano(), "added_on mismatch")
+	})
+
+	t.Run("future", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "add", "js", "-c", "foo", "--date", "2999-01-01")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+		assert.Equal(t, noteCount, 0, "note count mismatch")
+	})
+}
+
 func TestEditNote(t *testing.T) {
 	t.Run("content flag", func(t *testing.T) {
 		// Setup
@@ -323,6 +400,102 @@ func TestEditBook(t *testing.T) {
 		assert.Equal(t, n1.Dirty, false, "n1 Dirty mismatch")
 		assert.Equal(t, n1.USN, 0, "n1 USN mismatch")
 	})
+
+	setupNumericBook := func(t *testing.T) *database.DB {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		// a book with a numeric name, which older versions allowed, looks like a note id
+		database.MustExec(t, "inserting a numeric book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "numeric-book-uuid", "1")
+
+		return db
+	}
+
+	t.Run("book flag", func(t *testing.T) {
+		// Setup
+		db := setupNumericBook(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "--book", "1", "-n", "one")
+
+		// Test
+		var bookLabel, noteBody string
+		database.MustScan(t, "getting the book", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "numeric-book-uuid"), &bookLabel)
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 1), &noteBody)
+		assert.Equal(t, bookLabel, "one", "book label mismatch")
+		assert.Equal(t, noteBody, "n1 body", "note body should not change")
+	})
+
+	t.Run("note id", func(t *testing.T) {
+		// Setup
+		db := setupNumericBook(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "-c", "n1 body edited")
+
+		// Test
+		var bookLabel, noteBody string
+		database.MustScan(t, "getting the book", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "numeric-book-uuid"), &bookLabel)
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 1), &noteBody)
+		assert.Equal(t, bookLabel, "1", "book label should not change")
+		assert.Equal(t, noteBody, "n1 body edited", "note body mismatch")
+	})
+
+	t.Run("book flag with an argument", func(t *testing.T) {
+		// Setup
+		setupNumericBook(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "js", "--book", "1", "-n", "one")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+
+	t.Run("book flag without name", func(t *testing.T) {
+		// Setup
+		db := setupNumericBook(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "js", "-b", "linux")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--book is invalid for editing a book"), true, "error mismatch")
+
+		var bookLabel string
+		database.MustScan(t, "getting the book", db.QueryRow("SELECT label FROM books WHERE uuid = ?", "js-book-uuid"), &bookLabel)
+		assert.Equal(t, bookLabel, "js", "book label should not change")
+	})
+
+	t.Run("book name with a single note", func(t *testing.T) {
+		// Setup
+		db := setupNumericBook(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "linux", "-c", "n3 body edited")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--content is invalid for editing a book"), true, "error mismatch")
+
+		var noteBody string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 3), &noteBody)
+		assert.Equal(t, noteBody, "n3 body", "the only note in the book should not be edited")
+	})
 }
 
 func TestRemoveNote(t *testing.T) {
@@ -392,7 +565,7 @@ func TestRemoveNote(t *testing.T) {
 			assert.Equal(t, b2.USN, 122, "b2 usn mismatch")
 
 			assert.Equal(t, n1.UUID, "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f", "n1 should have UUID")
-			assert.Equal(t, n1.Body, "", "n1 body mismatch")
+			assert.Equal(t, n1.Body, "n1 body", "n1 body mismatch")
 			assert.Equal(t, n1.Deleted, true, "n1 deleted mismatch")
 			assert.Equal(t, n1.Dirty, true, "n1 Dirty mismatch")
 			assert.Equal(t, n1.USN, 11, "n1 usn mismatch")
@@ -452,10 +625,11 @@ func TestRemoveBook(t *testing.T) {
 			assert.Equal(t, linuxNoteCount, 1, "linux book book should have 1 note")
 
 			var b1, b2 database.Book
+			var b1OriginalLabel string
 			var n1, n2, n3 database.Note
 			database.MustScan(t, "getting b1",
-				db.QueryRow("SELECT label, dirty, deleted, usn FROM books WHERE uuid = ?", "js-book-uuid"),
-				&b1.Label, &b1.Dirty, &b1.Deleted, &b1.USN)
+				db.QueryRow("SELECT label, original_label, dirty, deleted, usn FROM books WHERE uuid = ?", "js-book-uuid"),
+				&b1.Label, &b1OriginalLabel, &b1.Dirty, &b1.Deleted, &b1.USN)
 			database.MustScan(t, "getting b2",
 				db.QueryRow("SELECT label, dirty, deleted, usn FROM books WHERE uuid = ?", "linux-book-uuid"),
 				&b2.Label, &b2.Dirty, &b2.Deleted, &b2.USN)
@@ -470,6 +644,7 @@ func TestRemoveBook(t *testing.T) {
 				&n3.UUID, &n3.Body, &n3.AddedOn, &n3.Deleted, &n3.Dirty, &n3.USN)
 
 			assert.NotEqual(t, b1.Label, "js", "b1 label mismatch")
+			assert.Equal(t, b1OriginalLabel, "js", "b1 original label mismatch")
 			assert.Equal(t, b1.Dirty, true, "b1 Dirty mismatch")
 			assert.Equal(t, b1.Deleted, true, "b1 deleted mismatch")
 			assert.Equal(t, b1.USN, 111, "b1 usn mismatch")
@@ -480,13 +655,13 @@ func TestRemoveBook(t *testing.T) {
 			assert.Equal(t, b2.USN, 122, "b2 usn mismatch")
 
 			assert.Equal(t, n1.UUID, "f0d0fbb7-31ff-45ae-9f0f-4e429c0c797f", "n1 should have UUID")
-			assert.Equal(t, n1.Body, "", "n1 body mismatch")
+			assert.Equal(t, n1.Body, "n1 body", "n1 body mismatch")
 			assert.Equal(t, n1.Dirty, true, "n1 Dirty mismatch")
 			assert.Equal(t, n1.Deleted, true, "n1 deleted mismatch")
 			assert.Equal(t, n1.USN, 11, "n1 usn mismatch")
 
 			assert.Equal(t, n2.UUID, "43827b9a-c2b0-4c06-a290-97991c896653", "n2 should have UUID")
-			assert.Equal(t, n2.Body, "", "n2 body mismatch")
+			assert.Equal(t, n2.Body, "n2 body", "n2 body mismatch")
 			assert.Equal(t, n2.Dirty, true, "n2 Dirty mismatch")
 			assert.Equal(t, n2.Deleted, true, "n2 deleted mismatch")
 			assert.Equal(t, n2.USN, 12, "n2 usn mismatch")
@@ -499,3 +674,2881 @@ func TestRemoveBook(t *testing.T) {
 		})
 	}
 }
+
+// dumpTables returns the rows of the books and notes tables in a comparable form
+func dumpTables(t *testing.T, db *database.DB) []string {
+	var ret []string
+
+	bookRows, err := db.Query("SELECT uuid, label, usn, dirty, deleted, archive FROM books ORDER BY uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "querying books"))
+	}
+	defer bookRows.Close()
+
+	for bookRows.Next() {
+		var b database.Book
+		var archive bool
+		if err := bookRows.Scan(&b.UUID, &b.Label, &b.USN, &b.Dirty, &b.Deleted, &archive); err != nil {
+			t.Fatal(errors.Wrap(err, "scanning a book"))
+		}
+
+		ret = append(ret, fmt.Sprintf("book %+v archive:%t", b, archive))
+	}
+
+	noteRows, err := db.Query("SELECT uuid, book_uuid, body, added_on, edited_on, usn, dirty, deleted FROM notes ORDER BY uuid")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "querying notes"))
+	}
+	defer noteRows.Close()
+
+	for noteRows.Next() {
+		var n database.Note
+		if err := noteRows.Scan(&n.UUID, &n.BookUUID, &n.Body, &n.AddedOn, &n.EditedOn, &n.USN, &n.Dirty, &n.Deleted); err != nil {
+			t.Fatal(errors.Wrap(err, "scanning a note"))
+		}
+
+		ret = append(ret, fmt.Sprintf("note %+v", n))
+	}
+
+	return ret
+}
+
+func TestDryRun(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "archive",
+			args: []string{"archive", "js", "--dry-run"},
+		},
+		{
+			name: "edit book name",
+			args: []string{"edit", "js", "-n", "js-edited", "--dry-run"},
+		},
+		{
+			name: "remove note",
+			args: []string{"remove", "1", "--dry-run"},
+		},
+		{
+			name: "remove book",
+			args: []string{"remove", "js", "--dry-run"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+			testutils.Setup2(t, db)
+
+			before := dumpTables(t, db)
+
+			// Execute
+			testutils.RunDnoteCmd(t, opts, binaryName, tc.args...)
+			defer testutils.RemoveDir(t, testDir)
+
+			// Test
+			after := dumpTables(t, db)
+			assert.DeepEqual(t, after, before, "rows changed")
+		})
+	}
+}
+
+func TestListDeletedNotes(t *testing.T) {
+	t.Run("deleted note", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		deletedOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--deleted")
+		activeOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js")
+
+		// Test
+		assert.Equal(t, strings.Contains(deletedOutput, "n1 body"), true, "deleted note should be listed with --deleted")
+		assert.Equal(t, strings.Contains(deletedOutput, "n2 body"), false, "active note should not be listed with --deleted")
+		assert.Equal(t, strings.Contains(activeOutput, "n1 body"), false, "deleted note should not be listed without --deleted")
+		assert.Equal(t, strings.Contains(activeOutput, "n2 body"), true, "active note should be listed without --deleted")
+	})
+
+	t.Run("deleted book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--deleted")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "deleted notes on book js"), true, fmt.Sprintf("header mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "n1 body"), true, fmt.Sprintf("n1 should be listed. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "n2 body"), true, fmt.Sprintf("n2 should be listed. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "n3 body"), false, fmt.Sprintf("n3 should not be listed. got: %s", output))
+	})
+}
+
+func TestListAllNotes(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "css-book-uuid", "css", true)
+	database.MustExec(t, "inserting a note in the archived book", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "css-book-uuid", "n4 body", 1515199971)
+	database.MustExec(t, "inserting a deleted note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "8a5e1d0a-3f33-4c4a-9f3e-2d1b7b6f0c52", "js-book-uuid", "", 1515199981, true)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--all-notes")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Test
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for idx, line := range lines {
+		lines[idx] = strings.TrimSpace(line)
+	}
+
+	expected := []string{
+		"(linux) (3) n3 body",
+		"(js) (1) n1 body",
+		"(js) (2) n2 body",
+	}
+	assert.DeepEqual(t, lines, expected, "output mismatch")
+}
+
+func TestListFormat(t *testing.T) {
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--format", "{{.BookLabel}}|{{.NoteCount}}|{{.Archive}}")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, output, "js|2|false\nlinux|1|false\n", "output mismatch")
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup4(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--format", "{{.RowID}}: {{.Body}}")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, output, "1: Booleans have toString()\n2: Date object implements mathematical comparisons\n", "output mismatch")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "--format", "{{.BookLabel")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String(), "parsing the format"), true, "should print the parse error")
+		assert.Equal(t, strings.Contains(stdout.String(), "linux"), false, "should not print any books")
+	})
+}
+
+func TestListPlain(t *testing.T) {
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--plain")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, output, "js\nlinux\n", "output mismatch")
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a multiline note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "js-book-uuid", "first line\nsecond line", 1515199999)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--plain")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, output, "2\tn2 body\n1\tn1 body\n4\tfirst line\n", "output mismatch")
+	})
+
+	t.Run("empty book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--plain")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, output, "", "output mismatch")
+	})
+}
+
+func TestSearchExcludeBook(t *testing.T) {
+	t.Run("single", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--exclude-book", "js")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "n3 body"), true, "linux note should match")
+		assert.Equal(t, strings.Contains(output, "n1 body"), false, "js note should not match")
+		assert.Equal(t, strings.Contains(output, "n2 body"), false, "js note should not match")
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--exclude-book", "js", "--exclude-book", "linux")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "body"), false, "no note should match")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "body", "-b", "js", "--exclude-book", "js")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "cannot be both searched and excluded"), true, "error mismatch")
+	})
+}
+
+func TestSearchWord(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "animals")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "the cat sat", 1542058875)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b1-uuid", "a category", 1542058876)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "cat", "-w")
+
+	// Test
+	assert.Equal(t, strings.Contains(output, "sat"), true, "whole word should match")
+	assert.Equal(t, strings.Contains(output, "egory"), false, "part of a word should not match")
+}
+
+func TestFindID(t *testing.T) {
+	t.Run("valid id", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "--id", "3")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "(linux)"), true, "book mismatch")
+		assert.Equal(t, strings.Contains(output, "n3 body"), true, "excerpt mismatch")
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "find", "--id", "99")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "note 99 not found"), true, "error mismatch")
+	})
+}
+
+func TestFindMaxResults(t *testing.T) {
+	t.Run("capped", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "body", "--max-results", "2")
+
+		// Test
+		assert.Equal(t, strings.Count(output, "body"), 2, fmt.Sprintf("result count mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "showing the first 2 matches"), true, fmt.Sprintf("warning mismatch. got: %s", output))
+	})
+
+	t.Run("under the cap", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "body", "--max-results", "3")
+
+		// Test
+		assert.Equal(t, strings.Count(output, "body"), 3, fmt.Sprintf("result count mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "showing the first"), false, fmt.Sprintf("should not warn. got: %s", output))
+	})
+
+	t.Run("invalid cap", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "find", "body", "--max-results", "0")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--max-results must be a positive number"), true, "error mismatch")
+	})
+}
+
+func TestSearchMaxResults(t *testing.T) {
+	t.Run("capped", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "body", "--max-results", "2")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running search. stderr: %s", stderr.String()))
+		}
+
+		// Test
+		assert.Equal(t, strings.Count(stdout.String(), "body"), 2, fmt.Sprintf("result count mismatch. got: %s", stdout.String()))
+		assert.Equal(t, strings.Contains(stderr.String(), "showing the first 2 matches"), true, fmt.Sprintf("warning mismatch. got: %s", stderr.String()))
+	})
+
+	t.Run("capped with whole words", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a note without the whole word", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "js-book-uuid", "bodies", 1515199955)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "body", "--word", "--max-results", "2")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running search. stderr: %s", stderr.String()))
+		}
+
+		// Test
+		assert.Equal(t, strings.Count(stdout.String(), "body"), 2, fmt.Sprintf("result count mismatch. got: %s", stdout.String()))
+		assert.Equal(t, strings.Contains(stdout.String(), "bodies"), false, fmt.Sprintf("partial word should not match. got: %s", stdout.String()))
+		assert.Equal(t, strings.Contains(stderr.String(), "showing the first 2 matches"), true, fmt.Sprintf("warning mismatch. got: %s", stderr.String()))
+	})
+
+	t.Run("largest cap", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--max-results", "9223372036854775807")
+
+		// Test
+		assert.Equal(t, strings.Count(output, "body"), 3, fmt.Sprintf("result count mismatch. got: %s", output))
+	})
+
+	t.Run("capped with json", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--max-results", "2", "--json")
+
+		// Test
+		var results []map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &results); err != nil {
+			t.Fatal(errors.Wrapf(err, "unmarshalling the output: %s", output))
+		}
+		assert.Equal(t, len(results), 2, "result count mismatch")
+	})
+
+	t.Run("under the cap", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "body", "--max-results", "3")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(errors.Wrapf(err, "running search. stderr: %s", stderr.String()))
+		}
+
+		// Test
+		assert.Equal(t, strings.Count(stdout.String(), "body"), 3, fmt.Sprintf("result count mismatch. got: %s", stdout.String()))
+		assert.Equal(t, strings.Contains(stderr.String(), "showing the first"), false, fmt.Sprintf("should not warn. got: %s", stderr.String()))
+	})
+
+	t.Run("invalid cap", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "body", "--max-results", "0")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--max-results must be a positive number"), true, "error mismatch")
+	})
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	testutils.Setup2(t, db)
+
+	for _, query := range []string{"", "   "} {
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", query)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, fmt.Sprintf("should fail for '%s'", query))
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "the search query is empty"), true, "error mismatch")
+	}
+
+	// blank arguments are left out of the query
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "", "n1")
+	assert.Equal(t, strings.Contains(output, "n1 body"), true, "n1 should be found")
+}
+
+func TestListGrep(t *testing.T) {
+	t.Run("book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "js-book-uuid", "a Promise resolves once", 1515199999)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--grep", "promise", "--plain")
+
+		// Test
+		assert.Equal(t, output, "4\ta Promise resolves once\n", "output mismatch")
+	})
+
+	t.Run("book without a match", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--grep", "promise")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "no notes in 'js' match the filters"), true, fmt.Sprintf("output mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "on book js"), false, "the book header should not be printed")
+	})
+
+	t.Run("book in JSON", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--grep", "N2", "--json")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "n2 body"), true, "n2 should be listed")
+		assert.Equal(t, strings.Contains(output, "n1 body"), false, "n1 should not be listed")
+	})
+
+	t.Run("all notes", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--all-notes", "--grep", "N3")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "n3 body"), true, "n3 should be listed")
+		assert.Equal(t, strings.Contains(output, "n1 body"), false, "n1 should not be listed")
+	})
+
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "--grep", "foo")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--grep can only be used when listing notes"), true, "error mismatch")
+	})
+}
+
+func TestNoteSource(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	testutils.Setup2(t, db)
+	database.MustExec(t, "inserting a web note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, source) VALUES (?, ?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "js-book-uuid", "web body", 1515199999, "web")
+
+	// Execute
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "cli body")
+
+	// Test
+	var source string
+	database.MustScan(t, "getting the note", db.QueryRow("SELECT source FROM notes WHERE body = ?", "cli body"), &source)
+	assert.Equal(t, source, "cli", "source mismatch")
+
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--source")
+	assert.Equal(t, strings.Contains(output, "[cli] cli body"), true, "cli source should be shown")
+	assert.Equal(t, strings.Contains(output, "[web] web body"), true, "web source should be shown")
+	assert.Equal(t, strings.Contains(output, "] n1 body"), false, "unknown source should not be shown")
+
+	output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--source", "web")
+	assert.Equal(t, strings.Contains(output, "web"), true, "web note should be found")
+	assert.Equal(t, strings.Contains(output, "cli body"), false, "cli note should not be found")
+	assert.Equal(t, strings.Contains(output, "n1 body"), false, "n1 should not be found")
+}
+
+func TestListJSON(t *testing.T) {
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--json")
+
+		// Test
+		var got []map[string]interface{}
+		testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+		assert.DeepEqual(t, got, []map[string]interface{}{
+			{"label": "js", "archive": false, "note_count": float64(2)},
+			{"label": "linux", "archive": false, "note_count": float64(1)},
+		}, "output mismatch")
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a multiline note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "linux-book-uuid", "first line\nsecond line", 1515199999)
+		database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "old-book-uuid", "old", true)
+		database.MustExec(t, "inserting a note in the archived book", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "f5b0a2c9-2b4e-4d8a-8c4e-0b2e1f9a7d31", "old-book-uuid", "old body", 1515199998)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--json", "--tree")
+
+		// Test
+		type treeNote struct {
+			RowID     int    `json:"rowid"`
+			BookLabel string `json:"book_label"`
+			Body      string `json:"body"`
+		}
+		type treeBook struct {
+			Label     string     `json:"label"`
+			NoteCount int        `json:"note_count"`
+			Notes     []treeNote `json:"notes"`
+		}
+
+		var got []treeBook
+		testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+		assert.DeepEqual(t, got, []treeBook{
+			{Label: "js", NoteCount: 2, Notes: []treeNote{
+				{RowID: 2, BookLabel: "js", Body: "n2 body"},
+				{RowID: 1, BookLabel: "js", Body: "n1 body"},
+			}},
+			{Label: "linux", NoteCount: 2, Notes: []treeNote{
+				{RowID: 3, BookLabel: "linux", Body: "n3 body"},
+				{RowID: 4, BookLabel: "linux", Body: "first line\nsecond line"},
+			}},
+		}, "output mismatch")
+
+		// archived books are included with --all
+		output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--json", "--tree", "--all")
+
+		var all []treeBook
+		testutils.MustUnmarshalJSON(t, []byte(output), &all)
+
+		assert.Equal(t, len(all), 3, "book count mismatch")
+		assert.DeepEqual(t, all[2], treeBook{Label: "old", NoteCount: 1, Notes: []treeNote{
+			{RowID: 5, BookLabel: "old", Body: "old body"},
+		}}, "archived book mismatch")
+	})
+
+	t.Run("tree without json", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "--tree")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--tree can only be used with --json"), true, "error mismatch")
+	})
+}
+
+func TestListFuzzy(t *testing.T) {
+	t.Run("ranked", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		database.MustExec(t, "inserting b1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "a-long-group")
+		database.MustExec(t, "inserting b2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "data-algo")
+		database.MustExec(t, "inserting b3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "algorithms")
+		database.MustExec(t, "inserting b4", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b4-uuid", "golang")
+		database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b3-uuid", "n1 body", 1515199943)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--fuzzy", "algo")
+
+		// Test
+		assert.Equal(t, output, "  • algorithms   (1)\n  • data-algo    (0)\n  • a-long-group (0)\n", "output mismatch")
+	})
+
+	t.Run("with a book name", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "js", "--fuzzy", "algo")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--fuzzy cannot be used with a book name"), true, "error mismatch")
+	})
+}
+
+func TestListInsensitiveBook(t *testing.T) {
+	t.Run("with --insensitive-book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "Linux", "--insensitive-book", "--plain")
+
+		// Test
+		assert.Equal(t, output, "3\tn3 body\n", "output mismatch")
+	})
+
+	t.Run("without --insensitive-book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "Linux")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestListSinceID(t *testing.T) {
+	t.Run("newer notes", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--since-id", "1", "--plain")
+
+		// Test
+		assert.Equal(t, output, "2\tn2 body\n", "output mismatch")
+	})
+
+	testCases := [][]string{
+		{"ls", "js", "--since-id", "-1"},
+		{"ls", "js", "--since-id", "foo"},
+		{"ls", "--since-id", "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc, " "), func(t *testing.T) {
+			// Setup
+			db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+			testutils.Setup2(t, db)
+			defer testutils.RemoveDir(t, testDir)
+
+			// Execute
+			cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, tc...)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "getting command"))
+			}
+
+			// Test
+			assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		})
+	}
+}
+
+func TestListPorcelain(t *testing.T) {
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "css-book-uuid", "css", true)
+		database.MustExec(t, "inserting a note in the archived book", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "css-book-uuid", "n4 body", 1515199971)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--porcelain", "--all")
+
+		// Test
+		assert.Equal(t, output, "js\t2\tfalse\nlinux\t1\tfalse\ncss\t1\ttrue\n", "output mismatch")
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a multiline note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "js-book-uuid", "first line\nsecond line", 1515199971)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--porcelain")
+
+		// Test
+		assert.Equal(t, output, "2\t1515199943\tn2 body\n1\t1515199951\tn1 body\n4\t1515199971\tfirst line\n", "output mismatch")
+	})
+
+	t.Run("with --json", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "--porcelain", "--json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestListEmpty(t *testing.T) {
+	setup := func(t *testing.T) {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting an empty book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "go-book-uuid", "go")
+		database.MustExec(t, "inserting a book with only deleted notes", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "rust-book-uuid", "rust")
+		database.MustExec(t, "inserting a deleted note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n4-uuid", "rust-book-uuid", "", 1515199971, true)
+		database.MustExec(t, "inserting an archived empty book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "css-book-uuid", "css", true)
+	}
+
+	t.Run("active books", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--empty", "--porcelain")
+
+		// Test
+		assert.Equal(t, output, "go\t0\tfalse\nrust\t0\tfalse\n", "output mismatch")
+	})
+
+	t.Run("with --all", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--empty", "--all", "--porcelain")
+
+		// Test
+		assert.Equal(t, output, "go\t0\tfalse\nrust\t0\tfalse\ncss\t0\ttrue\n", "output mismatch")
+	})
+
+	t.Run("with a book name", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "js", "--empty")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestSearchRepeat(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "css-book-uuid", "css", true)
+	database.MustExec(t, "inserting a note in the archived book", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "css-book-uuid", "n4 body", 1515199971)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--archived-only")
+	repeatOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "--repeat")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Test
+	assert.Equal(t, strings.Contains(output, "n4 body"), true, "archived note should match")
+	assert.Equal(t, strings.Contains(output, "n1 body"), false, "active note should not match")
+	assert.Equal(t, repeatOutput, output, "repeated output mismatch")
+}
+
+func TestSearchJSON(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	database.MustExec(t, "inserting a multiline note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "d2a2a5f4-2c4e-4f0b-9fcd-7b2c4f5ec1e1", "linux-book-uuid", "first line\nsecond body line", 1515199971)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "second", "--json")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Test
+	var got []map[string]interface{}
+	testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+	expected := []map[string]interface{}{
+		{
+			"rowid":      float64(4),
+			"book_label": "linux",
+			"body":       "first line\nsecond body line",
+			"archive":    false,
+			"added_on":   float64(1515199971),
+			"source":     "",
+		},
+	}
+	assert.DeepEqual(t, got, expected, "output mismatch")
+}
+
+func TestViewAll(t *testing.T) {
+	setup := func(t *testing.T) {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "java-book-uuid", "java", true)
+	}
+
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		setup(t)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "view")
+		allOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "view", "--all")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "java"), false, "archived book should not be listed without --all")
+		assert.Equal(t, strings.Contains(allOutput, "java"), true, "archived book should be listed with --all")
+		assert.Equal(t, strings.Contains(allOutput, "linux"), true, "active book should be listed with --all")
+	})
+
+	t.Run("books with a wildcard", func(t *testing.T) {
+		// Setup
+		setup(t)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "view", "j%")
+		allOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "view", "j%", "--all")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "js"), true, "active book should be listed without --all")
+		assert.Equal(t, strings.Contains(output, "java"), false, "archived book should not be listed without --all")
+		assert.Equal(t, strings.Contains(allOutput, "js"), true, "active book should be listed with --all")
+		assert.Equal(t, strings.Contains(allOutput, "java"), true, "archived book should be listed with --all")
+		assert.Equal(t, strings.Contains(allOutput, "linux"), false, "unmatched book should not be listed")
+	})
+
+	t.Run("note", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "view", "1", "--all")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String(), "--all flag is only valid when viewing books"), true, "error mismatch")
+	})
+}
+
+func TestCatPorcelain(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup4(t, db)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "js", "2", "--porcelain")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Test
+	assert.Equal(t, output, "note\t2\tjs\nDate object implements mathematical comparisons\x00", "output mismatch")
+}
+
+func TestCatNoteID(t *testing.T) {
+	t.Run("without the book name", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup4(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "2")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "Date object implements mathematical comparisons"), true, fmt.Sprintf("content mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "DEPRECATED"), false, fmt.Sprintf("should not warn about the book name. got: %s", output))
+	})
+
+	t.Run("with the book name", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup4(t, db)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "js", "2")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "Date object implements mathematical comparisons"), true, fmt.Sprintf("content mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "DEPRECATED"), true, fmt.Sprintf("should warn about the book name. got: %s", output))
+	})
+}
+
+func TestListReverse(t *testing.T) {
+	setup := func(t *testing.T) {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "go-book-uuid", "go")
+		database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "css-book-uuid", "css", true)
+		database.MustExec(t, "inserting another archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "rust-book-uuid", "rust", true)
+	}
+
+	t.Run("active books", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--reverse", "--plain")
+
+		// Test
+		assert.Equal(t, output, "linux\njs\ngo\n", "output mismatch")
+	})
+
+	t.Run("with --all", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "-r", "--all", "--porcelain")
+
+		// Test
+		assert.Equal(t, output, "linux\t1\tfalse\njs\t2\tfalse\ngo\t0\tfalse\nrust\t0\ttrue\ncss\t0\ttrue\n", "output mismatch")
+	})
+
+	t.Run("with a book name", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "ls", "js", "--reverse")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestListEmptyState(t *testing.T) {
+	t.Run("no books", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls")
+		formatOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "--format", "{{.BookLabel}}")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "no books yet"), true, fmt.Sprintf("output mismatch. got: %s", output))
+		assert.Equal(t, formatOutput, "", "should not print the empty state with --format")
+	})
+
+	t.Run("only archived books", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		database.MustExec(t, "inserting an archived book", db, "INSERT INTO books (uuid, label, archive) VALUES (?, ?, ?)", "java-book-uuid", "java", true)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "no active books"), true, fmt.Sprintf("output mismatch. got: %s", output))
+	})
+
+	t.Run("empty book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js")
+		deletedOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--deleted")
+		formatOutput := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--format", "{{.RowID}}")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "no notes in 'js' yet. Add one with `dnote add js`"), true, fmt.Sprintf("output mismatch. got: %s", output))
+		assert.Equal(t, strings.Contains(deletedOutput, "no deleted notes in 'js'"), true, fmt.Sprintf("deleted output mismatch. got: %s", deletedOutput))
+		assert.Equal(t, formatOutput, "", "should not print the empty state with --format")
+	})
+}
+
+func TestStatsJSON(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "stats", "js", "--json")
+	defer testutils.RemoveDir(t, testDir)
+
+	// Test
+	var got map[string]interface{}
+	testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+	assert.Equal(t, got["note_count"], float64(2), "note_count mismatch")
+	assert.Equal(t, got["word_count"], float64(4), "word_count mismatch")
+	assert.Equal(t, got["average_words"], float64(2), "average_words mismatch")
+	assert.Equal(t, got["oldest_note"], float64(1515199943), "oldest_note mismatch")
+	assert.Equal(t, got["newest_note"], float64(1515199951), "newest_note mismatch")
+	assert.Equal(t, got["active_book_count"], float64(1), "active_book_count mismatch")
+	assert.Equal(t, len(got["books"].([]interface{})), 1, "books length mismatch")
+}
+
+func TestSingleDashLongFlag(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup1(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	// Execute
+	cmd, _, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "archive", "js", "-reverse")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+
+	// Test
+	assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	assert.Equal(t, strings.Contains(stdout.String(), "Did you mean '--reverse'?"), true, "output should suggest the long flag")
+
+	var archive bool
+	database.MustScan(t, "getting the book", db.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &archive)
+	assert.Equal(t, archive, false, "book should not be archived")
+}
+
+func TestArchiveNotFound(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	database.MustExec(t, "inserting js", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-uuid", "js")
+
+	// Execute
+	cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "archive", "foo")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting command"))
+	}
+
+	// Test
+	assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	output := stdout.String() + stderr.String()
+	assert.Equal(t, strings.Contains(output, "book 'foo' not found"), true, "error mismatch")
+	assert.Equal(t, strings.Contains(output, "archiving the book"), false, "error should not be wrapped")
+
+	// the database should be left writable
+	testutils.RunDnoteCmd(t, opts, binaryName, "archive", "js")
+
+	var archive bool
+	database.MustScan(t, "getting js", db.QueryRow("SELECT archive FROM books WHERE uuid = ?", "js-uuid"), &archive)
+	assert.Equal(t, archive, true, "js should be archived")
+}
+
+func TestArchiveToggle(t *testing.T) {
+	t.Run("toggle", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		var archive bool
+
+		// Execute and test
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "archive", "js", "--toggle")
+		database.MustScan(t, "getting js", db.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &archive)
+		assert.Equal(t, archive, true, "js should be archived")
+		assert.Equal(t, strings.Contains(output, "archived js"), true, "output mismatch")
+
+		output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "archive", "js", "--toggle")
+		database.MustScan(t, "getting js", db.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &archive)
+		assert.Equal(t, archive, false, "js should be de-archived")
+		assert.Equal(t, strings.Contains(output, "de-archived js"), true, "output mismatch")
+	})
+
+	t.Run("with reverse", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "archive", "js", "--toggle", "-r")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--toggle cannot be used with --reverse"), true, "error mismatch")
+
+		var archive bool
+		database.MustScan(t, "getting js", db.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &archive)
+		assert.Equal(t, archive, false, "js should not be changed")
+	})
+}
+
+func TestArchiveOlderThan(t *testing.T) {
+	setup := func(t *testing.T) *database.DB {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		now := time.Now()
+		database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+		database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "linux-book-uuid", "linux")
+		database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "n1 body", now.AddDate(0, 0, -120).UnixNano())
+		database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "linux-book-uuid", "n2 body", now.AddDate(0, 0, -120).UnixNano())
+		database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "linux-book-uuid", "n3 body", now.AddDate(0, 0, -3).UnixNano())
+
+		return db
+	}
+
+	check := func(t *testing.T, db *database.DB) {
+		var jsArchive, linuxArchive bool
+		database.MustScan(t, "getting js", db.QueryRow("SELECT archive FROM books WHERE label = ?", "js"), &jsArchive)
+		database.MustScan(t, "getting linux", db.QueryRow("SELECT archive FROM books WHERE label = ?", "linux"), &linuxArchive)
+
+		assert.Equal(t, jsArchive, true, "js should be archived")
+		assert.Equal(t, linuxArchive, false, "linux should not be archived")
+	}
+
+	t.Run("with --yes", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "archive", "--older-than", "90d", "--yes")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "archived js"), true, "output should list the archived book")
+		assert.Equal(t, strings.Contains(output, "archiving 1/1 done"), true, "output should have the progress")
+		check(t, db)
+	})
+
+	t.Run("with --quiet", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "archive", "--older-than", "90d", "--yes", "--quiet")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "archiving"), false, "output should not have the progress")
+		assert.Equal(t, strings.Contains(output, "archived js"), false, "output should not list the archived book")
+		assert.Equal(t, strings.Contains(output, "js (1 notes)"), false, "output should not list the books to archive")
+		check(t, db)
+	})
+
+	t.Run("with confirmation", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, testutils.UserConfirm, binaryName, "archive", "--older-than", "90d")
+
+		// Test
+		check(t, db)
+	})
+
+	t.Run("with a book name", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "archive", "js", "--older-than", "90d")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestSearchDate(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	older := time.Date(2019, time.March, 1, 12, 0, 0, 0, time.Local)
+	newer := time.Date(2020, time.June, 15, 12, 0, 0, 0, time.Local)
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "closure one", older.UnixNano())
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js-book-uuid", "closure two", newer.UnixNano())
+
+	t.Run("date is printed", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "closure")
+
+		assert.Equal(t, strings.Contains(output, "Mar 1, 2019"), true, "output should have the older date")
+		assert.Equal(t, strings.Contains(output, "Jun 15, 2020"), true, "output should have the newer date")
+		assert.Equal(t, strings.Index(output, "Mar 1, 2019") < strings.Index(output, "Jun 15, 2020"), true, "equally relevant results should be in the order of creation")
+	})
+
+	t.Run("sort by date", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "closure", "--sort", "date")
+
+		assert.Equal(t, strings.Index(output, "Jun 15, 2020") < strings.Index(output, "Mar 1, 2019"), true, "the most recent note should be first")
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "search", "closure", "--sort", "foo")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestSearchSortRank(t *testing.T) {
+	type result struct {
+		BookLabel string `json:"book_label"`
+		Body      string `json:"body"`
+	}
+
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "linux-book-uuid", "linux")
+	database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "git-book-uuid", "git")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "linux-book-uuid", "a long note about many things that mentions rebase only once", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "git-book-uuid", "rebase often", 1515199942)
+	database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "linux-book-uuid", "rebase, rebase, rebase", 1515199943)
+
+	t.Run("rank by default", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "rebase", "--json")
+
+		var got []result
+		testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+		assert.Equal(t, len(got), 3, "result count mismatch")
+		assert.Equal(t, got[0].Body, "rebase, rebase, rebase", "the most relevant note should be first")
+		assert.Equal(t, got[1].Body, "rebase often", "the second most relevant note should be second")
+	})
+
+	t.Run("sort by book", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "rebase", "--json", "--sort", "book")
+
+		var got []result
+		testutils.MustUnmarshalJSON(t, []byte(output), &got)
+
+		labels := []string{}
+		for _, r := range got {
+			labels = append(labels, r.BookLabel)
+		}
+		assert.DeepEqual(t, labels, []string{"git", "linux", "linux"}, "results should be grouped by book")
+		assert.Equal(t, got[1].Body, "rebase, rebase, rebase", "the notes in a book should be ranked")
+	})
+}
+
+func TestSearchIncludeDeleted(t *testing.T) {
+	type result struct {
+		BookLabel string `json:"book_label"`
+		Body      string `json:"body"`
+		Deleted   bool   `json:"deleted"`
+	}
+
+	setup := func(t *testing.T) {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1")
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "linux")
+	}
+
+	t.Run("without the flag", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "n2 body"), true, "active note should match")
+		assert.Equal(t, strings.Contains(output, "n1 body"), false, "removed note should not match")
+		assert.Equal(t, strings.Contains(output, "n3 body"), false, "note in a removed book should not match")
+	})
+
+	t.Run("with the flag", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--include-deleted")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "n2 body"), true, "active note should match")
+		assert.Equal(t, strings.Contains(output, "(1) [deleted] n1 body"), true, fmt.Sprintf("removed note should match and be marked. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "(3) [deleted] n3 body"), true, fmt.Sprintf("note in a removed book should match and be marked. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "(2) [deleted]"), false, "active note should not be marked")
+	})
+
+	t.Run("removed book by name", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--include-deleted", "-b", "linux", "--json")
+
+		// Test
+		var got []result
+		testutils.MustUnmarshalJSON(t, []byte(output), &got)
+		assert.Equal(t, len(got), 1, "result count mismatch")
+		assert.Equal(t, got[0].BookLabel, "linux", "book label mismatch")
+		assert.Equal(t, got[0].Body, "n3 body", "body mismatch")
+		assert.Equal(t, got[0].Deleted, true, "deleted mismatch")
+	})
+}
+
+func TestSearchMarkFormat(t *testing.T) {
+	testCases := []struct {
+		markFormat string
+		expected   string
+	}{
+		{
+			markFormat: "html",
+			expected:   "(1) n1 <mark>body</mark>",
+		},
+		{
+			markFormat: "none",
+			expected:   "(1) n1 body",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.markFormat, func(t *testing.T) {
+			// Setup
+			db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+			testutils.Setup2(t, db)
+			defer testutils.RemoveDir(t, testDir)
+
+			// Execute
+			output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "body", "--mark-format", tc.markFormat)
+
+			// Test
+			assert.Equal(t, strings.Contains(output, tc.expected), true, fmt.Sprintf("output should contain '%s'", tc.expected))
+		})
+	}
+}
+
+func TestSearchGroup(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "linux-book-uuid", "linux")
+	database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "git-book-uuid", "git")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "linux-book-uuid", "a long note about many things that mentions rebase only once", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "git-book-uuid", "rebase often", 1515199942)
+	database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "linux-book-uuid", "rebase, rebase, rebase", 1515199943)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "rebase", "--group")
+
+	// Test
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	assert.Equal(t, len(lines), 6, fmt.Sprintf("line count mismatch. output: %s", output))
+	assert.Equal(t, lines[0], "  (linux)", "the book with the most relevant note should be first")
+	assert.Equal(t, strings.HasSuffix(lines[1], "(3) rebase, rebase, rebase"), true, "the most relevant note should be first in its book")
+	assert.Equal(t, strings.HasPrefix(lines[1], "    "), true, "notes should be indented beneath the book")
+	assert.Equal(t, strings.Contains(lines[2], "(1) a long note"), true, "the less relevant note should follow")
+	assert.Equal(t, lines[3], "", "books should be separated by a blank line")
+	assert.Equal(t, lines[4], "  (git)", "the other book should follow")
+	assert.Equal(t, strings.HasSuffix(lines[5], "(2) rebase often"), true, "note mismatch")
+	assert.Equal(t, strings.Count(output, "(linux)"), 1, "each book should be printed once")
+}
+
+func TestSearchFilesWithMatches(t *testing.T) {
+	setup := func(t *testing.T) {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "linux-book-uuid", "linux")
+		database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "git-book-uuid", "git")
+		database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "linux-book-uuid", "rebase once", 1515199941)
+		database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "git-book-uuid", "rebase often", 1515199942)
+		database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "linux-book-uuid", "rebase, rebase, rebase", 1515199943)
+		database.MustExec(t, "setting up note 4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "git-book-uuid", "merge", 1515199944)
+	}
+
+	t.Run("ids", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "rebase", "--ids", "--sort", "date")
+
+		// Test
+		assert.Equal(t, output, "(linux) 3\n(git) 2\n(linux) 1\n", "output mismatch")
+	})
+
+	t.Run("books", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "rebase", "--books", "--sort", "date")
+
+		// Test
+		assert.Equal(t, output, "linux\ngit\n", "output mismatch")
+	})
+
+	t.Run("with --json", func(t *testing.T) {
+		// Setup
+		setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "search", "rebase", "--ids", "--json")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestMerge(t *testing.T) {
+	setup := func(t *testing.T) *database.DB {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-uuid", "js")
+		database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js2-uuid", "js_2")
+		database.MustExec(t, "setting up book 3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "linux-uuid", "linux")
+		database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-uuid", "n1 body", 1515199941)
+		database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js2-uuid", "n2 body", 1515199942)
+		database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "js2-uuid", "n3 body", 1515199943)
+		database.MustExec(t, "setting up note 4", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n4-uuid", "linux-uuid", "n4 body", 1515199944)
+
+		return db
+	}
+
+	check := func(t *testing.T, db *database.DB) {
+		var jsNoteCount, movedNoteCount, linuxNoteCount int
+		database.MustScan(t, "counting js notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "js-uuid"), &jsNoteCount)
+		database.MustScan(t, "counting moved notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ? AND dirty = true", "js-uuid"), &movedNoteCount)
+		database.MustScan(t, "counting linux notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "linux-uuid"), &linuxNoteCount)
+
+		assert.Equal(t, jsNoteCount, 3, "js note count mismatch")
+		assert.Equal(t, movedNoteCount, 2, "moved note count mismatch")
+		assert.Equal(t, linuxNoteCount, 1, "linux note count mismatch")
+
+		var label string
+		var deleted, dirty bool
+		database.MustScan(t, "getting the merged book", db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "js2-uuid"), &label, &deleted, &dirty)
+		assert.NotEqual(t, label, "js_2", "label should be overridden")
+		assert.Equal(t, deleted, true, "deleted mismatch")
+		assert.Equal(t, dirty, true, "dirty mismatch")
+	}
+
+	t.Run("with --yes", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "merge", "js_2", "js", "--yes")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "merging 2/2 done"), true, "output should have the progress")
+		check(t, db)
+	})
+
+	t.Run("with --quiet", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "merge", "js_2", "js", "--yes", "--quiet")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "merging"), false, "output should not have the progress")
+		check(t, db)
+	})
+
+	t.Run("with confirmation", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, testutils.UserConfirm, binaryName, "merge", "js_2", "js")
+
+		// Test
+		check(t, db)
+	})
+
+	t.Run("nonexistent target", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "merge", "js_2", "foo", "--yes")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "js2-uuid"), &noteCount)
+		assert.Equal(t, noteCount, 2, "notes should not be moved")
+	})
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	confirmOpts := testutils.RunDnoteCmdOptions{
+		Env: append([]string{"DNOTE_CONFIRM_DESTRUCTIVE=always"}, opts.Env...),
+	}
+
+	decline := func(stdin io.WriteCloser) error {
+		if _, err := io.WriteString(stdin, "n\n"); err != nil {
+			return errors.Wrap(err, "indicating decline in stdin")
+		}
+
+		return nil
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "remove a note",
+			args: []string{"remove", "1", "--yes"},
+		},
+		{
+			name: "remove a book",
+			args: []string{"remove", "js", "--yes"},
+		},
+		{
+			name: "merge",
+			args: []string{"merge", "js", "linux", "--yes"},
+		},
+		{
+			name: "archive",
+			args: []string{"archive", "js"},
+		},
+		{
+			name: "archive --older-than",
+			args: []string{"archive", "--older-than", "90d", "--yes"},
+		},
+		{
+			name: "rename",
+			args: []string{"edit", "js", "-n", "javascript"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+			testutils.Setup2(t, db)
+			defer testutils.RemoveDir(t, testDir)
+
+			// Execute
+			testutils.WaitDnoteCmd(t, confirmOpts, decline, binaryName, tc.args...)
+
+			// Test
+			var noteCount, bookCount, archivedCount int
+			database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted = false"), &noteCount)
+			database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books WHERE deleted = false AND label IN ('js', 'linux')"), &bookCount)
+			database.MustScan(t, "counting archived books", db.QueryRow("SELECT count(*) FROM books WHERE archive = true"), &archivedCount)
+			assert.Equal(t, noteCount, 3, "noteCount mismatch")
+			assert.Equal(t, bookCount, 2, "bookCount mismatch")
+			assert.Equal(t, archivedCount, 0, "archivedCount mismatch")
+		})
+	}
+
+	t.Run("confirmed", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, confirmOpts, testutils.UserConfirm, binaryName, "remove", "1", "--yes")
+
+		// Test
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes WHERE deleted = false"), &noteCount)
+		assert.Equal(t, noteCount, 2, "noteCount mismatch")
+	})
+}
+
+func TestListMultiline(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "single line", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js-book-uuid", "first line\nsecond line", 1515199942)
+
+	getLine := func(output, substr string) string {
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, substr) {
+				return line
+			}
+		}
+
+		return ""
+	}
+
+	t.Run("marker", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--multiline-marker")
+
+		assert.Equal(t, strings.Contains(getLine(output, "single line"), "¶"), false, "single line note should not be marked")
+		assert.Equal(t, strings.Contains(getLine(output, "first line"), "¶ first line"), true, "multi-line note should be marked")
+	})
+
+	t.Run("no marker by default", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js")
+
+		assert.Equal(t, strings.Contains(output, "¶"), false, "notes should not be marked")
+	})
+
+	t.Run("single line only", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--single-line-only")
+
+		assert.Equal(t, strings.Contains(output, "single line"), true, "single line note should be listed")
+		assert.Equal(t, strings.Contains(output, "first line"), false, "multi-line note should not be listed")
+	})
+}
+
+func TestSearchPhrase(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "algorithms-book-uuid", "algorithms")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "algorithms-book-uuid", "merge sort is stable", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "algorithms-book-uuid", "merge two lists and sort them", 1515199942)
+
+	t.Run("terms", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "merge", "sort")
+
+		assert.Equal(t, strings.Contains(output, "stable"), true, "adjacent match should be found")
+		assert.Equal(t, strings.Contains(output, "two lists"), true, "non-adjacent match should be found")
+	})
+
+	t.Run("phrase", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "merge", "sort", "--phrase")
+
+		assert.Equal(t, strings.Contains(output, "stable"), true, "adjacent match should be found")
+		assert.Equal(t, strings.Contains(output, "two lists"), false, "non-adjacent match should not be found")
+	})
+}
+
+func TestSearchInteractiveFallback(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "algorithms-book-uuid", "algorithms")
+	database.MustExec(t, "setting up note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "algorithms-book-uuid", "merge sort is stable", 1515199941)
+
+	t.Run("with a query", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "-i", "stable")
+
+		assert.Equal(t, strings.Contains(output, "merge sort is"), true, "the regular search results should be printed")
+	})
+
+	t.Run("without a query", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "search", "-i")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = cmd.Run()
+		assert.NotEqual(t, err, nil, "the command should fail without a terminal")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "Incorrect number of argument"), true, "error mismatch")
+	})
+}
+
+func TestCatNav(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "first note", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js-book-uuid", "second note", 1515199942)
+
+	t.Run("nav", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "js", "1", "--nav")
+
+		assert.Equal(t, strings.Contains(output, "first note"), true, "the note should be printed")
+		assert.Equal(t, strings.Contains(output, "previous: none\tnext: 2"), true, "neighbors mismatch")
+	})
+
+	t.Run("next", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "js", "1", "--next")
+
+		assert.Equal(t, strings.Contains(output, "second note"), true, "the next note should be printed")
+		assert.Equal(t, strings.Contains(output, "previous: 1\tnext: none"), true, "neighbors mismatch")
+	})
+
+	t.Run("next of the last note", func(t *testing.T) {
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "cat", "js", "2", "--next")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = cmd.Run()
+		assert.NotEqual(t, err, nil, "the command should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "note 2 is the last note in the book"), true, "error mismatch")
+	})
+}
+
+func TestEditMergeInto(t *testing.T) {
+	setup := func(t *testing.T) *database.DB {
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+
+		database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-uuid", "js")
+		database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js2-uuid", "js_2")
+		database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-uuid", "n1 body", 1515199941)
+		database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "js2-uuid", "n2 body", 1515199942)
+		database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "js2-uuid", "n3 body", 1515199943)
+
+		return db
+	}
+
+	check := func(t *testing.T, db *database.DB) {
+		var jsNoteCount int
+		database.MustScan(t, "counting js notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "js-uuid"), &jsNoteCount)
+		assert.Equal(t, jsNoteCount, 3, "js note count mismatch")
+
+		var label string
+		var deleted bool
+		database.MustScan(t, "getting the merged book", db.QueryRow("SELECT label, deleted FROM books WHERE uuid = ?", "js2-uuid"), &label, &deleted)
+		assert.NotEqual(t, label, "js_2", "label should be overridden")
+		assert.Equal(t, deleted, true, "deleted mismatch")
+	}
+
+	t.Run("with --yes", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "edit", "--book", "js_2", "--merge-into", "js", "--yes")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "merging 2/2 done"), true, "output should have the progress")
+		check(t, db)
+	})
+
+	t.Run("with confirmation", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, testutils.UserConfirm, binaryName, "edit", "--book", "js_2", "--merge-into", "js")
+
+		// Test
+		check(t, db)
+	})
+
+	t.Run("nonexistent target", func(t *testing.T) {
+		// Setup
+		db := setup(t)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "--book", "js_2", "--merge-into", "foo")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "book 'foo' not found"), true, "error mismatch")
+
+		var noteCount int
+		var deleted bool
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes WHERE book_uuid = ?", "js2-uuid"), &noteCount)
+		database.MustScan(t, "getting the book", db.QueryRow("SELECT deleted FROM books WHERE uuid = ?", "js2-uuid"), &deleted)
+		assert.Equal(t, noteCount, 2, "notes should not be moved")
+		assert.Equal(t, deleted, false, "the book should not be removed")
+	})
+}
+
+func TestSearchMultipleBooks(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "setting up book 1", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "algorithms")
+	database.MustExec(t, "setting up book 2", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b2-uuid", "datastructures")
+	database.MustExec(t, "setting up book 3", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b3-uuid", "os")
+	database.MustExec(t, "setting up note 1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "heap sort", 1515199941)
+	database.MustExec(t, "setting up note 2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n2-uuid", "b2-uuid", "binary heap", 1515199942)
+	database.MustExec(t, "setting up note 3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b3-uuid", "heap memory", 1515199943)
+
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "search", "heap", "-b", "algorithms", "-b", "datastructures")
+
+	assert.Equal(t, strings.Contains(output, "(algorithms)"), true, "notes in algorithms should be found")
+	assert.Equal(t, strings.Contains(output, "(datastructures)"), true, "notes in datastructures should be found")
+	assert.Equal(t, strings.Contains(output, "(os)"), false, "notes in os should not be found")
+}
+
+func TestTrimOnSave(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "\n\n  \nfoo\n\nbar\n\n")
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes"), &body)
+		assert.Equal(t, body, "foo\n\nbar\n", "body mismatch")
+	})
+
+	t.Run("add whitespace only", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "add", "js", "-c", " \n\t\n")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "empty note not saved"), true, "error mismatch")
+
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+		assert.Equal(t, noteCount, 0, "note count mismatch")
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "-c", "\nnew content\n\n")
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = 1"), &body)
+		assert.Equal(t, body, "new content\n", "body mismatch")
+	})
+}
+
+// writeEditorScript writes a shell script to be used as an editor in tests and
+// returns its absolute path
+func writeEditorScript(t *testing.T, name, script string) string {
+	p, err := filepath.Abs(fmt.Sprintf("%s/%s", testDir, name))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the script path"))
+	}
+	if err := ioutil.WriteFile(p, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the script"))
+	}
+
+	return p
+}
+
+func TestOpen(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	testutils.Setup2(t, db)
+
+	copied, err := filepath.Abs(fmt.Sprintf("%s/opened.md", testDir))
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting the path"))
+	}
+	opener := writeEditorScript(t, "opener.sh", fmt.Sprintf("cp \"$1\" %s\n", copied))
+
+	t.Run("book", func(t *testing.T) {
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "open", "js", "--opener", opener)
+
+		// Test
+		b, err := ioutil.ReadFile(copied)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the opened file"))
+		}
+		assert.Equal(t, string(b), "# js\n\nn2 body\n\n---\n\nn1 body\n", "content mismatch")
+	})
+
+	t.Run("note", func(t *testing.T) {
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "open", "3", "--opener", opener)
+
+		// Test
+		b, err := ioutil.ReadFile(copied)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the opened file"))
+		}
+		assert.Equal(t, string(b), "n3 body", "content mismatch")
+	})
+
+	t.Run("failing opener", func(t *testing.T) {
+		failing := writeEditorScript(t, "failing.sh", "exit 1\n")
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "open", "js", "--opener", failing)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestAddBookTemplate(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	editor := writeEditorScript(t, "editor.sh", "printf 'today' >> \"$1\"\n")
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "template", "set", "journal", "-c", "# {{.Book}} {{.Now.Format \"2006\"}}\n")
+
+	t.Run("get", func(t *testing.T) {
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "template", "get", "journal")
+		assert.Equal(t, output, "# {{.Book}} {{.Now.Format \"2006\"}}\n", "output mismatch")
+	})
+
+	t.Run("book with the template", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "journal", "--date", "2019-06-01", "--editor", editor)
+
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body FROM notes INNER JOIN books ON books.uuid = notes.book_uuid WHERE books.label = ?", "journal"), &body)
+		assert.Equal(t, body, "# journal 2019\ntoday", "the editor should be pre-filled with the template")
+	})
+
+	t.Run("book without a template", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "snippets", "--editor", editor)
+
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body FROM notes INNER JOIN books ON books.uuid = notes.book_uuid WHERE books.label = ?", "snippets"), &body)
+		assert.Equal(t, body, "today", "the editor should be empty")
+	})
+
+	t.Run("unchanged template", func(t *testing.T) {
+		unchanged := writeEditorScript(t, "unchanged.sh", "exit 0\n")
+
+		var before int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &before)
+
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "add", "journal", "--editor", unchanged)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "the template was not changed"), true, "error mismatch")
+
+		var after int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &after)
+		assert.Equal(t, after, before, "no note should be added")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "template", "set", "journal", "-c", "")
+
+		var count int
+		database.MustScan(t, "counting templates", db.QueryRow("SELECT count(*) FROM book_templates"), &count)
+		assert.Equal(t, count, 0, "template count mismatch")
+	})
+}
+
+func TestEditSeededEditor(t *testing.T) {
+	t.Run("save", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+		editor := writeEditorScript(t, "editor.sh", "printf ' and more' >> \"$1\"\n")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "-c", "seed", "-E", "--editor", editor)
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 1), &body)
+		assert.Equal(t, body, "seed and more", "body mismatch")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+		editor := writeEditorScript(t, "editor.sh", "exit 1\n")
+
+		var before string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 1), &before)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "1", "-c", "seed", "-E", "--editor", editor)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+
+		var after string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = ?", 1), &after)
+		assert.Equal(t, after, before, "body should not change")
+	})
+
+	t.Run("cancel without seed", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+		editor := writeEditorScript(t, "editor.sh", "printf 'discarded' > \"$1\"\nexit 1\n")
+
+		var before string
+		var beforeEditedOn int64
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body, edited_on FROM notes WHERE rowid = ?", 1), &before, &beforeEditedOn)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "1", "--editor", editor)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "edit cancelled, note unchanged"), true, "output mismatch")
+
+		var after string
+		var afterEditedOn int64
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body, edited_on FROM notes WHERE rowid = ?", 1), &after, &afterEditedOn)
+		assert.Equal(t, after, before, "body should not change")
+		assert.Equal(t, afterEditedOn, beforeEditedOn, "edited_on should not change")
+	})
+
+	t.Run("without content", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "1", "-E")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--edit requires"), true, "error mismatch")
+	})
+}
+
+func TestEmptyNote(t *testing.T) {
+	t.Run("add whitespace only", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "add", "js", "-c", "  \t ")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "empty note not saved"), true, "error mismatch")
+
+		var noteCount, bookCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+		database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &bookCount)
+		assert.Equal(t, noteCount, 0, "note count mismatch")
+		assert.Equal(t, bookCount, 0, "book count mismatch")
+	})
+
+	t.Run("add valid body", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "foo")
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes"), &body)
+		assert.Equal(t, body, "foo", "body mismatch")
+	})
+
+	t.Run("add with --allow-empty", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "  ", "--allow-empty")
+
+		// Test
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+		assert.Equal(t, noteCount, 1, "note count mismatch")
+	})
+
+	t.Run("edit whitespace only", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		var originalBody string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = 1"), &originalBody)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "edit", "1", "-c", " \n ")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "empty note not saved"), true, "error mismatch")
+
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = 1"), &body)
+		assert.Equal(t, body, originalBody, "body mismatch")
+	})
+
+	t.Run("edit with --allow-empty", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		testutils.Setup1(t, db)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "-c", " \n ", "--allow-empty")
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes WHERE rowid = 1"), &body)
+		assert.Equal(t, body, "", "body mismatch")
+	})
+}
+
+func TestSyncStatus(t *testing.T) {
+	// Set up
+	database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "foo")
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "bar")
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "sync", "--status")
+
+	// Test
+	assert.Equal(t, strings.Contains(output, "last synced: never"), true, "last sync mismatch")
+	assert.Equal(t, strings.Contains(output, "notes: 2 added, 0 edited, 0 deleted"), true, "notes mismatch")
+	assert.Equal(t, strings.Contains(output, "books: 1 added, 0 edited, 0 deleted"), true, "books mismatch")
+}
+
+func TestCatRender(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+	database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "js-book-uuid", "js")
+	database.MustExec(t, "inserting a note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "js-book-uuid", "# Arrays\n- map\n```\n[].map(f)\n```", 1515199943)
+
+	// Execute
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "js", "1", "--render")
+
+	// Test
+	assert.Equal(t, strings.Contains(output, "Arrays\n  • map\n    [].map(f)"), true, "rendered content mismatch")
+	assert.Equal(t, strings.Contains(output, "# Arrays"), false, "heading marker should be removed")
+	assert.Equal(t, strings.Contains(output, "\x1b["), false, "output should not be styled when not a terminal")
+}
+
+func TestAddContentSource(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	defer testutils.RemoveDir(t, testDir)
+
+	fpath, err := filepath.Abs(filepath.Join(testDir, "clip.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fpath, []byte("clipped\nfrom a file"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the content file"))
+	}
+
+	// Execute
+	testutils.RunDnoteCmd(t, opts, binaryName, "add", "reading", "-c", "@"+fpath)
+
+	// Test
+	var body string
+	database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes"), &body)
+	assert.Equal(t, body, "clipped\nfrom a file", "body mismatch")
+}
+
+func TestAddFromStdin(t *testing.T) {
+	t.Run("piped without the flag", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		editor := writeEditorScript(t, "editor.sh", "printf 'from the editor' > \"$1\"\n")
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, func(stdin io.WriteCloser) error {
+			if _, err := io.WriteString(stdin, "piped content\n"); err != nil {
+				return err
+			}
+
+			return stdin.Close()
+		}, binaryName, "add", "chores", "--editor", editor)
+
+		// Test
+		var body string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT body FROM notes"), &body)
+		assert.Equal(t, body, "from the editor", "the standard input should not be read")
+	})
+
+	t.Run("multiple lines", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, func(stdin io.WriteCloser) error {
+			if _, err := io.WriteString(stdin, "# groceries\r\n- milk\r\n- eggs\r\n\r\n"); err != nil {
+				return err
+			}
+
+			return stdin.Close()
+		}, binaryName, "add", "chores", "--from-stdin")
+
+		// Test
+		var body, bookLabel string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body, books.label FROM notes INNER JOIN books ON books.uuid = notes.book_uuid"), &body, &bookLabel)
+		assert.Equal(t, body, "# groceries\n- milk\n- eggs", "body mismatch")
+		assert.Equal(t, bookLabel, "chores", "book mismatch")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "add", "chores", "--from-stdin")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+		cmd.Stdin = strings.NewReader("\n\n")
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "no content was given on the standard input"), true, "error mismatch")
+
+		var noteCount int
+		database.MustScan(t, "counting notes", db.QueryRow("SELECT count(*) FROM notes"), &noteCount)
+		assert.Equal(t, noteCount, 0, "note count mismatch")
+	})
+}
+
+func TestAddInbox(t *testing.T) {
+	t.Run("default inbox", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "--inbox", "-c", "call the bank")
+
+		// Test
+		var body, bookLabel string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body, books.label FROM notes INNER JOIN books ON books.uuid = notes.book_uuid"), &body, &bookLabel)
+		assert.Equal(t, body, "call the bank", "body mismatch")
+		assert.Equal(t, bookLabel, "inbox", "book mismatch")
+	})
+
+	t.Run("existing inbox", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+		database.MustExec(t, "inserting the inbox", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "inbox-uuid", "inbox")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "--inbox", "-c", "call the bank")
+
+		// Test
+		var bookCount int
+		var bookUUID string
+		database.MustScan(t, "counting books", db.QueryRow("SELECT count(*) FROM books"), &bookCount)
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT book_uuid FROM notes"), &bookUUID)
+		assert.Equal(t, bookCount, 1, "book count mismatch")
+		assert.Equal(t, bookUUID, "inbox-uuid", "book mismatch")
+	})
+
+	t.Run("configured inbox", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		configPath := fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.ConfigFilename)
+		if err := ioutil.WriteFile(configPath, []byte("editor: vim\ninboxBook: capture\n"), 0644); err != nil {
+			t.Fatal(errors.Wrap(err, "writing the config"))
+		}
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "--inbox", "-c", "call the bank")
+
+		// Test
+		var bookLabel string
+		database.MustScan(t, "getting the book", db.QueryRow("SELECT books.label FROM notes INNER JOIN books ON books.uuid = notes.book_uuid"), &bookLabel)
+		assert.Equal(t, bookLabel, "capture", "book mismatch")
+	})
+
+	t.Run("piped content", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.WaitDnoteCmd(t, opts, func(stdin io.WriteCloser) error {
+			if _, err := io.WriteString(stdin, "piped content\n"); err != nil {
+				return err
+			}
+
+			return stdin.Close()
+		}, binaryName, "add", "--inbox")
+
+		// Test
+		var body, bookLabel string
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT notes.body, books.label FROM notes INNER JOIN books ON books.uuid = notes.book_uuid"), &body, &bookLabel)
+		assert.Equal(t, body, "piped content\n", "body mismatch")
+		assert.Equal(t, bookLabel, "inbox", "book mismatch")
+	})
+
+	t.Run("with a book name", func(t *testing.T) {
+		// Setup
+		database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "add", "js", "--inbox", "-c", "foo")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "--inbox cannot be used with a book name"), true, "error mismatch")
+	})
+}
+
+func TestReindex(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	database.MustExec(t, "removing note 3 from the index", db, "INSERT INTO note_fts(note_fts, rowid, body) VALUES ('delete', ?, ?)", 3, "n3 body")
+	output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "n3")
+	assert.Equal(t, strings.Contains(output, "linux"), false, "note 3 should be missing from the index")
+
+	// Execute
+	output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "reindex")
+
+	// Test
+	assert.Equal(t, strings.Contains(output, "indexed 3 notes"), true, "output should have the number of indexed notes")
+
+	output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "n3")
+	assert.Equal(t, strings.Contains(output, "linux"), true, "note 3 should be found")
+
+	output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "n1")
+	assert.Equal(t, strings.Contains(output, "js"), true, "note 1 should still be found")
+}
+
+func TestSearchIndexSync(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	t.Run("added note", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "linux", "-c", "rsync flags")
+
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "rsync")
+		assert.Equal(t, strings.Contains(output, "(4)"), true, "added note should be found")
+	})
+
+	t.Run("edited note", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "4", "-c", "tar flags")
+
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "tar")
+		assert.Equal(t, strings.Contains(output, "(4)"), true, "edited note should be found by the new content")
+
+		output = testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "rsync")
+		assert.Equal(t, strings.Contains(output, "(4)"), false, "edited note should not be found by the old content")
+	})
+
+	t.Run("removed note", func(t *testing.T) {
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "4", "-y")
+
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "find", "tar")
+		assert.Equal(t, strings.Contains(output, "(4)"), false, "removed note should not be found")
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("note", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup4(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "1")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "restore", "1")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var n1, n2 database.Note
+		database.MustScan(t, "getting n1",
+			db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE rowid = ?", 1), &n1.Body, &n1.Deleted, &n1.Dirty)
+		database.MustScan(t, "getting n2",
+			db.QueryRow("SELECT deleted, dirty FROM notes WHERE rowid = ?", 2), &n2.Deleted, &n2.Dirty)
+
+		assert.Equal(t, n1.Body, "Booleans have toString()", "n1 body mismatch")
+		assert.Equal(t, n1.Deleted, false, "n1 deleted mismatch")
+		assert.Equal(t, n1.Dirty, true, "n1 dirty mismatch")
+		assert.Equal(t, n2.Deleted, false, "n2 deleted mismatch")
+		assert.Equal(t, n2.Dirty, false, "n2 dirty mismatch")
+
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "cat", "1")
+		assert.Equal(t, strings.Contains(output, "Booleans have toString()"), true, fmt.Sprintf("the restored note should be readable. got: %s", output))
+	})
+
+	t.Run("note in a deleted book", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup4(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "restore", "2")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var b1 database.Book
+		var n1, n2 database.Note
+		database.MustScan(t, "getting b1",
+			db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Label, &b1.Deleted, &b1.Dirty)
+		database.MustScan(t, "getting n1",
+			db.QueryRow("SELECT deleted FROM notes WHERE rowid = ?", 1), &n1.Deleted)
+		database.MustScan(t, "getting n2",
+			db.QueryRow("SELECT body, deleted, dirty FROM notes WHERE rowid = ?", 2), &n2.Body, &n2.Deleted, &n2.Dirty)
+
+		assert.Equal(t, b1.Label, "js", "b1 label mismatch")
+		assert.Equal(t, b1.Deleted, false, "b1 deleted mismatch")
+		assert.Equal(t, b1.Dirty, true, "b1 dirty mismatch")
+		assert.Equal(t, n1.Deleted, true, "n1 deleted mismatch")
+		assert.Equal(t, n2.Body, "Date object implements mathematical comparisons", "n2 body mismatch")
+		assert.Equal(t, n2.Deleted, false, "n2 deleted mismatch")
+		assert.Equal(t, n2.Dirty, true, "n2 dirty mismatch")
+	})
+
+	t.Run("book by name", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "restore", "--book", "js")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var b1 database.Book
+		var deletedCount int
+		database.MustScan(t, "getting b1",
+			db.QueryRow("SELECT label, deleted, dirty FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Label, &b1.Deleted, &b1.Dirty)
+		database.MustScan(t, "counting deleted notes",
+			db.QueryRow("SELECT count(*) FROM notes WHERE deleted = ?", true), &deletedCount)
+
+		assert.Equal(t, b1.Label, "js", "b1 label mismatch")
+		assert.Equal(t, b1.Deleted, false, "b1 deleted mismatch")
+		assert.Equal(t, b1.Dirty, true, "b1 dirty mismatch")
+		assert.Equal(t, deletedCount, 0, "deleted note count mismatch")
+
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js")
+		assert.Equal(t, strings.Contains(output, "n1 body"), true, fmt.Sprintf("n1 should be listed. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "n2 body"), true, fmt.Sprintf("n2 should be listed. got: %s", output))
+	})
+
+	t.Run("book by uuid", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "restore", "--book", "js-book-uuid")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Test
+		var b1 database.Book
+		database.MustScan(t, "getting b1",
+			db.QueryRow("SELECT label, deleted FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Label, &b1.Deleted)
+		assert.Equal(t, b1.Label, "js", "b1 label mismatch")
+		assert.Equal(t, b1.Deleted, false, "b1 deleted mismatch")
+	})
+
+	t.Run("book whose name is taken", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		testutils.RunDnoteCmd(t, opts, binaryName, "remove", "-y", "js")
+		testutils.RunDnoteCmd(t, opts, binaryName, "add", "js", "-c", "new body")
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(opts, binaryName, "restore", "--book", "js")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "a book named 'js' already exists"), true, "error mismatch")
+
+		var b1 database.Book
+		database.MustScan(t, "getting b1",
+			db.QueryRow("SELECT deleted FROM books WHERE uuid = ?", "js-book-uuid"), &b1.Deleted)
+		assert.Equal(t, b1.Deleted, true, "b1 deleted mismatch")
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup4(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "restore", "1")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "restoring an active note should fail")
+
+		var n1 database.Note
+		database.MustScan(t, "getting n1",
+			db.QueryRow("SELECT deleted, dirty FROM notes WHERE rowid = ?", 1), &n1.Deleted, &n1.Dirty)
+		assert.Equal(t, n1.Deleted, false, "n1 deleted mismatch")
+		assert.Equal(t, n1.Dirty, false, "n1 dirty mismatch")
+	})
+}
+
+func TestHistory(t *testing.T) {
+	t.Run("moved note", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "edit", "1", "-b", "linux")
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "history", "1")
+
+		// Test
+		var noteUUID string
+		database.MustScan(t, "getting the note uuid", db.QueryRow("SELECT uuid FROM notes WHERE rowid = ?", 1), &noteUUID)
+
+		var moveCount int
+		var fromBookUUID, toBookUUID string
+		database.MustScan(t, "counting note_moves", db.QueryRow("SELECT count(*) FROM note_moves"), &moveCount)
+		database.MustScan(t, "getting the move", db.QueryRow("SELECT from_book_uuid, to_book_uuid FROM note_moves WHERE note_uuid = ?", noteUUID), &fromBookUUID, &toBookUUID)
+
+		assert.Equal(t, moveCount, 1, "move count mismatch")
+		assert.Equal(t, fromBookUUID, "js-book-uuid", "fromBookUUID mismatch")
+		assert.Equal(t, toBookUUID, "linux-book-uuid", "toBookUUID mismatch")
+		assert.Equal(t, strings.Contains(output, "moved from js to linux"), true, fmt.Sprintf("output mismatch. got: %s", output))
+	})
+
+	t.Run("note never moved", func(t *testing.T) {
+		// Setup
+		db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+		testutils.Setup2(t, db)
+		defer testutils.RemoveDir(t, testDir)
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "history", "1")
+
+		// Test
+		assert.Equal(t, strings.Contains(output, "note 1 has not been moved"), true, fmt.Sprintf("output mismatch. got: %s", output))
+	})
+}
+
+func TestPin(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	t.Run("pin", func(t *testing.T) {
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "pin", "1")
+		plain := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--plain")
+		output := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js")
+
+		// Test
+		var pinned bool
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT pinned FROM notes WHERE rowid = ?", 1), &pinned)
+		assert.Equal(t, pinned, true, "pinned mismatch")
+		assert.Equal(t, plain, "1\tn1 body\n2\tn2 body\n", "the pinned note should be listed first")
+		assert.Equal(t, strings.Contains(output, "(1) ★ n1 body"), true, fmt.Sprintf("the pinned note should be marked. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "(2) n2 body"), true, fmt.Sprintf("the other note should not be marked. got: %s", output))
+	})
+
+	t.Run("no pins", func(t *testing.T) {
+		// Execute
+		plain := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--plain", "--no-pins")
+
+		// Test
+		assert.Equal(t, plain, "2\tn2 body\n1\tn1 body\n", "the notes should be in the usual order")
+	})
+
+	t.Run("unpin", func(t *testing.T) {
+		// Execute
+		testutils.RunDnoteCmd(t, opts, binaryName, "unpin", "1")
+		plain := testutils.RunDnoteCmdWithOutput(t, opts, binaryName, "ls", "js", "--plain")
+
+		// Test
+		var pinned bool
+		database.MustScan(t, "getting the note", db.QueryRow("SELECT pinned FROM notes WHERE rowid = ?", 1), &pinned)
+		assert.Equal(t, pinned, false, "pinned mismatch")
+		assert.Equal(t, plain, "2\tn2 body\n1\tn1 body\n", "output mismatch")
+	})
+
+	t.Run("nonexistent note", func(t *testing.T) {
+		// Execute
+		cmd, _, _, err := testutils.NewDnoteCmd(opts, binaryName, "pin", "99")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+	})
+}
+
+func TestCatCopy(t *testing.T) {
+	// Setup
+	db := database.InitTestDB(t, fmt.Sprintf("%s/%s/%s", testDir, consts.DnoteDirName, consts.DnoteDBFileName), nil)
+	testutils.Setup2(t, db)
+	defer testutils.RemoveDir(t, testDir)
+
+	t.Run("clipboard available", func(t *testing.T) {
+		// Setup
+		binDir, err := filepath.Abs(fmt.Sprintf("%s/bin", testDir))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting the bin directory"))
+		}
+		if err := os.MkdirAll(binDir, 0755); err != nil {
+			t.Fatal(errors.Wrap(err, "creating the bin directory"))
+		}
+		clipboardPath := fmt.Sprintf("%s/clipboard", binDir)
+		script := fmt.Sprintf("#!/bin/sh\ncat > \"%s\"\n", clipboardPath)
+		if err := ioutil.WriteFile(fmt.Sprintf("%s/wl-copy", binDir), []byte(script), 0755); err != nil {
+			t.Fatal(errors.Wrap(err, "writing the fake clipboard"))
+		}
+
+		copyOpts := testutils.RunDnoteCmdOptions{
+			Env: append([]string{fmt.Sprintf("PATH=%s%c%s", binDir, os.PathListSeparator, os.Getenv("PATH"))}, opts.Env...),
+		}
+
+		// Execute
+		output := testutils.RunDnoteCmdWithOutput(t, copyOpts, binaryName, "cat", "1", "--copy")
+
+		// Test
+		b, err := ioutil.ReadFile(clipboardPath)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the clipboard"))
+		}
+		assert.Equal(t, string(b), "n1 body", "clipboard content mismatch")
+		assert.Equal(t, strings.Contains(output, "n1 body"), true, fmt.Sprintf("the note should be printed. got: %s", output))
+		assert.Equal(t, strings.Contains(output, "copied note 1 to the clipboard"), true, fmt.Sprintf("output mismatch. got: %s", output))
+	})
+
+	t.Run("no clipboard", func(t *testing.T) {
+		// Setup
+		noClipboardOpts := testutils.RunDnoteCmdOptions{
+			Env: append([]string{fmt.Sprintf("PATH=%s", testDir)}, opts.Env...),
+		}
+
+		// Execute
+		cmd, stderr, stdout, err := testutils.NewDnoteCmd(noClipboardOpts, binaryName, "cat", "1", "--copy")
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting command"))
+		}
+
+		// Test
+		assert.NotEqual(t, cmd.Run(), nil, "should fail")
+		assert.Equal(t, strings.Contains(stdout.String()+stderr.String(), "no clipboard command found"), true, "error mismatch")
+	})
+}
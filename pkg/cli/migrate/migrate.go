@@ -47,6 +47,12 @@ var LocalSequence = []migration{
 	lm10,
 	lm11,
 	lm12,
+	lm13,
+	lm14,
+	lm15,
+	lm16,
+	lm17,
+	lm18,
 }
 
 // RemoteSequence is a list of remote migrations to be run
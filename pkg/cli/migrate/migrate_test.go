@@ -19,6 +19,7 @@
 package migrate
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v2"
@@ -1182,3 +1183,230 @@ func TestRemoteMigration1(t *testing.T) {
 	assert.Equal(t, postCSSBookUUID, newCSSBookUUID, "css book uuid was not updated correctly")
 	assert.Equal(t, postLinuxBookUUID, linuxBookUUID, "linux book uuid changed")
 }
+
+func TestLocalMigration13(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-13-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1515199943)
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm13.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	var n1Source string
+	database.MustScan(t, "getting n1", db.QueryRow("SELECT source FROM notes WHERE uuid = ?", "n1-uuid"), &n1Source)
+	assert.Equal(t, n1Source, "", "existing note should have no source")
+
+	database.MustExec(t, "inserting note with a source", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, source) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 body", 1515199951, "cli")
+
+	var n2Source string
+	database.MustScan(t, "getting n2", db.QueryRow("SELECT source FROM notes WHERE uuid = ?", "n2-uuid"), &n2Source)
+	assert.Equal(t, n2Source, "cli", "source mismatch")
+}
+
+func TestLocalMigration14(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-14-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "js")
+	database.MustExec(t, "inserting n1", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 closure", 1515199943)
+	database.MustExec(t, "inserting n2", db, "INSERT INTO notes (uuid, book_uuid, body, added_on, deleted) VALUES (?, ?, ?, ?, ?)", "n2-uuid", "b1-uuid", "n2 closure", 1515199951, true)
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm14.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	match := func(t *testing.T, term string) []string {
+		rows, err := db.Query("SELECT notes.uuid FROM note_fts INNER JOIN notes ON notes.rowid = note_fts.rowid WHERE note_fts MATCH ? ORDER BY notes.uuid", term)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "querying note_fts"))
+		}
+		defer rows.Close()
+
+		ret := []string{}
+		for rows.Next() {
+			var uuid string
+			if err := rows.Scan(&uuid); err != nil {
+				t.Fatal(errors.Wrap(err, "scanning a row"))
+			}
+
+			ret = append(ret, uuid)
+		}
+
+		return ret
+	}
+
+	assert.DeepEqual(t, match(t, "closure"), []string{"n1-uuid"}, "deleted note should be removed from the index")
+
+	database.MustExec(t, "inserting n3", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n3-uuid", "b1-uuid", "n3 closure", 1515199961)
+	assert.DeepEqual(t, match(t, "closure"), []string{"n1-uuid", "n3-uuid"}, "inserted note should be indexed")
+
+	database.MustExec(t, "updating n1", db, "UPDATE notes SET body = ? WHERE uuid = ?", "n1 promise", "n1-uuid")
+	assert.DeepEqual(t, match(t, "closure"), []string{"n3-uuid"}, "old content should be removed from the index")
+	assert.DeepEqual(t, match(t, "promise"), []string{"n1-uuid"}, "new content should be indexed")
+
+	database.MustExec(t, "deleting n3", db, "UPDATE notes SET deleted = ? WHERE uuid = ?", true, "n3-uuid")
+	assert.DeepEqual(t, match(t, "closure"), []string{}, "note marked deleted should be removed from the index")
+
+	database.MustExec(t, "restoring n3", db, "UPDATE notes SET deleted = ? WHERE uuid = ?", false, "n3-uuid")
+	assert.DeepEqual(t, match(t, "closure"), []string{"n3-uuid"}, "restored note should be indexed")
+
+	database.MustExec(t, "removing n1", db, "DELETE FROM notes WHERE uuid = ?", "n1-uuid")
+	database.MustExec(t, "removing n2", db, "DELETE FROM notes WHERE uuid = ?", "n2-uuid")
+	assert.DeepEqual(t, match(t, "promise"), []string{}, "removed note should be removed from the index")
+
+	database.MustExec(t, "checking the integrity", db, "INSERT INTO note_fts(note_fts) VALUES ('integrity-check')")
+}
+
+func TestLocalMigration15(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-15-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm15.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	database.MustExec(t, "inserting a template", db, "INSERT INTO book_templates (book_label, body) VALUES (?, ?)", "journal", "# today")
+
+	var body string
+	database.MustScan(t, "getting the template", db.QueryRow("SELECT body FROM book_templates WHERE book_label = ?", "journal"), &body)
+	assert.Equal(t, body, "# today", "body mismatch")
+
+	_, err = db.Exec("INSERT INTO book_templates (book_label, body) VALUES (?, ?)", "journal", "foo")
+	assert.NotEqual(t, err, nil, "book label should be unique")
+}
+
+func TestLocalMigration16(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-16-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm16.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	database.MustExec(t, "inserting a move", db, "INSERT INTO note_moves (note_uuid, from_book_uuid, to_book_uuid, moved_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "b2-uuid", 1542058875)
+
+	var fromBookUUID, toBookUUID string
+	database.MustScan(t, "getting the move", db.QueryRow("SELECT from_book_uuid, to_book_uuid FROM note_moves WHERE note_uuid = ?", "n1-uuid"), &fromBookUUID, &toBookUUID)
+	assert.Equal(t, fromBookUUID, "b1-uuid", "fromBookUUID mismatch")
+	assert.Equal(t, toBookUUID, "b2-uuid", "toBookUUID mismatch")
+}
+
+func TestLocalMigration17(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-17-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "b1")
+	database.MustExec(t, "inserting a note", db, "INSERT INTO notes (uuid, book_uuid, body, added_on) VALUES (?, ?, ?, ?)", "n1-uuid", "b1-uuid", "n1 body", 1542058875)
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm17.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	var pinned bool
+	database.MustScan(t, "getting the note", db.QueryRow("SELECT pinned FROM notes WHERE uuid = ?", "n1-uuid"), &pinned)
+	assert.Equal(t, pinned, false, "existing notes should not be pinned")
+}
+
+func TestLocalMigration18(t *testing.T) {
+	// set up
+	opts := database.TestDBOptions{SchemaSQLPath: "./fixtures/local-18-pre-schema.sql", SkipMigration: true}
+	ctx := context.InitTestCtx(t, paths, &opts)
+	defer context.TeardownTestCtx(t, ctx)
+
+	db := ctx.DB
+	database.MustExec(t, "inserting a book", db, "INSERT INTO books (uuid, label) VALUES (?, ?)", "b1-uuid", "b1")
+
+	// execute
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "beginning a transaction"))
+	}
+
+	err = lm18.run(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "failed to run"))
+	}
+
+	tx.Commit()
+
+	// test
+	var originalLabel sql.NullString
+	database.MustScan(t, "getting the book", db.QueryRow("SELECT original_label FROM books WHERE uuid = ?", "b1-uuid"), &originalLabel)
+	assert.Equal(t, originalLabel.Valid, false, "existing books should not have an original label")
+}
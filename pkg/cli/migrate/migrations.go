@@ -555,6 +555,122 @@ var lm12 = migration{
 	},
 }
 
+var lm13 = migration{
+	name: "add-source-to-notes",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec("ALTER TABLE notes ADD COLUMN source text DEFAULT ''")
+		if err != nil {
+			return errors.Wrap(err, "adding source column to notes")
+		}
+
+		return nil
+	},
+}
+
+var lm14 = migration{
+	name: "exclude-deleted-notes-from-fts-index",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`
+			DROP TRIGGER IF EXISTS notes_after_insert;
+			DROP TRIGGER IF EXISTS notes_after_delete;
+			DROP TRIGGER IF EXISTS notes_after_update;
+		`)
+		if err != nil {
+			return errors.Wrap(err, "dropping triggers for note_fts")
+		}
+
+		// Only the notes that are not deleted are indexed. A note is removed from
+		// the index with the exact values it was indexed with, so that the
+		// 'delete' commands are guarded by the same condition.
+		_, err = tx.Exec(`
+			CREATE TRIGGER notes_after_insert AFTER INSERT ON notes WHEN new.deleted = false BEGIN
+				INSERT INTO note_fts(rowid, body) VALUES (new.rowid, new.body);
+			END;
+			CREATE TRIGGER notes_after_delete AFTER DELETE ON notes WHEN old.deleted = false BEGIN
+				INSERT INTO note_fts(note_fts, rowid, body) VALUES ('delete', old.rowid, old.body);
+			END;
+			CREATE TRIGGER notes_after_update AFTER UPDATE ON notes BEGIN
+				INSERT INTO note_fts(note_fts, rowid, body) SELECT 'delete', old.rowid, old.body WHERE old.deleted = false;
+				INSERT INTO note_fts(rowid, body) SELECT new.rowid, new.body WHERE new.deleted = false;
+			END;
+		`)
+		if err != nil {
+			return errors.Wrap(err, "creating triggers for note_fts")
+		}
+
+		if _, err := tx.Exec("INSERT INTO note_fts(note_fts) VALUES ('delete-all')"); err != nil {
+			return errors.Wrap(err, "clearing note_fts")
+		}
+		if _, err := tx.Exec("INSERT INTO note_fts(rowid, body) SELECT rowid, body FROM notes WHERE deleted = false"); err != nil {
+			return errors.Wrap(err, "populating note_fts")
+		}
+
+		return nil
+	},
+}
+
+var lm15 = migration{
+	name: "create-book-templates",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS book_templates
+		(
+			book_label text PRIMARY KEY,
+			body text NOT NULL
+		)`)
+		if err != nil {
+			return errors.Wrap(err, "creating book_templates")
+		}
+
+		return nil
+	},
+}
+
+var lm16 = migration{
+	name: "create-note-moves",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS note_moves
+		(
+			note_uuid text NOT NULL,
+			from_book_uuid text NOT NULL,
+			to_book_uuid text NOT NULL,
+			moved_on integer NOT NULL
+		)`)
+		if err != nil {
+			return errors.Wrap(err, "creating note_moves")
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_note_moves_note_uuid ON note_moves(note_uuid);"); err != nil {
+			return errors.Wrap(err, "creating an index on note_moves")
+		}
+
+		return nil
+	},
+}
+
+var lm17 = migration{
+	name: "add-pinned-to-notes",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec("ALTER TABLE notes ADD COLUMN pinned bool DEFAULT false")
+		if err != nil {
+			return errors.Wrap(err, "adding pinned column to notes")
+		}
+
+		return nil
+	},
+}
+
+var lm18 = migration{
+	name: "add-original-label-to-books",
+	run: func(ctx context.DnoteCtx, tx *database.DB) error {
+		_, err := tx.Exec("ALTER TABLE books ADD COLUMN original_label text")
+		if err != nil {
+			return errors.Wrap(err, "adding original_label column to books")
+		}
+
+		return nil
+	},
+}
+
 var rm1 = migration{
 	name: "sync-book-uuids-from-server",
 	run: func(ctx context.DnoteCtx, tx *database.DB) error {
@@ -0,0 +1,104 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dnote/color"
+	"github.com/dnote/dnote/pkg/cli/log"
+)
+
+var (
+	colorHeading = color.New(color.Bold)
+	colorCode    = log.ColorGreen
+)
+
+var (
+	headingRe    = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	bulletRe     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe    = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	inlineCodeRe = regexp.MustCompile("`([^`]+)`")
+	codeFenceRe  = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// listIndent is the indentation of a list item for each level of nesting
+const listIndent = "  "
+
+// codeIndent is the indentation of the lines in a code block
+const codeIndent = "    "
+
+// renderInline styles the inline code spans in a line of text
+func renderInline(line string) string {
+	return inlineCodeRe.ReplaceAllStringFunc(line, func(s string) string {
+		return colorCode.Sprint(strings.Trim(s, "`"))
+	})
+}
+
+// listLevel returns the level of nesting of a list item from its leading
+// whitespace, counting a tab or every two spaces as one level
+func listLevel(leading string) int {
+	n := strings.Count(leading, "\t")*2 + strings.Count(leading, " ")
+
+	return n / 2
+}
+
+// Markdown renders the Markdown content for the terminal. The headings are
+// bold, the code is colored, and the list items are indented by their level
+// of nesting. The styles are left out if the output does not support colors.
+func Markdown(content string) string {
+	lines := strings.Split(content, "\n")
+	ret := make([]string, 0, len(lines))
+
+	inCode := false
+	for _, line := range lines {
+		if codeFenceRe.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+
+		if inCode {
+			ret = append(ret, codeIndent+colorCode.Sprint(line))
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			ret = append(ret, colorHeading.Sprint(renderInline(m[2])))
+			continue
+		}
+
+		if m := bulletRe.FindStringSubmatch(line); m != nil {
+			indent := strings.Repeat(listIndent, listLevel(m[1])+1)
+			ret = append(ret, fmt.Sprintf("%s• %s", indent, renderInline(m[2])))
+			continue
+		}
+
+		if m := orderedRe.FindStringSubmatch(line); m != nil {
+			indent := strings.Repeat(listIndent, listLevel(m[1])+1)
+			ret = append(ret, fmt.Sprintf("%s%s %s", indent, m[2], renderInline(m[3])))
+			continue
+		}
+
+		ret = append(ret, renderInline(line))
+	}
+
+	return strings.Join(ret, "\n")
+}
@@ -0,0 +1,102 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dnote/color"
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "plain text",
+			content:  "foo bar\nbaz",
+			expected: "foo bar\nbaz",
+		},
+		{
+			name:     "headings",
+			content:  "# Title\ntext\n### Sub title ###\n#hashtag",
+			expected: "Title\ntext\nSub title\n#hashtag",
+		},
+		{
+			name:     "heading ending with a hash",
+			content:  "## C#",
+			expected: "C#",
+		},
+		{
+			name:     "code fence",
+			content:  "before\n```go\nfunc main() {\n}\n```\nafter",
+			expected: "before\n    func main() {\n    }\nafter",
+		},
+		{
+			name:     "heading inside a code fence",
+			content:  "```\n# not a heading\n- not a list\n```",
+			expected: "    # not a heading\n    - not a list",
+		},
+		{
+			name:     "bullet list",
+			content:  "- one\n* two\n  - nested\n+ three",
+			expected: "  • one\n  • two\n    • nested\n  • three",
+		},
+		{
+			name:     "ordered list",
+			content:  "1. one\n2. two\n   1) nested",
+			expected: "  1. one\n  2. two\n    1) nested",
+		},
+		{
+			name:     "inline code",
+			content:  "run `go test` now",
+			expected: "run go test now",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, Markdown(tc.content), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestMarkdownStyled(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	got := Markdown("# Title\n```\ncode\n```\nplain")
+	lines := strings.Split(got, "\n")
+
+	assert.Equal(t, len(lines), 3, "line count mismatch")
+	assert.Equal(t, strings.Contains(lines[0], "\x1b["), true, "heading should be styled")
+	assert.Equal(t, strings.Contains(lines[0], "Title"), true, "heading text mismatch")
+	assert.Equal(t, strings.Contains(lines[1], "\x1b["), true, "code should be styled")
+	assert.Equal(t, strings.HasPrefix(lines[1], codeIndent), true, "code should be indented")
+	assert.Equal(t, lines[2], "plain", "plain text should not be styled")
+}
@@ -22,8 +22,9 @@ package output
 
 import (
 	"fmt"
-	"time"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/dnote/dnote/pkg/cli/database"
 	"github.com/dnote/dnote/pkg/cli/log"
@@ -55,7 +56,33 @@ func NoteHead(info database.NoteInfo) {
 }
 
 func NoteContent(info database.NoteInfo) {
-	fmt.Printf("%s", info.Content, " \n")
+	fmt.Printf("%s", info.Content)
+}
+
+// NotePorcelain writes a note in the porcelain format, which is meant to be
+// parsed by scripts and stays the same across versions. The format is a header
+// line "note\t<rowid>\t<book label>\n", followed by the content as is and a
+// terminating NUL byte.
+func NotePorcelain(w io.Writer, info database.NoteInfo) error {
+	if _, err := fmt.Fprintf(w, "note\t%d\t%s\n%s\x00", info.RowID, info.BookLabel, info.Content); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NoteNav prints the ids of the notes before and after a note in its book.
+// An id of 0 means there is no such note.
+func NoteNav(prev, next int) {
+	format := func(rowID int) string {
+		if rowID == 0 {
+			return "none"
+		}
+
+		return fmt.Sprintf("%d", rowID)
+	}
+
+	log.Infof("previous: %s\tnext: %s\n", format(prev), format(next))
 }
 
 // BookInfo prints a note information
@@ -0,0 +1,71 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/cli/database"
+	"github.com/pkg/errors"
+)
+
+func TestNotePorcelain(t *testing.T) {
+	testCases := []struct {
+		info     database.NoteInfo
+		expected []byte
+	}{
+		{
+			info: database.NoteInfo{
+				RowID:     12,
+				BookLabel: "js",
+				Content:   "foo bar",
+			},
+			expected: []byte("note\t12\tjs\nfoo bar\x00"),
+		},
+		{
+			info: database.NoteInfo{
+				RowID:     3,
+				BookLabel: "linux",
+				Content:   "first line\n\tsecond line\n",
+			},
+			expected: []byte("note\t3\tlinux\nfirst line\n\tsecond line\n\x00"),
+		},
+		{
+			info: database.NoteInfo{
+				RowID:     1,
+				BookLabel: "css",
+				Content:   "",
+			},
+			expected: []byte("note\t1\tcss\n\x00"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.info.BookLabel, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NotePorcelain(&buf, tc.info); err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, buf.Bytes(), tc.expected, "output mismatch")
+		})
+	}
+}
@@ -154,6 +154,27 @@ func RunDnoteCmd(t *testing.T, opts RunDnoteCmdOptions, binaryName string, arg .
 	t.Logf("\n%s", stdout)
 }
 
+// RunDnoteCmdWithOutput runs a dnote command and returns its standard output
+func RunDnoteCmdWithOutput(t *testing.T, opts RunDnoteCmdOptions, binaryName string, arg ...string) string {
+	t.Logf("running: %s %s", binaryName, strings.Join(arg, " "))
+
+	cmd, stderr, stdout, err := NewDnoteCmd(opts, binaryName, arg...)
+	if err != nil {
+		t.Logf("\n%s", stdout)
+		t.Fatal(errors.Wrap(err, "getting command").Error())
+	}
+
+	if err := cmd.Run(); err != nil {
+		t.Logf("\n%s", stdout)
+		t.Fatal(errors.Wrapf(err, "running command %s", stderr.String()))
+	}
+
+	// Print stdout if and only if test fails later
+	t.Logf("\n%s", stdout)
+
+	return stdout.String()
+}
+
 // WaitDnoteCmd runs a dnote command and waits until the command is exited
 func WaitDnoteCmd(t *testing.T, opts RunDnoteCmdOptions, runFunc func(io.WriteCloser) error, binaryName string, arg ...string) {
 	t.Logf("running: %s %s", binaryName, strings.Join(arg, " "))
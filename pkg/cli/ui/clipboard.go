@@ -0,0 +1,85 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoClipboard is returned when none of the clipboard commands is available
+var ErrNoClipboard = errors.New("no clipboard command found. Install one of pbcopy, wl-copy, xclip or xsel")
+
+// Clipboard writes text to the system clipboard
+type Clipboard interface {
+	WriteText(s string) error
+}
+
+// getClipboardCommands returns the commands that write their standard input
+// to the clipboard on the given operating system, in the order of preference
+func getClipboardCommands(goos string) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+
+	return [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+}
+
+// commandClipboard writes to the clipboard by piping the text to the first
+// of the commands that is installed
+type commandClipboard struct {
+	commands [][]string
+}
+
+// NewClipboard returns a Clipboard that uses the clipboard command of the
+// operating system
+func NewClipboard() Clipboard {
+	return commandClipboard{commands: getClipboardCommands(runtime.GOOS)}
+}
+
+func (c commandClipboard) WriteText(s string) error {
+	for _, args := range c.commands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(s)
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "running '%s'", args[0])
+		}
+
+		return nil
+	}
+
+	return ErrNoClipboard
+}
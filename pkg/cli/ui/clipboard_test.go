@@ -0,0 +1,105 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+func TestGetClipboardCommands(t *testing.T) {
+	testCases := []struct {
+		goos     string
+		expected [][]string
+	}{
+		{
+			goos:     "darwin",
+			expected: [][]string{{"pbcopy"}},
+		},
+		{
+			goos:     "windows",
+			expected: [][]string{{"clip.exe"}},
+		},
+		{
+			goos:     "linux",
+			expected: [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.goos, func(t *testing.T) {
+			assert.DeepEqual(t, getClipboardCommands(tc.goos), tc.expected, "commands mismatch")
+		})
+	}
+}
+
+func TestCommandClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake clipboards are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "dnote-clipboard")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake clipboard saves its standard input to a file
+	outPath := filepath.Join(dir, "clipboard")
+	fake := filepath.Join(dir, "fake-clipboard")
+	if err := ioutil.WriteFile(fake, []byte("#!/bin/sh\ncat > \""+outPath+"\"\n"), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the fake clipboard"))
+	}
+	failing := filepath.Join(dir, "failing-clipboard")
+	if err := ioutil.WriteFile(failing, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the failing clipboard"))
+	}
+	missing := filepath.Join(dir, "missing-clipboard")
+
+	t.Run("first available command", func(t *testing.T) {
+		defer os.Remove(outPath)
+
+		c := commandClipboard{commands: [][]string{{missing}, {fake}}}
+		if err := c.WriteText("n1 body\nsecond line"); err != nil {
+			t.Fatal(errors.Wrap(err, "executing"))
+		}
+
+		b, err := ioutil.ReadFile(outPath)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading the clipboard"))
+		}
+		assert.Equal(t, string(b), "n1 body\nsecond line", "clipboard content mismatch")
+	})
+
+	t.Run("no available command", func(t *testing.T) {
+		c := commandClipboard{commands: [][]string{{missing}}}
+		assert.Equal(t, c.WriteText("n1 body"), ErrNoClipboard, "error mismatch")
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		c := commandClipboard{commands: [][]string{{failing}, {fake}}}
+		assert.NotEqual(t, c.WriteText("n1 body"), nil, "should fail")
+	})
+}
@@ -24,10 +24,12 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 
 	"github.com/dnote/dnote/pkg/cli/consts"
 	"github.com/dnote/dnote/pkg/cli/context"
+	"github.com/dnote/dnote/pkg/cli/log"
 	"github.com/dnote/dnote/pkg/cli/utils"
 	"github.com/pkg/errors"
 )
@@ -49,44 +51,182 @@ func GetTmpContentPath(ctx context.DnoteCtx) (string, error) {
 	}
 }
 
-// getEditorCommand returns the system's editor command with appropriate flags,
-// if necessary, to make the command wait until editor is close to exit.
-func getEditorCommand() string {
-	editor := os.Getenv("EDITOR")
+// EditorFlag is the editor command given by the global --editor flag. If set,
+// it takes precedence over the environment and the configuration.
+var EditorFlag string
 
-	var ret string
+// EditorArgsFlag is the list of arguments given by the global, repeatable
+// --editor-args flag. They are passed to the editor before the file to edit.
+var EditorArgsFlag []string
 
+// editorEnvs is a list of environment variables from which an editor command
+// is looked up, in the order of precedence.
+var editorEnvs = []string{"DNOTE_EDITOR", "EDITOR", "VISUAL"}
+
+// getDefaultEditor returns the editor command to fall back to when none is set
+func getDefaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+
+	return "vi"
+}
+
+// withWaitFlags appends, if necessary, flags to the given editor command to
+// make it wait until the editor is closed to exit.
+func withWaitFlags(editor string) string {
 	switch editor {
 	case "atom":
-		ret = "atom -w"
+		return "atom -w"
 	case "subl":
-		ret = "subl -n -w"
+		return "subl -n -w"
+	case "code":
+		return "code -n -w"
 	case "mate":
-		ret = "mate -w"
-	case "vim":
-		ret = "vim"
-	case "nano":
-		ret = "nano"
-	case "emacs":
-		ret = "emacs"
-	case "nvim":
-		ret = "nvim"
-	default:
-		ret = "vi"
-	}
-
-	return ret
+		return "mate -w"
+	}
+
+	return editor
 }
 
-func newEditorCmd(ctx context.DnoteCtx, fpath string) (*exec.Cmd, error) {
-	args := strings.Fields(ctx.Editor)
+// splitCommand splits the given command into its arguments like a shell
+// does. An argument containing spaces can be quoted with single or double
+// quotes, and a backslash escapes the next character outside single quotes.
+func splitCommand(command string) ([]string, error) {
+	ret := []string{}
+
+	var cur strings.Builder
+	var quote rune
+	inArg, escaped := false, false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				ret = append(ret, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.Errorf("unterminated quote in '%s'", command)
+	}
+	if escaped {
+		return nil, errors.Errorf("trailing backslash in '%s'", command)
+	}
+	if inArg {
+		ret = append(ret, cur.String())
+	}
+
+	return ret, nil
+}
+
+// isRunnable checks if the executable of the given editor command can be found
+// and is executable
+func isRunnable(editor string) bool {
+	args, err := splitCommand(editor)
+	if err != nil || len(args) == 0 {
+		return false
+	}
+
+	_, err = exec.LookPath(args[0])
+
+	return err == nil
+}
+
+// getEditorCommand resolves the editor command to launch. It honors the --editor
+// flag, $DNOTE_EDITOR, $EDITOR, $VISUAL and the configured editor, in that order,
+// and falls back to a platform default. Candidates that are not runnable are skipped,
+// except for the flag, which is an explicit choice of the user.
+func getEditorCommand(ctx context.DnoteCtx) (string, error) {
+	if flag := strings.TrimSpace(EditorFlag); flag != "" {
+		if !isRunnable(flag) {
+			return "", errors.Errorf("editor '%s' given by --editor is not runnable", flag)
+		}
+
+		return flag, nil
+	}
+
+	var candidates []string
+	for _, key := range editorEnvs {
+		candidates = append(candidates, withWaitFlags(strings.TrimSpace(os.Getenv(key))))
+	}
+	candidates = append(candidates, strings.TrimSpace(ctx.Editor), getDefaultEditor())
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+
+		if isRunnable(candidate) {
+			return candidate, nil
+		}
+
+		log.Debug("skipping the editor '%s' that is not runnable\n", candidate)
+	}
+
+	return "", errors.New("no runnable editor found. Please set $EDITOR or pass --editor")
+}
+
+// getEditorArgs returns the arguments to launch the given editor command with
+// to edit the file at fpath. The extra arguments are passed after the ones in
+// the command itself and before the file.
+func getEditorArgs(editor string, extra []string, fpath string) ([]string, error) {
+	args, err := splitCommand(editor)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the editor command")
+	}
+	if len(args) == 0 {
+		return nil, errors.New("the editor command is empty")
+	}
+
+	args = append(args, extra...)
 	args = append(args, fpath)
 
+	return args, nil
+}
+
+func newEditorCmd(ctx context.DnoteCtx, fpath string) (*exec.Cmd, error) {
+	editor, err := getEditorCommand(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving the editor")
+	}
+
+	args, err := getEditorArgs(editor, EditorArgsFlag, fpath)
+	if err != nil {
+		return nil, err
+	}
+
 	return exec.Command(args[0], args[1:]...), nil
 }
 
+// ErrEditorCancelled is returned when the editor exits with a non-zero status,
+// e.g. ':cq' in vim, to signal that the content must not be saved
+var ErrEditorCancelled = errors.New("the editor exited with a non-zero status")
+
 // GetEditorInput gets the user input by launching a text editor and waiting for
-// it to exit
+// it to exit. If the editor exits with a non-zero status, the content is
+// discarded and ErrEditorCancelled is returned.
 func GetEditorInput(ctx context.DnoteCtx, fpath string) (string, error) {
 	ok, err := utils.FileExists(fpath)
 	if err != nil {
@@ -118,7 +258,15 @@ func GetEditorInput(ctx context.DnoteCtx, fpath string) (string, error) {
 	}
 
 	err = cmd.Wait()
-	if err != nil {
+	if _, ok := err.(*exec.ExitError); ok {
+		log.Debug("editor exited: %s\n", err.Error())
+
+		if err := os.Remove(fpath); err != nil {
+			return "", errors.Wrap(err, "removing the temporary content file")
+		}
+
+		return "", ErrEditorCancelled
+	} else if err != nil {
 		return "", errors.Wrap(err, "waiting for the editor")
 	}
 
@@ -20,7 +20,10 @@ package ui
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
@@ -97,3 +100,337 @@ func TestGetTmpContentPath(t *testing.T) {
 		assert.Equal(t, res, expected, "filename did not match")
 	})
 }
+
+// setupFakeEditors creates executables with the given names in a temporary
+// directory and prepends the directory to $PATH. It returns a function that
+// cleans up the directory and restores $PATH.
+func setupFakeEditors(t *testing.T, names ...string) func() {
+	dir, err := ioutil.TempDir("", "dnote-fake-editors")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+			t.Fatal(errors.Wrapf(err, "writing a fake editor %s", name))
+		}
+	}
+
+	originalPath := os.Getenv("PATH")
+	os.Setenv("PATH", fmt.Sprintf("%s%c%s", dir, os.PathListSeparator, originalPath))
+
+	return func() {
+		os.Setenv("PATH", originalPath)
+		os.RemoveAll(dir)
+	}
+}
+
+// setEnv sets the environment variables and returns a function that restores
+// their original values
+func setEnv(env map[string]string) func() {
+	original := map[string]string{}
+
+	for _, key := range editorEnvs {
+		original[key] = os.Getenv(key)
+		os.Setenv(key, env[key])
+	}
+
+	return func() {
+		for key, val := range original {
+			os.Setenv(key, val)
+		}
+	}
+}
+
+func TestGetEditorCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editors are shell scripts")
+	}
+
+	cleanup := setupFakeEditors(t, "fake-flag-editor", "fake-dnote-editor", "fake-editor", "fake-visual", "fake-config-editor", "vi")
+	defer cleanup()
+
+	testCases := []struct {
+		flag     string
+		env      map[string]string
+		config   string
+		expected string
+	}{
+		{
+			flag: "fake-flag-editor",
+			env: map[string]string{
+				"DNOTE_EDITOR": "fake-dnote-editor",
+				"EDITOR":       "fake-editor",
+				"VISUAL":       "fake-visual",
+			},
+			config:   "fake-config-editor",
+			expected: "fake-flag-editor",
+		},
+		{
+			flag: "",
+			env: map[string]string{
+				"DNOTE_EDITOR": "fake-dnote-editor",
+				"EDITOR":       "fake-editor",
+				"VISUAL":       "fake-visual",
+			},
+			config:   "fake-config-editor",
+			expected: "fake-dnote-editor",
+		},
+		{
+			flag: "",
+			env: map[string]string{
+				"EDITOR": "fake-editor",
+				"VISUAL": "fake-visual",
+			},
+			config:   "fake-config-editor",
+			expected: "fake-editor",
+		},
+		{
+			flag: "",
+			env: map[string]string{
+				"VISUAL": "fake-visual",
+			},
+			config:   "fake-config-editor",
+			expected: "fake-visual",
+		},
+		{
+			flag:     "",
+			env:      map[string]string{},
+			config:   "fake-config-editor",
+			expected: "fake-config-editor",
+		},
+		{
+			flag:     "",
+			env:      map[string]string{},
+			config:   "",
+			expected: "vi",
+		},
+		{
+			flag:     " fake-flag-editor --wait ",
+			env:      map[string]string{},
+			config:   "",
+			expected: "fake-flag-editor --wait",
+		},
+		{
+			flag: "",
+			env: map[string]string{
+				"DNOTE_EDITOR": "not-installed-editor",
+				"EDITOR":       "fake-editor --wait",
+			},
+			config:   "",
+			expected: "fake-editor --wait",
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			restore := setEnv(tc.env)
+			defer restore()
+
+			EditorFlag = tc.flag
+			defer func() { EditorFlag = "" }()
+
+			ctx := context.DnoteCtx{Editor: tc.config}
+
+			result, err := getEditorCommand(ctx)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.Equal(t, result, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("flag not runnable", func(t *testing.T) {
+		restore := setEnv(map[string]string{"EDITOR": "fake-editor"})
+		defer restore()
+
+		EditorFlag = "not-installed-editor"
+		defer func() { EditorFlag = "" }()
+
+		_, err := getEditorCommand(context.DnoteCtx{})
+		assert.NotEqual(t, err, nil, "error mismatch")
+	})
+
+	t.Run("none runnable", func(t *testing.T) {
+		restore := setEnv(map[string]string{"EDITOR": "not-installed-editor"})
+		defer restore()
+
+		originalPath := os.Getenv("PATH")
+		os.Setenv("PATH", "")
+		defer os.Setenv("PATH", originalPath)
+
+		_, err := getEditorCommand(context.DnoteCtx{Editor: "another-not-installed-editor"})
+		assert.NotEqual(t, err, nil, "error mismatch")
+	})
+}
+
+func TestNewEditorCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editors are shell scripts")
+	}
+
+	cleanup := setupFakeEditors(t, "fake-editor")
+	defer cleanup()
+
+	restore := setEnv(map[string]string{"EDITOR": "fake-editor --wait"})
+	defer restore()
+
+	cmd, err := newEditorCmd(context.DnoteCtx{}, "/tmp/note.md")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	assert.DeepEqual(t, cmd.Args, []string{"fake-editor", "--wait", "/tmp/note.md"}, "args mismatch")
+}
+
+func TestSplitCommand(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "vim", expected: []string{"vim"}},
+		{input: "  emacs   -nw ", expected: []string{"emacs", "-nw"}},
+		{input: `"/opt/My Editor/bin/edit" --wait`, expected: []string{"/opt/My Editor/bin/edit", "--wait"}},
+		{input: `emacs --eval '(setq x "y")'`, expected: []string{"emacs", "--eval", `(setq x "y")`}},
+		{input: `/opt/My\ Editor/edit -w`, expected: []string{"/opt/My Editor/edit", "-w"}},
+		{input: `code ""`, expected: []string{"code", ""}},
+		{input: "", expected: []string{}},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			got, err := splitCommand(tc.input)
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("unterminated quote", func(t *testing.T) {
+		_, err := splitCommand(`"/opt/My Editor/edit -w`)
+		assert.NotEqual(t, err, nil, "error mismatch")
+	})
+
+	t.Run("trailing backslash", func(t *testing.T) {
+		_, err := splitCommand(`vim \`)
+		assert.NotEqual(t, err, nil, "error mismatch")
+	})
+}
+
+func TestGetEditorArgs(t *testing.T) {
+	testCases := []struct {
+		editor   string
+		extra    []string
+		expected []string
+	}{
+		{
+			editor:   "vim",
+			extra:    []string{},
+			expected: []string{"vim", "/tmp/note.md"},
+		},
+		{
+			editor:   "emacs",
+			extra:    []string{"-nw"},
+			expected: []string{"emacs", "-nw", "/tmp/note.md"},
+		},
+		{
+			editor:   "code -n",
+			extra:    []string{"--wait"},
+			expected: []string{"code", "-n", "--wait", "/tmp/note.md"},
+		},
+		{
+			editor:   `"/opt/My Editor/edit" -w`,
+			extra:    []string{"--line", "1 2"},
+			expected: []string{"/opt/My Editor/edit", "-w", "--line", "1 2", "/tmp/note.md"},
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			got, err := getEditorArgs(tc.editor, tc.extra, "/tmp/note.md")
+			if err != nil {
+				t.Fatal(errors.Wrap(err, "executing"))
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("empty editor", func(t *testing.T) {
+		_, err := getEditorArgs("  ", []string{"-w"}, "/tmp/note.md")
+		assert.NotEqual(t, err, nil, "error mismatch")
+	})
+}
+
+func TestNewEditorCmdWithArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editors are shell scripts")
+	}
+
+	cleanup := setupFakeEditors(t, "fake-editor")
+	defer cleanup()
+
+	restore := setEnv(map[string]string{"EDITOR": "fake-editor --wait"})
+	defer restore()
+
+	editorArgs := EditorArgsFlag
+	EditorArgsFlag = []string{"-nw", "+1"}
+	defer func() { EditorArgsFlag = editorArgs }()
+
+	cmd, err := newEditorCmd(context.DnoteCtx{}, "/tmp/note.md")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+
+	assert.DeepEqual(t, cmd.Args, []string{"fake-editor", "--wait", "-nw", "+1", "/tmp/note.md"}, "args mismatch")
+}
+
+func TestIsRunnableNotExecutable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnote-editor")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "not-executable")
+	if err := ioutil.WriteFile(p, []byte("#!/bin/sh\nexit 0\n"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing a file"))
+	}
+
+	assert.Equal(t, isRunnable(p), false, "a file without the executable bit should not be runnable")
+}
+
+func TestGetEditorInputCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editors are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "dnote-editor")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a temporary directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	editor := filepath.Join(dir, "failing-editor")
+	if err := ioutil.WriteFile(editor, []byte("#!/bin/sh\nprintf 'discarded' > \"$1\"\nexit 1\n"), 0755); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the editor"))
+	}
+
+	EditorFlag = editor
+	defer func() { EditorFlag = "" }()
+
+	fpath := filepath.Join(dir, "note.md")
+	if err := ioutil.WriteFile(fpath, []byte("seed"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the content file"))
+	}
+
+	_, err = GetEditorInput(context.DnoteCtx{}, fpath)
+	assert.Equal(t, err, ErrEditorCancelled, "error mismatch")
+
+	_, err = os.Stat(fpath)
+	assert.Equal(t, os.IsNotExist(err), true, "the temporary content file should be removed")
+}
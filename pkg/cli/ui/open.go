@@ -0,0 +1,79 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// getDefaultOpener returns the command that opens a file in the default
+// application on the given operating system
+func getDefaultOpener(goos string) []string {
+	switch goos {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		// the empty argument is the title of the window, which start would
+		// otherwise take from a quoted path
+		return []string{"cmd", "/c", "start", ""}
+	}
+
+	return []string{"xdg-open"}
+}
+
+// getOpenerArgs returns the arguments to open the file at fpath with. If no
+// opener command is given, the default of the operating system is used.
+func getOpenerArgs(opener, goos, fpath string) ([]string, error) {
+	if opener == "" {
+		return append(getDefaultOpener(goos), fpath), nil
+	}
+
+	args, err := splitCommand(opener)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the opener command")
+	}
+	if len(args) == 0 {
+		return nil, errors.New("the opener command is empty")
+	}
+
+	return append(args, fpath), nil
+}
+
+// OpenFile opens the file at fpath with the given opener command, or the
+// default application of the operating system if it is empty
+func OpenFile(opener, fpath string) error {
+	args, err := getOpenerArgs(opener, runtime.GOOS, fpath)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "running '%s'", args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestGetOpenerArgs(t *testing.T) {
+	testCases := []struct {
+		opener   string
+		goos     string
+		expected []string
+	}{
+		{
+			goos:     "linux",
+			expected: []string{"xdg-open", "/tmp/dnote-js.md"},
+		},
+		{
+			goos:     "freebsd",
+			expected: []string{"xdg-open", "/tmp/dnote-js.md"},
+		},
+		{
+			goos:     "darwin",
+			expected: []string{"open", "/tmp/dnote-js.md"},
+		},
+		{
+			goos:     "windows",
+			expected: []string{"cmd", "/c", "start", "", "/tmp/dnote-js.md"},
+		},
+		{
+			opener:   "typora",
+			goos:     "linux",
+			expected: []string{"typora", "/tmp/dnote-js.md"},
+		},
+		{
+			opener:   `open -a "Marked 2"`,
+			goos:     "darwin",
+			expected: []string{"open", "-a", "Marked 2", "/tmp/dnote-js.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.goos+" "+tc.opener, func(t *testing.T) {
+			got, err := getOpenerArgs(tc.opener, tc.goos, "/tmp/dnote-js.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "result mismatch")
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := getOpenerArgs(`open -a "Marked 2`, "darwin", "/tmp/dnote-js.md")
+
+		assert.NotEqual(t, err, nil, "error should be returned")
+	})
+}
@@ -0,0 +1,75 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"github.com/dnote/dnote/pkg/cli/log"
+)
+
+// maxProgressUpdates is the most number of times a progress is reported so
+// that a bulk operation on many items does not flood the output
+const maxProgressUpdates = 10
+
+// Progress reports how many items of a bulk operation are done
+type Progress struct {
+	total    int
+	done     int
+	interval int
+	// report is called with the number of the items done and the total.
+	// Nothing is reported if it is nil.
+	report func(done, total int)
+}
+
+func newProgress(total int, report func(done, total int)) *Progress {
+	interval := (total + maxProgressUpdates - 1) / maxProgressUpdates
+	if interval < 1 {
+		interval = 1
+	}
+
+	return &Progress{
+		total:    total,
+		interval: interval,
+		report:   report,
+	}
+}
+
+// NewProgress returns a progress that prints '<label> k/N done' as the items
+// are done. It prints nothing if quiet is true.
+func NewProgress(label string, total int, quiet bool) *Progress {
+	if quiet {
+		return newProgress(total, nil)
+	}
+
+	return newProgress(total, func(done, total int) {
+		log.Infof("%s %d/%d done\n", label, done, total)
+	})
+}
+
+// Done marks one more item as done. The progress is reported at every interval
+// and when the last item is done.
+func (p *Progress) Done() {
+	p.done++
+
+	if p.report == nil {
+		return
+	}
+	if p.done%p.interval == 0 || p.done == p.total {
+		p.report(p.done, p.total)
+	}
+}
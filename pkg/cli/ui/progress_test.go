@@ -0,0 +1,93 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestProgress(t *testing.T) {
+	testCases := []struct {
+		total    int
+		expected []int
+	}{
+		{
+			total:    1,
+			expected: []int{1},
+		},
+		{
+			total:    3,
+			expected: []int{1, 2, 3},
+		},
+		{
+			total:    10,
+			expected: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+		{
+			total:    25,
+			expected: []int{3, 6, 9, 12, 15, 18, 21, 24, 25},
+		},
+		{
+			total:    100,
+			expected: []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("total %d", tc.total), func(t *testing.T) {
+			got := []int{}
+			p := newProgress(tc.total, func(done, total int) {
+				assert.Equal(t, total, tc.total, "total mismatch")
+				got = append(got, done)
+			})
+
+			for i := 0; i < tc.total; i++ {
+				p.Done()
+			}
+
+			assert.DeepEqual(t, got, tc.expected, "reported progress mismatch")
+		})
+	}
+
+	t.Run("many items", func(t *testing.T) {
+		count := 0
+		p := newProgress(10007, func(done, total int) {
+			count++
+		})
+
+		for i := 0; i < 10007; i++ {
+			p.Done()
+		}
+
+		assert.Equal(t, count <= maxProgressUpdates+1, true, fmt.Sprintf("reported %d times", count))
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		p := NewProgress("archiving", 5, true)
+		for i := 0; i < 5; i++ {
+			p.Done()
+		}
+
+		assert.Equal(t, p.report == nil, true, "report should be nil")
+		assert.Equal(t, p.done, 5, "done mismatch")
+	})
+}
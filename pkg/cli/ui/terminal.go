@@ -21,6 +21,7 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 	"syscall"
@@ -40,6 +41,26 @@ func readInput() (string, error) {
 	return strings.Trim(input, "\r\n"), nil
 }
 
+// ReadPipedInput returns the content piped or redirected to the standard input.
+// It returns false without reading if the standard input is a terminal or
+// another character device such as /dev/null.
+func ReadPipedInput() (string, bool, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", false, errors.Wrap(err, "inspecting stdin")
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		return "", false, nil
+	}
+
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", false, errors.Wrap(err, "reading stdin")
+	}
+
+	return string(b), true, nil
+}
+
 // PromptInput prompts the user input and saves the result to the destination
 func PromptInput(message string, dest *string) error {
 	log.Askf(message, false)
@@ -19,7 +19,10 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/dnote/dnote/pkg/server/database"
@@ -27,8 +30,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// pageSize is the number of items in a page of paginated results
+const pageSize = 30
+
 func paginate(conn *gorm.DB, page int) *gorm.DB {
-	limit := 30
+	limit := pageSize
 
 	// Paginate
 	if page > 0 {
@@ -41,6 +47,51 @@ func paginate(conn *gorm.DB, page int) *gorm.DB {
 	return conn
 }
 
+// getRequestURL returns the URL of the request as the client sent it. Unlike
+// r.URL, it keeps the prefix that the router strips, such as "/api".
+func getRequestURL(r *http.Request) *url.URL {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u
+	}
+
+	return r.URL
+}
+
+func getPageURL(u *url.URL, page int) string {
+	ret := *u
+	q := ret.Query()
+	q.Set("page", strconv.Itoa(page))
+	ret.RawQuery = q.Encode()
+
+	return ret.String()
+}
+
+// setPaginationHeaders sets the X-Total-Count header and the Link header with
+// the next and prev pages, if any, for the given page of the paginated result
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	u := getRequestURL(r)
+
+	links := []string{}
+	if page*pageSize < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, getPageURL(u, page+1)))
+	}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, getPageURL(u, page-1)))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// isBodyTooLarge checks if the error is from reading past the limit of a
+// request body wrapped by http.MaxBytesReader
+func isBodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
+
 func getBookIDs(books []database.Book) []int {
 	ret := []int{}
 
@@ -0,0 +1,53 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+)
+
+func TestGetClientType(t *testing.T) {
+	testCases := []struct {
+		origin    string
+		userAgent string
+		expected  string
+	}{
+		{origin: "moz-extension://abc", userAgent: "Mozilla/5.0", expected: "firefox-extension"},
+		{origin: "chrome-extension://abc", userAgent: "Mozilla/5.0", expected: "chrome-extension"},
+		{origin: "", userAgent: "Go-http-client/1.1", expected: "cli"},
+		{origin: "https://app.getdnote.com", userAgent: "Mozilla/5.0", expected: "web"},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			r, err := http.NewRequest("POST", "/v3/notes", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Origin", tc.origin)
+			r.Header.Set("User-Agent", tc.userAgent)
+
+			assert.Equal(t, getClientType(r), tc.expected, "client type mismatch")
+		})
+	}
+}
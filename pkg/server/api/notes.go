@@ -71,14 +71,17 @@ notes.edited_on,
 notes.usn,
 notes.deleted,
 notes.encrypted,
+notes.client,
 ts_headline('english_nostop', notes.body, plainto_tsquery('english_nostop', ?), ?) AS body
 	`, search, headlineOpts)
 }
 
-func respondWithNote(w http.ResponseWriter, note database.Note) {
+// respondWithNote responds with the note, or with 304 Not Modified if the
+// client already has the same representation of it
+func respondWithNote(w http.ResponseWriter, r *http.Request, note database.Note) {
 	presentedNote := presenters.PresentNote(note)
 
-	handlers.RespondJSON(w, http.StatusOK, presentedNote)
+	handlers.RespondJSONWithETag(w, r, presentedNote)
 }
 
 func parseSearchQuery(q url.Values) string {
@@ -110,17 +113,19 @@ func (a *API) getNote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	noteUUID := vars["noteUUID"]
 
+	// Respond with not found rather than forbidden for the notes that the user
+	// cannot view, so as not to reveal which uuids exist
 	note, ok, err := operations.GetNote(a.App.DB, noteUUID, user)
-	if !ok {
-		handlers.RespondNotFound(w)
-		return
-	}
 	if err != nil {
 		handlers.DoError(w, "finding note", err, http.StatusInternalServerError)
 		return
 	}
+	if !ok {
+		handlers.RespondNotFound(w)
+		return
+	}
 
-	respondWithNote(w, note)
+	respondWithNote(w, r, note)
 }
 
 /**** getNotesHandler */
@@ -142,18 +147,24 @@ func (a *API) getNotes(w http.ResponseWriter, r *http.Request) {
 		handlers.DoError(w, "No authenticated user found", nil, http.StatusInternalServerError)
 		return
 	}
-	query := r.URL.Query()
 
-	respondGetNotes(a.App.DB, user.ID, query, w)
+	respondGetNotes(a.App.DB, user.ID, r, w)
 }
 
-func respondGetNotes(db *gorm.DB, userID int, query url.Values, w http.ResponseWriter) {
-	q, err := parseGetNotesQuery(query)
+func respondGetNotes(db *gorm.DB, userID int, r *http.Request, w http.ResponseWriter) {
+	q, err := parseGetNotesQuery(r.URL.Query())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	respondWithNotesQuery(db, userID, q, r, w)
+}
+
+// respondWithNotesQuery responds with a page of notes matching the given query,
+// or with 304 Not Modified if the client already has the same page. The URL of
+// the request is used to build the links to the adjacent pages.
+func respondWithNotesQuery(db *gorm.DB, userID int, q getNotesQuery, r *http.Request, w http.ResponseWriter) {
 	conn := getNotesBaseQuery(db, userID, q)
 
 	var total int
@@ -174,11 +185,13 @@ func respondGetNotes(db *gorm.DB, userID int, query url.Values, w http.ResponseW
 		}
 	}
 
+	setPaginationHeaders(w, r, q.Page, total)
+
 	response := GetNotesResponse{
 		Notes: presenters.PresentNotes(notes),
 		Total: total,
 	}
-	handlers.RespondJSON(w, http.StatusOK, response)
+	handlers.RespondJSONWithETag(w, r, response)
 }
 
 type getNotesQuery struct {
@@ -186,8 +199,10 @@ type getNotesQuery struct {
 	Month     int
 	Page      int
 	Books     []string
+	BookUUID  string
 	Search    string
 	Encrypted bool
+	Source    string
 }
 
 func parseGetNotesQuery(q url.Values) (getNotesQuery, error) {
@@ -251,6 +266,7 @@ func parseGetNotesQuery(q url.Values) (getNotesQuery, error) {
 		Search:    parseSearchQuery(q),
 		Books:     books,
 		Encrypted: encrypted,
+		Source:    q.Get("source"),
 	}
 
 	return ret, nil
@@ -288,6 +304,12 @@ func getNotesBaseQuery(db *gorm.DB, userID int, q getNotesQuery) *gorm.DB {
 		conn = conn.Joins("INNER JOIN books ON books.uuid = notes.book_uuid").
 			Where("books.label in (?)", q.Books)
 	}
+	if q.BookUUID != "" {
+		conn = conn.Where("notes.book_uuid = ?", q.BookUUID)
+	}
+	if q.Source != "" {
+		conn = conn.Where("notes.client = ?", q.Source)
+	}
 
 	if q.Year != 0 || q.Month != 0 {
 		dateLowerbound, dateUpperbound := getDateBounds(q.Year, q.Month)
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -43,7 +44,9 @@ func getExpectedNotePayload(n database.Note, b database.Book, u database.User) p
 		Body:      n.Body,
 		AddedOn:   n.AddedOn,
 		Public:    n.Public,
+		ShareSlug: n.ShareSlug,
 		USN:       n.USN,
+		Source:    n.Client,
 		Book: presenters.NoteBook{
 			UUID:  b.UUID,
 			Label: b.Label,
@@ -164,6 +167,56 @@ func TestGetNotes(t *testing.T) {
 	assert.DeepEqual(t, payload, expected, "payload mismatch")
 }
 
+func TestGetNotesSource(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	n1 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n1 content",
+		Client:   "cli",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n2 content",
+		Client:   "web",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+
+	// Execute
+	req := testutils.MakeReq(server.URL, "GET", "/notes?source=web", "")
+	res := testutils.HTTPAuthDo(t, req, user)
+
+	// Test
+	assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+	var payload GetNotesResponse
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding payload"))
+	}
+
+	assert.Equal(t, payload.Total, 1, "total mismatch")
+	assert.Equal(t, len(payload.Notes), 1, "note count mismatch")
+	assert.Equal(t, payload.Notes[0].UUID, n2.UUID, "note mismatch")
+	assert.Equal(t, payload.Notes[0].Source, "web", "source mismatch")
+}
+
 func TestGetNote(t *testing.T) {
 	defer testutils.ClearData(testutils.DB)
 
@@ -321,6 +374,32 @@ func TestGetNote(t *testing.T) {
 		assert.DeepEqual(t, string(body), "not found\n", "payload mismatch")
 	})
 
+	t.Run("expired session accessing private note", func(t *testing.T) {
+		// Setup
+		session := database.Session{
+			Key:       "Vvgm3eBXfXGEFWERI7faiRJ3DAzJw+7DdT9J1LEyNfI=",
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(-time.Hour * 24),
+		}
+		testutils.MustExec(t, testutils.DB.Save(&session), "preparing session")
+
+		// Execute
+		url := fmt.Sprintf("/notes/%s", privateNote.UUID)
+		req := testutils.MakeReq(server.URL, "GET", url, "")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", session.Key))
+		res := testutils.HTTPDo(t, req)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "reading body"))
+		}
+
+		assert.DeepEqual(t, string(body), "not found\n", "payload mismatch")
+	})
+
 	t.Run("nonexistent", func(t *testing.T) {
 		// Execute
 		url := fmt.Sprintf("/notes/%s", "someRandomString")
@@ -355,3 +434,190 @@ func TestGetNote(t *testing.T) {
 		assert.DeepEqual(t, string(body), "not found\n", "payload mismatch")
 	})
 }
+
+func TestGetNotesPaginationHeaders(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	for i := 0; i < 65; i++ {
+		n := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     fmt.Sprintf("note %d", i),
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n), fmt.Sprintf("preparing note %d", i))
+	}
+
+	testCases := []struct {
+		path         string
+		expectedLink string
+	}{
+		{
+			path:         "/notes",
+			expectedLink: `</notes?page=2>; rel="next"`,
+		},
+		{
+			path:         "/notes?page=2",
+			expectedLink: `</notes?page=3>; rel="next", </notes?page=1>; rel="prev"`,
+		},
+		{
+			path:         "/notes?page=3",
+			expectedLink: `</notes?page=2>; rel="prev"`,
+		},
+		{
+			path:         "/notes?book=js&page=2",
+			expectedLink: `</notes?book=js&page=3>; rel="next", </notes?book=js&page=1>; rel="prev"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			// Execute
+			req := testutils.MakeReq(server.URL, "GET", tc.path, "")
+			res := testutils.HTTPAuthDo(t, req, user)
+
+			// Test
+			assert.StatusCodeEquals(t, res, http.StatusOK, "")
+			assert.Equal(t, res.Header.Get("X-Total-Count"), "65", "X-Total-Count mismatch")
+			assert.Equal(t, res.Header.Get("Link"), tc.expectedLink, "Link mismatch")
+		})
+	}
+
+	t.Run("mounted under /api", func(t *testing.T) {
+		// Setup
+		a := NewTestAPI(&app.App{Clock: clock.NewMock()})
+		r, err := NewRouter(&a)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "initializing router"))
+		}
+
+		// the router is mounted the same way as in the main package
+		mux := http.NewServeMux()
+		mux.Handle("/api/", http.StripPrefix("/api", r))
+		apiServer := httptest.NewServer(mux)
+		defer apiServer.Close()
+
+		// Execute
+		req := testutils.MakeReq(apiServer.URL, "GET", "/api/notes?page=2", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+		assert.Equal(t, res.Header.Get("Link"), `</api/notes?page=3>; rel="next", </api/notes?page=1>; rel="prev"`, "Link mismatch")
+	})
+
+	t.Run("single page", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/notes?year=2000", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+		assert.Equal(t, res.Header.Get("X-Total-Count"), "0", "X-Total-Count mismatch")
+		assert.Equal(t, res.Header.Get("Link"), "", "Link mismatch")
+	})
+}
+
+func TestGetNotesETag(t *testing.T) {
+	testCases := []struct {
+		name string
+		path func(note database.Note) string
+	}{
+		{
+			name: "note",
+			path: func(note database.Note) string {
+				return fmt.Sprintf("/notes/%s", note.UUID)
+			},
+		},
+		{
+			name: "notes",
+			path: func(note database.Note) string {
+				return "/notes"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			// Setup
+			server := MustNewServer(t, &app.App{
+				Clock: clock.NewMock(),
+			})
+			defer server.Close()
+
+			user := testutils.SetupUserData()
+
+			b1 := database.Book{
+				UserID: user.ID,
+				Label:  "js",
+			}
+			testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+			n1 := database.Note{
+				UserID:   user.ID,
+				BookUUID: b1.UUID,
+				Body:     "n1 content",
+			}
+			testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+			path := tc.path(n1)
+
+			// Execute
+			req := testutils.MakeReq(server.URL, "GET", path, "")
+			res := testutils.HTTPAuthDo(t, req, user)
+
+			// Test
+			assert.StatusCodeEquals(t, res, http.StatusOK, "")
+			etag := res.Header.Get("ETag")
+			assert.NotEqual(t, etag, "", "ETag should be set")
+
+			t.Run("matching If-None-Match", func(t *testing.T) {
+				// Execute
+				req := testutils.MakeReq(server.URL, "GET", path, "")
+				req.Header.Set("If-None-Match", etag)
+				res := testutils.HTTPAuthDo(t, req, user)
+
+				// Test
+				assert.StatusCodeEquals(t, res, http.StatusNotModified, "")
+				assert.Equal(t, res.Header.Get("ETag"), etag, "ETag mismatch")
+
+				body, err := ioutil.ReadAll(res.Body)
+				if err != nil {
+					t.Fatal(errors.Wrap(err, "reading body"))
+				}
+				assert.Equal(t, len(body), 0, "body should be empty")
+			})
+
+			t.Run("after a change", func(t *testing.T) {
+				// Setup
+				testutils.MustExec(t, testutils.DB.Model(&n1).Update("body", "n1 content updated"), "updating n1")
+
+				// Execute
+				req := testutils.MakeReq(server.URL, "GET", path, "")
+				req.Header.Set("If-None-Match", etag)
+				res := testutils.HTTPAuthDo(t, req, user)
+
+				// Test
+				assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+				newETag := res.Header.Get("ETag")
+				assert.NotEqual(t, newETag, "", "ETag should be set")
+				assert.NotEqual(t, newETag, etag, "ETag should change")
+			})
+		})
+	}
+}
@@ -51,6 +51,8 @@ func applyMiddleware(h http.HandlerFunc, rateLimit bool) http.Handler {
 		ret = handlers.Limit(ret)
 	}
 
+	ret = handlers.RequestID(ret)
+
 	return ret
 }
 
@@ -76,25 +78,32 @@ func NewRouter(a *API) (*mux.Router, error) {
 		{Method: "GET", Pattern: "/account/email-preference", HandlerFunc: handlers.TokenAuth(app, a.getEmailPreference, database.TokenTypeEmailPreference, nil), RateLimit: true},
 		{Method: "PATCH", Pattern: "/account/email-preference", HandlerFunc: handlers.TokenAuth(app, a.updateEmailPreference, database.TokenTypeEmailPreference, nil), RateLimit: true},
 		{Method: "GET", Pattern: "/notes", HandlerFunc: handlers.Auth(app, a.getNotes, nil), RateLimit: false},
+		// getNote authenticates optionally, so that public notes can be viewed by anyone
 		{Method: "GET", Pattern: "/notes/{noteUUID}", HandlerFunc: a.getNote, RateLimit: true},
+		{Method: "GET", Pattern: "/shared/{slug}", HandlerFunc: a.getSharedNote, RateLimit: true},
 		{Method: "GET", Pattern: "/calendar", HandlerFunc: handlers.Auth(app, a.getCalendar, nil), RateLimit: true},
 
 		// v3
-		{Method: "GET", Pattern: "/v3/sync/fragment", HandlerFunc: handlers.Cors(handlers.Auth(app, a.GetSyncFragment, &proOnly)), RateLimit: false},
-		{Method: "GET", Pattern: "/v3/sync/state", HandlerFunc: handlers.Cors(handlers.Auth(app, a.GetSyncState, &proOnly)), RateLimit: false},
-		{Method: "OPTIONS", Pattern: "/v3/books", HandlerFunc: handlers.Cors(a.BooksOptions), RateLimit: true},
-		{Method: "GET", Pattern: "/v3/books", HandlerFunc: handlers.Cors(handlers.Auth(app, a.GetBooks, &proOnly)), RateLimit: true},
-		{Method: "GET", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(handlers.Auth(app, a.GetBook, &proOnly)), RateLimit: true},
-		{Method: "POST", Pattern: "/v3/books", HandlerFunc: handlers.Cors(handlers.Auth(app, a.CreateBook, &proOnly)), RateLimit: false},
-		{Method: "PATCH", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(handlers.Auth(app, a.UpdateBook, &proOnly)), RateLimit: false},
-		{Method: "DELETE", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(handlers.Auth(app, a.DeleteBook, &proOnly)), RateLimit: false},
-		{Method: "OPTIONS", Pattern: "/v3/notes", HandlerFunc: handlers.Cors(a.NotesOptions), RateLimit: true},
-		{Method: "POST", Pattern: "/v3/notes", HandlerFunc: handlers.Cors(handlers.Auth(app, a.CreateNote, &proOnly)), RateLimit: false},
+		{Method: "GET", Pattern: "/v3/sync/fragment", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetSyncFragment, &proOnly)), RateLimit: false},
+		{Method: "GET", Pattern: "/v3/sync/state", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetSyncState, &proOnly)), RateLimit: false},
+		{Method: "OPTIONS", Pattern: "/v3/books", HandlerFunc: handlers.Cors(app, a.BooksOptions), RateLimit: true},
+		{Method: "GET", Pattern: "/v3/books", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetBooks, &proOnly)), RateLimit: true},
+		{Method: "GET", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetBook, &proOnly)), RateLimit: true},
+		{Method: "GET", Pattern: "/v3/books/{bookUUID}/notes", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetBookNotes, &proOnly)), RateLimit: true},
+		{Method: "POST", Pattern: "/v3/books", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.CreateBook, &proOnly)), RateLimit: false},
+		{Method: "PATCH", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.UpdateBook, &proOnly)), RateLimit: false},
+		{Method: "DELETE", Pattern: "/v3/books/{bookUUID}", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.DeleteBook, &proOnly)), RateLimit: false},
+		{Method: "OPTIONS", Pattern: "/v3/notes", HandlerFunc: handlers.Cors(app, a.NotesOptions), RateLimit: true},
+		{Method: "POST", Pattern: "/v3/notes", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.CreateNote, &proOnly)), RateLimit: false},
+		{Method: "GET", Pattern: "/v3/notes/count", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetNotesCount, &proOnly)), RateLimit: true},
+		{Method: "GET", Pattern: "/v3/export", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.GetExport, &proOnly)), RateLimit: true},
+		{Method: "POST", Pattern: "/v3/import", HandlerFunc: handlers.Cors(app, handlers.Auth(app, a.PostImport, &proOnly)), RateLimit: false},
 		{Method: "PATCH", Pattern: "/v3/notes/{noteUUID}", HandlerFunc: handlers.Auth(app, a.UpdateNote, &proOnly), RateLimit: false},
 		{Method: "DELETE", Pattern: "/v3/notes/{noteUUID}", HandlerFunc: handlers.Auth(app, a.DeleteNote, &proOnly), RateLimit: false},
-		{Method: "POST", Pattern: "/v3/signin", HandlerFunc: handlers.Cors(a.signin), RateLimit: true},
-		{Method: "OPTIONS", Pattern: "/v3/signout", HandlerFunc: handlers.Cors(a.signoutOptions), RateLimit: true},
-		{Method: "POST", Pattern: "/v3/signout", HandlerFunc: handlers.Cors(a.signout), RateLimit: true},
+		{Method: "POST", Pattern: "/v3/notes/{noteUUID}/restore", HandlerFunc: handlers.Auth(app, a.RestoreNote, &proOnly), RateLimit: false},
+		{Method: "POST", Pattern: "/v3/signin", HandlerFunc: handlers.Cors(app, a.signin), RateLimit: true},
+		{Method: "OPTIONS", Pattern: "/v3/signout", HandlerFunc: handlers.Cors(app, a.signoutOptions), RateLimit: true},
+		{Method: "POST", Pattern: "/v3/signout", HandlerFunc: handlers.Cors(app, a.signout), RateLimit: true},
 		{Method: "POST", Pattern: "/v3/register", HandlerFunc: a.register, RateLimit: true},
 	}
 
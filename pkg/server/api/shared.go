@@ -0,0 +1,49 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/handlers"
+	"github.com/gorilla/mux"
+)
+
+// getSharedNote responds with the public note that has the given share slug.
+// It does not require authentication.
+func (a *API) getSharedNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	slug := vars["slug"]
+
+	var note database.Note
+	conn := a.App.DB.Where("share_slug = ? AND public = ? AND deleted = ?", slug, true, false)
+	conn = database.PreloadNote(conn).First(&note)
+
+	if conn.RecordNotFound() {
+		handlers.RespondNotFound(w)
+		return
+	}
+	if err := conn.Error; err != nil {
+		handlers.DoError(w, "finding shared note", err, http.StatusInternalServerError)
+		return
+	}
+
+	respondWithNote(w, r, note)
+}
@@ -0,0 +1,118 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func TestGetSharedNote(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	n1 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n1 content",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+	setPublic := func(t *testing.T, public bool) updateNoteResp {
+		dat := fmt.Sprintf(`{"public": %t}`, public)
+		req := testutils.MakeReq(server.URL, "PATCH", fmt.Sprintf("/v3/notes/%s", n1.UUID), dat)
+		res := testutils.HTTPAuthDo(t, req, user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "updating the note")
+
+		var payload updateNoteResp
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		return payload
+	}
+
+	getShared := func(slug string) *http.Response {
+		req := testutils.MakeReq(server.URL, "GET", fmt.Sprintf("/shared/%s", slug), "")
+		return testutils.HTTPDo(t, req)
+	}
+
+	// Execute and test
+	payload := setPublic(t, true)
+	slug := payload.Result.ShareSlug
+	assert.NotEqual(t, slug, "", "share slug should be generated")
+
+	t.Run("public", func(t *testing.T) {
+		res := getShared(slug)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		var got struct {
+			UUID    string `json:"uuid"`
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		assert.Equal(t, got.UUID, n1.UUID, "uuid mismatch")
+		assert.Equal(t, got.Content, "n1 content", "content mismatch")
+	})
+
+	t.Run("private", func(t *testing.T) {
+		payload := setPublic(t, false)
+		assert.Equal(t, payload.Result.ShareSlug, slug, "share slug should be kept")
+
+		res := getShared(slug)
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+	})
+
+	t.Run("public again", func(t *testing.T) {
+		payload := setPublic(t, true)
+		assert.Equal(t, payload.Result.ShareSlug, slug, "share slug should be stable")
+
+		res := getShared(slug)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+	})
+
+	t.Run("nonexistent slug", func(t *testing.T) {
+		res := getShared("nonexistentSlug")
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+	})
+}
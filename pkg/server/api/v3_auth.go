@@ -65,6 +65,19 @@ type signinPayload struct {
 	Password string `json:"password"`
 }
 
+func validateSigninPayload(p signinPayload) handlers.ValidationError {
+	ret := handlers.ValidationError{}
+
+	if p.Email == "" {
+		ret["email"] = handlers.FieldRequired
+	}
+	if p.Password == "" {
+		ret["password"] = handlers.FieldRequired
+	}
+
+	return ret
+}
+
 func (a *API) signin(w http.ResponseWriter, r *http.Request) {
 	var params signinPayload
 	err := json.NewDecoder(r.Body).Decode(&params)
@@ -72,8 +85,8 @@ func (a *API) signin(w http.ResponseWriter, r *http.Request) {
 		handlers.DoError(w, "decoding payload", err, http.StatusInternalServerError)
 		return
 	}
-	if params.Email == "" || params.Password == "" {
-		http.Error(w, ErrLoginFailure.Error(), http.StatusUnauthorized)
+	if err := validateSigninPayload(params); len(err) > 0 {
+		handlers.RespondValidationError(w, err)
 		return
 	}
 
@@ -142,15 +155,19 @@ type registerPayload struct {
 	Password string `json:"password"`
 }
 
-func validateRegisterPayload(p registerPayload) error {
+func validateRegisterPayload(p registerPayload) handlers.ValidationError {
+	ret := handlers.ValidationError{}
+
 	if p.Email == "" {
-		return errors.New("email is required")
+		ret["email"] = handlers.FieldRequired
 	}
-	if len(p.Password) < 8 {
-		return errors.New("Password should be longer than 8 characters")
+	if p.Password == "" {
+		ret["password"] = handlers.FieldRequired
+	} else if validatePassword(p.Password) != nil {
+		ret["password"] = handlers.FieldTooShort
 	}
 
-	return nil
+	return ret
 }
 
 func parseRegisterPaylaod(r *http.Request) (registerPayload, error) {
@@ -173,8 +190,8 @@ func (a *API) register(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
-	if err := validateRegisterPayload(params); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := validateRegisterPayload(params); len(err) > 0 {
+		handlers.RespondValidationError(w, err)
 		return
 	}
 
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	"github.com/dnote/dnote/pkg/server/app"
 	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/handlers"
 	"github.com/dnote/dnote/pkg/server/testutils"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
@@ -139,6 +141,42 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestRegisterWithoutSMTP(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	emailBackend := testutils.MockEmailbackendImplementation{}
+	a := app.NewTest(&app.App{
+		Clock:        clock.NewMock(),
+		EmailBackend: &emailBackend,
+	})
+	a.Config.SMTP = config.SMTPConfig{}
+
+	api := API{App: &a}
+	r, err := NewRouter(&api)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "initializing server"))
+	}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	dat := `{"email": "alice@example.com", "password": "pass1234"}`
+	req := testutils.MakeReq(server.URL, "POST", "/v3/register", dat)
+
+	// Execute
+	res := testutils.HTTPDo(t, req)
+
+	// Test
+	assert.StatusCodeEquals(t, res, http.StatusCreated, "")
+
+	var accountCount int
+	testutils.MustExec(t, testutils.DB.Model(&database.Account{}).Where("email = ?", "alice@example.com").Count(&accountCount), "counting accounts")
+	assert.Equal(t, accountCount, 1, "account count mismatch")
+	assert.Equalf(t, len(emailBackend.Emails), 0, "email queue count mismatch")
+
+	assertSessionResp(t, res)
+}
+
 func TestRegisterMissingParams(t *testing.T) {
 	t.Run("missing email", func(t *testing.T) {
 		defer testutils.ClearData(testutils.DB)
@@ -194,6 +232,120 @@ func TestRegisterMissingParams(t *testing.T) {
 	})
 }
 
+// assertValidationResp asserts that the response is a validation error with the
+// given reasons for the invalid fields
+func assertValidationResp(t *testing.T, res *http.Response, expected map[string]string) {
+	assert.StatusCodeEquals(t, res, http.StatusBadRequest, "status code mismatch")
+
+	var got struct {
+		Error   string            `json:"error"`
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding payload"))
+	}
+
+	assert.Equal(t, got.Error, "validation", "error mismatch")
+	assert.Equal(t, got.Message, handlers.ValidationError(expected).Message(), "message mismatch")
+	assert.DeepEqual(t, got.Fields, expected, "fields mismatch")
+}
+
+func TestRegisterValidation(t *testing.T) {
+	testCases := []struct {
+		payload  string
+		expected map[string]string
+	}{
+		{
+			payload:  `{"password": "pass1234"}`,
+			expected: map[string]string{"email": "required"},
+		},
+		{
+			payload:  `{"email": "alice@example.com"}`,
+			expected: map[string]string{"password": "required"},
+		},
+		{
+			payload:  `{"email": "alice@example.com", "password": "pass"}`,
+			expected: map[string]string{"password": "too_short"},
+		},
+		{
+			payload:  `{}`,
+			expected: map[string]string{"email": "required", "password": "required"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.payload, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			// Setup
+			server := MustNewServer(t, &app.App{
+				Clock: clock.NewMock(),
+			})
+			defer server.Close()
+
+			req := testutils.MakeReq(server.URL, "POST", "/v3/register", tc.payload)
+
+			// Execute
+			res := testutils.HTTPDo(t, req)
+
+			// Test
+			assertValidationResp(t, res, tc.expected)
+
+			var accountCount int
+			testutils.MustExec(t, testutils.DB.Model(&database.Account{}).Count(&accountCount), "counting account")
+			assert.Equal(t, accountCount, 0, "accountCount mismatch")
+		})
+	}
+}
+
+func TestSignInValidation(t *testing.T) {
+	testCases := []struct {
+		payload  string
+		expected map[string]string
+	}{
+		{
+			payload:  `{"password": "pass1234"}`,
+			expected: map[string]string{"email": "required"},
+		},
+		{
+			payload:  `{"email": "alice@example.com"}`,
+			expected: map[string]string{"password": "required"},
+		},
+		{
+			payload:  `{}`,
+			expected: map[string]string{"email": "required", "password": "required"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.payload, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			// Setup
+			server := MustNewServer(t, &app.App{
+				Clock: clock.NewMock(),
+			})
+			defer server.Close()
+
+			u := testutils.SetupUserData()
+			testutils.SetupAccountData(u, "alice@example.com", "pass1234")
+
+			req := testutils.MakeReq(server.URL, "POST", "/v3/signin", tc.payload)
+
+			// Execute
+			res := testutils.HTTPDo(t, req)
+
+			// Test
+			assertValidationResp(t, res, tc.expected)
+
+			var sessionCount int
+			testutils.MustExec(t, testutils.DB.Model(&database.Session{}).Count(&sessionCount), "counting session")
+			assert.Equal(t, sessionCount, 0, "sessionCount mismatch")
+		})
+	}
+}
+
 func TestRegisterDuplicateEmail(t *testing.T) {
 	defer testutils.ClearData(testutils.DB)
 
@@ -24,13 +24,13 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/dnote/dnote/pkg/server/app"
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/handlers"
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/dnote/dnote/pkg/server/presenters"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
-	"github.com/pkg/errors"
 )
 
 type createBookPayload struct {
@@ -42,12 +42,14 @@ type CreateBookResp struct {
 	Book presenters.Book `json:"book"`
 }
 
-func validateCreateBookPayload(p createBookPayload) error {
+func validateCreateBookPayload(p createBookPayload) handlers.ValidationError {
+	ret := handlers.ValidationError{}
+
 	if p.Name == "" {
-		return errors.New("name is required")
+		ret["name"] = handlers.FieldRequired
 	}
 
-	return nil
+	return ret
 }
 
 // CreateBook creates a new book
@@ -64,9 +66,8 @@ func (a *API) CreateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = validateCreateBookPayload(params)
-	if err != nil {
-		handlers.DoError(w, "validating payload", err, http.StatusBadRequest)
+	if err := validateCreateBookPayload(params); len(err) > 0 {
+		handlers.RespondValidationError(w, err)
 		return
 	}
 
@@ -167,6 +168,35 @@ func (a *API) GetBook(w http.ResponseWriter, r *http.Request) {
 	handlers.RespondJSON(w, http.StatusOK, p)
 }
 
+// GetBookNotes returns a page of notes in a book for the user
+func (a *API) GetBookNotes(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(helpers.KeyUser).(database.User)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bookUUID := vars["bookUUID"]
+
+	book, err := a.App.GetBook(user, bookUUID)
+	if err == app.ErrBookNotFound {
+		handlers.RespondNotFound(w)
+		return
+	} else if err != nil {
+		handlers.DoError(w, "finding book", err, http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseGetNotesQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.BookUUID = book.UUID
+
+	respondWithNotesQuery(a.App.DB, user.ID, q, r, w)
+}
+
 type updateBookPayload struct {
 	Name *string `json:"name"`
 }
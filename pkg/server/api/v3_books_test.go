@@ -455,6 +455,29 @@ func TestCreateBookDuplicate(t *testing.T) {
 	assert.Equal(t, userRecord.MaxUSN, 101, "user max_usn mismatch")
 }
 
+func TestCreateBookValidation(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+
+	// Execute
+	req := testutils.MakeReq(server.URL, "POST", "/v3/books", `{"name": ""}`)
+	res := testutils.HTTPAuthDo(t, req, user)
+
+	// Test
+	assertValidationResp(t, res, map[string]string{"name": "required"})
+
+	var bookCount int
+	testutils.MustExec(t, testutils.DB.Model(&database.Book{}).Count(&bookCount), "counting books")
+	assert.Equal(t, bookCount, 0, "book count mismatch")
+}
+
 func TestUpdateBook(t *testing.T) {
 	updatedLabel := "updated-label"
 
@@ -546,3 +569,195 @@ func TestUpdateBook(t *testing.T) {
 		}()
 	}
 }
+
+func TestGetBookNotes(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{
+		UserID: user.ID,
+		Label:  "css",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+	b3 := database.Book{
+		UserID: anotherUser.ID,
+		Label:  "css",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b3), "preparing b3")
+
+	n1 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n1 content",
+		USN:      11,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "n2 content",
+		USN:      14,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b2.UUID,
+		Body:     "n3 content",
+		USN:      17,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+	n4 := database.Note{
+		UserID:   user.ID,
+		BookUUID: b1.UUID,
+		Body:     "",
+		USN:      18,
+		Deleted:  true,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n4), "preparing n4")
+	n5 := database.Note{
+		UserID:   anotherUser.ID,
+		BookUUID: b3.UUID,
+		Body:     "n5 content",
+		USN:      19,
+	}
+	testutils.MustExec(t, testutils.DB.Save(&n5), "preparing n5")
+
+	t.Run("own book", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", fmt.Sprintf("/v3/books/%s/notes", b1.UUID), "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		var payload GetNotesResponse
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		var n1Record, n2Record database.Note
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", n1.UUID).First(&n1Record), "finding n1Record")
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", n2.UUID).First(&n2Record), "finding n2Record")
+
+		expected := GetNotesResponse{
+			Notes: []presenters.Note{
+				getExpectedNotePayload(n2Record, b1, user),
+				getExpectedNotePayload(n1Record, b1, user),
+			},
+			Total: 2,
+		}
+
+		assert.DeepEqual(t, payload, expected, "payload mismatch")
+	})
+
+	t.Run("another user's book", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", fmt.Sprintf("/v3/books/%s/notes", b3.UUID), "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+	})
+
+	t.Run("nonexistent book", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/v3/books/9a3d4fb6-3d4f-4c6c-8d8b-2a5c6f0e1a11/notes", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+	})
+}
+
+func TestGetBookNotesPagination(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+
+	b1 := database.Book{
+		UserID: user.ID,
+		Label:  "js",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{
+		UserID: user.ID,
+		Label:  "css",
+	}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+
+	for i := 0; i < 32; i++ {
+		n := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     fmt.Sprintf("js note %d", i),
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n), fmt.Sprintf("preparing js note %d", i))
+	}
+	for i := 0; i < 5; i++ {
+		n := database.Note{
+			UserID:   user.ID,
+			BookUUID: b2.UUID,
+			Body:     fmt.Sprintf("css note %d", i),
+		}
+		testutils.MustExec(t, testutils.DB.Save(&n), fmt.Sprintf("preparing css note %d", i))
+	}
+
+	testCases := []struct {
+		page          int
+		expectedCount int
+	}{
+		{
+			page:          1,
+			expectedCount: 30,
+		},
+		{
+			page:          2,
+			expectedCount: 2,
+		},
+		{
+			page:          3,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("page %d", tc.page), func(t *testing.T) {
+			// Execute
+			req := testutils.MakeReq(server.URL, "GET", fmt.Sprintf("/v3/books/%s/notes?page=%d", b1.UUID, tc.page), "")
+			res := testutils.HTTPAuthDo(t, req, user)
+
+			// Test
+			assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+			var payload GetNotesResponse
+			if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding payload"))
+			}
+
+			assert.Equal(t, payload.Total, 32, "total mismatch")
+			assert.Equal(t, len(payload.Notes), tc.expectedCount, "note count mismatch")
+			for _, n := range payload.Notes {
+				assert.Equal(t, n.Book.UUID, b1.UUID, "book uuid mismatch")
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/handlers"
+	"github.com/dnote/dnote/pkg/server/helpers"
+	"github.com/dnote/dnote/pkg/server/log"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// exportFormatJSON exports a single JSON document with the books and the notes
+	exportFormatJSON = "json"
+	// exportFormatNDJSON exports one JSON object per line, each being a book or a note
+	exportFormatNDJSON = "ndjson"
+)
+
+// ExportBook is a book in an export
+type ExportBook struct {
+	UUID     string `json:"uuid"`
+	Label    string `json:"label"`
+	AddedOn  int64  `json:"added_on"`
+	EditedOn int64  `json:"edited_on"`
+}
+
+// ExportNote is a note in an export
+type ExportNote struct {
+	UUID     string `json:"uuid"`
+	BookUUID string `json:"book_uuid"`
+	Content  string `json:"content"`
+	AddedOn  int64  `json:"added_on"`
+	EditedOn int64  `json:"edited_on"`
+	Public   bool   `json:"public"`
+}
+
+// ExportLine is a line in an export in the ndjson format. Its type is either
+// "book" or "note", and only the matching field is set.
+type ExportLine struct {
+	Type string      `json:"type"`
+	Book *ExportBook `json:"book,omitempty"`
+	Note *ExportNote `json:"note,omitempty"`
+}
+
+// exportWriter writes the books and the notes of an export as they are read,
+// so that the export need not be held in memory
+type exportWriter struct {
+	w      io.Writer
+	format string
+	// count is the number of items written in the current JSON array
+	count int
+}
+
+func (e *exportWriter) writeString(s string) error {
+	_, err := io.WriteString(e.w, s)
+	return err
+}
+
+// begin writes the opening of the document
+func (e *exportWriter) begin() error {
+	if e.format == exportFormatNDJSON {
+		return nil
+	}
+
+	return e.writeString(`{"books":[`)
+}
+
+// beginNotes writes the separation between the books and the notes
+func (e *exportWriter) beginNotes() error {
+	if e.format == exportFormatNDJSON {
+		return nil
+	}
+
+	e.count = 0
+
+	return e.writeString(`],"notes":[`)
+}
+
+// end writes the closing of the document
+func (e *exportWriter) end() error {
+	if e.format == exportFormatNDJSON {
+		return nil
+	}
+
+	return e.writeString("]}\n")
+}
+
+func (e *exportWriter) writeItem(item interface{}, line ExportLine) error {
+	var v interface{} = item
+	if e.format == exportFormatNDJSON {
+		v = line
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "marshalling")
+	}
+
+	if e.format == exportFormatNDJSON {
+		b = append(b, '\n')
+	} else if e.count > 0 {
+		b = append([]byte{','}, b...)
+	}
+	e.count++
+
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *exportWriter) writeBook(b ExportBook) error {
+	return e.writeItem(b, ExportLine{Type: "book", Book: &b})
+}
+
+func (e *exportWriter) writeNote(n ExportNote) error {
+	return e.writeItem(n, ExportLine{Type: "note", Note: &n})
+}
+
+// writeExport streams the books and the notes of the user to the writer
+func writeExport(db *gorm.DB, e *exportWriter, userID int) error {
+	if err := e.begin(); err != nil {
+		return errors.Wrap(err, "beginning the export")
+	}
+
+	bookRows, err := db.Model(&database.Book{}).
+		Where("user_id = ? AND deleted = ?", userID, false).
+		Order("label ASC").Rows()
+	if err != nil {
+		return errors.Wrap(err, "querying books")
+	}
+	defer bookRows.Close()
+
+	for bookRows.Next() {
+		var book database.Book
+		if err := db.ScanRows(bookRows, &book); err != nil {
+			return errors.Wrap(err, "scanning a book")
+		}
+
+		if err := e.writeBook(ExportBook{
+			UUID:     book.UUID,
+			Label:    book.Label,
+			AddedOn:  book.AddedOn,
+			EditedOn: book.EditedOn,
+		}); err != nil {
+			return errors.Wrap(err, "writing a book")
+		}
+	}
+	if err := bookRows.Err(); err != nil {
+		return errors.Wrap(err, "iterating books")
+	}
+
+	if err := e.beginNotes(); err != nil {
+		return errors.Wrap(err, "beginning the notes")
+	}
+
+	noteRows, err := db.Model(&database.Note{}).
+		Where("user_id = ? AND deleted = ?", userID, false).
+		Order("added_on ASC, id ASC").Rows()
+	if err != nil {
+		return errors.Wrap(err, "querying notes")
+	}
+	defer noteRows.Close()
+
+	for noteRows.Next() {
+		var note database.Note
+		if err := db.ScanRows(noteRows, &note); err != nil {
+			return errors.Wrap(err, "scanning a note")
+		}
+
+		if err := e.writeNote(ExportNote{
+			UUID:     note.UUID,
+			BookUUID: note.BookUUID,
+			Content:  note.Body,
+			AddedOn:  note.AddedOn,
+			EditedOn: note.EditedOn,
+			Public:   note.Public,
+		}); err != nil {
+			return errors.Wrap(err, "writing a note")
+		}
+	}
+	if err := noteRows.Err(); err != nil {
+		return errors.Wrap(err, "iterating notes")
+	}
+
+	if err := e.end(); err != nil {
+		return errors.Wrap(err, "ending the export")
+	}
+
+	return nil
+}
+
+// GetExport streams all books and notes of the user for a backup, either as a
+// single JSON document or as newline-delimited JSON if the format is "ndjson"
+func (a *API) GetExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(helpers.KeyUser).(database.User)
+	if !ok {
+		handlers.DoError(w, "No authenticated user found", nil, http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatJSON
+	}
+
+	var contentType, filename string
+	switch format {
+	case exportFormatJSON:
+		contentType = "application/json"
+		filename = "dnote-export.json"
+	case exportFormatNDJSON:
+		contentType = "application/x-ndjson"
+		filename = "dnote-export.ndjson"
+	default:
+		http.Error(w, "invalid format", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.WriteHeader(http.StatusOK)
+
+	// The status has already been sent, so an error can only end the response
+	// early, leaving an incomplete document.
+	if err := writeExport(a.App.DB, &exportWriter{w: w, format: format}, user.ID); err != nil {
+		log.WithFields(log.Fields{
+			"userId": user.ID,
+		}).ErrorWrap(err, "exporting")
+	}
+}
@@ -0,0 +1,142 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+type exportPayload struct {
+	Books []ExportBook `json:"books"`
+	Notes []ExportNote `json:"notes"`
+}
+
+func TestGetExport(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: user.ID, Label: "css"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+	b3 := database.Book{UserID: anotherUser.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b3), "preparing b3")
+	b4 := database.Book{UserID: user.ID, Label: "deleted", Deleted: true}
+	testutils.MustExec(t, testutils.DB.Save(&b4), "preparing b4")
+
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n1", AddedOn: 1}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{UserID: user.ID, BookUUID: b2.UUID, Body: "n2", AddedOn: 2}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "", AddedOn: 3, Deleted: true}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+	n4 := database.Note{UserID: anotherUser.ID, BookUUID: b3.UUID, Body: "n4", AddedOn: 4}
+	testutils.MustExec(t, testutils.DB.Save(&n4), "preparing n4")
+
+	t.Run("json", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/v3/export", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+		assert.Equal(t, res.Header.Get("Content-Type"), "application/json", "content type mismatch")
+
+		var payload exportPayload
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		assert.DeepEqual(t, payload.Books, []ExportBook{
+			{UUID: b2.UUID, Label: "css"},
+			{UUID: b1.UUID, Label: "js"},
+		}, "books mismatch")
+		assert.DeepEqual(t, payload.Notes, []ExportNote{
+			{UUID: n1.UUID, BookUUID: b1.UUID, Content: "n1", AddedOn: 1},
+			{UUID: n2.UUID, BookUUID: b2.UUID, Content: "n2", AddedOn: 2},
+		}, "notes mismatch")
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/v3/export?format=ndjson", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+		assert.Equal(t, res.Header.Get("Content-Type"), "application/x-ndjson", "content type mismatch")
+
+		lines := []ExportLine{}
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			var line ExportLine
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding a line"))
+			}
+
+			lines = append(lines, line)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(errors.Wrap(err, "reading the response"))
+		}
+
+		assert.DeepEqual(t, lines, []ExportLine{
+			{Type: "book", Book: &ExportBook{UUID: b2.UUID, Label: "css"}},
+			{Type: "book", Book: &ExportBook{UUID: b1.UUID, Label: "js"}},
+			{Type: "note", Note: &ExportNote{UUID: n1.UUID, BookUUID: b1.UUID, Content: "n1", AddedOn: 1}},
+			{Type: "note", Note: &ExportNote{UUID: n2.UUID, BookUUID: b2.UUID, Content: "n2", AddedOn: 2}},
+		}, "lines mismatch")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/v3/export?format=xml", "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusBadRequest, "")
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		// Execute
+		req := testutils.MakeReq(server.URL, "GET", "/v3/export", "")
+		res := testutils.HTTPDo(t, req)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusUnauthorized, "")
+	})
+}
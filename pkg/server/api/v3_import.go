@@ -0,0 +1,205 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/handlers"
+	"github.com/dnote/dnote/pkg/server/helpers"
+	"github.com/pkg/errors"
+)
+
+// ImportResp is the response for an import
+type ImportResp struct {
+	app.ImportSummary
+	// Malformed is the number of records that could not be parsed at all
+	Malformed int `json:"malformed"`
+}
+
+// importData is the parsed content of an import
+type importData struct {
+	books       []app.ImportBook
+	notes       []app.ImportNote
+	failedBooks int
+	failedNotes int
+	malformed   int
+}
+
+func (d *importData) addBook(b ExportBook) {
+	d.books = append(d.books, app.ImportBook{
+		UUID:     b.UUID,
+		Label:    b.Label,
+		AddedOn:  b.AddedOn,
+		EditedOn: b.EditedOn,
+	})
+}
+
+func (d *importData) addNote(n ExportNote) {
+	d.notes = append(d.notes, app.ImportNote{
+		BookUUID: n.BookUUID,
+		Content:  n.Content,
+		AddedOn:  n.AddedOn,
+		EditedOn: n.EditedOn,
+		Public:   n.Public,
+	})
+}
+
+// parseImportJSON parses an export in the json format. Each book and note is
+// parsed on its own so that a malformed one does not fail the others.
+func parseImportJSON(r io.Reader) (importData, error) {
+	var ret importData
+
+	var doc struct {
+		Books []json.RawMessage `json:"books"`
+		Notes []json.RawMessage `json:"notes"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return ret, errors.Wrap(err, "decoding the document")
+	}
+
+	for _, raw := range doc.Books {
+		var b ExportBook
+		if err := json.Unmarshal(raw, &b); err != nil {
+			ret.failedBooks++
+			continue
+		}
+
+		ret.addBook(b)
+	}
+	for _, raw := range doc.Notes {
+		var n ExportNote
+		if err := json.Unmarshal(raw, &n); err != nil {
+			ret.failedNotes++
+			continue
+		}
+
+		ret.addNote(n)
+	}
+
+	return ret, nil
+}
+
+// parseImportNDJSON parses an export in the ndjson format. The lines that
+// cannot be parsed are counted as malformed.
+func parseImportNDJSON(r io.Reader) (importData, error) {
+	var ret importData
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return ret, errors.Wrap(err, "reading a line")
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var l ExportLine
+			if jsonErr := json.Unmarshal(trimmed, &l); jsonErr != nil {
+				ret.malformed++
+			} else if l.Type == "book" && l.Book != nil {
+				ret.addBook(*l.Book)
+			} else if l.Type == "note" && l.Note != nil {
+				ret.addNote(*l.Note)
+			} else {
+				ret.malformed++
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return ret, nil
+}
+
+// PostImport recreates the books and notes from an export for the user. The
+// mode is either "merge", the default, or "replace" to delete the existing
+// books and notes first. A payload larger than the maximum import size is
+// rejected.
+func (a *API) PostImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(helpers.KeyUser).(database.User)
+	if !ok {
+		handlers.DoError(w, "No authenticated user found", nil, http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = app.ImportModeMerge
+	}
+	if mode != app.ImportModeMerge && mode != app.ImportModeReplace {
+		http.Error(w, "invalid mode", http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = exportFormatJSON
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+			format = exportFormatNDJSON
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, a.App.Config.GetMaxImportSize())
+
+	var data importData
+	var err error
+	switch format {
+	case exportFormatJSON:
+		data, err = parseImportJSON(r.Body)
+	case exportFormatNDJSON:
+		data, err = parseImportNDJSON(r.Body)
+	default:
+		http.Error(w, "invalid format", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if isBodyTooLarge(errors.Cause(err)) {
+			http.Error(w, "import is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := a.App.Import(user, data.books, data.notes, mode)
+	if err != nil {
+		handlers.DoError(w, "importing", err, http.StatusInternalServerError)
+		return
+	}
+
+	summary.FailedBooks += data.failedBooks
+	summary.FailedNotes += data.failedNotes
+
+	handlers.RespondJSON(w, http.StatusOK, ImportResp{
+		ImportSummary: summary,
+		Malformed:     data.malformed,
+	})
+}
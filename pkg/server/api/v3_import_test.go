@@ -0,0 +1,241 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/clock"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/config"
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
+)
+
+func decodeImportResp(t *testing.T, res *http.Response) ImportResp {
+	var ret ImportResp
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding payload"))
+	}
+
+	return ret
+}
+
+// getActiveBodies returns the bodies of the active notes of the user, keyed by
+// their book labels
+func getActiveBodies(t *testing.T, userID int) map[string][]string {
+	var notes []database.Note
+	testutils.MustExec(t, testutils.DB.Preload("Book").Where("user_id = ? AND deleted = ?", userID, false).Order("added_on ASC").Find(&notes), "finding notes")
+
+	ret := map[string][]string{}
+	for _, n := range notes {
+		ret[n.Book.Label] = append(ret[n.Book.Label], n.Body)
+	}
+
+	return ret
+}
+
+const importPayload = `{
+	"books": [
+		{"uuid": "b1", "label": "js"},
+		{"uuid": "b2", "label": "css"}
+	],
+	"notes": [
+		{"uuid": "n1", "book_uuid": "b1", "content": "n1 content", "added_on": 1},
+		{"uuid": "n2", "book_uuid": "b2", "content": "n2 content", "added_on": 2}
+	]
+}`
+
+func TestPostImport(t *testing.T) {
+	t.Run("too large", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+			Config: config.Config{
+				MaxImportSize: int64(len(importPayload) - 1),
+			},
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+
+		// Execute
+		req := testutils.MakeReq(server.URL, "POST", "/v3/import", importPayload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusRequestEntityTooLarge, "")
+
+		var noteCount int
+		testutils.MustExec(t, testutils.DB.Model(&database.Note{}).Where("user_id = ?", user.ID).Count(&noteCount), "counting notes")
+		assert.Equal(t, noteCount, 0, "note count mismatch")
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+		anotherUser := testutils.SetupUserData()
+
+		b1 := database.Book{UserID: user.ID, Label: "js"}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+		n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "existing", AddedOn: 0}
+		testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+		// Execute
+		req := testutils.MakeReq(server.URL, "POST", "/v3/import?mode=merge", importPayload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		payload := decodeImportResp(t, res)
+		assert.Equal(t, payload.ImportedBooks, 1, "imported books mismatch")
+		assert.Equal(t, payload.ImportedNotes, 2, "imported notes mismatch")
+		assert.Equal(t, payload.FailedBooks, 0, "failed books mismatch")
+		assert.Equal(t, payload.FailedNotes, 0, "failed notes mismatch")
+
+		assert.DeepEqual(t, getActiveBodies(t, user.ID), map[string][]string{
+			"js":  {"existing", "n1 content"},
+			"css": {"n2 content"},
+		}, "notes mismatch")
+		assert.DeepEqual(t, getActiveBodies(t, anotherUser.ID), map[string][]string{}, "another user's notes mismatch")
+
+		// importing again skips the notes that already exist
+		req = testutils.MakeReq(server.URL, "POST", "/v3/import", importPayload)
+		res = testutils.HTTPAuthDo(t, req, user)
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		payload = decodeImportResp(t, res)
+		assert.Equal(t, payload.ImportedNotes, 0, "imported notes mismatch on the second import")
+		assert.Equal(t, payload.SkippedNotes, 2, "skipped notes mismatch on the second import")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+
+		b1 := database.Book{UserID: user.ID, Label: "linux"}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+		n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "existing"}
+		testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+		// Execute
+		req := testutils.MakeReq(server.URL, "POST", "/v3/import?mode=replace", importPayload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		payload := decodeImportResp(t, res)
+		assert.Equal(t, payload.ImportedBooks, 2, "imported books mismatch")
+		assert.Equal(t, payload.ImportedNotes, 2, "imported notes mismatch")
+
+		assert.DeepEqual(t, getActiveBodies(t, user.ID), map[string][]string{
+			"js":  {"n1 content"},
+			"css": {"n2 content"},
+		}, "notes mismatch")
+
+		var n1Record database.Note
+		testutils.MustExec(t, testutils.DB.Where("id = ?", n1.ID).First(&n1Record), "finding n1")
+		assert.Equal(t, n1Record.Deleted, true, "n1 should be deleted")
+
+		var b1Record database.Book
+		testutils.MustExec(t, testutils.DB.Where("id = ?", b1.ID).First(&b1Record), "finding b1")
+		assert.Equal(t, b1Record.Deleted, true, "b1 should be deleted")
+	})
+
+	t.Run("partially malformed", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+
+		body := `{"type":"book","book":{"uuid":"b1","label":"js"}}
+{"type":"book","book":{"uuid":"b2","label":"foo bar"}}
+{"type":"book","book":{"uuid":"b3","label":"123"}}
+not json
+{"type":"note","note":{"uuid":"n1","book_uuid":"b1","content":"n1 content"}}
+{"type":"note","note":{"uuid":"n2","book_uuid":"b2","content":"in an invalid book"}}
+{"type":"note","note":{"uuid":"n3","book_uuid":"unknown","content":"in an unknown book"}}
+{"type":"note","note":{"uuid":"n4","book_uuid":"b1","content":1}}
+`
+
+		// Execute
+		req := testutils.MakeReq(server.URL, "POST", "/v3/import?format=ndjson", body)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		payload := decodeImportResp(t, res)
+		assert.Equal(t, payload.ImportedBooks, 1, "imported books mismatch")
+		assert.Equal(t, payload.ImportedNotes, 1, "imported notes mismatch")
+		assert.Equal(t, payload.FailedBooks, 2, "failed books mismatch")
+		assert.Equal(t, payload.FailedNotes, 2, "failed notes mismatch")
+		assert.Equal(t, payload.Malformed, 2, "malformed mismatch")
+
+		assert.DeepEqual(t, getActiveBodies(t, user.ID), map[string][]string{
+			"js": {"n1 content"},
+		}, "notes mismatch")
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user := testutils.SetupUserData()
+
+		// Execute
+		req := testutils.MakeReq(server.URL, "POST", "/v3/import?mode=overwrite", importPayload)
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusBadRequest, "")
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/dnote/dnote/pkg/server/app"
 	"github.com/dnote/dnote/pkg/server/database"
@@ -29,7 +30,6 @@ import (
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/dnote/dnote/pkg/server/presenters"
 	"github.com/gorilla/mux"
-	"github.com/pkg/errors"
 )
 
 type updateNotePayload struct {
@@ -70,8 +70,11 @@ func (a *API) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var note database.Note
-	if err := a.App.DB.Where("uuid = ? AND user_id = ?", noteUUID, user.ID).First(&note).Error; err != nil {
+	note, err := a.App.GetNote(user, noteUUID)
+	if err == app.ErrNoteNotFound {
+		handlers.RespondNotFound(w)
+		return
+	} else if err != nil {
 		handlers.DoError(w, "finding note", err, http.StatusInternalServerError)
 		return
 	}
@@ -83,21 +86,24 @@ func (a *API) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		Content:  params.Content,
 		Public:   params.Public,
 	})
-	if err != nil {
+	if err == app.ErrBookNotFound {
+		tx.Rollback()
+		handlers.DoError(w, "updating note", err, http.StatusBadRequest)
+		return
+	} else if err != nil {
 		tx.Rollback()
 		handlers.DoError(w, "updating note", err, http.StatusInternalServerError)
 		return
 	}
 
-	var book database.Book
-	if err := tx.Where("uuid = ? AND user_id = ?", note.BookUUID, user.ID).First(&book).Error; err != nil {
-		tx.Rollback()
+	tx.Commit()
+
+	book, err := a.App.GetBook(user, note.BookUUID)
+	if err != nil {
 		handlers.DoError(w, fmt.Sprintf("finding book %s to preload", note.BookUUID), err, http.StatusInternalServerError)
 		return
 	}
 
-	tx.Commit()
-
 	// preload associations
 	note.User = user
 	note.Book = book
@@ -110,11 +116,13 @@ func (a *API) UpdateNote(w http.ResponseWriter, r *http.Request) {
 }
 
 type deleteNoteResp struct {
-	Status int             `json:"status"`
-	Result presenters.Note `json:"result"`
+	Status    int             `json:"status"`
+	Result    presenters.Note `json:"result"`
+	DeletedAt time.Time       `json:"deleted_at"`
 }
 
-// DeleteNote removes note
+// DeleteNote marks a note deleted and responds with the deleted note so that
+// clients can offer to undo the deletion
 func (a *API) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	noteUUID := vars["noteUUID"]
@@ -125,15 +133,18 @@ func (a *API) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var note database.Note
-	if err := a.App.DB.Where("uuid = ? AND user_id = ?", noteUUID, user.ID).Preload("Book").First(&note).Error; err != nil {
+	note, err := a.App.GetNote(user, noteUUID)
+	if err == app.ErrNoteNotFound {
+		handlers.RespondNotFound(w)
+		return
+	} else if err != nil {
 		handlers.DoError(w, "finding note", err, http.StatusInternalServerError)
 		return
 	}
 
 	tx := a.App.DB.Begin()
 
-	n, err := a.App.DeleteNote(tx, user, note)
+	n, err := a.App.SoftDeleteNote(tx, user, note)
 	if err != nil {
 		tx.Rollback()
 		handlers.DoError(w, "deleting note", err, http.StatusInternalServerError)
@@ -142,8 +153,65 @@ func (a *API) DeleteNote(w http.ResponseWriter, r *http.Request) {
 
 	tx.Commit()
 
+	n.User = user
+
 	resp := deleteNoteResp{
-		Status: http.StatusNoContent,
+		Status:    http.StatusOK,
+		Result:    presenters.PresentNote(n),
+		DeletedAt: presenters.FormatTS(n.UpdatedAt),
+	}
+	handlers.RespondJSON(w, http.StatusOK, resp)
+}
+
+type restoreNoteResp struct {
+	Status int             `json:"status"`
+	Result presenters.Note `json:"result"`
+}
+
+// RestoreNote undoes the deletion of a note
+func (a *API) RestoreNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteUUID := vars["noteUUID"]
+
+	user, ok := r.Context().Value(helpers.KeyUser).(database.User)
+	if !ok {
+		handlers.DoError(w, "No authenticated user found", nil, http.StatusInternalServerError)
+		return
+	}
+
+	note, err := a.App.GetNote(user, noteUUID)
+	if err == app.ErrNoteNotFound {
+		handlers.RespondNotFound(w)
+		return
+	} else if err != nil {
+		handlers.DoError(w, "finding note", err, http.StatusInternalServerError)
+		return
+	}
+
+	if !note.Deleted {
+		http.Error(w, "note is not deleted", http.StatusConflict)
+		return
+	}
+	if note.Book.Deleted {
+		http.Error(w, "the book of the note is deleted", http.StatusConflict)
+		return
+	}
+
+	tx := a.App.DB.Begin()
+
+	n, err := a.App.RestoreNote(tx, user, note)
+	if err != nil {
+		tx.Rollback()
+		handlers.DoError(w, "restoring note", err, http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	n.User = user
+
+	resp := restoreNoteResp{
+		Status: http.StatusOK,
 		Result: presenters.PresentNote(n),
 	}
 	handlers.RespondJSON(w, http.StatusOK, resp)
@@ -156,12 +224,14 @@ type createNotePayload struct {
 	EditedOn *int64 `json:"edited_on"`
 }
 
-func validateCreateNotePayload(p createNotePayload) error {
+func validateCreateNotePayload(p createNotePayload) handlers.ValidationError {
+	ret := handlers.ValidationError{}
+
 	if p.BookUUID == "" {
-		return errors.New("bookUUID is required")
+		ret["book_uuid"] = handlers.FieldRequired
 	}
 
-	return nil
+	return ret
 }
 
 // CreateNoteResp is a response for creating a note
@@ -177,21 +247,39 @@ func (a *API) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maxSize := a.App.Config.GetMaxNoteBodySize()
+
+	// The payload is JSON encoded, and escaping can make it larger than the
+	// note body it carries. Bound the read loosely and check the body below.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize*2+1024)
+
 	var params createNotePayload
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "note is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
 		handlers.DoError(w, "decoding payload", err, http.StatusInternalServerError)
 		return
 	}
 
-	err = validateCreateNotePayload(params)
-	if err != nil {
-		handlers.DoError(w, "validating payload", err, http.StatusBadRequest)
+	if int64(len(params.Content)) > maxSize {
+		http.Error(w, "note is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := validateCreateNotePayload(params); len(err) > 0 {
+		handlers.RespondValidationError(w, err)
 		return
 	}
 
-	var book database.Book
-	if err := a.App.DB.Where("uuid = ? AND user_id = ?", params.BookUUID, user.ID).First(&book).Error; err != nil {
+	book, err := a.App.GetBook(user, params.BookUUID)
+	if err == app.ErrBookNotFound {
+		handlers.DoError(w, "finding book", err, http.StatusBadRequest)
+		return
+	} else if err != nil {
 		handlers.DoError(w, "finding book", err, http.StatusInternalServerError)
 		return
 	}
@@ -218,3 +306,35 @@ func (a *API) NotesOptions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "POST")
 	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Version")
 }
+
+// GetNotesCountResp is a response for counting notes
+type GetNotesCountResp struct {
+	Count int `json:"count"`
+}
+
+// GetNotesCount responds with the number of notes of the user, optionally
+// scoped to the books given by their labels
+func (a *API) GetNotesCount(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(helpers.KeyUser).(database.User)
+	if !ok {
+		handlers.DoError(w, "No authenticated user found", nil, http.StatusInternalServerError)
+		return
+	}
+
+	conn := a.App.DB.Model(database.Note{}).
+		Where("notes.user_id = ? AND notes.deleted = ?", user.ID, false)
+
+	books := r.URL.Query()["book"]
+	if len(books) > 0 {
+		conn = conn.Joins("INNER JOIN books ON books.uuid = notes.book_uuid").
+			Where("books.label in (?) AND books.deleted = ?", books, false)
+	}
+
+	var count int
+	if err := conn.Count(&count).Error; err != nil {
+		handlers.DoError(w, "counting notes", err, http.StatusInternalServerError)
+		return
+	}
+
+	handlers.RespondJSON(w, http.StatusOK, GetNotesCountResp{Count: count})
+}
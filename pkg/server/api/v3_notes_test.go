@@ -19,15 +19,19 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
 	"github.com/dnote/dnote/pkg/clock"
 	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/testutils"
+	"github.com/pkg/errors"
 )
 
 func TestCreateNote(t *testing.T) {
@@ -81,6 +85,75 @@ func TestCreateNote(t *testing.T) {
 	assert.Equal(t, noteRecord.BookUUID, b1.UUID, "note book_uuid mismatch")
 	assert.Equal(t, noteRecord.Body, "note content", "note content mismatch")
 	assert.Equal(t, noteRecord.USN, 102, "note usn mismatch")
+	assert.Equal(t, noteRecord.Client, "cli", "note client mismatch")
+}
+
+func TestCreateNoteMaxBodySize(t *testing.T) {
+	testCases := []struct {
+		name           string
+		content        string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "under the limit",
+			content:        strings.Repeat("a", 99),
+			expectedStatus: http.StatusCreated,
+			expectedCount:  1,
+		},
+		{
+			name:           "at the limit",
+			content:        strings.Repeat("a", 100),
+			expectedStatus: http.StatusCreated,
+			expectedCount:  1,
+		},
+		{
+			name:           "over the limit",
+			content:        strings.Repeat("a", 101),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCount:  0,
+		},
+		{
+			name:           "payload over the limit",
+			content:        strings.Repeat("a", 10000),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCount:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			// Setup
+			server := MustNewServer(t, &app.App{
+				Clock: clock.NewMock(),
+				Config: config.Config{
+					MaxNoteBodySize: 100,
+				},
+			})
+			defer server.Close()
+
+			user := testutils.SetupUserData()
+			b1 := database.Book{
+				UserID: user.ID,
+				Label:  "js",
+			}
+			testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+			// Execute
+			dat := fmt.Sprintf(`{"book_uuid": "%s", "content": "%s"}`, b1.UUID, tc.content)
+			req := testutils.MakeReq(server.URL, "POST", "/v3/notes", dat)
+			res := testutils.HTTPAuthDo(t, req, user)
+
+			// Test
+			assert.StatusCodeEquals(t, res, tc.expectedStatus, "")
+
+			var noteCount int
+			testutils.MustExec(t, testutils.DB.Model(&database.Note{}).Count(&noteCount), "counting notes")
+			assert.Equal(t, noteCount, tc.expectedCount, "note count mismatch")
+		})
+	}
 }
 
 func TestUpdateNote(t *testing.T) {
@@ -382,13 +455,343 @@ func TestDeleteNote(t *testing.T) {
 			assert.Equalf(t, noteCount, 1, "note count mismatch")
 
 			assert.Equal(t, noteRecord.UUID, note.UUID, "note uuid mismatch for test case")
-			assert.Equal(t, noteRecord.Body, "", "note content mismatch for test case")
+			assert.Equal(t, noteRecord.Body, tc.content, "note content mismatch for test case")
 			assert.Equal(t, noteRecord.Deleted, true, "note deleted mismatch for test case")
 			assert.Equal(t, noteRecord.BookUUID, note.BookUUID, "note book_uuid mismatch for test case")
 			assert.Equal(t, noteRecord.UserID, note.UserID, "note user_id mismatch for test case")
 			assert.Equal(t, noteRecord.USN, tc.expectedUSN, "note usn mismatch for test case")
 
 			assert.Equal(t, userRecord.MaxUSN, tc.expectedMaxUSN, "user max_usn mismatch for test case")
+
+			var payload deleteNoteResp
+			if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding payload"))
+			}
+
+			assert.Equal(t, payload.Status, http.StatusOK, "status mismatch")
+			assert.Equal(t, payload.Result.UUID, note.UUID, "result uuid mismatch")
+			assert.Equal(t, payload.Result.Body, tc.content, "result content mismatch")
+			assert.Equal(t, payload.Result.USN, tc.expectedUSN, "result usn mismatch")
+			assert.Equal(t, payload.Result.Book.UUID, b1.UUID, "result book mismatch")
+			assert.Equal(t, payload.DeletedAt.IsZero(), false, "deleted_at should be set")
+
+			// the deleted note is not listed
+			listReq := testutils.MakeReq(server.URL, "GET", "/notes", "")
+			listRes := testutils.HTTPAuthDo(t, listReq, user)
+			assert.StatusCodeEquals(t, listRes, http.StatusOK, "")
+
+			var list GetNotesResponse
+			if err := json.NewDecoder(listRes.Body).Decode(&list); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding the notes"))
+			}
+			assert.Equal(t, list.Total, 0, "total mismatch")
+		})
+	}
+}
+
+func TestRestoreNote(t *testing.T) {
+	setup := func(t *testing.T, noteDeleted, bookDeleted bool) (database.User, database.Book, database.Note) {
+		user := testutils.SetupUserData()
+		testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 981), "preparing user max_usn")
+
+		b1 := database.Book{
+			UserID:  user.ID,
+			Label:   "js",
+			Deleted: bookDeleted,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+		note := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     "n1 content",
+			Deleted:  noteDeleted,
+			USN:      12,
+		}
+		testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+		return user, b1, note
+	}
+
+	t.Run("success", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user, b1, note := setup(t, true, false)
+
+		// Execute
+		endpoint := fmt.Sprintf("/v3/notes/%s/restore", note.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+		var noteRecord database.Note
+		var userRecord database.User
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user record")
+
+		assert.Equal(t, noteRecord.Deleted, false, "note deleted mismatch")
+		assert.Equal(t, noteRecord.Body, "n1 content", "note content mismatch")
+		assert.Equal(t, noteRecord.USN, 982, "note usn mismatch")
+		assert.Equal(t, userRecord.MaxUSN, 982, "user max_usn mismatch")
+
+		var payload restoreNoteResp
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		assert.Equal(t, payload.Result.UUID, note.UUID, "result uuid mismatch")
+		assert.Equal(t, payload.Result.Body, "n1 content", "result content mismatch")
+		assert.Equal(t, payload.Result.USN, 982, "result usn mismatch")
+		assert.Equal(t, payload.Result.Book.UUID, b1.UUID, "result book mismatch")
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user, _, note := setup(t, false, false)
+
+		// Execute
+		endpoint := fmt.Sprintf("/v3/notes/%s/restore", note.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusConflict, "")
+
+		var noteRecord database.Note
+		var userRecord database.User
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+		testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user record")
+
+		assert.Equal(t, noteRecord.USN, 12, "note usn mismatch")
+		assert.Equal(t, userRecord.MaxUSN, 981, "user max_usn mismatch")
+	})
+
+	t.Run("book deleted", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		user, _, note := setup(t, true, true)
+
+		// Execute
+		endpoint := fmt.Sprintf("/v3/notes/%s/restore", note.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, user)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusConflict, "")
+
+		var noteRecord database.Note
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+		assert.Equal(t, noteRecord.Deleted, true, "note deleted mismatch")
+	})
+
+	t.Run("not owned", func(t *testing.T) {
+		defer testutils.ClearData(testutils.DB)
+
+		// Setup
+		server := MustNewServer(t, &app.App{
+			Clock: clock.NewMock(),
+		})
+		defer server.Close()
+
+		_, _, note := setup(t, true, false)
+		anotherUser := testutils.SetupUserData()
+
+		// Execute
+		endpoint := fmt.Sprintf("/v3/notes/%s/restore", note.UUID)
+		req := testutils.MakeReq(server.URL, "POST", endpoint, "")
+		res := testutils.HTTPAuthDo(t, req, anotherUser)
+
+		// Test
+		assert.StatusCodeEquals(t, res, http.StatusNotFound, "")
+
+		var noteRecord database.Note
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+		assert.Equal(t, noteRecord.Deleted, true, "note deleted mismatch")
+	})
+}
+
+func TestNotesNotOwned(t *testing.T) {
+	setup := func(t *testing.T) (database.User, database.Book, database.Note) {
+		user := testutils.SetupUserData()
+		b1 := database.Book{
+			UserID: user.ID,
+			Label:  "js",
+		}
+		testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+		note := database.Note{
+			UserID:   user.ID,
+			BookUUID: b1.UUID,
+			Body:     "n1 content",
+		}
+		testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+		return user, b1, note
+	}
+
+	checkNote := func(t *testing.T, note database.Note) {
+		var noteRecord database.Note
+		var noteCount int
+		testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+		testutils.MustExec(t, testutils.DB.Model(&database.Note{}).Count(&noteCount), "counting notes")
+
+		assert.Equal(t, noteRecord.Body, "n1 content", "note content mismatch")
+		assert.Equal(t, noteRecord.Deleted, false, "note deleted mismatch")
+		assert.Equal(t, noteCount, 1, "note count mismatch")
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		endpoint       func(note database.Note, book database.Book) string
+		payload        func(note database.Note, book database.Book) string
+		expectedStatus int
+	}{
+		{
+			name:   "update",
+			method: "PATCH",
+			endpoint: func(note database.Note, book database.Book) string {
+				return fmt.Sprintf("/v3/notes/%s", note.UUID)
+			},
+			payload: func(note database.Note, book database.Book) string {
+				return `{"content": "foo"}`
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "delete",
+			method: "DELETE",
+			endpoint: func(note database.Note, book database.Book) string {
+				return fmt.Sprintf("/v3/notes/%s", note.UUID)
+			},
+			payload: func(note database.Note, book database.Book) string {
+				return ""
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "create in the book",
+			method: "POST",
+			endpoint: func(note database.Note, book database.Book) string {
+				return "/v3/notes"
+			},
+			payload: func(note database.Note, book database.Book) string {
+				return fmt.Sprintf(`{"book_uuid": "%s", "content": "foo"}`, book.UUID)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer testutils.ClearData(testutils.DB)
+
+			// Setup
+			server := MustNewServer(t, &app.App{
+				Clock: clock.NewMock(),
+			})
+			defer server.Close()
+
+			_, b1, note := setup(t)
+			anotherUser := testutils.SetupUserData()
+
+			// Execute
+			req := testutils.MakeReq(server.URL, tc.method, tc.endpoint(note, b1), tc.payload(note, b1))
+			res := testutils.HTTPAuthDo(t, req, anotherUser)
+
+			// Test
+			assert.StatusCodeEquals(t, res, tc.expectedStatus, "")
+			checkNote(t, note)
+		})
+	}
+}
+
+func TestGetNotesCount(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	// Setup
+	server := MustNewServer(t, &app.App{
+		Clock: clock.NewMock(),
+	})
+	defer server.Close()
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: user.ID, Label: "css"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+	b3 := database.Book{UserID: anotherUser.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b3), "preparing b3")
+
+	n1 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n1"}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+	n2 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "n2"}
+	testutils.MustExec(t, testutils.DB.Save(&n2), "preparing n2")
+	n3 := database.Note{UserID: user.ID, BookUUID: b2.UUID, Body: "n3"}
+	testutils.MustExec(t, testutils.DB.Save(&n3), "preparing n3")
+	n4 := database.Note{UserID: user.ID, BookUUID: b1.UUID, Body: "", Deleted: true}
+	testutils.MustExec(t, testutils.DB.Save(&n4), "preparing n4")
+	n5 := database.Note{UserID: anotherUser.ID, BookUUID: b3.UUID, Body: "n5"}
+	testutils.MustExec(t, testutils.DB.Save(&n5), "preparing n5")
+
+	testCases := []struct {
+		endpoint string
+		expected int
+	}{
+		{
+			endpoint: "/v3/notes/count",
+			expected: 3,
+		},
+		{
+			endpoint: "/v3/notes/count?book=js",
+			expected: 2,
+		},
+		{
+			endpoint: "/v3/notes/count?book=js&book=css",
+			expected: 3,
+		},
+		{
+			endpoint: "/v3/notes/count?book=foo",
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			// Execute
+			req := testutils.MakeReq(server.URL, "GET", tc.endpoint, "")
+			res := testutils.HTTPAuthDo(t, req, user)
+
+			// Test
+			assert.StatusCodeEquals(t, res, http.StatusOK, "")
+
+			var payload GetNotesCountResp
+			if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+				t.Fatal(errors.Wrap(err, "decoding payload"))
+			}
+
+			assert.Equal(t, payload.Count, tc.expected, "count mismatch")
 		})
 	}
 }
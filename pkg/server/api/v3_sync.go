@@ -63,6 +63,7 @@ type SyncFragNote struct {
 	Body      string    `json:"content"`
 	Public    bool      `json:"public"`
 	Deleted   bool      `json:"deleted"`
+	Source    string    `json:"source"`
 }
 
 // NewFragNote presents the given note as a SyncFragNote
@@ -78,6 +79,7 @@ func NewFragNote(note database.Note) SyncFragNote {
 		Public:    note.Public,
 		Deleted:   note.Deleted,
 		BookUUID:  note.BookUUID,
+		Source:    note.Client,
 	}
 }
 
@@ -24,14 +24,19 @@ import (
 	"strings"
 
 	"github.com/dnote/dnote/pkg/server/config"
+	"github.com/dnote/dnote/pkg/server/log"
 	"github.com/dnote/dnote/pkg/server/mailer"
 	"github.com/pkg/errors"
 )
 
 var defaultSender = "sung@getdnote.com"
 
-// GetSenderEmail returns the sender email
+// GetSenderEmail returns the sender email. The sender configured for SMTP, if
+// any, takes precedence.
 func GetSenderEmail(c config.Config, want string) (string, error) {
+	if c.SMTP.From != "" {
+		return c.SMTP.From, nil
+	}
 	if !c.OnPremise {
 		return want, nil
 	}
@@ -92,12 +97,26 @@ func (a *App) SendVerificationEmail(email, tokenValue string) error {
 	return nil
 }
 
-// SendWelcomeEmail sends welcome email
+// SendWelcomeEmail sends welcome email. It does nothing if SMTP is not
+// configured, so that a welcome email never blocks the registration.
 func (a *App) SendWelcomeEmail(email string) error {
-	body, err := a.EmailTemplates.Execute(mailer.EmailTypeWelcome, mailer.EmailKindText, mailer.WelcomeTmplData{
+	if !a.Config.SMTP.IsConfigured() {
+		log.Warn("not sending the welcome email because SMTP is not configured")
+		return nil
+	}
+
+	data := mailer.WelcomeTmplData{
 		AccountEmail: email,
 		WebURL:       a.Config.WebURL,
-	})
+	}
+
+	var body string
+	var err error
+	if a.Config.WelcomeEmailTemplate != "" {
+		body, err = mailer.ExecuteFile(a.Config.WelcomeEmailTemplate, data)
+	} else {
+		body, err = a.EmailTemplates.Execute(mailer.EmailTypeWelcome, mailer.EmailKindText, data)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "executing reset verification template for %s", email)
 	}
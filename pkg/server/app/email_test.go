@@ -20,6 +20,9 @@ package app
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/dnote/dnote/pkg/assert"
@@ -103,6 +106,77 @@ func TestSendWelcomeEmail(t *testing.T) {
 	}
 }
 
+func TestSendWelcomeEmailConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnote-email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmplPath := filepath.Join(dir, "welcome.txt")
+	if err := ioutil.WriteFile(tmplPath, []byte("Hi {{ .AccountEmail }}, visit {{ .WebURL }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("configured", func(t *testing.T) {
+		c := config.Load()
+		c.WebURL = "http://example.com"
+		c.SMTP = config.SMTPConfig{
+			Host:     "smtp.example.com",
+			Port:     "587",
+			Username: "user",
+			Password: "pass",
+			From:     "hello@example.com",
+		}
+		c.WelcomeEmailTemplate = tmplPath
+
+		emailBackend := testutils.MockEmailbackendImplementation{}
+		a := NewTest(&App{
+			EmailBackend: &emailBackend,
+			Config:       c,
+		})
+
+		if err := a.SendWelcomeEmail("alice@example.com"); err != nil {
+			t.Fatal(err, "failed to perform")
+		}
+
+		assert.Equalf(t, len(emailBackend.Emails), 1, "email queue count mismatch")
+		assert.Equal(t, emailBackend.Emails[0].From, "hello@example.com", "email sender mismatch")
+		assert.DeepEqual(t, emailBackend.Emails[0].To, []string{"alice@example.com"}, "email recipient mismatch")
+		assert.Equal(t, emailBackend.Emails[0].Body, "Hi alice@example.com, visit http://example.com", "email body mismatch")
+	})
+
+	t.Run("missing template", func(t *testing.T) {
+		c := config.Load()
+		c.WelcomeEmailTemplate = filepath.Join(dir, "missing.txt")
+
+		emailBackend := testutils.MockEmailbackendImplementation{}
+		a := NewTest(&App{
+			EmailBackend: &emailBackend,
+			Config:       c,
+		})
+
+		err := a.SendWelcomeEmail("alice@example.com")
+
+		assert.NotEqual(t, err, nil, "error should be returned")
+		assert.Equalf(t, len(emailBackend.Emails), 0, "email queue count mismatch")
+	})
+
+	t.Run("SMTP not configured", func(t *testing.T) {
+		emailBackend := testutils.MockEmailbackendImplementation{}
+		a := NewTest(&App{
+			EmailBackend: &emailBackend,
+		})
+		a.Config.SMTP = config.SMTPConfig{}
+
+		if err := a.SendWelcomeEmail("alice@example.com"); err != nil {
+			t.Fatal(err, "failed to perform")
+		}
+
+		assert.Equalf(t, len(emailBackend.Emails), 0, "email queue count mismatch")
+	})
+}
+
 func TestSendPasswordResetEmail(t *testing.T) {
 	testCases := []struct {
 		onPremise      bool
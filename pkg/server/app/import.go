@@ -0,0 +1,267 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/helpers"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ImportModeMerge adds the imported books and notes to the existing ones
+	ImportModeMerge = "merge"
+	// ImportModeReplace deletes the existing books and notes before importing
+	ImportModeReplace = "replace"
+)
+
+// ImportBook is a book to import
+type ImportBook struct {
+	UUID     string
+	Label    string
+	AddedOn  int64
+	EditedOn int64
+}
+
+// ImportNote is a note to import. Its BookUUID refers to an ImportBook.
+type ImportNote struct {
+	BookUUID string
+	Content  string
+	AddedOn  int64
+	EditedOn int64
+	Public   bool
+}
+
+// ImportSummary is the result of an import
+type ImportSummary struct {
+	// ImportedBooks is the number of books created. The books merged into the
+	// existing books of the same label are not counted.
+	ImportedBooks int `json:"imported_books"`
+	ImportedNotes int `json:"imported_notes"`
+	// SkippedNotes is the number of notes that already existed when merging
+	SkippedNotes int `json:"skipped_notes"`
+	FailedBooks  int `json:"failed_books"`
+	FailedNotes  int `json:"failed_notes"`
+}
+
+// validateImportLabel validates the label of an imported book by the same
+// rules the clients apply to the book names
+func validateImportLabel(label string) error {
+	if label == "" {
+		return errors.New("label is empty")
+	}
+	if _, err := strconv.Atoi(label); err == nil {
+		return errors.New("label cannot contain only numbers")
+	}
+	if strings.ContainsAny(label, " \r\n") {
+		return errors.New("label cannot contain spaces or line breaks")
+	}
+
+	return nil
+}
+
+// clearUserData deletes all books and notes of the user, giving each a new usn
+// so that the deletions are synced to the clients
+func (a *App) clearUserData(tx *gorm.DB, user database.User) error {
+	var notes []database.Note
+	if err := tx.Where("user_id = ? AND deleted = ?", user.ID, false).Find(&notes).Error; err != nil {
+		return errors.Wrap(err, "finding notes")
+	}
+	for _, note := range notes {
+		if _, err := a.DeleteNote(tx, user, note); err != nil {
+			return errors.Wrap(err, "deleting a note")
+		}
+	}
+
+	var books []database.Book
+	if err := tx.Where("user_id = ? AND deleted = ?", user.ID, false).Find(&books).Error; err != nil {
+		return errors.Wrap(err, "finding books")
+	}
+	for _, book := range books {
+		if _, err := a.DeleteBook(tx, user, book); err != nil {
+			return errors.Wrap(err, "deleting a book")
+		}
+	}
+
+	return nil
+}
+
+// importBook returns the uuid of the user's book with the label of the given
+// book, creating the book if it does not exist
+func (a *App) importBook(tx *gorm.DB, user database.User, b ImportBook) (string, bool, error) {
+	var existing database.Book
+	conn := tx.Where("user_id = ? AND label = ? AND deleted = ?", user.ID, b.Label, false).First(&existing)
+	if err := conn.Error; err == nil {
+		return existing.UUID, false, nil
+	} else if !conn.RecordNotFound() {
+		return "", false, errors.Wrap(err, "finding the book")
+	}
+
+	nextUSN, err := incrementUserUSN(tx, user.ID)
+	if err != nil {
+		return "", false, errors.Wrap(err, "incrementing user max_usn")
+	}
+
+	uuid, err := helpers.GenUUID()
+	if err != nil {
+		return "", false, err
+	}
+
+	addedOn := b.AddedOn
+	if addedOn == 0 {
+		addedOn = a.Clock.Now().UnixNano()
+	}
+
+	book := database.Book{
+		UUID:     uuid,
+		UserID:   user.ID,
+		Label:    b.Label,
+		AddedOn:  addedOn,
+		EditedOn: b.EditedOn,
+		USN:      nextUSN,
+	}
+	if err := tx.Create(&book).Error; err != nil {
+		return "", false, errors.Wrap(err, "inserting book")
+	}
+
+	return uuid, true, nil
+}
+
+// importNote creates the note in the book with the given uuid. It returns
+// false if the book already has a note with the same content added at the same
+// time, as is the case when an export is imported twice.
+func (a *App) importNote(tx *gorm.DB, user database.User, bookUUID string, n ImportNote) (bool, error) {
+	var count int
+	if err := tx.Model(&database.Note{}).
+		Where("user_id = ? AND book_uuid = ? AND body = ? AND added_on = ? AND deleted = ?", user.ID, bookUUID, n.Content, n.AddedOn, false).
+		Count(&count).Error; err != nil {
+		return false, errors.Wrap(err, "checking duplicate")
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	nextUSN, err := incrementUserUSN(tx, user.ID)
+	if err != nil {
+		return false, errors.Wrap(err, "incrementing user max_usn")
+	}
+
+	uuid, err := helpers.GenUUID()
+	if err != nil {
+		return false, err
+	}
+
+	addedOn := n.AddedOn
+	if addedOn == 0 {
+		addedOn = a.Clock.Now().UnixNano()
+	}
+
+	note := database.Note{
+		UUID:     uuid,
+		BookUUID: bookUUID,
+		UserID:   user.ID,
+		AddedOn:  addedOn,
+		EditedOn: n.EditedOn,
+		USN:      nextUSN,
+		Body:     n.Content,
+		Public:   n.Public,
+		Client:   "import",
+	}
+	if err := setShareSlug(&note); err != nil {
+		return false, err
+	}
+	if err := tx.Create(&note).Error; err != nil {
+		return false, errors.Wrap(err, "inserting note")
+	}
+
+	return true, nil
+}
+
+// Import recreates the given books and notes for the user in a transaction.
+// The books with an invalid label and the notes that are too large or do not
+// belong to an imported book are skipped and counted as failed. A book is merged
+// into the existing book with the same label. In the replace mode, the existing
+// books and notes are deleted first.
+func (a *App) Import(user database.User, books []ImportBook, notes []ImportNote, mode string) (ImportSummary, error) {
+	var ret ImportSummary
+
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		return ret, errors.Errorf("unknown import mode '%s'", mode)
+	}
+
+	tx := a.DB.Begin()
+
+	if mode == ImportModeReplace {
+		if err := a.clearUserData(tx, user); err != nil {
+			tx.Rollback()
+			return ret, errors.Wrap(err, "clearing the existing data")
+		}
+	}
+
+	// bookUUIDs maps the uuids in the import to the uuids of the user's books
+	bookUUIDs := map[string]string{}
+	for _, b := range books {
+		if b.UUID == "" || validateImportLabel(b.Label) != nil {
+			ret.FailedBooks++
+			continue
+		}
+
+		uuid, created, err := a.importBook(tx, user, b)
+		if err != nil {
+			tx.Rollback()
+			return ret, errors.Wrapf(err, "importing book '%s'", b.Label)
+		}
+
+		bookUUIDs[b.UUID] = uuid
+		if created {
+			ret.ImportedBooks++
+		}
+	}
+
+	maxSize := a.Config.GetMaxNoteBodySize()
+	for _, n := range notes {
+		bookUUID, ok := bookUUIDs[n.BookUUID]
+		if !ok || int64(len(n.Content)) > maxSize {
+			ret.FailedNotes++
+			continue
+		}
+
+		created, err := a.importNote(tx, user, bookUUID, n)
+		if err != nil {
+			tx.Rollback()
+			return ret, errors.Wrap(err, "importing a note")
+		}
+
+		if created {
+			ret.ImportedNotes++
+		} else {
+			ret.SkippedNotes++
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return ret, errors.Wrap(err, "committing the transaction")
+	}
+
+	return ret, nil
+}
@@ -19,17 +19,86 @@
 package app
 
 import (
+	"github.com/dnote/dnote/pkg/server/crypt"
 	"github.com/dnote/dnote/pkg/server/database"
 	"github.com/dnote/dnote/pkg/server/helpers"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
 
+// shareSlugLength is the number of random bytes in a share slug
+const shareSlugLength = 12
+
+var (
+	// ErrNoteNotFound is an error for when a note does not exist or belongs to
+	// another user
+	ErrNoteNotFound = errors.New("note not found")
+	// ErrBookNotFound is an error for when a book does not exist or belongs to
+	// another user
+	ErrBookNotFound = errors.New("book not found")
+)
+
+// GetNote returns the note with the given uuid that belongs to the user, with
+// its book preloaded
+func (a *App) GetNote(user database.User, uuid string) (database.Note, error) {
+	var ret database.Note
+	conn := a.DB.Where("uuid = ? AND user_id = ?", uuid, user.ID).Preload("Book").First(&ret)
+	if conn.RecordNotFound() {
+		return ret, ErrNoteNotFound
+	}
+	if err := conn.Error; err != nil {
+		return ret, errors.Wrap(err, "finding note")
+	}
+
+	return ret, nil
+}
+
+// GetBook returns the book with the given uuid that belongs to the user
+func (a *App) GetBook(user database.User, uuid string) (database.Book, error) {
+	return getUserBook(a.DB, user, uuid)
+}
+
+func getUserBook(db *gorm.DB, user database.User, uuid string) (database.Book, error) {
+	var ret database.Book
+	conn := db.Where("uuid = ? AND user_id = ?", uuid, user.ID).First(&ret)
+	if conn.RecordNotFound() {
+		return ret, ErrBookNotFound
+	}
+	if err := conn.Error; err != nil {
+		return ret, errors.Wrap(err, "finding book")
+	}
+
+	return ret, nil
+}
+
+// setShareSlug generates a share slug for the given note if it is public and
+// does not have one yet. The slug is kept when the note is made private so that
+// the link stays the same if the note is shared again.
+func setShareSlug(note *database.Note) error {
+	if !note.Public || note.ShareSlug != "" {
+		return nil
+	}
+
+	slug, err := crypt.GetRandomURLStr(shareSlugLength)
+	if err != nil {
+		return errors.Wrap(err, "generating share slug")
+	}
+
+	note.ShareSlug = slug
+
+	return nil
+}
+
 // CreateNote creates a note with the next usn and updates the user's max_usn.
-// It returns the created note.
+// It returns the created note. The book must belong to the user.
 func (a *App) CreateNote(user database.User, bookUUID, content string, addedOn *int64, editedOn *int64, public bool, client string) (database.Note, error) {
 	tx := a.DB.Begin()
 
+	if _, err := getUserBook(tx, user, bookUUID); err != nil {
+		tx.Rollback()
+		return database.Note{}, err
+	}
+
 	nextUSN, err := incrementUserUSN(tx, user.ID)
 	if err != nil {
 		tx.Rollback()
@@ -67,6 +136,10 @@ func (a *App) CreateNote(user database.User, bookUUID, content string, addedOn *
 		Encrypted: false,
 		Client:    client,
 	}
+	if err := setShareSlug(&note); err != nil {
+		tx.Rollback()
+		return note, err
+	}
 	if err := tx.Create(&note).Error; err != nil {
 		tx.Rollback()
 		return note, errors.Wrap(err, "inserting note")
@@ -111,8 +184,18 @@ func (r UpdateNoteParams) GetPublic() bool {
 	return *r.Public
 }
 
-// UpdateNote creates a note with the next usn and updates the user's max_usn
+// UpdateNote updates a note with the next usn and updates the user's max_usn.
+// If the book is changed, the new book must belong to the user.
 func (a *App) UpdateNote(tx *gorm.DB, user database.User, note database.Note, p *UpdateNoteParams) (database.Note, error) {
+	if user.ID != note.UserID {
+		return note, errors.New("Not allowed")
+	}
+	if p.BookUUID != nil {
+		if _, err := getUserBook(tx, user, p.GetBookUUID()); err != nil {
+			return note, err
+		}
+	}
+
 	nextUSN, err := incrementUserUSN(tx, user.ID)
 	if err != nil {
 		return note, errors.Wrap(err, "incrementing user max_usn")
@@ -127,6 +210,9 @@ func (a *App) UpdateNote(tx *gorm.DB, user database.User, note database.Note, p
 	if p.Public != nil {
 		note.Public = p.GetPublic()
 	}
+	if err := setShareSlug(&note); err != nil {
+		return note, err
+	}
 
 	note.USN = nextUSN
 	note.EditedOn = a.Clock.Now().UnixNano()
@@ -141,8 +227,13 @@ func (a *App) UpdateNote(tx *gorm.DB, user database.User, note database.Note, p
 	return note, nil
 }
 
-// DeleteNote marks a note deleted with the next usn and updates the user's max_usn
+// DeleteNote marks a note deleted with the next usn, clears its content and
+// updates the user's max_usn
 func (a *App) DeleteNote(tx *gorm.DB, user database.User, note database.Note) (database.Note, error) {
+	if user.ID != note.UserID {
+		return note, errors.New("Not allowed")
+	}
+
 	nextUSN, err := incrementUserUSN(tx, user.ID)
 	if err != nil {
 		return note, errors.Wrap(err, "incrementing user max_usn")
@@ -160,6 +251,52 @@ func (a *App) DeleteNote(tx *gorm.DB, user database.User, note database.Note) (d
 	return note, nil
 }
 
+// SoftDeleteNote marks a note deleted with the next usn and updates the user's
+// max_usn. Unlike DeleteNote, it keeps the content so that the note can be restored.
+func (a *App) SoftDeleteNote(tx *gorm.DB, user database.User, note database.Note) (database.Note, error) {
+	if user.ID != note.UserID {
+		return note, errors.New("Not allowed")
+	}
+
+	nextUSN, err := incrementUserUSN(tx, user.ID)
+	if err != nil {
+		return note, errors.Wrap(err, "incrementing user max_usn")
+	}
+
+	if err := tx.Model(&note).
+		Update(map[string]interface{}{
+			"usn":     nextUSN,
+			"deleted": true,
+		}).Error; err != nil {
+		return note, errors.Wrap(err, "deleting note")
+	}
+
+	return note, nil
+}
+
+// RestoreNote marks a deleted note not deleted with the next usn and updates
+// the user's max_usn
+func (a *App) RestoreNote(tx *gorm.DB, user database.User, note database.Note) (database.Note, error) {
+	if user.ID != note.UserID {
+		return note, errors.New("Not allowed")
+	}
+
+	nextUSN, err := incrementUserUSN(tx, user.ID)
+	if err != nil {
+		return note, errors.Wrap(err, "incrementing user max_usn")
+	}
+
+	if err := tx.Model(&note).
+		Update(map[string]interface{}{
+			"usn":     nextUSN,
+			"deleted": false,
+		}).Error; err != nil {
+		return note, errors.Wrap(err, "restoring note")
+	}
+
+	return note, nil
+}
+
 // GetUserNoteByUUID retrives a digest by the uuid for the given user
 func (a *App) GetUserNoteByUUID(userID int, uuid string) (*database.Note, error) {
 	var ret database.Note
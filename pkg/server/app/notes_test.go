@@ -191,6 +191,50 @@ func TestUpdateNote(t *testing.T) {
 	}
 }
 
+func TestUpdateNoteShareSlug(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	note := database.Note{UserID: user.ID, Body: "test content", BookUUID: b1.UUID}
+	testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+	a := NewTest(&App{
+		Clock: clock.NewMock(),
+	})
+
+	setPublic := func(n database.Note, public bool) database.Note {
+		tx := testutils.DB.Begin()
+		ret, err := a.UpdateNote(tx, user, n, &UpdateNoteParams{
+			Public: &public,
+		})
+		if err != nil {
+			tx.Rollback()
+			t.Fatal(errors.Wrap(err, "updating note"))
+		}
+		tx.Commit()
+
+		return ret
+	}
+
+	assert.Equal(t, note.ShareSlug, "", "private note should not have a share slug")
+
+	note = setPublic(note, true)
+	slug := note.ShareSlug
+	assert.NotEqual(t, slug, "", "public note should have a share slug")
+
+	note = setPublic(note, false)
+	assert.Equal(t, note.ShareSlug, slug, "share slug should be kept when made private")
+
+	note = setPublic(note, true)
+	assert.Equal(t, note.ShareSlug, slug, "share slug should be stable")
+
+	var noteRecord database.Note
+	testutils.MustExec(t, testutils.DB.Where("id = ?", note.ID).First(&noteRecord), "finding note")
+	assert.Equal(t, noteRecord.ShareSlug, slug, "share slug mismatch")
+}
+
 func TestDeleteNote(t *testing.T) {
 	testCases := []struct {
 		userUSN     int
@@ -259,3 +303,208 @@ func TestDeleteNote(t *testing.T) {
 		}()
 	}
 }
+
+func TestSoftDeleteNote(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 3), "preparing user max_usn")
+
+	b1 := database.Book{UserID: user.ID, Label: "testBook"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	note := database.Note{UserID: user.ID, Deleted: false, Body: "test content", BookUUID: b1.UUID}
+	testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+	a := NewTest(nil)
+
+	tx := testutils.DB.Begin()
+	ret, err := a.SoftDeleteNote(tx, user, note)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "deleting note"))
+	}
+	tx.Commit()
+
+	var noteRecord database.Note
+	var userRecord database.User
+	testutils.MustExec(t, testutils.DB.First(&noteRecord), "finding note")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user")
+
+	assert.Equal(t, noteRecord.Body, "test content", "note content mismatch")
+	assert.Equal(t, noteRecord.Deleted, true, "note deleted flag mismatch")
+	assert.Equal(t, noteRecord.USN, 4, "note usn mismatch")
+	assert.Equal(t, userRecord.MaxUSN, 4, "user max_usn mismatch")
+
+	assert.Equal(t, ret.Body, "test content", "returned content mismatch")
+	assert.Equal(t, ret.Deleted, true, "returned deleted flag mismatch")
+	assert.Equal(t, ret.USN, 4, "returned usn mismatch")
+}
+
+func TestRestoreNote(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	testutils.MustExec(t, testutils.DB.Model(&user).Update("max_usn", 7), "preparing user max_usn")
+
+	b1 := database.Book{UserID: user.ID, Label: "testBook"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	note := database.Note{UserID: user.ID, Deleted: true, Body: "test content", BookUUID: b1.UUID}
+	testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+	a := NewTest(nil)
+
+	tx := testutils.DB.Begin()
+	ret, err := a.RestoreNote(tx, user, note)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(errors.Wrap(err, "restoring note"))
+	}
+	tx.Commit()
+
+	var noteRecord database.Note
+	var userRecord database.User
+	testutils.MustExec(t, testutils.DB.First(&noteRecord), "finding note")
+	testutils.MustExec(t, testutils.DB.Where("id = ?", user.ID).First(&userRecord), "finding user")
+
+	assert.Equal(t, noteRecord.Body, "test content", "note content mismatch")
+	assert.Equal(t, noteRecord.Deleted, false, "note deleted flag mismatch")
+	assert.Equal(t, noteRecord.USN, 8, "note usn mismatch")
+	assert.Equal(t, userRecord.MaxUSN, 8, "user max_usn mismatch")
+
+	assert.Equal(t, ret.Deleted, false, "returned deleted flag mismatch")
+	assert.Equal(t, ret.USN, 8, "returned usn mismatch")
+}
+
+func TestGetNote(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	n1 := database.Note{UserID: user.ID, Body: "n1 content", BookUUID: b1.UUID}
+	testutils.MustExec(t, testutils.DB.Save(&n1), "preparing n1")
+
+	a := NewTest(nil)
+
+	t.Run("owned", func(t *testing.T) {
+		got, err := a.GetNote(user, n1.UUID)
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "getting note"))
+		}
+
+		assert.Equal(t, got.UUID, n1.UUID, "uuid mismatch")
+		assert.Equal(t, got.Body, "n1 content", "body mismatch")
+		assert.Equal(t, got.Book.Label, "js", "book should be preloaded")
+	})
+
+	t.Run("not owned", func(t *testing.T) {
+		_, err := a.GetNote(anotherUser, n1.UUID)
+		assert.Equal(t, err, ErrNoteNotFound, "error mismatch")
+	})
+
+	t.Run("nonexistent", func(t *testing.T) {
+		_, err := a.GetNote(user, "1f8e6bbd-0c9a-4dc2-a2a3-c6d6f7a2bb7a")
+		assert.Equal(t, err, ErrNoteNotFound, "error mismatch")
+	})
+}
+
+func TestGetBook(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+
+	a := NewTest(nil)
+
+	got, err := a.GetBook(user, b1.UUID)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "getting book"))
+	}
+	assert.Equal(t, got.Label, "js", "label mismatch")
+
+	_, err = a.GetBook(anotherUser, b1.UUID)
+	assert.Equal(t, err, ErrBookNotFound, "error mismatch for another user")
+}
+
+func TestNoteUserScoping(t *testing.T) {
+	defer testutils.ClearData(testutils.DB)
+
+	user := testutils.SetupUserData()
+	anotherUser := testutils.SetupUserData()
+
+	b1 := database.Book{UserID: user.ID, Label: "js"}
+	testutils.MustExec(t, testutils.DB.Save(&b1), "preparing b1")
+	b2 := database.Book{UserID: anotherUser.ID, Label: "css"}
+	testutils.MustExec(t, testutils.DB.Save(&b2), "preparing b2")
+
+	note := database.Note{UserID: user.ID, Body: "n1 content", BookUUID: b1.UUID}
+	testutils.MustExec(t, testutils.DB.Save(&note), "preparing note")
+
+	a := NewTest(nil)
+
+	t.Run("create in the book of another user", func(t *testing.T) {
+		_, err := a.CreateNote(user, b2.UUID, "foo", nil, nil, false, "")
+		assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+	})
+
+	t.Run("update the note of another user", func(t *testing.T) {
+		content := "bar"
+
+		tx := testutils.DB.Begin()
+		_, err := a.UpdateNote(tx, anotherUser, note, &UpdateNoteParams{Content: &content})
+		tx.Rollback()
+
+		assert.NotEqual(t, err, nil, "should fail")
+	})
+
+	t.Run("move to the book of another user", func(t *testing.T) {
+		bookUUID := b2.UUID
+
+		tx := testutils.DB.Begin()
+		_, err := a.UpdateNote(tx, user, note, &UpdateNoteParams{BookUUID: &bookUUID})
+		tx.Rollback()
+
+		assert.Equal(t, err, ErrBookNotFound, "error mismatch")
+	})
+
+	t.Run("delete the note of another user", func(t *testing.T) {
+		tx := testutils.DB.Begin()
+		_, err := a.DeleteNote(tx, anotherUser, note)
+		tx.Rollback()
+
+		assert.NotEqual(t, err, nil, "should fail")
+	})
+
+	t.Run("soft delete the note of another user", func(t *testing.T) {
+		tx := testutils.DB.Begin()
+		_, err := a.SoftDeleteNote(tx, anotherUser, note)
+		tx.Rollback()
+
+		assert.NotEqual(t, err, nil, "should fail")
+	})
+
+	t.Run("restore the note of another user", func(t *testing.T) {
+		tx := testutils.DB.Begin()
+		_, err := a.RestoreNote(tx, anotherUser, note)
+		tx.Rollback()
+
+		assert.NotEqual(t, err, nil, "should fail")
+	})
+
+	var noteRecord database.Note
+	var noteCount int
+	testutils.MustExec(t, testutils.DB.Where("uuid = ?", note.UUID).First(&noteRecord), "finding note")
+	testutils.MustExec(t, testutils.DB.Model(&database.Note{}).Count(&noteCount), "counting notes")
+
+	assert.Equal(t, noteRecord.Body, "n1 content", "note content should not change")
+	assert.Equal(t, noteRecord.BookUUID, b1.UUID, "note book should not change")
+	assert.Equal(t, noteRecord.Deleted, false, "note should not be deleted")
+	assert.Equal(t, noteCount, 1, "no note should be created")
+}
@@ -60,6 +60,18 @@ func NewTest(appParams *App) App {
 	if appParams != nil && appParams.Config.DisableRegistration {
 		a.Config.DisableRegistration = appParams.Config.DisableRegistration
 	}
+	if appParams != nil && appParams.Config.MaxNoteBodySize != 0 {
+		a.Config.MaxNoteBodySize = appParams.Config.MaxNoteBodySize
+	}
+	if appParams != nil && appParams.Config.MaxImportSize != 0 {
+		a.Config.MaxImportSize = appParams.Config.MaxImportSize
+	}
+	if appParams != nil && appParams.Config.SMTP.From != "" {
+		a.Config.SMTP.From = appParams.Config.SMTP.From
+	}
+	if appParams != nil && appParams.Config.WelcomeEmailTemplate != "" {
+		a.Config.WelcomeEmailTemplate = appParams.Config.WelcomeEmailTemplate
+	}
 
 	return a
 }
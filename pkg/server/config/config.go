@@ -23,8 +23,27 @@ import (
 	"github.com/pkg/errors"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultMaxNoteBodySize is the maximum size of a note body in bytes, used
+// if none is configured
+const DefaultMaxNoteBodySize int64 = 1 << 20
+
+// DefaultMaxImportSize is the maximum size of an import payload in bytes, used
+// if none is configured
+const DefaultMaxImportSize int64 = 32 << 20
+
+// DefaultShutdownTimeout is how long the server waits for the in-flight
+// requests to finish when shutting down, used if none is configured
+const DefaultShutdownTimeout = 30 * time.Second
+
+// DefaultAllowedOrigins are the origins allowed to make cross-origin requests
+// to the API, used if none is configured. They match the browser extensions.
+var DefaultAllowedOrigins = []string{"moz-extension://*", "chrome-extension://*"}
+
 var (
 	// ErrDBMissingHost is an error for an incomplete configuration missing the host
 	ErrDBMissingHost = errors.New("DB Host is empty")
@@ -38,6 +57,14 @@ var (
 	ErrWebURLInvalid = errors.New("Invalid WebURL")
 	// ErrPortInvalid is an error for an incomplete configuration with invalid port
 	ErrPortInvalid = errors.New("Invalid Port")
+	// ErrMaxNoteBodySizeInvalid is an error for an invalid maximum note body size
+	ErrMaxNoteBodySizeInvalid = errors.New("Invalid MaxNoteBodySize")
+	// ErrMaxImportSizeInvalid is an error for an invalid maximum import size
+	ErrMaxImportSizeInvalid = errors.New("Invalid MaxImportSize")
+	// ErrLogFormatInvalid is an error for an unknown log format
+	ErrLogFormatInvalid = errors.New("Invalid LogFormat")
+	// ErrShutdownTimeoutInvalid is an error for an invalid shutdown timeout
+	ErrShutdownTimeoutInvalid = errors.New("Invalid ShutdownTimeout")
 )
 
 // PostgresConfig holds the postgres connection configuration.
@@ -90,6 +117,30 @@ func loadDBConfig() PostgresConfig {
 	}
 }
 
+// SMTPConfig holds the configuration for sending emails over SMTP
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// IsConfigured returns true if the server and the credentials are all configured
+func (c SMTPConfig) IsConfigured() bool {
+	return c.Host != "" && c.Port != "" && c.Username != "" && c.Password != ""
+}
+
+func loadSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Host:     os.Getenv("SmtpHost"),
+		Port:     os.Getenv("SmtpPort"),
+		Username: os.Getenv("SmtpUsername"),
+		Password: os.Getenv("SmtpPassword"),
+		From:     os.Getenv("SmtpFrom"),
+	}
+}
+
 // Config is an application configuration
 type Config struct {
 	WebURL              string
@@ -97,6 +148,83 @@ type Config struct {
 	DisableRegistration bool
 	Port                string
 	DB                  PostgresConfig
+	MaxNoteBodySize     int64
+	MaxImportSize       int64
+	LogFormat           string
+	ShutdownTimeout     time.Duration
+	AllowedOrigins      []string
+	SMTP                SMTPConfig
+	// WelcomeEmailTemplate is the path to a text template for the welcome
+	// email. The built-in template is used if it is empty.
+	WelcomeEmailTemplate string
+}
+
+func loadMaxNoteBodySize() int64 {
+	val := os.Getenv("MaxNoteBodySize")
+	if val == "" {
+		return DefaultMaxNoteBodySize
+	}
+
+	ret, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || ret <= 0 {
+		panic(errors.Wrapf(ErrMaxNoteBodySizeInvalid, "provided: '%s'", val))
+	}
+
+	return ret
+}
+
+func loadMaxImportSize() int64 {
+	val := os.Getenv("MaxImportSize")
+	if val == "" {
+		return DefaultMaxImportSize
+	}
+
+	ret, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || ret <= 0 {
+		panic(errors.Wrapf(ErrMaxImportSizeInvalid, "provided: '%s'", val))
+	}
+
+	return ret
+}
+
+func loadLogFormat() string {
+	val := os.Getenv("LogFormat")
+	if val == "" {
+		return "json"
+	}
+
+	return val
+}
+
+func loadShutdownTimeout() time.Duration {
+	val := os.Getenv("ShutdownTimeout")
+	if val == "" {
+		return DefaultShutdownTimeout
+	}
+
+	ret, err := time.ParseDuration(val)
+	if err != nil || ret <= 0 {
+		panic(errors.Wrapf(ErrShutdownTimeoutInvalid, "provided: '%s'", val))
+	}
+
+	return ret
+}
+
+func loadAllowedOrigins() []string {
+	val := os.Getenv("AllowedOrigins")
+	if val == "" {
+		return nil
+	}
+
+	ret := []string{}
+	for _, origin := range strings.Split(val, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin != "" {
+			ret = append(ret, origin)
+		}
+	}
+
+	return ret
 }
 
 // Load constructs and returns a new config based on the environment variables.
@@ -107,11 +235,18 @@ func Load() Config {
 	}
 
 	c := Config{
-		WebURL:              os.Getenv("WebURL"),
-		Port:                port,
-		OnPremise:           readBoolEnv("OnPremise"),
-		DisableRegistration: readBoolEnv("DisableRegistration"),
-		DB:                  loadDBConfig(),
+		WebURL:               os.Getenv("WebURL"),
+		Port:                 port,
+		OnPremise:            readBoolEnv("OnPremise"),
+		DisableRegistration:  readBoolEnv("DisableRegistration"),
+		DB:                   loadDBConfig(),
+		MaxNoteBodySize:      loadMaxNoteBodySize(),
+		MaxImportSize:        loadMaxImportSize(),
+		LogFormat:            loadLogFormat(),
+		ShutdownTimeout:      loadShutdownTimeout(),
+		AllowedOrigins:       loadAllowedOrigins(),
+		SMTP:                 loadSMTPConfig(),
+		WelcomeEmailTemplate: os.Getenv("WelcomeEmailTemplate"),
 	}
 
 	if err := validate(c); err != nil {
@@ -126,6 +261,64 @@ func (c *Config) SetOnPremise(val bool) {
 	c.OnPremise = val
 }
 
+// GetMaxNoteBodySize returns the maximum size of a note body in bytes
+func (c Config) GetMaxNoteBodySize() int64 {
+	if c.MaxNoteBodySize <= 0 {
+		return DefaultMaxNoteBodySize
+	}
+
+	return c.MaxNoteBodySize
+}
+
+// GetMaxImportSize returns the maximum size of an import payload in bytes
+func (c Config) GetMaxImportSize() int64 {
+	if c.MaxImportSize <= 0 {
+		return DefaultMaxImportSize
+	}
+
+	return c.MaxImportSize
+}
+
+// GetShutdownTimeout returns how long to wait for the in-flight requests to
+// finish when shutting down
+func (c Config) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+
+	return c.ShutdownTimeout
+}
+
+// GetAllowedOrigins returns the origins allowed to make cross-origin requests.
+// An origin can be "*" to allow all origins, or end with "*" to allow all
+// origins with the given prefix.
+func (c Config) GetAllowedOrigins() []string {
+	if len(c.AllowedOrigins) == 0 {
+		return DefaultAllowedOrigins
+	}
+
+	return c.AllowedOrigins
+}
+
+// IsOriginAllowed returns true if the given origin is allowed to make
+// cross-origin requests
+func (c Config) IsOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	for _, allowed := range c.GetAllowedOrigins() {
+		if allowed == origin {
+			return true
+		}
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(origin, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func validate(c Config) error {
 	if _, err := url.ParseRequestURI(c.WebURL); err != nil {
 		return errors.Wrapf(ErrWebURLInvalid, "provided: '%s'", c.WebURL)
@@ -133,6 +326,9 @@ func validate(c Config) error {
 	if c.Port == "" {
 		return ErrPortInvalid
 	}
+	if c.LogFormat != "" && c.LogFormat != "json" && c.LogFormat != "text" {
+		return errors.Wrapf(ErrLogFormatInvalid, "provided: '%s'", c.LogFormat)
+	}
 
 	if c.DB.Host == "" {
 		return ErrDBMissingHost
@@ -21,6 +21,7 @@ package config
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/dnote/dnote/pkg/assert"
 	"github.com/pkg/errors"
@@ -115,6 +116,34 @@ func TestValidate(t *testing.T) {
 			},
 			expectedErr: ErrPortInvalid,
 		},
+		{
+			config: Config{
+				DB: PostgresConfig{
+					Host: "mockHost",
+					Port: "5432",
+					Name: "mockDB",
+					User: "mockUser",
+				},
+				WebURL:    "http://mock.url",
+				Port:      "3000",
+				LogFormat: "text",
+			},
+			expectedErr: nil,
+		},
+		{
+			config: Config{
+				DB: PostgresConfig{
+					Host: "mockHost",
+					Port: "5432",
+					Name: "mockDB",
+					User: "mockUser",
+				},
+				WebURL:    "http://mock.url",
+				Port:      "3000",
+				LogFormat: "xml",
+			},
+			expectedErr: ErrLogFormatInvalid,
+		},
 	}
 
 	for idx, tc := range testCases {
@@ -125,3 +154,158 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestSMTPIsConfigured(t *testing.T) {
+	testCases := []struct {
+		config   SMTPConfig
+		expected bool
+	}{
+		{
+			config:   SMTPConfig{Host: "smtp.example.com", Port: "587", Username: "user", Password: "pass"},
+			expected: true,
+		},
+		{
+			config:   SMTPConfig{Host: "smtp.example.com", Port: "587", Username: "user", Password: "pass", From: "hello@example.com"},
+			expected: true,
+		},
+		{
+			config:   SMTPConfig{Host: "smtp.example.com", Port: "587", Username: "user"},
+			expected: false,
+		},
+		{
+			config:   SMTPConfig{From: "hello@example.com"},
+			expected: false,
+		},
+		{
+			config:   SMTPConfig{},
+			expected: false,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.config.IsConfigured(), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetMaxNoteBodySize(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		expected int64
+	}{
+		{
+			config:   Config{MaxNoteBodySize: 2048},
+			expected: 2048,
+		},
+		{
+			config:   Config{},
+			expected: DefaultMaxNoteBodySize,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.config.GetMaxNoteBodySize(), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetMaxImportSize(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		expected int64
+	}{
+		{
+			config:   Config{MaxImportSize: 2048},
+			expected: 2048,
+		},
+		{
+			config:   Config{},
+			expected: DefaultMaxImportSize,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.config.GetMaxImportSize(), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestGetShutdownTimeout(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		expected time.Duration
+	}{
+		{
+			config:   Config{ShutdownTimeout: 5 * time.Second},
+			expected: 5 * time.Second,
+		},
+		{
+			config:   Config{},
+			expected: DefaultShutdownTimeout,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.config.GetShutdownTimeout(), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestIsOriginAllowed(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		origin   string
+		expected bool
+	}{
+		{
+			config:   Config{},
+			origin:   "chrome-extension://abc",
+			expected: true,
+		},
+		{
+			config:   Config{},
+			origin:   "moz-extension://abc",
+			expected: true,
+		},
+		{
+			config:   Config{},
+			origin:   "https://example.com",
+			expected: false,
+		},
+		{
+			config:   Config{AllowedOrigins: []string{"https://example.com"}},
+			origin:   "https://example.com",
+			expected: true,
+		},
+		{
+			config:   Config{AllowedOrigins: []string{"https://example.com"}},
+			origin:   "https://example.com.evil.com",
+			expected: false,
+		},
+		{
+			config:   Config{AllowedOrigins: []string{"https://example.com"}},
+			origin:   "chrome-extension://abc",
+			expected: false,
+		},
+		{
+			config:   Config{AllowedOrigins: []string{"*"}},
+			origin:   "https://example.com",
+			expected: true,
+		},
+		{
+			config:   Config{AllowedOrigins: []string{"*"}},
+			origin:   "",
+			expected: false,
+		},
+	}
+
+	for idx, tc := range testCases {
+		t.Run(fmt.Sprintf("test case %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.config.IsOriginAllowed(tc.origin), tc.expected, "result mismatch")
+		})
+	}
+}
@@ -52,6 +52,17 @@ func GetRandomStr(numBytes int) (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
+// GetRandomURLStr generates a cryptographically secure pseudorandom string of
+// the given size in byte that is safe to use in a URL
+func GetRandomURLStr(numBytes int) (string, error) {
+	b, err := getRandomBytes(numBytes)
+	if err != nil {
+		return "", errors.Wrap(err, "generating random bits")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // HashAuthKey hashes the authKey provided by a client
 func HashAuthKey(authKey, salt string, iteration int) string {
 	keyHashBits := pbkdf2.Key([]byte(authKey), []byte(salt), iteration, 32, sha256.New)
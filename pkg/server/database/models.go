@@ -56,6 +56,7 @@ type Note struct {
 	EditedOn  int64  `json:"edited_on"`
 	TSV       string `json:"-" gorm:"type:tsvector"`
 	Public    bool   `json:"public" gorm:"default:false"`
+	ShareSlug string `json:"-" gorm:"index"`
 	USN       int    `json:"-" gorm:"index"`
 	Deleted   bool   `json:"-" gorm:"default:false"`
 	Encrypted bool   `json:"-" gorm:"default:false"`
@@ -21,7 +21,6 @@ package handlers
 import (
 	"context"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/dnote/dnote/pkg/server/app"
@@ -60,16 +59,31 @@ func authWithToken(db *gorm.DB, r *http.Request, tokenType string, p *AuthParams
 	return user, token, true, nil
 }
 
-// Cors allows browser extensions to load resources
-func Cors(next http.HandlerFunc) http.HandlerFunc {
+// Cors allows the origins configured in the app to load resources. The
+// requests from other origins get no CORS headers, and their preflight requests
+// are rejected.
+func Cors(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		if !a.Config.IsOriginAllowed(origin) {
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
 
-		// Allow browser extensions
-		if strings.HasPrefix(origin, "moz-extension://") || strings.HasPrefix(origin, "chrome-extension://") {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+			next.ServeHTTP(w, r)
+			return
 		}
 
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -105,6 +119,8 @@ func Auth(a *app.App, next http.HandlerFunc, p *AuthParams) http.HandlerFunc {
 			}
 		}
 
+		setLogUser(r, user.ID)
+
 		ctx := context.WithValue(r.Context(), helpers.KeyUser, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -144,6 +160,8 @@ func TokenAuth(a *app.App, next http.HandlerFunc, tokenType string, p *AuthParam
 			}
 		}
 
+		setLogUser(r, user.ID)
+
 		ctx = context.WithValue(ctx, helpers.KeyUser, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,128 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/server/app"
+	"github.com/dnote/dnote/pkg/server/config"
+)
+
+func TestCors(t *testing.T) {
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		origin         string
+		expectedCode   int
+		expectedOrigin string
+		expectedMethod string
+	}{
+		{
+			name:           "allowed",
+			allowedOrigins: []string{"https://example.com"},
+			method:         "GET",
+			origin:         "https://example.com",
+			expectedCode:   http.StatusOK,
+			expectedOrigin: "https://example.com",
+		},
+		{
+			name:           "allowed preflight",
+			allowedOrigins: []string{"https://example.com"},
+			method:         "OPTIONS",
+			origin:         "https://example.com",
+			expectedCode:   http.StatusOK,
+			expectedOrigin: "https://example.com",
+			expectedMethod: "GET",
+		},
+		{
+			name:           "disallowed",
+			allowedOrigins: []string{"https://example.com"},
+			method:         "GET",
+			origin:         "https://evil.com",
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "disallowed preflight",
+			allowedOrigins: []string{"https://example.com"},
+			method:         "OPTIONS",
+			origin:         "https://evil.com",
+			expectedCode:   http.StatusForbidden,
+		},
+		{
+			name:           "wildcard",
+			allowedOrigins: []string{"*"},
+			method:         "GET",
+			origin:         "https://evil.com",
+			expectedCode:   http.StatusOK,
+			expectedOrigin: "https://evil.com",
+		},
+		{
+			name:           "prefix wildcard",
+			allowedOrigins: []string{"chrome-extension://*"},
+			method:         "GET",
+			origin:         "chrome-extension://abc",
+			expectedCode:   http.StatusOK,
+			expectedOrigin: "chrome-extension://abc",
+		},
+		{
+			name:           "default",
+			allowedOrigins: nil,
+			method:         "GET",
+			origin:         "moz-extension://abc",
+			expectedCode:   http.StatusOK,
+			expectedOrigin: "moz-extension://abc",
+		},
+		{
+			name:           "no origin",
+			allowedOrigins: []string{"*"},
+			method:         "GET",
+			origin:         "",
+			expectedCode:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := app.App{Config: config.Config{AllowedOrigins: tc.allowedOrigins}}
+			h := Cors(&a, inner)
+
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, rec.Code, tc.expectedCode, "status code mismatch")
+			assert.Equal(t, rec.Header().Get("Access-Control-Allow-Origin"), tc.expectedOrigin, "allow origin mismatch")
+			assert.Equal(t, rec.Header().Get("Access-Control-Allow-Methods"), tc.expectedMethod, "allow methods mismatch")
+		})
+	}
+}
@@ -19,8 +19,12 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -73,7 +77,8 @@ func UnsetSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &cookie)
 }
 
-// DoError logs the error and responds with the given status code with a generic status text
+// DoError logs the error and responds with the given status code with a generic status text.
+// If the request has an id, it is included in both, so that a reported error can be traced in the logs.
 func DoError(w http.ResponseWriter, msg string, err error, statusCode int) {
 	var message string
 	if err == nil {
@@ -82,11 +87,19 @@ func DoError(w http.ResponseWriter, msg string, err error, statusCode int) {
 		message = errors.Wrap(err, msg).Error()
 	}
 
-	log.WithFields(log.Fields{
+	fields := log.Fields{
 		"statusCode": statusCode,
-	}).Error(message)
+	}
 
 	statusText := http.StatusText(statusCode)
+
+	if requestID := w.Header().Get(RequestIDHeader); requestID != "" {
+		fields["requestId"] = requestID
+		statusText = fmt.Sprintf("%s (request id: %s)", statusText, requestID)
+	}
+
+	log.WithFields(fields).Error(message)
+
 	http.Error(w, statusText, statusCode)
 }
 
@@ -100,6 +113,113 @@ func RespondJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
 	}
 }
 
+// The reasons for a field to fail the validation
+const (
+	// FieldRequired is the reason for a missing field
+	FieldRequired = "required"
+	// FieldTooShort is the reason for a field that is shorter than allowed
+	FieldTooShort = "too_short"
+)
+
+// ValidationError is an error for a request payload that failed the
+// validation. It maps the name of each invalid field to the reason.
+type ValidationError map[string]string
+
+func (e ValidationError) Error() string {
+	fields := []string{}
+	for field, reason := range e {
+		fields = append(fields, fmt.Sprintf("%s %s", field, reason))
+	}
+	sort.Strings(fields)
+
+	return fmt.Sprintf("invalid payload: %s", strings.Join(fields, ", "))
+}
+
+// fieldReasonMessages are the formats of the human readable messages for the
+// reasons, given the name of the field
+var fieldReasonMessages = map[string]string{
+	FieldRequired: "%s is required",
+	FieldTooShort: "%s is too short",
+}
+
+// Message returns a human readable message for the error that can be shown to
+// the users, e.g. "Email is required. Password is too short."
+func (e ValidationError) Message() string {
+	messages := []string{}
+	for field, reason := range e {
+		name := strings.Replace(field, "_", " ", -1)
+		name = strings.ToUpper(name[:1]) + name[1:]
+
+		format, ok := fieldReasonMessages[reason]
+		if !ok {
+			format = "%s is invalid"
+		}
+
+		messages = append(messages, fmt.Sprintf(format, name)+".")
+	}
+	sort.Strings(messages)
+
+	return strings.Join(messages, " ")
+}
+
+// validationErrorResponse is the body of a response for a ValidationError
+type validationErrorResponse struct {
+	Error   string            `json:"error"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// RespondValidationError responds with bad request, a human readable message,
+// and the reason for each invalid field, e.g. {"error": "validation",
+// "message": "Email is required.", "fields": {"email": "required"}}
+func RespondValidationError(w http.ResponseWriter, err ValidationError) {
+	RespondJSON(w, http.StatusBadRequest, validationErrorResponse{
+		Error:   "validation",
+		Message: err.Message(),
+		Fields:  err,
+	})
+}
+
+// etagMatches returns whether the value of an If-None-Match header matches the
+// given entity tag. Weak tags match their strong counterparts.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RespondJSONWithETag encodes the given payload into a JSON format and writes it
+// to the given response writer with an ETag computed from the content. If the
+// request has a matching If-None-Match header, it responds with 304 Not Modified
+// without a body instead.
+func RespondJSONWithETag(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+		DoError(w, "encoding response", err, http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf("\"%x\"", sha256.Sum256(buf.Bytes()))
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := buf.WriteTo(w); err != nil {
+		log.ErrorWrap(err, "writing response")
+	}
+}
+
 // NotSupported is the handler for the route that is no longer supported
 func NotSupported(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "API version is not supported. Please upgrade your client.", http.StatusGone)
@@ -694,3 +694,83 @@ func TestTokenAuthMiddleWare_ProOnly(t *testing.T) {
 		assert.Equal(t, res.StatusCode, http.StatusUnauthorized, "status code mismatch")
 	})
 }
+
+func TestETagMatches(t *testing.T) {
+	testCases := []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{
+			ifNoneMatch: "",
+			expected:    false,
+		},
+		{
+			ifNoneMatch: `"abc"`,
+			expected:    true,
+		},
+		{
+			ifNoneMatch: `W/"abc"`,
+			expected:    true,
+		},
+		{
+			ifNoneMatch: `"xyz", "abc"`,
+			expected:    true,
+		},
+		{
+			ifNoneMatch: `"xyz"`,
+			expected:    false,
+		},
+		{
+			ifNoneMatch: "*",
+			expected:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ifNoneMatch, func(t *testing.T) {
+			assert.Equal(t, etagMatches(tc.ifNoneMatch, `"abc"`), tc.expected, "result mismatch")
+		})
+	}
+}
+
+func TestRespondJSONWithETag(t *testing.T) {
+	payload := map[string]string{"body": "foo"}
+
+	// Execute
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	RespondJSONWithETag(w, req, payload)
+
+	// Test
+	assert.Equal(t, w.Code, http.StatusOK, "status code mismatch")
+	assert.Equal(t, w.Body.String(), "{\"body\":\"foo\"}\n", "body mismatch")
+	etag := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, "", "ETag should be set")
+
+	t.Run("matching If-None-Match", func(t *testing.T) {
+		// Execute
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		RespondJSONWithETag(w, req, payload)
+
+		// Test
+		assert.Equal(t, w.Code, http.StatusNotModified, "status code mismatch")
+		assert.Equal(t, w.Body.Len(), 0, "body should be empty")
+	})
+}
+
+func TestRespondValidationError(t *testing.T) {
+	verr := ValidationError{"email": FieldRequired, "password": FieldTooShort}
+
+	// Execute
+	w := httptest.NewRecorder()
+	RespondValidationError(w, verr)
+
+	// Test
+	assert.Equal(t, w.Code, http.StatusBadRequest, "status code mismatch")
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json", "content type mismatch")
+	assert.Equal(t, w.Body.String(), "{\"error\":\"validation\",\"message\":\"Email is required. Password is too short.\",\"fields\":{\"email\":\"required\",\"password\":\"too_short\"}}\n", "body mismatch")
+	assert.Equal(t, verr.Error(), "invalid payload: email required, password too_short", "error message mismatch")
+	assert.Equal(t, ValidationError{"book_uuid": FieldRequired}.Message(), "Book uuid is required.", "message mismatch")
+}
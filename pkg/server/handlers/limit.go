@@ -19,6 +19,8 @@
 package handlers
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
 	"sync"
@@ -105,14 +107,57 @@ func lookupIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
+// rateLimitedResp is a response for a rate limited request
+type rateLimitedResp struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retry_after"`
+}
+
+const rateLimitedHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Too many requests - Dnote</title>
+</head>
+<body>
+<h1>Too many requests</h1>
+<p>You are going a little too fast. Please try again in %d seconds.</p>
+</body>
+</html>
+`
+
+// respondTooManyRequests responds with the number of seconds the client should
+// wait before retrying, as HTML for browsers and as JSON otherwise
+func respondTooManyRequests(w http.ResponseWriter, r *http.Request, delay time.Duration) {
+	retryAfter := int(math.Ceil(delay.Seconds()))
+
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfter))
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(w, rateLimitedHTML, retryAfter)
+		return
+	}
+
+	RespondJSON(w, http.StatusTooManyRequests, rateLimitedResp{
+		Error:      "rate limited",
+		RetryAfter: retryAfter,
+	})
+}
+
 // Limit is a middleware to rate limit the handler
 func Limit(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		identifier := lookupIP(r)
 		limiter := getVisitor(identifier)
 
-		if !limiter.Allow() {
-			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		reservation := limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			// do not consume the token on behalf of the rejected request
+			reservation.Cancel()
+
+			respondTooManyRequests(w, r, delay)
 			log.WithFields(log.Fields{
 				"ip": identifier,
 			}).Warn("Too many requests")
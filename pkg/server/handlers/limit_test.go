@@ -0,0 +1,106 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/pkg/errors"
+)
+
+// exhaustLimit makes requests from the given ip until the limit is reached
+func exhaustLimit(h http.Handler, ip string) {
+	for i := 0; i < 60; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", ip)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	inner := func(w http.ResponseWriter, r *http.Request) {}
+
+	t.Run("json", func(t *testing.T) {
+		h := Limit(http.HandlerFunc(inner))
+		exhaustLimit(h, "10.0.0.1")
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Code, http.StatusTooManyRequests, "status code mismatch")
+
+		retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+		if err != nil {
+			t.Fatal(errors.Wrap(err, "parsing Retry-After"))
+		}
+		if retryAfter < 1 {
+			t.Errorf("Retry-After should be positive. got %d", retryAfter)
+		}
+
+		var payload rateLimitedResp
+		if err := json.NewDecoder(rec.Body).Decode(&payload); err != nil {
+			t.Fatal(errors.Wrap(err, "decoding payload"))
+		}
+
+		assert.Equal(t, payload.Error, "rate limited", "error mismatch")
+		assert.Equal(t, payload.RetryAfter, retryAfter, "retry_after mismatch")
+	})
+
+	t.Run("html", func(t *testing.T) {
+		h := Limit(http.HandlerFunc(inner))
+		exhaustLimit(h, "10.0.0.2")
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.2")
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		body, err := ioutil.ReadAll(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, rec.Code, http.StatusTooManyRequests, "status code mismatch")
+		assert.NotEqual(t, rec.Header().Get("Retry-After"), "", "Retry-After should be set")
+		assert.Equal(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html"), true, "Content-Type mismatch")
+		assert.Equal(t, strings.Contains(string(body), "Too many requests"), true, "body mismatch")
+	})
+
+	t.Run("under the limit", func(t *testing.T) {
+		h := Limit(http.HandlerFunc(inner))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.3")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Code, http.StatusOK, "status code mismatch")
+		assert.Equal(t, rec.Header().Get("Retry-After"), "", "Retry-After should not be set")
+	})
+}
@@ -19,6 +19,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -39,22 +40,50 @@ func (w *logResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+type logInfoKey struct{}
+
+// logInfo holds the information about a request that only becomes known to
+// the inner handlers, such as the authenticated user. The inner handlers see
+// a derived request, so the middleware shares a pointer through the context.
+type logInfo struct {
+	userID int
+}
+
+// setLogUser records the authenticated user of the request for logging
+func setLogUser(r *http.Request, userID int) {
+	if info, ok := r.Context().Value(logInfoKey{}).(*logInfo); ok {
+		info.userID = userID
+	}
+}
+
 // Logging is a logging middleware
 func Logging(inner http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		info := &logInfo{}
+		r = r.WithContext(context.WithValue(r.Context(), logInfoKey{}, info))
+
 		lw := logResponseWriter{w, http.StatusOK}
 		inner.ServeHTTP(&lw, r)
 
-		log.WithFields(log.Fields{
+		fields := log.Fields{
 			"origin":     r.Header.Get("Origin"),
 			"remoteAddr": lookupIP(r),
 			"uri":        r.RequestURI,
+			"path":       r.URL.Path,
 			"statusCode": lw.statusCode,
 			"method":     r.Method,
 			"duration":   fmt.Sprintf("%dms", time.Since(start)/1000000),
 			"userAgent":  r.Header.Get("User-Agent"),
-		}).Info("incoming request")
+		}
+		if info.userID != 0 {
+			fields["userId"] = info.userID
+		}
+		if requestID := GetRequestID(r.Context()); requestID != "" {
+			fields["requestId"] = requestID
+		}
+
+		log.WithFields(fields).Info("incoming request")
 	}
 }
@@ -0,0 +1,88 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/server/log"
+	"github.com/pkg/errors"
+)
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		setLogUser(r, 7)
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	req := httptest.NewRequest("GET", "/v3/books?page=2", nil)
+	req.Header.Set("X-Request-ID", "mock-request-id")
+	rec := httptest.NewRecorder()
+	RequestID(Logging(http.HandlerFunc(inner))).ServeHTTP(rec, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding the log entry"))
+	}
+
+	assert.Equal(t, entry["msg"], "incoming request", "msg mismatch")
+	assert.Equal(t, entry["method"], "GET", "method mismatch")
+	assert.Equal(t, entry["path"], "/v3/books", "path mismatch")
+	assert.Equal(t, entry["statusCode"], float64(http.StatusTeapot), "statusCode mismatch")
+	assert.Equal(t, entry["userId"], float64(7), "userId mismatch")
+	assert.Equal(t, entry["requestId"], "mock-request-id", "requestId mismatch")
+	if _, ok := entry["duration"]; !ok {
+		t.Error("duration is missing")
+	}
+}
+
+func TestLoggingUnauthenticated(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	inner := func(w http.ResponseWriter, r *http.Request) {}
+
+	req := httptest.NewRequest("POST", "/v3/signin", nil)
+	rec := httptest.NewRecorder()
+	Logging(http.HandlerFunc(inner)).ServeHTTP(rec, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(errors.Wrap(err, "decoding the log entry"))
+	}
+
+	assert.Equal(t, entry["statusCode"], float64(http.StatusOK), "statusCode mismatch")
+	if _, ok := entry["userId"]; ok {
+		t.Error("userId should be omitted for unauthenticated requests")
+	}
+	if _, ok := entry["requestId"]; ok {
+		t.Error("requestId should be omitted if the request has none")
+	}
+}
@@ -0,0 +1,73 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dnote/dnote/pkg/server/helpers"
+	"github.com/dnote/dnote/pkg/server/log"
+)
+
+// RequestIDHeader is the header that carries the id of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request id provided by a client.
+// Longer ids are replaced so that they do not bloat the logs.
+const maxRequestIDLength = 128
+
+// GetRequestID returns the id of the request stored in the given context, or
+// an empty string if there is none
+func GetRequestID(ctx context.Context) string {
+	id, ok := ctx.Value(helpers.KeyRequestID).(string)
+	if !ok {
+		return ""
+	}
+
+	return id
+}
+
+// RequestID is a middleware that identifies each request. It reuses the id
+// provided by the client, if any, and otherwise generates one. The id is
+// stored in the request context and echoed in the response header.
+func RequestID(inner http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the request may have been identified by an outer middleware already
+		if id := GetRequestID(r.Context()); id != "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			generated, err := helpers.GenUUID()
+			if err != nil {
+				log.ErrorWrap(err, "generating a request id")
+			}
+
+			id = generated
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), helpers.KeyRequestID, id)
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,107 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnote/dnote/pkg/assert"
+	"github.com/dnote/dnote/pkg/server/helpers"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Run("provided", func(t *testing.T) {
+		var got string
+		inner := func(w http.ResponseWriter, r *http.Request) {
+			got = GetRequestID(r.Context())
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "mock-request-id")
+		rec := httptest.NewRecorder()
+		RequestID(http.HandlerFunc(inner)).ServeHTTP(rec, req)
+
+		assert.Equal(t, got, "mock-request-id", "context request id mismatch")
+		assert.Equal(t, rec.Header().Get(RequestIDHeader), "mock-request-id", "header request id mismatch")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var got string
+		inner := func(w http.ResponseWriter, r *http.Request) {
+			got = GetRequestID(r.Context())
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		rec := httptest.NewRecorder()
+		RequestID(http.HandlerFunc(inner)).ServeHTTP(rec, req)
+
+		assert.Equal(t, helpers.ValidateUUID(got), true, "a request id should have been generated")
+		assert.Equal(t, rec.Header().Get(RequestIDHeader), got, "header request id mismatch")
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		var got string
+		inner := func(w http.ResponseWriter, r *http.Request) {
+			got = GetRequestID(r.Context())
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, strings.Repeat("a", maxRequestIDLength+1))
+		rec := httptest.NewRecorder()
+		RequestID(http.HandlerFunc(inner)).ServeHTTP(rec, req)
+
+		assert.Equal(t, helpers.ValidateUUID(got), true, "the provided request id should have been replaced")
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var got string
+		inner := func(w http.ResponseWriter, r *http.Request) {
+			got = GetRequestID(r.Context())
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		rec := httptest.NewRecorder()
+		RequestID(RequestID(http.HandlerFunc(inner))).ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Header().Get(RequestIDHeader), got, "the outer request id should be kept")
+	})
+}
+
+func TestDoErrorRequestID(t *testing.T) {
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		DoError(w, "mock error", nil, http.StatusInternalServerError)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "mock-request-id")
+	rec := httptest.NewRecorder()
+	RequestID(http.HandlerFunc(inner)).ServeHTTP(rec, req)
+
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, rec.Code, http.StatusInternalServerError, "status code mismatch")
+	assert.Equal(t, strings.TrimSpace(string(body)), "Internal Server Error (request id: mock-request-id)", "body mismatch")
+}
@@ -22,7 +22,10 @@ package log
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -35,8 +38,28 @@ const (
 	levelInfo  = "info"
 	levelWarn  = "warn"
 	levelError = "error"
+
+	// FormatJSON writes each log entry as a JSON object on a single line
+	FormatJSON = "json"
+	// FormatText writes each log entry as a human readable line
+	FormatText = "text"
+)
+
+var (
+	format           = FormatJSON
+	output io.Writer = os.Stderr
 )
 
+// SetFormat sets the format in which the log entries are written
+func SetFormat(f string) {
+	format = f
+}
+
+// SetOutput sets the destination of the log entries
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // Fields represents a set of information to be included in the log
 type Fields map[string]interface{}
 
@@ -105,12 +128,33 @@ func (e Entry) formatJSON(level, msg string) []byte {
 	return serialized
 }
 
+func (e Entry) formatText(level, msg string) []byte {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", e.Timestamp.Format(time.RFC3339), strings.ToUpper(level), msg)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
+	}
+
+	return []byte(b.String())
+}
+
 func (e Entry) write(level, msg string) {
-	serialized := e.formatJSON(level, msg)
+	var serialized []byte
+	if format == FormatText {
+		serialized = e.formatText(level, msg)
+	} else {
+		serialized = e.formatJSON(level, msg)
+	}
 
-	_, err := fmt.Fprintln(os.Stderr, string(serialized))
+	_, err := fmt.Fprintln(output, string(serialized))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "writing to stderr: %v\n", err)
+		fmt.Fprintf(os.Stderr, "writing log: %v\n", err)
 	}
 }
 
@@ -119,6 +163,11 @@ func Info(msg string) {
 	newEntry(Fields{}).Info(msg)
 }
 
+// Warn logs a warning message without additional fields
+func Warn(msg string) {
+	newEntry(Fields{}).Warn(msg)
+}
+
 // Error logs an error message without additional fields
 func Error(msg string) {
 	newEntry(Fields{}).Error(msg)
@@ -24,6 +24,7 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/pkg/errors"
 	"gopkg.in/gomail.v2"
 )
@@ -39,6 +40,7 @@ type Backend interface {
 // SimpleBackendImplementation is an implementation of the Backend
 // that sends an email without queueing.
 type SimpleBackendImplementation struct {
+	SMTP config.SMTPConfig
 }
 
 type dialerParams struct {
@@ -48,26 +50,21 @@ type dialerParams struct {
 	Password string
 }
 
-func getSMTPParams() (*dialerParams, error) {
-	portEnv := os.Getenv("SmtpPort")
-	hostEnv := os.Getenv("SmtpHost")
-	usernameEnv := os.Getenv("SmtpUsername")
-	passwordEnv := os.Getenv("SmtpPassword")
-
-	if portEnv == "" || hostEnv == "" || usernameEnv == "" || passwordEnv == "" {
+func getSMTPParams(c config.SMTPConfig) (*dialerParams, error) {
+	if !c.IsConfigured() {
 		return nil, ErrSMTPNotConfigured
 	}
 
-	port, err := strconv.Atoi(portEnv)
+	port, err := strconv.Atoi(c.Port)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing SMTP port")
 	}
 
 	p := &dialerParams{
-		Host:     hostEnv,
+		Host:     c.Host,
 		Port:     port,
-		Username: usernameEnv,
-		Password: passwordEnv,
+		Username: c.Username,
+		Password: c.Password,
 	}
 
 	return p, nil
@@ -89,7 +86,7 @@ func (b *SimpleBackendImplementation) Queue(subject, from string, to []string, c
 	m.SetHeader("Subject", subject)
 	m.SetBody(contentType, body)
 
-	p, err := getSMTPParams()
+	p, err := getSMTPParams(b.SMTP)
 	if err != nil {
 		return errors.Wrap(err, "getting dialer params")
 	}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	htemplate "html/template"
 	"io"
+	"io/ioutil"
 	ttemplate "text/template"
 
 	"github.com/aymerick/douceur/inliner"
@@ -176,6 +177,27 @@ func initTextTmpl(box *packr.Box, templateName string) (template, error) {
 	return t, nil
 }
 
+// ExecuteFile parses the text template in the file at the given path and
+// executes it with the given data
+func ExecuteFile(path string, data interface{}) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "reading template")
+	}
+
+	t, err := ttemplate.New(path).Parse(string(content))
+	if err != nil {
+		return "", errors.Wrap(err, "parsing template")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, data); err != nil {
+		return "", errors.Wrap(err, "executing the template")
+	}
+
+	return buf.String(), nil
+}
+
 // Execute executes the template with the given name with the givn data
 func (tmpl Templates) Execute(name, kind string, data interface{}) (string, error) {
 	t, err := tmpl.get(name, kind)
@@ -20,7 +20,9 @@ package mailer
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -104,3 +106,28 @@ func TestResetPasswordEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnote-mailer")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "creating a directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "welcome.txt")
+	if err := ioutil.WriteFile(path, []byte("Welcome {{ .AccountEmail }}"), 0644); err != nil {
+		t.Fatal(errors.Wrap(err, "writing the template"))
+	}
+
+	body, err := ExecuteFile(path, WelcomeTmplData{AccountEmail: "alice@example.com"})
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "executing"))
+	}
+	if body != "Welcome alice@example.com" {
+		t.Errorf("body mismatch. got %s", body)
+	}
+
+	if _, err := ExecuteFile(filepath.Join(dir, "missing.txt"), nil); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+}
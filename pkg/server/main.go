@@ -19,17 +19,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/dnote/dnote/pkg/clock"
 	"github.com/dnote/dnote/pkg/server/api"
 	"github.com/dnote/dnote/pkg/server/app"
 	"github.com/dnote/dnote/pkg/server/config"
 	"github.com/dnote/dnote/pkg/server/database"
+	"github.com/dnote/dnote/pkg/server/handlers"
 	"github.com/dnote/dnote/pkg/server/job"
+	serverLog "github.com/dnote/dnote/pkg/server/log"
 	"github.com/dnote/dnote/pkg/server/mailer"
 	"github.com/dnote/dnote/pkg/server/web"
 	"github.com/jinzhu/gorm"
@@ -106,7 +114,7 @@ func initApp(c config.Config) app.App {
 		DB:             db,
 		Clock:          clock.New(),
 		EmailTemplates: mailer.NewTemplates(nil),
-		EmailBackend:   &mailer.SimpleBackendImplementation{},
+		EmailBackend:   &mailer.SimpleBackendImplementation{SMTP: c.SMTP},
 		Config:         c,
 	}
 }
@@ -123,8 +131,41 @@ func runJob(a app.App) error {
 	return nil
 }
 
+// serve serves the requests accepted on the listener until a signal is
+// received on stop. It then stops accepting new connections and waits for the
+// in-flight requests to finish, giving up after the timeout.
+func serve(srv *http.Server, ln net.Listener, stop <-chan os.Signal, timeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return errors.Wrap(err, "serving")
+	case sig := <-stop:
+		serverLog.WithFields(serverLog.Fields{
+			"signal":  sig.String(),
+			"timeout": timeout.String(),
+		}).Info("shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "shutting down")
+	}
+	<-serveErr
+
+	serverLog.Info("shut down")
+
+	return nil
+}
+
 func startCmd() {
 	c := config.Load()
+	serverLog.SetFormat(c.LogFormat)
 
 	app := initApp(c)
 	defer app.DB.Close()
@@ -142,8 +183,20 @@ func startCmd() {
 		panic(errors.Wrap(err, "initializing server"))
 	}
 
+	ln, err := net.Listen("tcp", ":"+*port)
+	if err != nil {
+		panic(errors.Wrap(err, "listening"))
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	server := &http.Server{Handler: handlers.RequestID(srv)}
+
 	log.Printf("Dnote version %s is running on port %s", versionTag, *port)
-	log.Fatalln(http.ListenAndServe(":"+*port, srv))
+	if err := serve(server, ln, stop, c.GetShutdownTimeout()); err != nil {
+		log.Fatalln(err)
+	}
 }
 
 func versionCmd() {
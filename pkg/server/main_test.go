@@ -0,0 +1,106 @@
+/* Copyright (C) 2019, 2020, 2021 Monomax Software Pty Ltd
+ *
+ * This file is part of Dnote.
+ *
+ * Dnote is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * Dnote is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with Dnote.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dnote/dnote/pkg/assert"
+	serverLog "github.com/dnote/dnote/pkg/server/log"
+	"github.com/pkg/errors"
+)
+
+func TestServeShutdown(t *testing.T) {
+	serverLog.SetOutput(ioutil.Discard)
+	defer serverLog.SetOutput(os.Stderr)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "listening"))
+	}
+	addr := ln.Addr().String()
+
+	stop := make(chan os.Signal, 1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(&http.Server{Handler: handler}, ln, stop, 5*time.Second)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + addr)
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+
+		b, err := ioutil.ReadAll(res.Body)
+		resCh <- result{body: string(b), err: err}
+	}()
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	// new connections are refused once the shutdown has begun
+	refused := false
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			refused = true
+			break
+		}
+		conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, refused, true, "new connection should be refused")
+
+	select {
+	case <-serveErr:
+		t.Fatal("serve returned before the in-flight request finished")
+	default:
+	}
+
+	close(release)
+
+	res := <-resCh
+	if res.err != nil {
+		t.Fatal(errors.Wrap(res.err, "making the in-flight request"))
+	}
+	assert.Equal(t, res.body, "done", "body mismatch")
+	assert.Equal(t, <-serveErr, nil, "serve error mismatch")
+}
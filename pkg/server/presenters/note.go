@@ -32,7 +32,9 @@ type Note struct {
 	Body      string    `json:"content"`
 	AddedOn   int64     `json:"added_on"`
 	Public    bool      `json:"public"`
+	ShareSlug string    `json:"share_slug"`
 	USN       int       `json:"usn"`
+	Source    string    `json:"source"`
 	Book      NoteBook  `json:"book"`
 	User      NoteUser  `json:"user"`
 }
@@ -58,7 +60,9 @@ func PresentNote(note database.Note) Note {
 		Body:      note.Body,
 		AddedOn:   note.AddedOn,
 		Public:    note.Public,
+		ShareSlug: note.ShareSlug,
 		USN:       note.USN,
+		Source:    note.Client,
 		Book: NoteBook{
 			UUID:  note.Book.UUID,
 			Label: note.Book.Label,